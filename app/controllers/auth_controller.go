@@ -1,8 +1,10 @@
 package controllers
 
 import (
-	"context"
+	"errors"
+	"fmt"
 	"github.com/aryanicosa/go-fiber-rest-api/app/models"
+	"github.com/aryanicosa/go-fiber-rest-api/app/queries"
 	"github.com/aryanicosa/go-fiber-rest-api/pkg/response"
 	"github.com/aryanicosa/go-fiber-rest-api/pkg/utils"
 	"github.com/aryanicosa/go-fiber-rest-api/platform/cache"
@@ -48,10 +50,14 @@ func UserSignUp(c *fiber.Ctx) error {
 	}
 
 	// Checking role from sign up data.
-	role, err := utils.VerifyRole(signUp.UserRole)
+	roles, err := database.RoleDB().GetRolesByNames([]string{signUp.UserRole})
 	if err != nil {
+		// Return status 500 and error message.
+		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+	}
+	if len(roles) == 0 {
 		// Return status 400 and error message.
-		return response.RespondError(c, fiber.StatusBadRequest, err.Error())
+		return response.RespondError(c, fiber.StatusBadRequest, fmt.Sprintf("role '%v' does not exist", signUp.UserRole))
 	}
 
 	// Create a new user struct.
@@ -63,7 +69,7 @@ func UserSignUp(c *fiber.Ctx) error {
 	user.Email = signUp.Email
 	user.PasswordHash = utils.GeneratePassword(signUp.Password)
 	user.UserStatus = 1 // 0 == blocked, 1 == active
-	user.UserRole = role
+	user.UserRoles = []string{roles[0].Name}
 
 	// Validate user fields.
 	if err := validate.Struct(user); err != nil {
@@ -91,6 +97,7 @@ func UserSignUp(c *fiber.Ctx) error {
 // UserSignIn godoc
 // @Summary 	Sign In
 // @Description Sign In a User to get access token
+// @Description Every sign in starts a new session, other devices of the user stay signed in
 // @Description Require Basic Auth
 // @Accept 		json
 // @Produce 	json
@@ -99,6 +106,8 @@ func UserSignUp(c *fiber.Ctx) error {
 // @Param models.SignIn body models.SignIn true "User Credentials"
 // @Success 200 {object} utils.Tokens
 // @Failure 400 {object} response.HTTPError
+// @Failure 401 {object} response.HTTPError
+// @Failure 403 {object} response.HTTPError
 // @Failure 404 {object} response.HTTPError
 // @Failure 500 {object} response.HTTPError
 // @Router /v1/user/sign/in [post]
@@ -127,35 +136,49 @@ func UserSignIn(c *fiber.Ctx) error {
 		return response.RespondError(c, fiber.StatusUnauthorized, "wrong user email address or password")
 	}
 
-	// Get role credentials from founded user.
-	credentials, err := utils.GetCredentialsByRole(foundedUser.UserRole)
-	if err != nil {
+	// Checking, if the user is blocked.
+	if foundedUser.UserStatus == 0 {
 		// Return status 403 and error message.
-		return response.RespondError(c, fiber.StatusForbidden, err.Error())
+		return response.RespondError(c, fiber.StatusForbidden, errUserBlocked)
+	}
+
+	// Get permissions of all roles of founded user.
+	credentials, err := database.RoleDB().GetUserPermissions(foundedUser.ID)
+	if err != nil {
+		// Return status 500 and error message.
+		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	// Start a new session, other devices of the user stay signed in.
+	now := time.Now()
+	session := &models.Session{
+		ID:         uuid.New(),
+		UserID:     foundedUser.ID,
+		Device:     sessionDevice(c, signIn.Device),
+		IP:         c.IP(),
+		CreatedAt:  now,
+		LastUsedAt: now,
+		ExpiresAt:  now.Add(utils.RefreshTokenLifetime()),
 	}
 
 	// Generate a new pair of access and refresh tokens.
-	tokens, err := utils.GenerateNewTokens(foundedUser.ID.String(), credentials)
+	tokens, err := utils.GenerateNewSessionTokens(foundedUser.ID.String(), session.ID.String(), credentials)
 	if err != nil {
 		// Return status 500 and token generation error.
 		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
 	}
 
-	// Define user ID.
-	userID := foundedUser.ID.String()
-
 	// Create a new Redis connection.
-	connRedis, err := cache.RedisConnection()
+	sessions, err := cache.SessionCache()
 	if err != nil {
 		// Return status 500 and Redis connection error.
 		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
 	}
 
-	// Save refresh token to Redis.
-	errSaveToRedis := connRedis.Set(context.Background(), userID, tokens.RefreshToken, 0).Err()
-	if errSaveToRedis != nil {
+	// Save session with its refresh token to Redis.
+	if err := sessions.SaveSession(session, tokens.RefreshToken); err != nil {
 		// Return status 500 and Redis connection error.
-		return response.RespondError(c, fiber.StatusInternalServerError, errSaveToRedis.Error())
+		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
 	}
 
 	// Return status 200 OK.
@@ -164,7 +187,7 @@ func UserSignIn(c *fiber.Ctx) error {
 
 // UserSignOut godoc
 // @Summary 	Sign Out
-// @Description de-authorize User and revoke token from redis
+// @Description de-authorize User and revoke the session of the token from redis
 // @Accept 		json
 // @Produce 	json
 // @Tags 						User
@@ -181,21 +204,25 @@ func UserSignOut(c *fiber.Ctx) error {
 		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
 	}
 
-	// Define user ID.
-	userID := claims.UserID.String()
-
-	// Create a new Redis connection.
-	connRedis, err := cache.RedisConnection()
-	if err != nil {
-		// Return status 500 and Redis connection error.
-		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+	// Deny the token itself, it stops working before it expires.
+	if claims.TokenID != "" {
+		denylist, err := cache.TokenDenylistCache()
+		if err != nil {
+			// Return status 500 and Redis connection error.
+			return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+		}
+		if err := denylist.DenyToken(claims.TokenID, time.Unix(claims.Expires, 0)); err != nil {
+			// Return status 500 and Redis connection error.
+			return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+		}
 	}
 
-	// Save refresh token to Redis.
-	errDelFromRedis := connRedis.Del(context.Background(), userID).Err()
-	if errDelFromRedis != nil {
-		// Return status 500 and Redis deletion error.
-		return response.RespondError(c, fiber.StatusInternalServerError, errDelFromRedis.Error())
+	// End the session of the token, other devices stay signed in.
+	if claims.SessionID != uuid.Nil {
+		if err := revokeSessions(models.Session{ID: claims.SessionID, UserID: claims.UserID}); err != nil {
+			// Return status 500 and Redis deletion error.
+			return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+		}
 	}
 
 	// Return status 204 no content.
@@ -205,15 +232,17 @@ func UserSignOut(c *fiber.Ctx) error {
 // RenewTokens godoc
 // @Summary 	Get New Access token use refresh token
 // @Description re-authorize a User to get access token using refresh token
+// @Description A refresh token works once, the response has the next one, reusing one revokes the whole session
 // @Description Require valid user token
 // @Accept 		json
 // @Produce 	json
 // @Tags 						User
 // @Security ApiKeyAuth
-// @Param models.SignIn body models.SignIn true "User Credentials"
+// @Param models.Renew body models.Renew true "Refresh token"
 // @Success 200 {object} utils.Tokens
 // @Failure 400 {object} response.HTTPError
 // @Failure 401 {object} response.HTTPError
+// @Failure 403 {object} response.HTTPError
 // @Failure 500 {object} response.HTTPError
 // @Router /v1/user/sign/renew [post]
 func RenewTokens(c *fiber.Ctx) error {
@@ -253,49 +282,83 @@ func RenewTokens(c *fiber.Ctx) error {
 	}
 
 	// Checking, if now time greater than RefreshToken token expiration time.
-	if now < expiresRefreshToken {
-		// Define user ID.
-		userID := claims.UserID
-
-		// Get user by ID.
-		db := database.UserDB()
-		foundedUser, err := db.GetUserByID(userID)
-		if err != nil {
-			// Return, if user not found.
-			return response.RespondError(c, fiber.StatusNotFound, "user with the given ID is not found")
-		}
-
-		// Get role credentials from founded user.
-		credentials, err := utils.GetCredentialsByRole(foundedUser.UserRole)
-		if err != nil {
-			// Return status 400 and error message.
-			return response.RespondError(c, fiber.StatusBadRequest, err.Error())
-		}
+	if now >= expiresRefreshToken {
+		// Return status 401 and unauthorized error message.
+		return response.RespondError(c, fiber.StatusUnauthorized, "unauthorized, your session was ended earlier")
+	}
 
-		// Generate JWT AccessToken & RefreshToken tokens.
-		tokens, err := utils.GenerateNewTokens(userID.String(), credentials)
-		if err != nil {
-			// Return status 500 and token generation error.
-			return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
-		}
+	// Create a new Redis connection.
+	sessions, err := cache.SessionCache()
+	if err != nil {
+		// Return status 500 and Redis connection error.
+		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+	}
 
-		// Create a new Redis connection.
-		connRedis, err := cache.RedisConnection()
-		if err != nil {
+	// Exchange the refresh token of the session of the token, each one works only once.
+	session, err := sessions.UseRefreshToken(renew.RefreshToken, claims.UserID, claims.SessionID)
+	if errors.Is(err, queries.ErrRefreshTokenReused) && session.ID != uuid.Nil {
+		// The session is ended already, deny the access tokens issued to it as well.
+		if err := revokeSessions(session); err != nil {
 			// Return status 500 and Redis connection error.
 			return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
 		}
-
-		// Save refresh token to Redis.
-		errRedis := connRedis.Set(context.Background(), userID.String(), tokens.RefreshToken, 0).Err()
-		if errRedis != nil {
-			// Return status 500 and Redis connection error.
-			return response.RespondError(c, fiber.StatusInternalServerError, errRedis.Error())
+	}
+	if err != nil {
+		if errors.Is(err, queries.ErrRefreshTokenInvalid) || errors.Is(err, queries.ErrRefreshTokenReused) {
+			// Return status 401 and unauthorized error message.
+			return response.RespondError(c, fiber.StatusUnauthorized, err.Error())
 		}
+		// Return status 500 and Redis connection error.
+		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+	}
 
-		return response.RespondSuccess(c, fiber.StatusOK, tokens)
-	} else {
-		// Return status 401 and unauthorized error message.
-		return response.RespondError(c, fiber.StatusUnauthorized, "unauthorized, your session was ended earlier")
+	// Get user by ID.
+	db := database.UserDB()
+	foundedUser, err := db.GetUserByID(session.UserID)
+	if err != nil {
+		// Return, if user not found.
+		return response.RespondError(c, fiber.StatusNotFound, "user with the given ID is not found")
+	}
+
+	// Checking, if the user was blocked meanwhile.
+	if foundedUser.UserStatus == 0 {
+		// Return status 403 and error message.
+		return response.RespondError(c, fiber.StatusForbidden, errUserBlocked)
+	}
+
+	// Get permissions of all roles of founded user.
+	credentials, err := database.RoleDB().GetUserPermissions(foundedUser.ID)
+	if err != nil {
+		// Return status 500 and error message.
+		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	// Generate JWT AccessToken & RefreshToken tokens.
+	tokens, err := utils.GenerateNewSessionTokens(foundedUser.ID.String(), session.ID.String(), credentials)
+	if err != nil {
+		// Return status 500 and token generation error.
+		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	// Save session with its new refresh token to Redis, it lasts from now on.
+	session.IP = c.IP()
+	session.LastUsedAt = time.Now()
+	session.ExpiresAt = session.LastUsedAt.Add(utils.RefreshTokenLifetime())
+	if err := sessions.SaveSession(&session, tokens.RefreshToken); err != nil {
+		// Return status 500 and Redis connection error.
+		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	return response.RespondSuccess(c, fiber.StatusOK, tokens)
+}
+
+// sessionDevice func for naming the session of a sign in, by the given device or the User-Agent.
+func sessionDevice(c *fiber.Ctx, device string) string {
+	if device == "" {
+		device = c.Get(fiber.HeaderUserAgent)
+	}
+	if runes := []rune(device); len(runes) > 100 {
+		device = string(runes[:100])
 	}
+	return device
 }
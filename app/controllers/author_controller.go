@@ -0,0 +1,575 @@
+package controllers
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/aryanicosa/go-fiber-rest-api/app/models"
+	"github.com/aryanicosa/go-fiber-rest-api/app/queries"
+	"github.com/aryanicosa/go-fiber-rest-api/pkg/repository"
+	"github.com/aryanicosa/go-fiber-rest-api/pkg/response"
+	"github.com/aryanicosa/go-fiber-rest-api/pkg/utils"
+	"github.com/aryanicosa/go-fiber-rest-api/platform/database"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// GetAuthors godoc
+// @Description Will display authors sorted by name, with the number of published books credited to them
+// @Description Require Basic Auth
+// @Summary Get All Authors
+// @Tags Author
+// @Accept json
+// @Produce json
+// @Security BasicAuth
+// @Param page query int false "Page number, starts from 1"
+// @Param limit query int false "Page size, up to 100"
+// @Success 200 {object} models.AllAuthors
+// @Failure 400 {object} response.HTTPError
+// @Failure 404 {object} response.HTTPError
+// @Router /v1/authors [get]
+func GetAuthors(c *fiber.Ctx) error {
+	pagination, err := utils.ParsePagination(c)
+	if err != nil {
+		// Return status 400 and error message.
+		return response.RespondError(c, fiber.StatusBadRequest, err.Error())
+	}
+	if pagination.UseCursor {
+		// Authors are sorted by name, so there is no keyset to follow.
+		return response.RespondError(c, fiber.StatusBadRequest, "cursor paging is not supported for authors")
+	}
+
+	// Get one page of authors.
+	db := database.AuthorDB()
+	authors, total, err := db.GetAuthors(pagination)
+	if err != nil {
+		// Return, if authors not found.
+		return response.RespondError(c, fiber.StatusNotFound, "authors were not found")
+	}
+
+	allAuthors := &models.AllAuthors{
+		Authors: authors,
+		Count:   total,
+		Page:    pagination.Page,
+		Limit:   pagination.Limit,
+		Links:   utils.BuildPageLinks(c, pagination, total, false, nil, nil),
+	}
+
+	// Return status 200 OK.
+	return response.RespondSuccess(c, fiber.StatusOK, allAuthors)
+}
+
+// GetAuthorBooks godoc
+// @Description Will display published books by the author name shown on them, ignoring case
+// @Description Accepts the same filters, sort and paging as the books list
+// @Description Require Basic Auth
+// @Summary Get books by author
+// @Tags Author
+// @Accept json
+// @Produce json
+// @Security BasicAuth
+// @Param name path string true "Author name"
+// @Param page query int false "Page number, starts from 1"
+// @Param limit query int false "Page size, up to 100"
+// @Param cursor query string false "Keyset cursor from `links`"
+// @Param sort query string false "Comma separated columns, prefix with `-` for descending"
+// @Success 200 {object} models.AllBooks
+// @Failure 400 {object} response.HTTPError
+// @Failure 404 {object} response.HTTPError
+// @Router /v1/authors/{name}/books [get]
+func GetAuthorBooks(c *fiber.Ctx) error {
+	// Catch author name from URL.
+	name, err := url.PathUnescape(c.Params("name"))
+	if err != nil || name == "" {
+		// Return status 400 and error message.
+		return response.RespondError(c, fiber.StatusBadRequest, "author name is malformed")
+	}
+
+	// Read filters, sort and paging from query params.
+	filter, err := parsePublicBookFilter(c)
+	if err != nil {
+		// Return status 400 and error message.
+		return response.RespondError(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	// Get one page of the author books.
+	db := database.BookDB()
+	page, err := db.GetBooksByAuthor(name, filter)
+	if err != nil {
+		// Return, if books not found.
+		return response.RespondError(c, fiber.StatusNotFound, "books were not found")
+	}
+
+	// Return status 200 OK.
+	return response.RespondSuccess(c, fiber.StatusOK, newAllBooks(c, &filter.Pagination, page))
+}
+
+// GetAuthor godoc
+// @Description Will display the profile of one author
+// @Description Require Basic Auth
+// @Summary Get author by ID
+// @Tags Author
+// @Accept json
+// @Produce json
+// @Security BasicAuth
+// @Param author_id path string true "Author ID"
+// @Success 200 {object} models.Author
+// @Failure 400 {object} response.HTTPError
+// @Failure 404 {object} response.HTTPError
+// @Router /v1/author/{author_id} [get]
+func GetAuthor(c *fiber.Ctx) error {
+	// Catch author ID from URL.
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return response.RespondError(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	// Get author by ID.
+	author, err := database.AuthorDB().GetAuthor(id)
+	if err != nil {
+		// Return status 404 and author not found error.
+		return response.RespondError(c, fiber.StatusNotFound, "author with given ID not found")
+	}
+
+	// Return status 200 OK.
+	return response.RespondSuccess(c, fiber.StatusOK, author)
+}
+
+// CreateAuthor godoc
+// @Description Add an author profile, books are credited to it under `/v1/book/{book_id}/authors`
+// @Description Require valid user token with `book:create` credential
+// @Summary Create new author
+// @Tags Author
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param models.Author body models.Author true "Author data"
+// @Success 201 {object} models.Author
+// @Failure 400 {object} response.HTTPError
+// @Failure 401 {object} response.HTTPError
+// @Failure 403 {object} response.HTTPError
+// @Failure 500 {object} response.HTTPError
+// @Router /v1/author [post]
+func CreateAuthor(c *fiber.Ctx) error {
+	// Get claims from JWT.
+	claims, err := utils.ExtractTokenMetadata(c)
+	if err != nil {
+		// Return status 500 and JWT parse error.
+		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	if isError, errorCode, errorMessage := claimCheck(claims, repository.BookCreateCredential); isError {
+		return response.RespondError(c, errorCode, errorMessage)
+	}
+
+	// Create new Author struct
+	author := &models.Author{}
+
+	// Check, if received JSON data is valid.
+	if err := c.BodyParser(author); err != nil {
+		// Return status 400 and error message.
+		return response.RespondError(c, fiber.StatusBadRequest, "unable to parse request body")
+	}
+
+	// Set initialized default data for author:
+	author.ID = uuid.New()
+	author.CreatedAt = time.Now()
+	author.UpdatedAt = author.CreatedAt
+
+	// Validate author fields.
+	validate := utils.NewValidator()
+	if err := validate.Struct(author); err != nil {
+		// Return, if some fields are not valid.
+		return response.RespondError(c, fiber.StatusBadRequest, utils.ValidatorErrors(err))
+	}
+
+	if err := database.AuthorDB().CreateAuthor(author); err != nil {
+		// Return status 500 and error message.
+		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	// Return status 201.
+	return response.RespondSuccess(c, fiber.StatusCreated, author)
+}
+
+// UpdateAuthor godoc
+// @Description Change the profile of an author, author names shown on books are kept
+// @Description Require valid user token with `book:taxonomy` credential
+// @Summary Update author
+// @Tags Author
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param author_id path string true "Author ID"
+// @Param models.Author body models.Author true "Author data"
+// @Success 200 {object} models.Author
+// @Failure 400 {object} response.HTTPError
+// @Failure 401 {object} response.HTTPError
+// @Failure 403 {object} response.HTTPError
+// @Failure 404 {object} response.HTTPError
+// @Failure 500 {object} response.HTTPError
+// @Router /v1/author/{author_id} [put]
+func UpdateAuthor(c *fiber.Ctx) error {
+	// Catch author ID from URL.
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return response.RespondError(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	// Get claims from JWT.
+	claims, err := utils.ExtractTokenMetadata(c)
+	if err != nil {
+		// Return status 500 and JWT parse error.
+		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	if isError, errorCode, errorMessage := claimCheck(claims, repository.BookTaxonomyCredential); isError {
+		return response.RespondError(c, errorCode, errorMessage)
+	}
+
+	// Checking, if author with given ID is exists.
+	db := database.AuthorDB()
+	foundedAuthor, err := db.GetAuthor(id)
+	if err != nil {
+		// Return status 404 and author not found error.
+		return response.RespondError(c, fiber.StatusNotFound, "author with given ID not found")
+	}
+
+	// Create new Author struct
+	author := &models.Author{}
+
+	// Check, if received JSON data is valid.
+	if err := c.BodyParser(author); err != nil {
+		// Return status 400 and error message.
+		return response.RespondError(c, fiber.StatusBadRequest, "unable to parse request body")
+	}
+
+	// Keep data that can not be changed.
+	author.ID = foundedAuthor.ID
+	author.CreatedAt = foundedAuthor.CreatedAt
+	author.UpdatedAt = time.Now()
+
+	// Validate author fields.
+	validate := utils.NewValidator()
+	if err := validate.Struct(author); err != nil {
+		// Return, if some fields are not valid.
+		return response.RespondError(c, fiber.StatusBadRequest, utils.ValidatorErrors(err))
+	}
+
+	if err := db.UpdateAuthor(author); err != nil {
+		// Return status 500 and error message.
+		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	// Return status 200 OK.
+	return response.RespondSuccess(c, fiber.StatusOK, author)
+}
+
+// DeleteAuthor godoc
+// @Description Books keep existing and keep the author name shown on them, but lose the credit
+// @Description Require valid user token with `book:taxonomy` credential
+// @Summary Delete an author
+// @Tags Author
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param author_id path string true "Author ID"
+// @Success 204
+// @Failure 400 {object} response.HTTPError
+// @Failure 401 {object} response.HTTPError
+// @Failure 403 {object} response.HTTPError
+// @Failure 404 {object} response.HTTPError
+// @Router /v1/author/{author_id} [delete]
+func DeleteAuthor(c *fiber.Ctx) error {
+	// Catch author ID from URL.
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return response.RespondError(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	// Get claims from JWT.
+	claims, err := utils.ExtractTokenMetadata(c)
+	if err != nil {
+		// Return status 500 and JWT parse error.
+		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	if isError, errorCode, errorMessage := claimCheck(claims, repository.BookTaxonomyCredential); isError {
+		return response.RespondError(c, errorCode, errorMessage)
+	}
+
+	if err := database.AuthorDB().DeleteAuthor(id); err != nil {
+		// Return status 404 and error message.
+		return response.RespondError(c, fiber.StatusNotFound, err.Error())
+	}
+
+	// Return status 204 no content.
+	return response.RespondSuccess(c, fiber.StatusNoContent, "")
+}
+
+// GetAuthorBooksByID godoc
+// @Description Will display published books crediting one author in any role, whatever author name is shown on them
+// @Description Accepts the same filters, sort and paging as the books list
+// @Description Require Basic Auth
+// @Summary Get books credited to an author
+// @Tags Author
+// @Accept json
+// @Produce json
+// @Security BasicAuth
+// @Param author_id path string true "Author ID"
+// @Param page query int false "Page number, starts from 1"
+// @Param limit query int false "Page size, up to 100"
+// @Param cursor query string false "Keyset cursor from `links`"
+// @Param sort query string false "Comma separated columns, prefix with `-` for descending"
+// @Success 200 {object} models.AllBooks
+// @Failure 400 {object} response.HTTPError
+// @Failure 404 {object} response.HTTPError
+// @Router /v1/author/{author_id}/books [get]
+func GetAuthorBooksByID(c *fiber.Ctx) error {
+	// Catch author ID from URL.
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return response.RespondError(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	// Read filters, sort and paging from query params.
+	filter, err := parsePublicBookFilter(c)
+	if err != nil {
+		// Return status 400 and error message.
+		return response.RespondError(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	// Checking, if author with given ID is exists.
+	author, err := database.AuthorDB().GetAuthor(id)
+	if err != nil {
+		// Return status 404 and author not found error.
+		return response.RespondError(c, fiber.StatusNotFound, "author with given ID not found")
+	}
+
+	filter.AuthorID = &author.ID
+
+	// Get one page of the author books.
+	page, err := database.BookDB().GetBooks(filter)
+	if err != nil {
+		// Return, if books not found.
+		return response.RespondError(c, fiber.StatusNotFound, "books were not found")
+	}
+
+	// Return status 200 OK.
+	return response.RespondSuccess(c, fiber.StatusOK, newAllBooks(c, &filter.Pagination, page))
+}
+
+// GetBookAuthors godoc
+// @Description Will display authors credited on a book with their roles, in the order of the credits
+// @Description Require Basic Auth
+// @Description Unpublished books are only shown to the owner, collaborators and users with `book:publish` credential, sending their token instead
+// @Summary Get authors of a book
+// @Tags Author
+// @Accept json
+// @Produce json
+// @Security BasicAuth
+// @Security ApiKeyAuth
+// @Param book_id path string true "Book ID"
+// @Success 200 {array} models.BookAuthor
+// @Failure 400 {object} response.HTTPError
+// @Failure 404 {object} response.HTTPError
+// @Failure 500 {object} response.HTTPError
+// @Router /v1/book/{book_id}/authors [get]
+func GetBookAuthors(c *fiber.Ctx) error {
+	// Catch book ID from URL.
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return response.RespondError(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	// Checking, if book with given ID is exists.
+	foundedBook, err := database.BookDB().GetBookById(id)
+	if err != nil {
+		// Return status 404 and book not found error.
+		return response.RespondError(c, fiber.StatusNotFound, "book with given ID not found")
+	}
+
+	// Checking, if caller may see the book.
+	if isError, errorCode, errorMessage := bookVisibleCheck(c, &foundedBook); isError {
+		return response.RespondError(c, errorCode, errorMessage)
+	}
+
+	authors, err := database.AuthorDB().GetBookAuthors(foundedBook.ID)
+	if err != nil {
+		// Return status 500 and error message.
+		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	// Return status 200 OK.
+	return response.RespondSuccess(c, fiber.StatusOK, authors)
+}
+
+// SetBookAuthors godoc
+// @Description Replace all credits of a book with the given authors and roles, in order
+// @Description The author name shown on the book becomes the names of authors credited with role `author`
+// @Description Require valid user token with `book:update` credential
+// @Summary Set authors of a book
+// @Tags Author
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param book_id path string true "Book ID"
+// @Param models.BookAuthors body models.BookAuthors true "Author credits"
+// @Param reason query string false "Required when updating a book of another user with `book:update:any`"
+// @Param If-Match header string false "ETag of the edited version, answered with 412 if outdated"
+// @Success 200 {array} models.BookAuthor
+// @Failure 400 {object} response.HTTPError
+// @Failure 401 {object} response.HTTPError
+// @Failure 403 {object} response.HTTPError
+// @Failure 404 {object} response.HTTPError
+// @Failure 412 {object} response.HTTPError
+// @Failure 500 {object} response.HTTPError
+// @Router /v1/book/{book_id}/authors [put]
+func SetBookAuthors(c *fiber.Ctx) error {
+	// Catch book ID from URL.
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return response.RespondError(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	// Get claims from JWT.
+	claims, err := utils.ExtractTokenMetadata(c)
+	if err != nil {
+		// Return status 500 and JWT parse error.
+		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	if isError, errorCode, errorMessage := claimCheck(claims, repository.BookUpdateCredential); isError {
+		return response.RespondError(c, errorCode, errorMessage)
+	}
+
+	// Create new BookAuthors struct
+	bookAuthors := &models.BookAuthors{}
+
+	// Check, if received JSON data is valid.
+	if err := c.BodyParser(bookAuthors); err != nil {
+		// Return status 400 and error message.
+		return response.RespondError(c, fiber.StatusBadRequest, "unable to parse request body")
+	}
+
+	// Validate credits fields.
+	validate := utils.NewValidator()
+	if err := validate.Struct(bookAuthors); err != nil {
+		// Return, if some fields are not valid.
+		return response.RespondError(c, fiber.StatusBadRequest, utils.ValidatorErrors(err))
+	}
+
+	// Checking, if book with given ID is exists.
+	foundedBook, err := database.BookDB().GetBookById(id)
+	if err != nil {
+		// Return status 404 and book not found error.
+		return response.RespondError(c, fiber.StatusNotFound, "book with given ID not found")
+	}
+
+	// Check, if client edits the latest version.
+	if isError, errorCode, errorMessage := bookPreconditionCheck(c, &foundedBook); isError {
+		return response.RespondError(c, errorCode, errorMessage)
+	}
+
+	// Only the owner and editors can update the book, others need an audited override.
+	auditLog, isError, errorCode, errorMessage := bookPolicyCheck(c, &foundedBook, claims, bookActionUpdate)
+	if isError {
+		return response.RespondError(c, errorCode, errorMessage)
+	}
+
+	// Checking, if all authors exist, and naming the book after those credited as author.
+	byline, isError, errorCode, errorMessage := bookAuthorsBylineCheck(bookAuthors.Authors)
+	if isError {
+		return response.RespondError(c, errorCode, errorMessage)
+	}
+
+	// Credits, the author name, its revision and the override are written together.
+	err = database.Transaction(func(tx *gorm.DB) error {
+		if err := (&queries.AuthorQueries{DB: tx}).SetBookAuthors(foundedBook.ID, bookAuthors.Authors); err != nil {
+			return err
+		}
+		if err := createBookAuditLog(tx, auditLog); err != nil {
+			return err
+		}
+		if byline == "" || byline == foundedBook.Author {
+			return nil
+		}
+
+		_, err := (&queries.BookQueries{DB: tx}).PatchBook(foundedBook.ID, foundedBook.Version, map[string]interface{}{
+			"author":     byline,
+			"updated_at": time.Now(),
+		})
+		if err != nil {
+			return err
+		}
+		return recordBookRevision(tx, foundedBook.ID, claims.UserID, repository.BookUpdateAction)
+	})
+	if err != nil {
+		if errors.Is(err, queries.ErrBookVersionMismatch) {
+			// Return status 412 and error message.
+			return response.RespondError(c, fiber.StatusPreconditionFailed, err.Error())
+		}
+		// Return status 500 and error message.
+		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	authors, err := database.AuthorDB().GetBookAuthors(foundedBook.ID)
+	if err != nil {
+		// Return status 500 and error message.
+		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	// Return status 200 OK.
+	return response.RespondSuccess(c, fiber.StatusOK, authors)
+}
+
+// bookAuthorsBylineCheck func for checking that all credited authors exist,
+// and joining the names of those credited as author, empty if there are none.
+func bookAuthorsBylineCheck(credits []models.BookAuthorCredit) (string, bool, int, interface{}) {
+	ids := make([]uuid.UUID, 0, len(credits))
+	for _, credit := range credits {
+		ids = append(ids, credit.AuthorID)
+	}
+
+	authors, err := database.AuthorDB().GetAuthorsByIDs(ids)
+	if err != nil {
+		// Return status 500 and error message.
+		return "", true, fiber.StatusInternalServerError, err.Error()
+	}
+	names := make(map[uuid.UUID]string, len(authors))
+	for _, author := range authors {
+		names[author.ID] = author.Name
+	}
+
+	bylineNames := []string{}
+	for _, credit := range credits {
+		name, ok := names[credit.AuthorID]
+		if !ok {
+			// Return status 400 and error message.
+			return "", true, fiber.StatusBadRequest, "author not found: " + credit.AuthorID.String()
+		}
+		if credit.Role == repository.AuthorRoleAuthor {
+			bylineNames = append(bylineNames, name)
+		}
+	}
+
+	byline := strings.Join(bylineNames, ", ")
+	if len(byline) > 255 {
+		// Return status 400 and error message.
+		return "", true, fiber.StatusBadRequest, "names of credited authors are longer than 255 characters"
+	}
+
+	return byline, false, 0, ""
+}
+
+// recreditBookAuthor func for crediting a book to its new author name with given transaction, so the credits
+// keep up with updates, patches and reverts. Nothing is done while the name stays the same.
+func recreditBookAuthor(tx *gorm.DB, before *models.Book, author string) error {
+	if author == before.Author {
+		return nil
+	}
+	return (&queries.AuthorQueries{DB: tx}).RecreditBookAuthorByName(before.ID, author)
+}
@@ -0,0 +1,300 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aryanicosa/go-fiber-rest-api/app/models"
+	"github.com/aryanicosa/go-fiber-rest-api/app/queries"
+	"github.com/aryanicosa/go-fiber-rest-api/pkg/repository"
+	"github.com/aryanicosa/go-fiber-rest-api/pkg/response"
+	"github.com/aryanicosa/go-fiber-rest-api/pkg/utils"
+	"github.com/aryanicosa/go-fiber-rest-api/platform/database"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// bookAttrFilterPrefix is the prefix of query params filtering books by a registered attribute.
+const bookAttrFilterPrefix = "attr."
+
+// bookAttrNamePattern matches names of registered attributes, which are also their keys in `book_attrs`.
+var bookAttrNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// GetBookAttrDefinitions godoc
+// @Description Will display the extra attributes books may have in `book_attrs`, sorted by name
+// @Description Require Basic Auth
+// @Summary Get registered book attributes
+// @Tags Book attribute
+// @Accept json
+// @Produce json
+// @Security BasicAuth
+// @Success 200 {array} models.BookAttrDefinition
+// @Failure 500 {object} response.HTTPError
+// @Router /v1/attrs [get]
+func GetBookAttrDefinitions(c *fiber.Ctx) error {
+	// Get all definitions.
+	definitions, err := database.BookAttrDB().GetBookAttrDefinitions()
+	if err != nil {
+		// Return status 500 and error message.
+		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	// Return status 200 OK.
+	return response.RespondSuccess(c, fiber.StatusOK, definitions)
+}
+
+// CreateBookAttrDefinition godoc
+// @Description Register an extra attribute of books, set in `book_attrs` under its name
+// @Description `type` is one of `string`, `number`, `integer`, `boolean` or `enum`, only enums have `enum_values`
+// @Description Books without a value of a `required` attribute must get one on their next update
+// @Description Require valid user token with `book:attrs` credential
+// @Summary Register book attribute
+// @Tags Book attribute
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param models.BookAttrDefinition body models.BookAttrDefinition true "Attribute definition"
+// @Success 201 {object} models.BookAttrDefinition
+// @Failure 400 {object} response.HTTPError
+// @Failure 401 {object} response.HTTPError
+// @Failure 403 {object} response.HTTPError
+// @Failure 409 {object} response.HTTPError
+// @Failure 500 {object} response.HTTPError
+// @Router /v1/attr [post]
+func CreateBookAttrDefinition(c *fiber.Ctx) error {
+	// Get claims from JWT.
+	claims, err := utils.ExtractTokenMetadata(c)
+	if err != nil {
+		// Return status 500 and JWT parse error.
+		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	if isError, errorCode, errorMessage := claimCheck(claims, repository.BookAttrsCredential); isError {
+		return response.RespondError(c, errorCode, errorMessage)
+	}
+
+	// Create new BookAttrDefinition struct
+	definition := &models.BookAttrDefinition{}
+
+	// Check, if received JSON data is valid.
+	if err := c.BodyParser(definition); err != nil {
+		// Return status 400 and error message.
+		return response.RespondError(c, fiber.StatusBadRequest, "unable to parse request body")
+	}
+
+	// Set initialized default data for definition:
+	definition.ID = uuid.New()
+	definition.CreatedAt = time.Now()
+	if definition.EnumValues == nil {
+		definition.EnumValues = models.EnumValues{}
+	}
+
+	// Validate definition fields.
+	validate := utils.NewValidator()
+	if err := validate.Struct(definition); err != nil {
+		// Return, if some fields are not valid.
+		return response.RespondError(c, fiber.StatusBadRequest, utils.ValidatorErrors(err))
+	}
+	if !bookAttrNamePattern.MatchString(definition.Name) {
+		// Return status 400 and error message.
+		return response.RespondError(c, fiber.StatusBadRequest, "name must be lower case letters, digits and underscores")
+	}
+	for _, name := range models.BookAttrsBuiltIn {
+		if definition.Name == name {
+			// Return status 409 and error message.
+			return response.RespondError(c, fiber.StatusConflict, "'"+name+"' is a built-in book attribute")
+		}
+	}
+	if (definition.Type == repository.BookAttrTypeEnum) != (len(definition.EnumValues) > 0) {
+		// Return status 400 and error message.
+		return response.RespondError(c, fiber.StatusBadRequest, "enum_values must be given for enum attributes only")
+	}
+
+	if err := database.BookAttrDB().CreateBookAttrDefinition(definition); err != nil {
+		if errors.Is(err, queries.ErrBookAttrDefinitionExists) {
+			// Return status 409 and error message.
+			return response.RespondError(c, fiber.StatusConflict, err.Error())
+		}
+		// Return status 500 and error message.
+		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	// Return status 201.
+	return response.RespondSuccess(c, fiber.StatusCreated, definition)
+}
+
+// DeleteBookAttrDefinition godoc
+// @Description Books keep existing but lose their value of the attribute
+// @Description Require valid user token with `book:attrs` credential
+// @Summary Delete a registered book attribute
+// @Tags Book attribute
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param attr_id path string true "Attribute definition ID"
+// @Success 204
+// @Failure 400 {object} response.HTTPError
+// @Failure 401 {object} response.HTTPError
+// @Failure 403 {object} response.HTTPError
+// @Failure 404 {object} response.HTTPError
+// @Router /v1/attr/{attr_id} [delete]
+func DeleteBookAttrDefinition(c *fiber.Ctx) error {
+	// Catch definition ID from URL.
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return response.RespondError(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	// Get claims from JWT.
+	claims, err := utils.ExtractTokenMetadata(c)
+	if err != nil {
+		// Return status 500 and JWT parse error.
+		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	if isError, errorCode, errorMessage := claimCheck(claims, repository.BookAttrsCredential); isError {
+		return response.RespondError(c, errorCode, errorMessage)
+	}
+
+	if err := database.BookAttrDB().DeleteBookAttrDefinition(id); err != nil {
+		// Return status 404 and error message.
+		return response.RespondError(c, fiber.StatusNotFound, err.Error())
+	}
+
+	// Return status 204 no content.
+	return response.RespondSuccess(c, fiber.StatusNoContent, "")
+}
+
+// bookAttrsCheck func for checking extra attributes of a book against the definitions registered in given queries.
+func bookAttrsCheck(db *queries.BookAttrQueries, attrs *models.BookAttrs) (bool, int, interface{}) {
+	definitions, err := db.GetBookAttrDefinitions()
+	if err != nil {
+		// Return status 500 and error message.
+		return true, fiber.StatusInternalServerError, err.Error()
+	}
+
+	if fields := bookAttrsErrors(attrs, definitions); len(fields) > 0 {
+		// Return status 400 and error message for each invalid attribute.
+		return true, fiber.StatusBadRequest, fields
+	}
+
+	return false, 0, ""
+}
+
+// bookAttrsErrors func for listing extra attributes that are unknown, missing or of the wrong type, by name.
+func bookAttrsErrors(attrs *models.BookAttrs, definitions []models.BookAttrDefinition) map[string]string {
+	fields := map[string]string{}
+
+	known := make(map[string]bool, len(definitions))
+	for _, definition := range definitions {
+		known[definition.Name] = true
+
+		value, ok := attrs.Extra[definition.Name]
+		if !ok {
+			if definition.Required {
+				fields[definition.Name] = "attribute is required"
+			}
+			continue
+		}
+		if message := bookAttrValueError(&definition, value); message != "" {
+			fields[definition.Name] = message
+		}
+	}
+
+	for name := range attrs.Extra {
+		if !known[name] {
+			fields[name] = "attribute is not registered"
+		}
+	}
+
+	return fields
+}
+
+// bookAttrValueError func for describing why a value does not fit its attribute, empty if it does.
+func bookAttrValueError(definition *models.BookAttrDefinition, value interface{}) string {
+	switch definition.Type {
+	case repository.BookAttrTypeString:
+		if _, ok := value.(string); !ok {
+			return "attribute must be a string"
+		}
+	case repository.BookAttrTypeNumber:
+		if _, ok := value.(float64); !ok {
+			return "attribute must be a number"
+		}
+	case repository.BookAttrTypeInteger:
+		if number, ok := value.(float64); !ok || number != math.Trunc(number) {
+			return "attribute must be an integer"
+		}
+	case repository.BookAttrTypeBoolean:
+		if _, ok := value.(bool); !ok {
+			return "attribute must be a boolean"
+		}
+	case repository.BookAttrTypeEnum:
+		s, _ := value.(string)
+		for _, enumValue := range definition.EnumValues {
+			if s == enumValue {
+				return ""
+			}
+		}
+		return "attribute must be one of " + strings.Join(definition.EnumValues, ", ")
+	}
+
+	return ""
+}
+
+// parseBookAttrFilter func for reading `attr.<name>` query params into values of registered attributes.
+func parseBookAttrFilter(c *fiber.Ctx) (map[string]interface{}, error) {
+	params := map[string]string{}
+	c.Context().QueryArgs().VisitAll(func(key, value []byte) {
+		if name := string(key); strings.HasPrefix(name, bookAttrFilterPrefix) {
+			params[strings.TrimPrefix(name, bookAttrFilterPrefix)] = string(value)
+		}
+	})
+	if len(params) == 0 {
+		return nil, nil
+	}
+
+	definitions, err := database.BookAttrDB().GetBookAttrDefinitions()
+	if err != nil {
+		return nil, err
+	}
+	types := make(map[string]string, len(definitions))
+	for _, definition := range definitions {
+		types[definition.Name] = definition.Type
+	}
+
+	attrs := make(map[string]interface{}, len(params))
+	for name, value := range params {
+		switch types[name] {
+		case repository.BookAttrTypeString, repository.BookAttrTypeEnum:
+			attrs[name] = value
+		case repository.BookAttrTypeNumber:
+			number, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return nil, fmt.Errorf("%v%v must be a number", bookAttrFilterPrefix, name)
+			}
+			attrs[name] = number
+		case repository.BookAttrTypeInteger:
+			number, err := strconv.Atoi(value)
+			if err != nil {
+				return nil, fmt.Errorf("%v%v must be an integer", bookAttrFilterPrefix, name)
+			}
+			attrs[name] = number
+		case repository.BookAttrTypeBoolean:
+			b, err := strconv.ParseBool(value)
+			if err != nil {
+				return nil, fmt.Errorf("%v%v must be a boolean", bookAttrFilterPrefix, name)
+			}
+			attrs[name] = b
+		default:
+			return nil, fmt.Errorf("book attribute '%v' is not registered", name)
+		}
+	}
+
+	return attrs, nil
+}
@@ -0,0 +1,374 @@
+package controllers
+
+import (
+	"errors"
+	"time"
+
+	"github.com/aryanicosa/go-fiber-rest-api/app/models"
+	"github.com/aryanicosa/go-fiber-rest-api/app/queries"
+	"github.com/aryanicosa/go-fiber-rest-api/pkg/repository"
+	"github.com/aryanicosa/go-fiber-rest-api/pkg/response"
+	"github.com/aryanicosa/go-fiber-rest-api/pkg/utils"
+	"github.com/aryanicosa/go-fiber-rest-api/platform/database"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// errBookBatchRolledBack is returned inside an atomic batch to roll back the operations applied so far.
+var errBookBatchRolledBack = errors.New("batch was rolled back")
+
+// BatchBooks godoc
+// @Description Create, update and delete up to 100 books in one request, each operation checked like its single book endpoint
+// @Description With `atomic` all operations run in one transaction and the first failure rolls back all of them
+// @Description Otherwise each operation is applied on its own and failures do not stop the others
+// @Description Each result has the status the single book endpoint would have answered with
+// @Description Require valid user token
+// @Summary Apply many book operations
+// @Tags Book
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param models.BookBatch body models.BookBatch true "Operations in the order they are applied"
+// @Success 200 {object} models.BookBatchReport
+// @Failure 400 {object} response.HTTPError
+// @Failure 401 {object} response.HTTPError
+// @Failure 500 {object} response.HTTPError
+// @Router /v1/books/batch [post]
+func BatchBooks(c *fiber.Ctx) error {
+	// Get claims from JWT.
+	claims, err := utils.ExtractTokenMetadata(c)
+	if err != nil {
+		// Return status 500 and JWT parse error.
+		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	// Create new BookBatch struct
+	batch := &models.BookBatch{}
+
+	// Check, if received JSON data is valid.
+	if err := c.BodyParser(batch); err != nil {
+		// Return status 400 and error message.
+		return response.RespondError(c, fiber.StatusBadRequest, "unable to parse request body")
+	}
+
+	// Validate batch fields.
+	validate := utils.NewValidator()
+	if err := validate.Struct(batch); err != nil {
+		// Return, if some fields are not valid.
+		return response.RespondError(c, fiber.StatusBadRequest, utils.ValidatorErrors(err))
+	}
+
+	report := &models.BookBatchReport{
+		Atomic:  batch.Atomic,
+		Results: make([]models.BookBatchResult, 0, len(batch.Operations)),
+	}
+
+	if batch.Atomic {
+		err = database.Transaction(func(tx *gorm.DB) error {
+			for i := range batch.Operations {
+				result := applyBookBatchOperation(tx, claims, i, &batch.Operations[i])
+				report.Results = append(report.Results, result)
+				if result.Error != nil {
+					return errBookBatchRolledBack
+				}
+			}
+			return nil
+		})
+		if err != nil && !errors.Is(err, errBookBatchRolledBack) {
+			// Return status 500 and error message.
+			return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+		}
+		if err != nil {
+			bookBatchRollBack(report, batch)
+		}
+	} else {
+		for i := range batch.Operations {
+			// Each operation is written with its revision and audit log, or not at all.
+			var result models.BookBatchResult
+			err := database.Transaction(func(tx *gorm.DB) error {
+				result = applyBookBatchOperation(tx, claims, i, &batch.Operations[i])
+				if result.Error != nil {
+					return errBookBatchRolledBack
+				}
+				return nil
+			})
+			if err != nil && !errors.Is(err, errBookBatchRolledBack) {
+				result = models.BookBatchResult{
+					Index:  i,
+					Op:     batch.Operations[i].Op,
+					ID:     batch.Operations[i].ID,
+					Status: fiber.StatusInternalServerError,
+					Error:  err.Error(),
+				}
+			}
+			report.Results = append(report.Results, result)
+		}
+	}
+
+	for _, result := range report.Results {
+		if result.Error != nil {
+			report.Failed++
+		} else {
+			report.Succeeded++
+		}
+	}
+
+	// Return status 200 OK.
+	return response.RespondSuccess(c, fiber.StatusOK, report)
+}
+
+// bookBatchRollBack func for marking all operations of a rolled back atomic batch as failed,
+// those after the failed operation were never tried.
+func bookBatchRollBack(report *models.BookBatchReport, batch *models.BookBatch) {
+	report.RolledBack = true
+
+	for i := range report.Results {
+		if report.Results[i].Error == nil {
+			report.Results[i].Status = fiber.StatusFailedDependency
+			report.Results[i].Error = "rolled back, another operation of the batch failed"
+			report.Results[i].Book = nil
+		}
+	}
+	for i := len(report.Results); i < len(batch.Operations); i++ {
+		report.Results = append(report.Results, models.BookBatchResult{
+			Index:  i,
+			Op:     batch.Operations[i].Op,
+			ID:     batch.Operations[i].ID,
+			Status: fiber.StatusFailedDependency,
+			Error:  "skipped, another operation of the batch failed",
+		})
+	}
+}
+
+// applyBookBatchOperation func for applying one operation of a batch with given transaction.
+func applyBookBatchOperation(tx *gorm.DB, claims *utils.TokenMetadata, index int, op *models.BookBatchOperation) models.BookBatchResult {
+	result := models.BookBatchResult{Index: index, Op: op.Op, ID: op.ID}
+
+	var book *models.Book
+	var isError bool
+	var errorCode int
+	var errorMessage interface{}
+
+	switch op.Op {
+	case "create":
+		book, isError, errorCode, errorMessage = batchCreateBook(tx, claims, op)
+	case "update":
+		book, isError, errorCode, errorMessage = batchUpdateBook(tx, claims, op)
+	case "delete":
+		isError, errorCode, errorMessage = batchDeleteBook(tx, claims, op)
+	}
+
+	result.Status = errorCode
+	if isError {
+		result.Error = errorMessage
+		return result
+	}
+
+	result.Book = book
+	if book != nil {
+		result.ID = &book.ID
+	}
+	return result
+}
+
+// batchCreateBook func for creating a book of a batch, with the checks of CreateBook.
+func batchCreateBook(tx *gorm.DB, claims *utils.TokenMetadata, op *models.BookBatchOperation) (*models.Book, bool, int, interface{}) {
+	if isError, errorCode, errorMessage := claimCheck(claims, repository.BookCreateCredential); isError {
+		return nil, isError, errorCode, errorMessage
+	}
+	if op.Book == nil {
+		// Return status 400 and error message.
+		return nil, true, fiber.StatusBadRequest, "book is required to create a book"
+	}
+
+	// Set initialized default data for book:
+	book := *op.Book
+	book.ID = uuid.New()
+	book.CreatedAt = time.Now()
+	book.UserID = claims.UserID
+	book.BookStatus = repository.BookStatusDraft // moved on through transitions only
+
+	// Validate book fields.
+	validate := utils.NewValidator()
+	if err := validate.Struct(&book); err != nil {
+		// Return, if some fields are not valid.
+		return nil, true, fiber.StatusBadRequest, utils.ValidatorErrors(err)
+	}
+
+	// Check extra attributes against the registered definitions.
+	if isError, errorCode, errorMessage := bookAttrsCheck(&queries.BookAttrQueries{DB: tx}, &book.BookAttrs); isError {
+		return nil, isError, errorCode, errorMessage
+	}
+
+	// Store the ISBN as ISBN-13, once per book, earlier operations of the batch included.
+	db := &queries.BookQueries{DB: tx}
+	if isError, errorCode, errorMessage := bookISBNCheck(db, &book, book.ID); isError {
+		return nil, isError, errorCode, errorMessage
+	}
+
+	if err := db.CreateBook(&book); err != nil {
+		if errors.Is(err, queries.ErrBookISBNExists) {
+			// Return status 409 and error message.
+			return nil, true, fiber.StatusConflict, err.Error()
+		}
+		// Return status 500 and error message.
+		return nil, true, fiber.StatusInternalServerError, err.Error()
+	}
+	book.Version = 1
+
+	if err := recordBookRevision(tx, book.ID, claims.UserID, repository.BookCreateAction); err != nil {
+		// Return status 500 and error message.
+		return nil, true, fiber.StatusInternalServerError, err.Error()
+	}
+
+	return &book, false, fiber.StatusCreated, nil
+}
+
+// batchUpdateBook func for updating a book of a batch, with the checks of UpdateBook.
+func batchUpdateBook(tx *gorm.DB, claims *utils.TokenMetadata, op *models.BookBatchOperation) (*models.Book, bool, int, interface{}) {
+	if isError, errorCode, errorMessage := claimCheck(claims, repository.BookUpdateCredential); isError {
+		return nil, isError, errorCode, errorMessage
+	}
+	if op.ID == nil || op.Book == nil {
+		// Return status 400 and error message.
+		return nil, true, fiber.StatusBadRequest, "id and book are required to update a book"
+	}
+
+	// Checking, if book with given ID is exists.
+	db := &queries.BookQueries{DB: tx}
+	foundedBook, err := db.GetBookById(*op.ID)
+	if err != nil {
+		// Return status 404 and book not found error.
+		return nil, true, fiber.StatusNotFound, "book with given ID not found"
+	}
+
+	// Set initialized default data for book, the status is moved on through transitions only:
+	book := *op.Book
+	book.ID = foundedBook.ID
+	book.CreatedAt = foundedBook.CreatedAt
+	book.UpdatedAt = time.Now()
+	book.UserID = foundedBook.UserID
+	book.BookStatus = foundedBook.BookStatus
+
+	// Validate book fields.
+	validate := utils.NewValidator()
+	if err := validate.Struct(&book); err != nil {
+		// Return, if some fields are not valid.
+		return nil, true, fiber.StatusBadRequest, utils.ValidatorErrors(err)
+	}
+
+	// Check extra attributes against the registered definitions.
+	if isError, errorCode, errorMessage := bookAttrsCheck(&queries.BookAttrQueries{DB: tx}, &book.BookAttrs); isError {
+		return nil, isError, errorCode, errorMessage
+	}
+
+	// Check, if client edits the latest version.
+	if isError, errorCode, errorMessage := bookBatchVersionCheck(&foundedBook, op.Version); isError {
+		return nil, isError, errorCode, errorMessage
+	}
+
+	// Only the owner and editors can update the book, others need an audited override.
+	auditLog, isError, errorCode, errorMessage := bookReasonPolicyCheck(&foundedBook, claims, bookActionUpdate, op.Reason)
+	if isError {
+		return nil, isError, errorCode, errorMessage
+	}
+
+	// Store the ISBN as ISBN-13, once per book, earlier operations of the batch included.
+	if isError, errorCode, errorMessage := bookISBNCheck(db, &book, foundedBook.ID); isError {
+		return nil, isError, errorCode, errorMessage
+	}
+
+	// Update book by given ID, unless it changed since it was read.
+	if err := db.UpdateBook(foundedBook.ID, foundedBook.Version, &book); err != nil {
+		if errors.Is(err, queries.ErrBookVersionMismatch) {
+			// Return status 412 and error message.
+			return nil, true, fiber.StatusPreconditionFailed, err.Error()
+		}
+		if errors.Is(err, queries.ErrBookISBNExists) {
+			// Return status 409 and error message.
+			return nil, true, fiber.StatusConflict, err.Error()
+		}
+		// Return status 500 and error message.
+		return nil, true, fiber.StatusInternalServerError, err.Error()
+	}
+	book.Version = foundedBook.Version + 1
+
+	if err := recreditBookAuthor(tx, &foundedBook, book.Author); err != nil {
+		// Return status 500 and error message.
+		return nil, true, fiber.StatusInternalServerError, err.Error()
+	}
+
+	if err := recordBookRevision(tx, foundedBook.ID, claims.UserID, repository.BookUpdateAction); err != nil {
+		// Return status 500 and error message.
+		return nil, true, fiber.StatusInternalServerError, err.Error()
+	}
+
+	if err := createBookAuditLog(tx, auditLog); err != nil {
+		// Return status 500 and error message.
+		return nil, true, fiber.StatusInternalServerError, err.Error()
+	}
+
+	return &book, false, fiber.StatusOK, nil
+}
+
+// batchDeleteBook func for moving a book of a batch to the trash, with the checks of DeleteBook.
+func batchDeleteBook(tx *gorm.DB, claims *utils.TokenMetadata, op *models.BookBatchOperation) (bool, int, interface{}) {
+	if isError, errorCode, errorMessage := claimCheck(claims, repository.BookDeleteCredential); isError {
+		return isError, errorCode, errorMessage
+	}
+	if op.ID == nil {
+		// Return status 400 and error message.
+		return true, fiber.StatusBadRequest, "id is required to delete a book"
+	}
+
+	// Checking, if book with given ID is exists.
+	db := &queries.BookQueries{DB: tx}
+	foundedBook, err := db.GetBookById(*op.ID)
+	if err != nil {
+		// Return status 404 and book not found error.
+		return true, fiber.StatusNotFound, "book with given ID not found"
+	}
+
+	// Check, if client deletes the latest version.
+	if isError, errorCode, errorMessage := bookBatchVersionCheck(&foundedBook, op.Version); isError {
+		return isError, errorCode, errorMessage
+	}
+
+	// Only the owner can delete the book, others need an audited override.
+	auditLog, isError, errorCode, errorMessage := bookReasonPolicyCheck(&foundedBook, claims, bookActionDelete, op.Reason)
+	if isError {
+		return isError, errorCode, errorMessage
+	}
+
+	if err := db.DeleteBook(foundedBook.ID, foundedBook.Version); err != nil {
+		if errors.Is(err, queries.ErrBookVersionMismatch) {
+			// Return status 412 and error message.
+			return true, fiber.StatusPreconditionFailed, err.Error()
+		}
+		// Return status 500 and error message.
+		return true, fiber.StatusInternalServerError, err.Error()
+	}
+
+	if err := recordBookRevision(tx, foundedBook.ID, claims.UserID, repository.BookDeleteAction); err != nil {
+		// Return status 500 and error message.
+		return true, fiber.StatusInternalServerError, err.Error()
+	}
+
+	if err := createBookAuditLog(tx, auditLog); err != nil {
+		// Return status 500 and error message.
+		return true, fiber.StatusInternalServerError, err.Error()
+	}
+
+	return false, fiber.StatusNoContent, nil
+}
+
+// bookBatchVersionCheck func for checking the expected version of an operation against the stored book version.
+func bookBatchVersionCheck(book *models.Book, version *int) (bool, int, interface{}) {
+	if version != nil && *version != book.Version {
+		// Return status 412 and error message.
+		return true, fiber.StatusPreconditionFailed, queries.ErrBookVersionMismatch.Error()
+	}
+
+	return false, 0, ""
+}
@@ -0,0 +1,315 @@
+package controllers
+
+import (
+	"github.com/aryanicosa/go-fiber-rest-api/app/models"
+	"github.com/aryanicosa/go-fiber-rest-api/app/queries"
+	"github.com/aryanicosa/go-fiber-rest-api/pkg/repository"
+	"github.com/aryanicosa/go-fiber-rest-api/pkg/response"
+	"github.com/aryanicosa/go-fiber-rest-api/pkg/utils"
+	"github.com/aryanicosa/go-fiber-rest-api/platform/database"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// GetBookCollaborators godoc
+// @Description Will display collaborators of a book
+// @Description Require valid user token of the owner or a collaborator
+// @Summary Get book collaborators
+// @Tags Book Collaborator
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param book_id path string true "Book ID"
+// @Success 200 {array} models.BookCollaborator
+// @Failure 400 {object} response.HTTPError
+// @Failure 403 {object} response.HTTPError
+// @Failure 404 {object} response.HTTPError
+// @Failure 500 {object} response.HTTPError
+// @Router /v1/book/{book_id}/collaborators [get]
+func GetBookCollaborators(c *fiber.Ctx) error {
+	// Catch book ID from URL.
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return response.RespondError(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	// Get claims from JWT.
+	claims, err := utils.ExtractTokenMetadata(c)
+	if err != nil {
+		// Return status 500 and JWT parse error.
+		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	// Checking, if book with given ID is exists.
+	foundedBook, err := database.BookDB().GetBookById(id)
+	if err != nil {
+		// Return status 404 and book not found error.
+		return response.RespondError(c, fiber.StatusNotFound, "book with given ID not found")
+	}
+
+	if _, isError, errorCode, errorMessage := bookPolicyCheck(c, &foundedBook, claims, bookActionView); isError {
+		return response.RespondError(c, errorCode, errorMessage)
+	}
+
+	// Get collaborators of the book.
+	collaborators, err := database.BookCollaboratorDB().GetCollaborators(foundedBook.ID)
+	if err != nil {
+		// Return status 500 and error message.
+		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	// Return status 200 OK.
+	return response.RespondSuccess(c, fiber.StatusOK, collaborators)
+}
+
+// AddBookCollaborator godoc
+// @Description Add a collaborator to a book or change the role of an existing one
+// @Description Roles are `editor` (can update) and `viewer`
+// @Description Require valid user token of the owner with `book:update` credential
+// @Summary Add book collaborator
+// @Tags Book Collaborator
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param book_id path string true "Book ID"
+// @Param models.AddCollaborator body models.AddCollaborator true "Collaborator data"
+// @Success 201 {object} models.BookCollaborator
+// @Failure 400 {object} response.HTTPError
+// @Failure 401 {object} response.HTTPError
+// @Failure 403 {object} response.HTTPError
+// @Failure 404 {object} response.HTTPError
+// @Failure 500 {object} response.HTTPError
+// @Router /v1/book/{book_id}/collaborators [post]
+func AddBookCollaborator(c *fiber.Ctx) error {
+	// Catch book ID from URL.
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return response.RespondError(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	// Get claims from JWT.
+	claims, err := utils.ExtractTokenMetadata(c)
+	if err != nil {
+		// Return status 500 and JWT parse error.
+		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	if isError, errorCode, errorMessage := claimCheck(claims, repository.BookUpdateCredential); isError {
+		return response.RespondError(c, errorCode, errorMessage)
+	}
+
+	// Create new AddCollaborator struct
+	addCollaborator := &models.AddCollaborator{}
+
+	// Check, if received JSON data is valid.
+	if err := c.BodyParser(addCollaborator); err != nil {
+		// Return status 400 and error message.
+		return response.RespondError(c, fiber.StatusBadRequest, "unable to parse request body")
+	}
+
+	// Validate collaborator fields.
+	validate := utils.NewValidator()
+	if err := validate.Struct(addCollaborator); err != nil {
+		// Return, if some fields are not valid.
+		return response.RespondError(c, fiber.StatusBadRequest, utils.ValidatorErrors(err))
+	}
+
+	// Checking, if book with given ID is exists.
+	foundedBook, err := database.BookDB().GetBookById(id)
+	if err != nil {
+		// Return status 404 and book not found error.
+		return response.RespondError(c, fiber.StatusNotFound, "book with given ID not found")
+	}
+
+	if _, isError, errorCode, errorMessage := bookPolicyCheck(c, &foundedBook, claims, bookActionManage); isError {
+		return response.RespondError(c, errorCode, errorMessage)
+	}
+
+	if addCollaborator.UserID == foundedBook.UserID {
+		// Return status 400 and error message.
+		return response.RespondError(c, fiber.StatusBadRequest, "the owner can not be a collaborator of own book")
+	}
+
+	// Checking, if user with given ID is exists.
+	foundedUser, err := database.UserDB().GetUserByID(addCollaborator.UserID)
+	if err != nil || foundedUser.ID == uuid.Nil {
+		// Return status 404 and user not found error.
+		return response.RespondError(c, fiber.StatusNotFound, "user with given ID not found")
+	}
+
+	collaborator := &models.BookCollaborator{
+		BookID:           foundedBook.ID,
+		UserID:           foundedUser.ID,
+		CreatedAt:        time.Now(),
+		CollaboratorRole: addCollaborator.CollaboratorRole,
+	}
+
+	// Save collaborator of the book.
+	if err := database.BookCollaboratorDB().SaveCollaborator(collaborator); err != nil {
+		// Return status 500 and error message.
+		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	// Return status 201.
+	return response.RespondSuccess(c, fiber.StatusCreated, collaborator)
+}
+
+// RemoveBookCollaborator godoc
+// @Description Remove a collaborator from a book
+// @Description Require valid user token of the owner, or of the collaborator leaving the book
+// @Summary Remove book collaborator
+// @Tags Book Collaborator
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param book_id path string true "Book ID"
+// @Param user_id path string true "Collaborator user ID"
+// @Success 204
+// @Failure 400 {object} response.HTTPError
+// @Failure 403 {object} response.HTTPError
+// @Failure 404 {object} response.HTTPError
+// @Failure 500 {object} response.HTTPError
+// @Router /v1/book/{book_id}/collaborators/{user_id} [delete]
+func RemoveBookCollaborator(c *fiber.Ctx) error {
+	// Catch book and user IDs from URL.
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return response.RespondError(c, fiber.StatusBadRequest, err.Error())
+	}
+	userID, err := uuid.Parse(c.Params("user_id"))
+	if err != nil {
+		return response.RespondError(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	// Get claims from JWT.
+	claims, err := utils.ExtractTokenMetadata(c)
+	if err != nil {
+		// Return status 500 and JWT parse error.
+		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	// Checking, if book with given ID is exists.
+	foundedBook, err := database.BookDB().GetBookById(id)
+	if err != nil {
+		// Return status 404 and book not found error.
+		return response.RespondError(c, fiber.StatusNotFound, "book with given ID not found")
+	}
+
+	// A collaborator can always leave, anyone else needs to manage the book.
+	if claims.UserID != userID {
+		if _, isError, errorCode, errorMessage := bookPolicyCheck(c, &foundedBook, claims, bookActionManage); isError {
+			return response.RespondError(c, errorCode, errorMessage)
+		}
+	}
+
+	// Delete collaborator of the book.
+	if err := database.BookCollaboratorDB().DeleteCollaborator(foundedBook.ID, userID); err != nil {
+		// Return status 404 and error message.
+		return response.RespondError(c, fiber.StatusNotFound, err.Error())
+	}
+
+	// Return status 204 no content.
+	return response.RespondSuccess(c, fiber.StatusNoContent, "")
+}
+
+// TransferBookOwnership godoc
+// @Description Hand a book over to another user, the previous owner stays on as an editor
+// @Description Require valid user token of the owner with `book:update` credential
+// @Summary Transfer book ownership
+// @Tags Book Collaborator
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param book_id path string true "Book ID"
+// @Param models.TransferOwnership body models.TransferOwnership true "New owner"
+// @Success 200 {object} models.Book
+// @Failure 400 {object} response.HTTPError
+// @Failure 401 {object} response.HTTPError
+// @Failure 403 {object} response.HTTPError
+// @Failure 404 {object} response.HTTPError
+// @Failure 500 {object} response.HTTPError
+// @Router /v1/book/{book_id}/transfer [post]
+func TransferBookOwnership(c *fiber.Ctx) error {
+	// Catch book ID from URL.
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return response.RespondError(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	// Get claims from JWT.
+	claims, err := utils.ExtractTokenMetadata(c)
+	if err != nil {
+		// Return status 500 and JWT parse error.
+		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	if isError, errorCode, errorMessage := claimCheck(claims, repository.BookUpdateCredential); isError {
+		return response.RespondError(c, errorCode, errorMessage)
+	}
+
+	// Create new TransferOwnership struct
+	transfer := &models.TransferOwnership{}
+
+	// Check, if received JSON data is valid.
+	if err := c.BodyParser(transfer); err != nil {
+		// Return status 400 and error message.
+		return response.RespondError(c, fiber.StatusBadRequest, "unable to parse request body")
+	}
+
+	// Validate transfer fields.
+	validate := utils.NewValidator()
+	if err := validate.Struct(transfer); err != nil {
+		// Return, if some fields are not valid.
+		return response.RespondError(c, fiber.StatusBadRequest, utils.ValidatorErrors(err))
+	}
+
+	// Checking, if book with given ID is exists.
+	db := database.BookDB()
+	foundedBook, err := db.GetBookById(id)
+	if err != nil {
+		// Return status 404 and book not found error.
+		return response.RespondError(c, fiber.StatusNotFound, "book with given ID not found")
+	}
+
+	if _, isError, errorCode, errorMessage := bookPolicyCheck(c, &foundedBook, claims, bookActionManage); isError {
+		return response.RespondError(c, errorCode, errorMessage)
+	}
+
+	if transfer.UserID == foundedBook.UserID {
+		// Return status 400 and error message.
+		return response.RespondError(c, fiber.StatusBadRequest, "the book is already owned by given user")
+	}
+
+	// Checking, if user with given ID is exists.
+	foundedUser, err := database.UserDB().GetUserByID(transfer.UserID)
+	if err != nil || foundedUser.ID == uuid.Nil {
+		// Return status 404 and user not found error.
+		return response.RespondError(c, fiber.StatusNotFound, "user with given ID not found")
+	}
+
+	// Transfer the book to the new owner, with its revision.
+	err = database.Transaction(func(tx *gorm.DB) error {
+		err := (&queries.BookCollaboratorQueries{DB: tx}).TransferOwnership(foundedBook.ID, foundedBook.UserID, foundedUser.ID)
+		if err != nil {
+			return err
+		}
+		return recordBookRevision(tx, foundedBook.ID, claims.UserID, repository.BookTransferAction)
+	})
+	if err != nil {
+		// Return status 500 and error message.
+		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	// Get the book as it is now.
+	transferredBook, err := db.GetBookById(foundedBook.ID)
+	if err != nil {
+		// Return status 500 and error message.
+		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	// Return status 200 OK.
+	return response.RespondSuccess(c, fiber.StatusOK, transferredBook)
+}
@@ -1,69 +1,155 @@
 package controllers
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/aryanicosa/go-fiber-rest-api/app/queries"
 	"github.com/aryanicosa/go-fiber-rest-api/pkg/response"
 	"github.com/aryanicosa/go-fiber-rest-api/platform/database"
 	"github.com/google/uuid"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/aryanicosa/go-fiber-rest-api/app/models"
 	"github.com/aryanicosa/go-fiber-rest-api/pkg/repository"
 	"github.com/aryanicosa/go-fiber-rest-api/pkg/utils"
 	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
 )
 
 // GetBooks godoc
-// @Description Will display all books
+// @Description Will display published books page by page, filtered and sorted by query params
+// @Description Books in other statuses are listed by `/v1/books/workflow`, `book_status` is refused here
+// @Description `facets` count the tags and categories of all matching books
+// @Description Filter by registered book attributes with `attr.<name>=<value>`, e.g. `attr.pages=320`
+// @Description Use `cursor` (empty to start) for keyset paging on `created_at,id` instead of `page`
 // @Description Require Basic Auth
 // @Summary Get All Books
 // @Tags Book
 // @Accept json
 // @Produce json
 // @Security BasicAuth
-// @Success 200 {array} models.BookForPublic
+// @Param page query int false "Page number, starts from 1"
+// @Param limit query int false "Page size, up to 100"
+// @Param cursor query string false "Keyset cursor from `links`"
+// @Param author query string false "Exact author name"
+// @Param title query string false "Title substring"
+// @Param user_id query string false "Creator user ID"
+// @Param author_id query string false "Credited author ID, in any role"
+// @Param rating_min query int false "Minimum rating"
+// @Param rating_max query int false "Maximum rating"
+// @Param tag query string false "Comma separated tag slugs, books must have all of them"
+// @Param category query string false "Category slug, books of subcategories match too"
+// @Param sort query string false "Comma separated columns, prefix with `-` for descending, e.g. `-created_at,title`"
+// @Success 200 {object} models.AllBooks
+// @Failure 400 {object} response.HTTPError
 // @Failure 404 {object} response.HTTPError
+// @Failure 500 {object} response.HTTPError
 // @Router /v1/books [get]
 func GetBooks(c *fiber.Ctx) error {
-	// Get all books.
+	// Read filters, sort and paging from query params.
+	filter, err := parsePublicBookFilter(c)
+	if err != nil {
+		// Return status 400 and error message.
+		return response.RespondError(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	// Get one page of books.
 	db := database.BookDB()
-	books, err := db.GetBooks()
+	page, err := db.GetBooks(filter)
 	if err != nil {
 		// Return, if books not found.
 		return response.RespondError(c, fiber.StatusNotFound, "books were not found")
 	}
 
-	var booksForPublic []models.BookForPublic
-	for _, book := range books {
-		arrBookForPublic := []models.BookForPublic{
-			{
-				ID:         book.ID,
-				Title:      book.Title,
-				Author:     book.Author,
-				BookStatus: book.BookStatus,
-				BookAttrs:  book.BookAttrs,
-			},
-		}
-		booksForPublic = arrBookForPublic
-	}
+	allBooks := newAllBooks(c, &filter.Pagination, page)
 
-	allBooks := &models.AllBooks{
-		Books: booksForPublic,
-		Count: int64(len(books)),
+	// Count tags and categories of all matching books for filter sidebars.
+	allBooks.Facets = &models.BookFacets{}
+	if allBooks.Facets.Tags, err = database.TagDB().GetTagFacets(filter, bookTagFacetsLimit); err != nil {
+		// Return status 500 and error message.
+		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
 	}
+	if allBooks.Facets.Categories, err = database.CategoryDB().GetCategoryFacets(filter); err != nil {
+		// Return status 500 and error message.
+		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+	}
+
 	// Return status 200 OK.
 	return response.RespondSuccess(c, fiber.StatusOK, allBooks)
 }
 
+// SearchBooks godoc
+// @Description Will display published books matching a full-text query on title, author and description, best match first
+// @Description Require Basic Auth
+// @Summary Search books
+// @Tags Book
+// @Accept json
+// @Produce json
+// @Security BasicAuth
+// @Param q query string true "Search query, supports quoted phrases, `or` and `-` to exclude words"
+// @Param page query int false "Page number, starts from 1"
+// @Param limit query int false "Page size, up to 100"
+// @Success 200 {object} models.BookSearchResults
+// @Failure 400 {object} response.HTTPError
+// @Failure 500 {object} response.HTTPError
+// @Router /v1/books/search [get]
+func SearchBooks(c *fiber.Ctx) error {
+	// Catch search query from URL.
+	query := strings.TrimSpace(c.Query("q"))
+	if query == "" {
+		// Return status 400 and error message.
+		return response.RespondError(c, fiber.StatusBadRequest, "search query `q` is required")
+	}
+
+	pagination, err := utils.ParsePagination(c)
+	if err != nil {
+		// Return status 400 and error message.
+		return response.RespondError(c, fiber.StatusBadRequest, err.Error())
+	}
+	if pagination.UseCursor {
+		// Results are ordered by rank, so there is no keyset to follow.
+		return response.RespondError(c, fiber.StatusBadRequest, "cursor paging is not supported for search")
+	}
+
+	// Search books.
+	db := database.BookDB()
+	books, total, err := db.SearchBooks(&models.BookSearch{Query: query, Pagination: *pagination})
+	if err != nil {
+		// Return status 500 and error message.
+		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	results := &models.BookSearchResults{
+		Books: books,
+		Count: total,
+		Page:  pagination.Page,
+		Limit: pagination.Limit,
+		Links: utils.BuildPageLinks(c, pagination, total, false, nil, nil),
+	}
+
+	// Return status 200 OK.
+	return response.RespondSuccess(c, fiber.StatusOK, results)
+}
+
 // GetBook godoc
 // @Description Will display specific book by it's ID
 // @Description Require valid user token
+// @Description Unpublished books are only shown to the owner, collaborators and users with `book:publish` credential, sending their token instead
 // @Summary Get book by ID
 // @Tags Book
 // @Accept json
 // @Produce json
 // @Security BasicAuth
+// @Security ApiKeyAuth
 // @Param book_id path string true "Book ID"
+// @Param If-None-Match header string false "ETag of a cached copy, answered with 304 if still current"
 // @Success 200 {object} models.Book
+// @Success 304
 // @Failure 404 {object} response.HTTPError
 // @Failure 500 {object} response.HTTPError
 // @Router /v1/book/id [get]
@@ -82,11 +168,67 @@ func GetBook(c *fiber.Ctx) error {
 		return response.RespondError(c, fiber.StatusNotFound, "book with the given ID is not found")
 	}
 
+	// Checking, if caller may see the book.
+	if isError, errorCode, errorMessage := bookVisibleCheck(c, &book); isError {
+		return response.RespondError(c, errorCode, errorMessage)
+	}
+
+	// Tag the response with the book version.
+	etag := utils.VersionETag(book.Version)
+	c.Set(fiber.HeaderETag, etag)
+
+	// Return status 304, if client already has this version.
+	if ifNoneMatch := c.Get(fiber.HeaderIfNoneMatch); ifNoneMatch != "" && utils.MatchETag(ifNoneMatch, etag) {
+		return c.SendStatus(fiber.StatusNotModified)
+	}
+
+	// Return status 200 OK.
+	return response.RespondSuccess(c, fiber.StatusOK, book)
+}
+
+// GetBookByISBN godoc
+// @Description Hyphens are ignored and ISBN-10 is matched as its ISBN-13
+// @Description Require Basic Auth
+// @Description Unpublished books are only shown to the owner, collaborators and users with `book:publish` credential, sending their token instead
+// @Summary Get book by ISBN
+// @Tags Book
+// @Accept json
+// @Produce json
+// @Security BasicAuth
+// @Security ApiKeyAuth
+// @Param isbn path string true "ISBN-10 or ISBN-13"
+// @Success 200 {object} models.Book
+// @Failure 400 {object} response.HTTPError
+// @Failure 404 {object} response.HTTPError
+// @Router /v1/book/isbn/{isbn} [get]
+func GetBookByISBN(c *fiber.Ctx) error {
+	// Catch ISBN from URL.
+	isbn, err := utils.NormalizeISBN(c.Params("isbn"))
+	if err != nil {
+		// Return status 400 and error message.
+		return response.RespondError(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	// Get book by ISBN.
+	book, err := database.BookDB().GetBookByISBN(isbn)
+	if err != nil {
+		// Return, if book not found.
+		return response.RespondError(c, fiber.StatusNotFound, "book with the given ISBN is not found")
+	}
+
+	// Checking, if caller may see the book.
+	if isError, errorCode, errorMessage := bookVisibleCheck(c, &book); isError {
+		return response.RespondError(c, errorCode, errorMessage)
+	}
+
 	// Return status 200 OK.
+	c.Set(fiber.HeaderETag, utils.VersionETag(book.Version))
 	return response.RespondSuccess(c, fiber.StatusOK, book)
 }
 
 // CreateBook godoc
+// @Description `isbn` may be given as ISBN-10 or ISBN-13 and is stored as ISBN-13, answered with 409 if another book has it
+// @Description `book_attrs` may also hold the attributes registered under `/v1/attrs`, checked against their type
 // @Description Require valid user token
 // @Summary Create new book
 // @Tags Book
@@ -99,6 +241,7 @@ func GetBook(c *fiber.Ctx) error {
 // @Failure 403 {object} response.HTTPError
 // @Failure 404 {object} response.HTTPError
 // @Failure 500 {object} response.HTTPError
+// @Failure 409 {object} response.HTTPError
 // @Router /v1/book [post]
 func CreateBook(c *fiber.Ctx) error {
 	// Get claims from JWT.
@@ -111,7 +254,7 @@ func CreateBook(c *fiber.Ctx) error {
 	// Set credential needed `book:delete` from JWT data of current book.
 	credentialNeed := repository.BookCreateCredential
 
-	if isError, errorCode, errorMessage := bookClaimCheck(claims, credentialNeed); isError {
+	if isError, errorCode, errorMessage := claimCheck(claims, credentialNeed); isError {
 		return response.RespondError(c, errorCode, errorMessage)
 	}
 
@@ -131,7 +274,7 @@ func CreateBook(c *fiber.Ctx) error {
 	book.ID = uuid.New()
 	book.CreatedAt = time.Now()
 	book.UserID = claims.UserID
-	book.BookStatus = 1 // 0 == draft, 1 == active
+	book.BookStatus = repository.BookStatusDraft // moved on through transitions only
 
 	// Validate book fields.
 	if err := validate.Struct(book); err != nil {
@@ -139,9 +282,28 @@ func CreateBook(c *fiber.Ctx) error {
 		return response.RespondError(c, fiber.StatusBadRequest, utils.ValidatorErrors(err))
 	}
 
-	// Create book by given model.
-	db := database.BookDB()
-	if err := db.CreateBook(book); err != nil {
+	// Check extra attributes against the registered definitions.
+	if isError, errorCode, errorMessage := bookAttrsCheck(database.BookAttrDB(), &book.BookAttrs); isError {
+		return response.RespondError(c, errorCode, errorMessage)
+	}
+
+	// Store the ISBN as ISBN-13, once per book.
+	if isError, errorCode, errorMessage := bookISBNCheck(database.BookDB(), book, book.ID); isError {
+		return response.RespondError(c, errorCode, errorMessage)
+	}
+
+	// Create book by given model, with its first revision.
+	err = database.Transaction(func(tx *gorm.DB) error {
+		if err := (&queries.BookQueries{DB: tx}).CreateBook(book); err != nil {
+			return err
+		}
+		return recordBookRevision(tx, book.ID, claims.UserID, repository.BookCreateAction)
+	})
+	if err != nil {
+		if errors.Is(err, queries.ErrBookISBNExists) {
+			// Return status 409 and error message.
+			return response.RespondError(c, fiber.StatusConflict, err.Error())
+		}
 		// Return status 500 and error message.
 		return response.RespondError(c, fiber.StatusBadRequest, err.Error())
 	}
@@ -150,6 +312,7 @@ func CreateBook(c *fiber.Ctx) error {
 }
 
 // UpdateBook godoc
+// @Description A new `author` name credits the book to the author of that name in place of its current authors
 // @Description Require valid user token
 // @Summary Update a book
 // @Tags Book
@@ -158,11 +321,15 @@ func CreateBook(c *fiber.Ctx) error {
 // @Security ApiKeyAuth
 // @Param book_id path string true "Book ID"
 // @Param models.Book body models.Book true "Book data"
+// @Param reason query string false "Required when updating a book of another user with `book:update:any`"
+// @Param If-Match header string false "ETag of the edited version, answered with 412 if outdated"
 // @Success 201 {object} models.Book
 // @Failure 400 {object} response.HTTPError
 // @Failure 401 {object} response.HTTPError
 // @Failure 403 {object} response.HTTPError
 // @Failure 404 {object} response.HTTPError
+// @Failure 409 {object} response.HTTPError
+// @Failure 412 {object} response.HTTPError
 // @Failure 500 {object} response.HTTPError
 // @Router /v1/book/id [put]
 func UpdateBook(c *fiber.Ctx) error {
@@ -182,7 +349,7 @@ func UpdateBook(c *fiber.Ctx) error {
 	// Set credential needed `book:delete` from JWT data of current book.
 	credentialNeed := repository.BookUpdateCredential
 
-	if isError, errorCode, errorMessage := bookClaimCheck(claims, credentialNeed); isError {
+	if isError, errorCode, errorMessage := claimCheck(claims, credentialNeed); isError {
 		return response.RespondError(c, errorCode, errorMessage)
 	}
 
@@ -203,38 +370,233 @@ func UpdateBook(c *fiber.Ctx) error {
 		return response.RespondError(c, fiber.StatusNotFound, "book with given ID not found")
 	}
 
-	// Set user ID from JWT data of current user.
-	userID := claims.UserID
+	// Set initialized default data for book, the status is moved on through transitions only:
+	book.UpdatedAt = time.Now()
+	book.BookStatus = foundedBook.BookStatus
+
+	// Create a new validator for a Book model.
+	validate := utils.NewValidator()
+
+	// Validate book fields.
+	if err := validate.Struct(book); err != nil {
+		// Return, if some fields are not valid.
+		return response.RespondError(c, fiber.StatusBadRequest, utils.ValidatorErrors(err))
+	}
+
+	// Check extra attributes against the registered definitions.
+	if isError, errorCode, errorMessage := bookAttrsCheck(database.BookAttrDB(), &book.BookAttrs); isError {
+		return response.RespondError(c, errorCode, errorMessage)
+	}
 
-	// Only the creator can delete his book.
-	if foundedBook.UserID == userID {
-		// Set initialized default data for book:
-		book.UpdatedAt = time.Now()
+	// Check, if client edits the latest version.
+	if isError, errorCode, errorMessage := bookPreconditionCheck(c, &foundedBook); isError {
+		return response.RespondError(c, errorCode, errorMessage)
+	}
 
-		// Create a new validator for a Book model.
-		validate := utils.NewValidator()
+	// Only the owner and editors can update the book, others need an audited override.
+	auditLog, isError, errorCode, errorMessage := bookPolicyCheck(c, &foundedBook, claims, bookActionUpdate)
+	if isError {
+		return response.RespondError(c, errorCode, errorMessage)
+	}
 
-		// Validate book fields.
-		if err := validate.Struct(book); err != nil {
-			// Return, if some fields are not valid.
-			return response.RespondError(c, fiber.StatusBadRequest, utils.ValidatorErrors(err))
-		}
+	// Store the ISBN as ISBN-13, once per book.
+	if isError, errorCode, errorMessage := bookISBNCheck(database.BookDB(), book, foundedBook.ID); isError {
+		return response.RespondError(c, errorCode, errorMessage)
+	}
 
-		// Update book by given ID.
-		if err := db.UpdateBook(foundedBook.ID, book); err != nil {
-			// Return status 500 and error message.
-			return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+	// Update book by given ID, unless it changed since it was read, and record the override with it.
+	err = database.Transaction(func(tx *gorm.DB) error {
+		if err := (&queries.BookQueries{DB: tx}).UpdateBook(foundedBook.ID, foundedBook.Version, book); err != nil {
+			return err
+		}
+		if err := recreditBookAuthor(tx, &foundedBook, book.Author); err != nil {
+			return err
+		}
+		if err := recordBookRevision(tx, foundedBook.ID, claims.UserID, repository.BookUpdateAction); err != nil {
+			return err
+		}
+		return createBookAuditLog(tx, auditLog)
+	})
+	if err != nil {
+		if errors.Is(err, queries.ErrBookVersionMismatch) {
+			// Return status 412 and error message.
+			return response.RespondError(c, fiber.StatusPreconditionFailed, err.Error())
 		}
+		if errors.Is(err, queries.ErrBookISBNExists) {
+			// Return status 409 and error message.
+			return response.RespondError(c, fiber.StatusConflict, err.Error())
+		}
+		// Return status 500 and error message.
+		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+	}
 
-		// Return status 201.
-		return response.RespondSuccess(c, fiber.StatusCreated, book)
-	} else {
-		// Return status 403 and permission denied error message.
-		return response.RespondError(c, fiber.StatusForbidden, "permission denied, only the creator can update this book")
+	// Return status 201.
+	c.Set(fiber.HeaderETag, utils.VersionETag(book.Version))
+	return response.RespondSuccess(c, fiber.StatusCreated, book)
+}
+
+// PatchBook godoc
+// @Description Update only some fields of a book, zero values included
+// @Description Send `application/merge-patch+json` (RFC 7396) or `application/json-patch+json` (RFC 6902), plain `application/json` is read as merge patch
+// @Description A new `author` name credits the book to the author of that name, as a full update does
+// @Description Require valid user token
+// @Summary Patch a book
+// @Tags Book
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param book_id path string true "Book ID"
+// @Param patch body object true "Merge patch object or JSON Patch operations array"
+// @Param reason query string false "Required when updating a book of another user with `book:update:any`"
+// @Param If-Match header string false "ETag of the edited version, answered with 412 if outdated"
+// @Success 200 {object} models.Book
+// @Failure 400 {object} response.HTTPError
+// @Failure 401 {object} response.HTTPError
+// @Failure 403 {object} response.HTTPError
+// @Failure 404 {object} response.HTTPError
+// @Failure 409 {object} response.HTTPError
+// @Failure 412 {object} response.HTTPError
+// @Failure 415 {object} response.HTTPError
+// @Failure 500 {object} response.HTTPError
+// @Router /v1/book/{book_id} [patch]
+func PatchBook(c *fiber.Ctx) error {
+	// Catch book ID from URL.
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return response.RespondError(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	// Get claims from JWT.
+	claims, err := utils.ExtractTokenMetadata(c)
+	if err != nil {
+		// Return status 500 and JWT parse error.
+		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	if isError, errorCode, errorMessage := claimCheck(claims, repository.BookUpdateCredential); isError {
+		return response.RespondError(c, errorCode, errorMessage)
+	}
+
+	// Checking, if book with given ID is exists.
+	db := database.BookDB()
+	foundedBook, err := db.GetBookById(id)
+	if err != nil {
+		// Return status 404 and book not found error.
+		return response.RespondError(c, fiber.StatusNotFound, "book with given ID not found")
+	}
+
+	// Apply the patch document to the stored book.
+	current, err := json.Marshal(foundedBook)
+	if err != nil {
+		// Return status 500 and error message.
+		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	var patched []byte
+	switch strings.TrimSpace(strings.Split(string(c.Request().Header.ContentType()), ";")[0]) {
+	case utils.JSONPatchContentType:
+		patched, err = utils.ApplyJSONPatch(current, c.Body())
+	case utils.MergePatchContentType, fiber.MIMEApplicationJSON:
+		patched, err = utils.MergePatch(current, c.Body())
+	default:
+		// Return status 415 and error message.
+		return response.RespondError(c, fiber.StatusUnsupportedMediaType,
+			"content type must be "+utils.MergePatchContentType+" or "+utils.JSONPatchContentType)
+	}
+	if err != nil {
+		// Return status 400 and error message.
+		return response.RespondError(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	book := &models.Book{}
+	if err := json.Unmarshal(patched, book); err != nil {
+		// Return status 400 and error message.
+		return response.RespondError(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	// Read-only fields keep their stored values.
+	book.ID = foundedBook.ID
+	book.UserID = foundedBook.UserID
+	book.CreatedAt = foundedBook.CreatedAt
+	book.UpdatedAt = foundedBook.UpdatedAt
+	book.Version = foundedBook.Version
+	book.BookStatus = foundedBook.BookStatus
+
+	// Validate the merged book as a whole.
+	validate := utils.NewValidator()
+	if err := validate.Struct(book); err != nil {
+		// Return, if some fields are not valid.
+		return response.RespondError(c, fiber.StatusBadRequest, utils.ValidatorErrors(err))
 	}
+
+	// Check extra attributes against the registered definitions.
+	if isError, errorCode, errorMessage := bookAttrsCheck(database.BookAttrDB(), &book.BookAttrs); isError {
+		return response.RespondError(c, errorCode, errorMessage)
+	}
+
+	// Check, if client edits the latest version.
+	if isError, errorCode, errorMessage := bookPreconditionCheck(c, &foundedBook); isError {
+		return response.RespondError(c, errorCode, errorMessage)
+	}
+
+	// Only the owner and editors can update the book, others need an audited override.
+	auditLog, isError, errorCode, errorMessage := bookPolicyCheck(c, &foundedBook, claims, bookActionUpdate)
+	if isError {
+		return response.RespondError(c, errorCode, errorMessage)
+	}
+
+	// Store the ISBN as ISBN-13, once per book.
+	if isError, errorCode, errorMessage := bookISBNCheck(database.BookDB(), book, foundedBook.ID); isError {
+		return response.RespondError(c, errorCode, errorMessage)
+	}
+
+	// Write only the changed columns.
+	columns := bookChangedColumns(&foundedBook, book)
+	if len(columns) == 0 {
+		// Return status 200 OK, nothing to change.
+		c.Set(fiber.HeaderETag, utils.VersionETag(book.Version))
+		return response.RespondSuccess(c, fiber.StatusOK, book)
+	}
+
+	book.UpdatedAt = time.Now()
+	columns["updated_at"] = book.UpdatedAt
+
+	// Write the changed columns and record the override with them.
+	err = database.Transaction(func(tx *gorm.DB) error {
+		version, err := (&queries.BookQueries{DB: tx}).PatchBook(foundedBook.ID, foundedBook.Version, columns)
+		if err != nil {
+			return err
+		}
+		book.Version = version
+		if err := recreditBookAuthor(tx, &foundedBook, book.Author); err != nil {
+			return err
+		}
+		if err := recordBookRevision(tx, book.ID, claims.UserID, repository.BookUpdateAction); err != nil {
+			return err
+		}
+		return createBookAuditLog(tx, auditLog)
+	})
+	if err != nil {
+		if errors.Is(err, queries.ErrBookVersionMismatch) {
+			// Return status 412 and error message.
+			return response.RespondError(c, fiber.StatusPreconditionFailed, err.Error())
+		}
+		if errors.Is(err, queries.ErrBookISBNExists) {
+			// Return status 409 and error message.
+			return response.RespondError(c, fiber.StatusConflict, err.Error())
+		}
+		// Return status 500 and error message.
+		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	// Return status 200 OK.
+	c.Set(fiber.HeaderETag, utils.VersionETag(book.Version))
+	return response.RespondSuccess(c, fiber.StatusOK, book)
 }
 
 // DeleteBook godoc
+// @Description Move a book to the trash, it can be restored until purged after the retention period
+// @Description Purging deletes the book and its revision history for good, only its audit logs are kept
 // @Description Require valid user token
 // @Summary Delete a book
 // @Tags Book
@@ -242,11 +604,14 @@ func UpdateBook(c *fiber.Ctx) error {
 // @Produce json
 // @Security ApiKeyAuth
 // @Param book_id path string true "Book ID"
+// @Param reason query string false "Required when deleting a book of another user with `book:delete:any`"
+// @Param If-Match header string false "ETag of the deleted version, answered with 412 if outdated"
 // @Success 204
 // @Failure 400 {object} response.HTTPError
 // @Failure 401 {object} response.HTTPError
 // @Failure 403 {object} response.HTTPError
 // @Failure 404 {object} response.HTTPError
+// @Failure 412 {object} response.HTTPError
 // @Failure 500 {object} response.HTTPError
 // @Router /v1/book/id [delete]
 func DeleteBook(c *fiber.Ctx) error {
@@ -266,7 +631,7 @@ func DeleteBook(c *fiber.Ctx) error {
 	// Set credential needed `book:delete` from JWT data of current book.
 	credentialNeed := repository.BookDeleteCredential
 
-	if isError, errorCode, errorMessage := bookClaimCheck(claims, credentialNeed); isError {
+	if isError, errorCode, errorMessage := claimCheck(claims, credentialNeed); isError {
 		return response.RespondError(c, errorCode, errorMessage)
 	}
 
@@ -278,29 +643,223 @@ func DeleteBook(c *fiber.Ctx) error {
 		return response.RespondError(c, fiber.StatusNotFound, "book with given ID not found")
 	}
 
-	// Set user ID from JWT data of current user.
-	userID := claims.UserID
+	// Check, if client deletes the latest version.
+	if isError, errorCode, errorMessage := bookPreconditionCheck(c, &foundedBook); isError {
+		return response.RespondError(c, errorCode, errorMessage)
+	}
+
+	// Only the owner can delete the book, others need an audited override.
+	auditLog, isError, errorCode, errorMessage := bookPolicyCheck(c, &foundedBook, claims, bookActionDelete)
+	if isError {
+		return response.RespondError(c, errorCode, errorMessage)
+	}
 
-	// Only the creator can delete his book.
-	if foundedBook.UserID == userID {
-		// Delete book by given ID.
-		if err := db.DeleteBook(foundedBook.ID); err != nil {
-			// Return status 500 and error message.
-			return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+	// Delete book by given ID and record the override with it.
+	err = database.Transaction(func(tx *gorm.DB) error {
+		if err := (&queries.BookQueries{DB: tx}).DeleteBook(foundedBook.ID, foundedBook.Version); err != nil {
+			return err
+		}
+		if err := recordBookRevision(tx, foundedBook.ID, claims.UserID, repository.BookDeleteAction); err != nil {
+			return err
+		}
+		return createBookAuditLog(tx, auditLog)
+	})
+	if err != nil {
+		if errors.Is(err, queries.ErrBookVersionMismatch) {
+			// Return status 412 and error message.
+			return response.RespondError(c, fiber.StatusPreconditionFailed, err.Error())
 		}
+		// Return status 500 and error message.
+		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+	}
 
-		// Return status 204 no content.
-		return response.RespondSuccess(c, fiber.StatusNoContent, "")
-	} else {
-		// Return status 403 and permission denied error message.
-		return response.RespondError(c, fiber.StatusForbidden, "permission denied, only the creator can delete this book")
+	// Return status 204 no content.
+	return response.RespondSuccess(c, fiber.StatusNoContent, "")
+}
+
+// GetTrashedBooks godoc
+// @Description Will display trashed books of the current user, or of everyone with `book:delete:any` credential
+// @Description Require valid user token
+// @Summary Get trashed books
+// @Tags Book
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param page query int false "Page number, starts from 1"
+// @Param limit query int false "Page size, up to 100"
+// @Param user_id query string false "Owner user ID, only with `book:delete:any` credential"
+// @Success 200 {object} models.AllBooks
+// @Failure 400 {object} response.HTTPError
+// @Failure 401 {object} response.HTTPError
+// @Failure 403 {object} response.HTTPError
+// @Failure 500 {object} response.HTTPError
+// @Router /v1/books/trash [get]
+func GetTrashedBooks(c *fiber.Ctx) error {
+	// Get claims from JWT.
+	claims, err := utils.ExtractTokenMetadata(c)
+	if err != nil {
+		// Return status 500 and JWT parse error.
+		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	if isError, errorCode, errorMessage := claimCheck(claims, repository.BookDeleteCredential); isError {
+		return response.RespondError(c, errorCode, errorMessage)
+	}
+
+	// Read filters, sort and paging from query params.
+	filter, err := parseBookFilter(c)
+	if err != nil {
+		// Return status 400 and error message.
+		return response.RespondError(c, fiber.StatusBadRequest, err.Error())
+	}
+	filter.Trashed = true
+
+	// Owners only see their own trash.
+	if !claims.Credentials[repository.BookDeleteAnyCredential] {
+		if filter.UserID != nil && *filter.UserID != claims.UserID {
+			// Return status 403 and permission denied error message.
+			return response.RespondError(c, fiber.StatusForbidden, "permission denied, credential not eligible")
+		}
+		filter.UserID = &claims.UserID
+	}
+
+	// Get one page of trashed books.
+	db := database.BookDB()
+	page, err := db.GetBooks(filter)
+	if err != nil {
+		// Return status 500 and error message.
+		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	// Return status 200 OK.
+	return response.RespondSuccess(c, fiber.StatusOK, newAllBooks(c, &filter.Pagination, page))
+}
+
+// RestoreBook godoc
+// @Description Take a book out of the trash
+// @Description Require valid user token of the owner with `book:delete` credential
+// @Summary Restore a trashed book
+// @Tags Book
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param book_id path string true "Book ID"
+// @Param reason query string false "Required when restoring a book of another user with `book:delete:any`"
+// @Success 200 {object} models.Book
+// @Failure 400 {object} response.HTTPError
+// @Failure 401 {object} response.HTTPError
+// @Failure 403 {object} response.HTTPError
+// @Failure 404 {object} response.HTTPError
+// @Failure 500 {object} response.HTTPError
+// @Router /v1/book/{book_id}/restore [post]
+func RestoreBook(c *fiber.Ctx) error {
+	// Catch book ID from URL.
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return response.RespondError(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	// Get claims from JWT.
+	claims, err := utils.ExtractTokenMetadata(c)
+	if err != nil {
+		// Return status 500 and JWT parse error.
+		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	if isError, errorCode, errorMessage := claimCheck(claims, repository.BookDeleteCredential); isError {
+		return response.RespondError(c, errorCode, errorMessage)
+	}
+
+	// Checking, if book with given ID is in the trash.
+	db := database.BookDB()
+	trashedBook, err := db.GetTrashedBookById(id)
+	if err != nil {
+		// Return status 404 and book not found error.
+		return response.RespondError(c, fiber.StatusNotFound, "book with given ID not found in the trash")
+	}
+
+	// Only the owner can restore the book, others need an audited override.
+	auditLog, isError, errorCode, errorMessage := bookPolicyCheck(c, &trashedBook, claims, bookActionRestore)
+	if isError {
+		return response.RespondError(c, errorCode, errorMessage)
+	}
+
+	// Restore book by given ID and record the override with it.
+	err = database.Transaction(func(tx *gorm.DB) error {
+		if err := (&queries.BookQueries{DB: tx}).RestoreBook(trashedBook.ID); err != nil {
+			return err
+		}
+		if err := recordBookRevision(tx, trashedBook.ID, claims.UserID, repository.BookRestoreAction); err != nil {
+			return err
+		}
+		return createBookAuditLog(tx, auditLog)
+	})
+	if err != nil {
+		// Return status 500 and error message.
+		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	trashedBook.DeletedAt = nil
+
+	// Return status 200 OK.
+	return response.RespondSuccess(c, fiber.StatusOK, trashedBook)
+}
+
+// GetBookAuditLogs godoc
+// @Description Will display who acted on a book of another user with an override credential, and why
+// @Description Require valid user token with `book:update:any` or `book:delete:any` credential
+// @Summary Get book audit logs
+// @Tags Book
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param book_id path string true "Book ID"
+// @Success 200 {array} models.BookAuditLog
+// @Failure 400 {object} response.HTTPError
+// @Failure 401 {object} response.HTTPError
+// @Failure 403 {object} response.HTTPError
+// @Failure 500 {object} response.HTTPError
+// @Router /v1/book/{book_id}/audit [get]
+func GetBookAuditLogs(c *fiber.Ctx) error {
+	// Catch book ID from URL.
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return response.RespondError(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	// Get claims from JWT.
+	claims, err := utils.ExtractTokenMetadata(c)
+	if err != nil {
+		// Return status 500 and JWT parse error.
+		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
 	}
+
+	// Only users able to override can read the audit trail.
+	credentialNeed := repository.BookUpdateAnyCredential
+	if claims.Credentials[repository.BookDeleteAnyCredential] {
+		credentialNeed = repository.BookDeleteAnyCredential
+	}
+
+	if isError, errorCode, errorMessage := claimCheck(claims, credentialNeed); isError {
+		return response.RespondError(c, errorCode, errorMessage)
+	}
+
+	// Get audit logs of the book, kept even after the book is deleted.
+	logs, err := database.BookAuditDB().GetBookAuditLogs(id)
+	if err != nil {
+		// Return status 500 and error message.
+		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	// Return status 200 OK.
+	return response.RespondSuccess(c, fiber.StatusOK, logs)
 }
 
-func bookClaimCheck(claims *utils.TokenMetadata, credentialNeeded string) (bool, int, interface{}) {
+// claimCheck func for checking the token is not expired and carries given credential.
+func claimCheck(claims *utils.TokenMetadata, credentialNeeded string) (bool, int, interface{}) {
 	now := time.Now().Unix()
 
-	// Set expiration time from JWT data of current book.
+	// Set expiration time from JWT data of current user.
 	expires := claims.Expires
 
 	// Checking, if now time greater than expiration from JWT.
@@ -311,7 +870,7 @@ func bookClaimCheck(claims *utils.TokenMetadata, credentialNeeded string) (bool,
 
 	isValidCredential := claims.Credentials[credentialNeeded]
 
-	// Only book creator with `book:delete` credential can delete his book.
+	// Only users with the needed credential can go on.
 	if !isValidCredential {
 		// Return status 403 and permission denied error message.
 		return true, fiber.StatusForbidden, "permission denied, credential not eligible"
@@ -319,3 +878,363 @@ func bookClaimCheck(claims *utils.TokenMetadata, credentialNeeded string) (bool,
 
 	return false, 0, ""
 }
+
+// Actions a user can take on a book, checked by bookPolicyCheck.
+const (
+	bookActionView    = "view"
+	bookActionUpdate  = "update"
+	bookActionDelete  = "delete"
+	bookActionRestore = "restore"
+	bookActionManage  = "manage" // manage collaborators and transfer ownership
+)
+
+// bookDefaultOverrideCredentials maps book actions to credentials allowing them on books of other users,
+// unless BOOK_OVERRIDE_CREDENTIALS says otherwise.
+var bookDefaultOverrideCredentials = map[string]string{
+	bookActionUpdate:  repository.BookUpdateAnyCredential,
+	bookActionDelete:  repository.BookDeleteAnyCredential,
+	bookActionRestore: repository.BookDeleteAnyCredential,
+}
+
+// bookOverrideCredentials func for getting the credentials allowing actions on books of other users,
+// from BOOK_OVERRIDE_CREDENTIALS given as comma separated `action=credential` pairs, an empty value turns
+// overrides off. Only the actions of bookDefaultOverrideCredentials can be overridden.
+func bookOverrideCredentials() map[string]string {
+	value, ok := os.LookupEnv("BOOK_OVERRIDE_CREDENTIALS")
+	if !ok {
+		return bookDefaultOverrideCredentials
+	}
+
+	credentials := map[string]string{}
+	for _, pair := range strings.Split(value, ",") {
+		action, credential, found := strings.Cut(pair, "=")
+		action, credential = strings.TrimSpace(action), strings.TrimSpace(credential)
+		if _, known := bookDefaultOverrideCredentials[action]; found && known && credential != "" {
+			credentials[action] = credential
+		}
+	}
+	return credentials
+}
+
+// bookPolicyCheck func for checking if a user may take an action on a book.
+// Users lacking access can still act with an override credential, given a `reason` query param.
+// The override is returned as an audit log, to be written with the action by createBookAuditLog.
+func bookPolicyCheck(c *fiber.Ctx, book *models.Book, claims *utils.TokenMetadata, action string) (*models.BookAuditLog, bool, int, interface{}) {
+	return bookReasonPolicyCheck(book, claims, action, c.Query("reason"))
+}
+
+// bookReasonPolicyCheck func for checking if a user may take an action on a book, like bookPolicyCheck,
+// with the override reason given directly.
+func bookReasonPolicyCheck(book *models.Book, claims *utils.TokenMetadata, action, reason string) (*models.BookAuditLog, bool, int, interface{}) {
+	isError, errorCode, errorMessage := bookAccessCheck(book, claims.UserID, action)
+	if !isError || errorCode != fiber.StatusForbidden {
+		return nil, isError, errorCode, errorMessage
+	}
+
+	// Checking, if user holds an override credential for the action.
+	credential, ok := bookOverrideCredentials()[action]
+	if !ok || !claims.Credentials[credential] {
+		return nil, isError, errorCode, errorMessage
+	}
+
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		// Return status 400 and error message.
+		return nil, true, fiber.StatusBadRequest, "a reason is required to " + action + " a book of another user"
+	}
+
+	auditLog := &models.BookAuditLog{
+		ID:         uuid.New(),
+		CreatedAt:  time.Now(),
+		BookID:     book.ID,
+		OwnerID:    book.UserID,
+		ActorID:    claims.UserID,
+		Action:     action,
+		Credential: credential,
+		Reason:     reason,
+	}
+
+	return auditLog, false, 0, ""
+}
+
+// createBookAuditLog func for recording an override with given transaction, once the action it allowed succeeded.
+func createBookAuditLog(tx *gorm.DB, auditLog *models.BookAuditLog) error {
+	if auditLog == nil {
+		// No override was needed.
+		return nil
+	}
+	return (&queries.BookAuditQueries{DB: tx}).CreateBookAuditLog(auditLog)
+}
+
+// bookAccessCheck func for checking if the owner or a collaborator may take an action on a book.
+// The owner may do everything, editors may view and update, viewers may only view.
+func bookAccessCheck(book *models.Book, userID uuid.UUID, action string) (bool, int, interface{}) {
+	// The owner has full access.
+	if book.UserID == userID {
+		return false, 0, ""
+	}
+
+	// Otherwise consult the collaborators of the book.
+	db := database.BookCollaboratorDB()
+	collaborator, err := db.GetCollaborator(book.ID, userID)
+	if err != nil {
+		// Return status 500 and error message.
+		return true, fiber.StatusInternalServerError, err.Error()
+	}
+	if collaborator == nil {
+		// Return status 403 and permission denied error message.
+		return true, fiber.StatusForbidden, "permission denied, only the owner or collaborators can " + action + " this book"
+	}
+
+	switch action {
+	case bookActionView:
+		return false, 0, ""
+	case bookActionUpdate:
+		if collaborator.CollaboratorRole == repository.BookEditorRoleName {
+			return false, 0, ""
+		}
+	}
+
+	// Return status 403 and permission denied error message.
+	return true, fiber.StatusForbidden, "permission denied, a " + collaborator.CollaboratorRole + " can not " + action + " this book"
+}
+
+// bookVisibleCheck func for hiding unpublished books, only the owner, collaborators and users with
+// `book:publish` credential see them, by sending their token instead of Basic Auth.
+func bookVisibleCheck(c *fiber.Ctx, book *models.Book) (bool, int, interface{}) {
+	if book.BookStatus == repository.BookStatusPublished {
+		return false, 0, ""
+	}
+
+	// Basic Auth callers only see published books.
+	if c.Locals("jwt") == nil {
+		// Return status 404 and book not found error.
+		return true, fiber.StatusNotFound, "book with the given ID is not found"
+	}
+	claims, err := utils.ExtractTokenMetadata(c)
+	if err != nil {
+		// Return status 500 and JWT parse error.
+		return true, fiber.StatusInternalServerError, err.Error()
+	}
+	if claims.Credentials[repository.BookPublishCredential] {
+		return false, 0, ""
+	}
+
+	isError, errorCode, errorMessage := bookAccessCheck(book, claims.UserID, bookActionView)
+	if isError && errorCode == fiber.StatusForbidden {
+		// Return status 404 and book not found error, not telling the book exists.
+		return true, fiber.StatusNotFound, "book with the given ID is not found"
+	}
+	return isError, errorCode, errorMessage
+}
+
+// bookPreconditionCheck func for checking the `If-Match` header against the stored book version.
+func bookPreconditionCheck(c *fiber.Ctx, book *models.Book) (bool, int, interface{}) {
+	ifMatch := c.Get(fiber.HeaderIfMatch)
+	if ifMatch == "" {
+		// No precondition given.
+		return false, 0, ""
+	}
+
+	// Any listed tag may match, weak tags never satisfy If-Match.
+	etag := utils.VersionETag(book.Version)
+	for _, candidate := range strings.Split(ifMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if !strings.HasPrefix(candidate, "W/") && utils.MatchETag(candidate, etag) {
+			return false, 0, ""
+		}
+	}
+
+	// Return status 412 and error message.
+	return true, fiber.StatusPreconditionFailed, queries.ErrBookVersionMismatch.Error()
+}
+
+// bookISBNCheck func for normalizing the ISBN of a validated book to ISBN-13,
+// and checking that no book other than the one with given ID has it, as seen by given queries.
+func bookISBNCheck(db *queries.BookQueries, book *models.Book, id uuid.UUID) (bool, int, interface{}) {
+	if book.ISBN == nil || strings.TrimSpace(*book.ISBN) == "" {
+		// No ISBN given.
+		book.ISBN = nil
+		return false, 0, ""
+	}
+
+	isbn, err := utils.NormalizeISBN(*book.ISBN)
+	if err != nil {
+		// Return status 400 and error message.
+		return true, fiber.StatusBadRequest, err.Error()
+	}
+	book.ISBN = &isbn
+
+	isTaken, err := db.IsISBNTaken(isbn, id)
+	if err != nil {
+		// Return status 500 and error message.
+		return true, fiber.StatusInternalServerError, err.Error()
+	}
+	if isTaken {
+		// Return status 409 and error message.
+		return true, fiber.StatusConflict, queries.ErrBookISBNExists.Error()
+	}
+
+	return false, 0, ""
+}
+
+// bookChangedColumns func for listing editable columns whose values differ between two books.
+func bookChangedColumns(before, after *models.Book) map[string]interface{} {
+	columns := map[string]interface{}{}
+
+	if before.Title != after.Title {
+		columns["title"] = after.Title
+	}
+	if before.Author != after.Author {
+		columns["author"] = after.Author
+	}
+	if !reflect.DeepEqual(before.ISBN, after.ISBN) {
+		columns["isbn"] = after.ISBN
+	}
+	if !reflect.DeepEqual(before.BookAttrs, after.BookAttrs) {
+		columns["book_attrs"] = after.BookAttrs
+	}
+
+	return columns
+}
+
+// newAllBooks func for building a public books list response with page links.
+func newAllBooks(c *fiber.Ctx, p *models.Pagination, page *models.BookPage) *models.AllBooks {
+	booksForPublic := make([]models.BookForPublic, 0, len(page.Books))
+	for _, book := range page.Books {
+		booksForPublic = append(booksForPublic, models.BookForPublic{
+			ID:            book.ID,
+			Title:         book.Title,
+			Author:        book.Author,
+			ISBN:          book.ISBN,
+			BookStatus:    book.BookStatus,
+			BookAttrs:     book.BookAttrs,
+			RatingAverage: book.RatingAverage,
+			RatingCount:   book.RatingCount,
+			DeletedAt:     book.DeletedAt,
+		})
+	}
+
+	// Keyset edges of the returned page.
+	var first, last *models.Cursor
+	if len(page.Books) > 0 {
+		first = &models.Cursor{CreatedAt: page.Books[0].CreatedAt, ID: page.Books[0].ID}
+		last = &models.Cursor{CreatedAt: page.Books[len(page.Books)-1].CreatedAt, ID: page.Books[len(page.Books)-1].ID}
+	}
+
+	allBooks := &models.AllBooks{
+		Books: booksForPublic,
+		Count: page.Total,
+		Limit: p.Limit,
+		Links: utils.BuildPageLinks(c, p, page.Total, page.HasMore, first, last),
+	}
+	if !p.UseCursor {
+		allBooks.Page = p.Page
+	}
+
+	return allBooks
+}
+
+// parsePublicBookFilter func for reading filters of public books lists, which only show published books.
+func parsePublicBookFilter(c *fiber.Ctx) (*models.BookFilter, error) {
+	if c.Query("book_status") != "" {
+		return nil, errors.New("book_status is not supported here, use /v1/books/workflow")
+	}
+
+	filter, err := parseBookFilter(c)
+	if err != nil {
+		return nil, err
+	}
+	filter.BookStatus = repository.BookStatusPublished
+
+	return filter, nil
+}
+
+// parseBookFilter func for reading books list filters from query params.
+func parseBookFilter(c *fiber.Ctx) (*models.BookFilter, error) {
+	pagination, err := utils.ParsePagination(c)
+	if err != nil {
+		return nil, err
+	}
+
+	filter := &models.BookFilter{
+		Author:     c.Query("author"),
+		Title:      c.Query("title"),
+		Pagination: *pagination,
+	}
+
+	if status := c.Query("book_status"); status != "" {
+		if _, ok := repository.BookStatusTransitions[status]; !ok {
+			return nil, fmt.Errorf("book_status '%v' does not exist", status)
+		}
+		filter.BookStatus = status
+	}
+
+	if userID := c.Query("user_id"); userID != "" {
+		id, err := uuid.Parse(userID)
+		if err != nil {
+			return nil, fmt.Errorf("user_id must be a valid UUID")
+		}
+		filter.UserID = &id
+	}
+
+	if authorID := c.Query("author_id"); authorID != "" {
+		id, err := uuid.Parse(authorID)
+		if err != nil {
+			return nil, fmt.Errorf("author_id must be a valid UUID")
+		}
+		filter.AuthorID = &id
+	}
+
+	if ratingMin := c.Query("rating_min"); ratingMin != "" {
+		r, err := strconv.Atoi(ratingMin)
+		if err != nil {
+			return nil, fmt.Errorf("rating_min must be a number")
+		}
+		filter.RatingMin = &r
+	}
+
+	if ratingMax := c.Query("rating_max"); ratingMax != "" {
+		r, err := strconv.Atoi(ratingMax)
+		if err != nil {
+			return nil, fmt.Errorf("rating_max must be a number")
+		}
+		filter.RatingMax = &r
+	}
+
+	if tags := c.Query("tag"); tags != "" {
+		for _, tag := range strings.Split(tags, ",") {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				filter.Tags = append(filter.Tags, tag)
+			}
+		}
+	}
+	filter.Category = c.Query("category")
+
+	if filter.Attrs, err = parseBookAttrFilter(c); err != nil {
+		return nil, err
+	}
+
+	if sort := c.Query("sort"); sort != "" {
+		for _, column := range strings.Split(sort, ",") {
+			field := models.SortField{Column: strings.TrimSpace(column)}
+			if strings.HasPrefix(field.Column, "-") {
+				field.Desc = true
+				field.Column = strings.TrimPrefix(field.Column, "-")
+			}
+			if _, ok := queries.BookSortColumns[field.Column]; !ok {
+				return nil, fmt.Errorf("sort by '%v' is not supported", field.Column)
+			}
+			filter.Sort = append(filter.Sort, field)
+		}
+	}
+
+	// Keyset paging only follows `created_at,id`.
+	if filter.UseCursor {
+		if len(filter.Sort) > 1 || (len(filter.Sort) == 1 && filter.Sort[0].Column != "created_at") {
+			return nil, fmt.Errorf("cursor paging only supports sort by created_at")
+		}
+	}
+
+	return filter, nil
+}
@@ -0,0 +1,233 @@
+package controllers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/aryanicosa/go-fiber-rest-api/app/queries"
+	"github.com/aryanicosa/go-fiber-rest-api/pkg/repository"
+	"github.com/aryanicosa/go-fiber-rest-api/pkg/response"
+	"github.com/aryanicosa/go-fiber-rest-api/pkg/utils"
+	"github.com/aryanicosa/go-fiber-rest-api/platform/database"
+	"github.com/aryanicosa/go-fiber-rest-api/platform/storage"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	// bookCoverDefaultMaxSizeMB is the biggest cover accepted when BOOK_COVER_MAX_SIZE_MB is not set.
+	bookCoverDefaultMaxSizeMB = 5
+
+	// bookCoverMaxPixels is the biggest cover accepted in pixels, checked before decoding it.
+	bookCoverMaxPixels = 40_000_000
+)
+
+// bookCoverTypes maps accepted cover content types to file extensions.
+var bookCoverTypes = map[string]string{
+	"image/jpeg": "jpg",
+	"image/png":  "png",
+	"image/gif":  "gif",
+}
+
+// bookCoverThumbnails maps thumbnail names to their widths in pixels.
+var bookCoverThumbnails = map[string]int{
+	"small":  160,
+	"medium": 480,
+}
+
+// UploadBookCover godoc
+// @Description Upload a JPEG, PNG or GIF cover, saved with `small` and `medium` thumbnails
+// @Description `book_attrs.picture` is set to the URL of the cover, previous covers are kept for the revision history
+// @Description Require valid user token with `book:update` credential
+// @Summary Upload a book cover
+// @Tags Book
+// @Accept mpfd
+// @Produce json
+// @Security ApiKeyAuth
+// @Param book_id path string true "Book ID"
+// @Param cover formData file true "Cover image"
+// @Param reason query string false "Required when updating a book of another user with `book:update:any`"
+// @Param If-Match header string false "ETag of the edited version, answered with 412 if outdated"
+// @Success 200 {object} models.Book
+// @Failure 400 {object} response.HTTPError
+// @Failure 401 {object} response.HTTPError
+// @Failure 403 {object} response.HTTPError
+// @Failure 404 {object} response.HTTPError
+// @Failure 412 {object} response.HTTPError
+// @Failure 413 {object} response.HTTPError
+// @Failure 415 {object} response.HTTPError
+// @Failure 500 {object} response.HTTPError
+// @Router /v1/book/{book_id}/cover [post]
+func UploadBookCover(c *fiber.Ctx) error {
+	// Catch book ID from URL.
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return response.RespondError(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	// Get claims from JWT.
+	claims, err := utils.ExtractTokenMetadata(c)
+	if err != nil {
+		// Return status 500 and JWT parse error.
+		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	if isError, errorCode, errorMessage := claimCheck(claims, repository.BookUpdateCredential); isError {
+		return response.RespondError(c, errorCode, errorMessage)
+	}
+
+	// Read the uploaded cover, up to the size limit.
+	fileHeader, err := c.FormFile("cover")
+	if err != nil {
+		// Return status 400 and error message.
+		return response.RespondError(c, fiber.StatusBadRequest, "a `cover` file is required")
+	}
+
+	maxSizeMB, _ := strconv.Atoi(os.Getenv("BOOK_COVER_MAX_SIZE_MB"))
+	if maxSizeMB <= 0 {
+		maxSizeMB = bookCoverDefaultMaxSizeMB
+	}
+	maxSize := int64(maxSizeMB) << 20
+	if fileHeader.Size > maxSize {
+		// Return status 413 and error message.
+		return response.RespondError(c, fiber.StatusRequestEntityTooLarge, fmt.Sprintf("cover must be at most %d MB", maxSizeMB))
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		// Return status 400 and error message.
+		return response.RespondError(c, fiber.StatusBadRequest, err.Error())
+	}
+	defer file.Close()
+
+	cover, err := io.ReadAll(io.LimitReader(file, maxSize+1))
+	if err != nil {
+		// Return status 400 and error message.
+		return response.RespondError(c, fiber.StatusBadRequest, err.Error())
+	}
+	if int64(len(cover)) > maxSize {
+		// Return status 413 and error message.
+		return response.RespondError(c, fiber.StatusRequestEntityTooLarge, fmt.Sprintf("cover must be at most %d MB", maxSizeMB))
+	}
+
+	// Trust the content, not the declared type.
+	contentType := http.DetectContentType(cover)
+	extension, ok := bookCoverTypes[contentType]
+	if !ok {
+		// Return status 415 and error message.
+		return response.RespondError(c, fiber.StatusUnsupportedMediaType, "cover must be a JPEG, PNG or GIF image")
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(cover))
+	if err != nil {
+		// Return status 400 and error message.
+		return response.RespondError(c, fiber.StatusBadRequest, "cover is not a valid image")
+	}
+	if config.Width*config.Height > bookCoverMaxPixels {
+		// Return status 400 and error message.
+		return response.RespondError(c, fiber.StatusBadRequest, "cover has too many pixels")
+	}
+
+	// Checking, if book with given ID is exists.
+	db := database.BookDB()
+	foundedBook, err := db.GetBookById(id)
+	if err != nil {
+		// Return status 404 and book not found error.
+		return response.RespondError(c, fiber.StatusNotFound, "book with given ID not found")
+	}
+
+	// Check, if client edits the latest version.
+	if isError, errorCode, errorMessage := bookPreconditionCheck(c, &foundedBook); isError {
+		return response.RespondError(c, errorCode, errorMessage)
+	}
+
+	// Only the owner and editors can update the book, others need an audited override.
+	auditLog, isError, errorCode, errorMessage := bookPolicyCheck(c, &foundedBook, claims, bookActionUpdate)
+	if isError {
+		return response.RespondError(c, errorCode, errorMessage)
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(cover))
+	if err != nil {
+		// Return status 400 and error message.
+		return response.RespondError(c, fiber.StatusBadRequest, "cover is not a valid image")
+	}
+
+	// Save the cover and its thumbnails under a new name, so cached old covers are never served.
+	store := storage.Store()
+	keyPrefix := "books/" + foundedBook.ID.String() + "/cover-" + uuid.New().String()
+	savedKeys := []string{}
+	removeSaved := func() {
+		for _, key := range savedKeys {
+			if err := store.Delete(key); err != nil {
+				log.Printf("unable to remove cover file %v: %v", key, err)
+			}
+		}
+	}
+
+	if err := store.Put(keyPrefix+"."+extension, cover, contentType); err != nil {
+		// Return status 500 and error message.
+		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+	}
+	savedKeys = append(savedKeys, keyPrefix+"."+extension)
+
+	book := foundedBook
+	book.BookAttrs.Picture = store.URL(keyPrefix + "." + extension)
+	book.BookAttrs.Thumbnails = map[string]string{}
+	for name, width := range bookCoverThumbnails {
+		thumbnail, thumbnailType, err := utils.EncodeImage(utils.ResizeImage(img, width), contentType != "image/jpeg")
+		if err != nil {
+			removeSaved()
+			// Return status 500 and error message.
+			return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+		}
+
+		key := keyPrefix + "-" + name + "." + bookCoverTypes[thumbnailType]
+		if err := store.Put(key, thumbnail, thumbnailType); err != nil {
+			removeSaved()
+			// Return status 500 and error message.
+			return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+		}
+		savedKeys = append(savedKeys, key)
+		book.BookAttrs.Thumbnails[name] = store.URL(key)
+	}
+
+	// Point the book at the new cover, unless it changed since it was read.
+	book.UpdatedAt = time.Now()
+	err = database.Transaction(func(tx *gorm.DB) error {
+		version, err := (&queries.BookQueries{DB: tx}).PatchBook(foundedBook.ID, foundedBook.Version, map[string]interface{}{
+			"book_attrs": book.BookAttrs,
+			"updated_at": book.UpdatedAt,
+		})
+		if err != nil {
+			return err
+		}
+		book.Version = version
+		if err := recordBookRevision(tx, foundedBook.ID, claims.UserID, repository.BookUpdateAction); err != nil {
+			return err
+		}
+		return createBookAuditLog(tx, auditLog)
+	})
+	if err != nil {
+		removeSaved()
+		if errors.Is(err, queries.ErrBookVersionMismatch) {
+			// Return status 412 and error message.
+			return response.RespondError(c, fiber.StatusPreconditionFailed, err.Error())
+		}
+		// Return status 500 and error message.
+		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	// Return status 200 OK.
+	c.Set(fiber.HeaderETag, utils.VersionETag(book.Version))
+	return response.RespondSuccess(c, fiber.StatusOK, book)
+}
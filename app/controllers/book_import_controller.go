@@ -0,0 +1,551 @@
+package controllers
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aryanicosa/go-fiber-rest-api/app/models"
+	"github.com/aryanicosa/go-fiber-rest-api/pkg/repository"
+	"github.com/aryanicosa/go-fiber-rest-api/pkg/response"
+	"github.com/aryanicosa/go-fiber-rest-api/pkg/utils"
+	"github.com/aryanicosa/go-fiber-rest-api/platform/database"
+	"github.com/gofiber/fiber/v2"
+	fiberutils "github.com/gofiber/fiber/v2/utils"
+	"github.com/google/uuid"
+)
+
+const (
+	// bookCSVContentType is the media type of books imported and exported as CSV.
+	bookCSVContentType = "text/csv"
+
+	// bookNDJSONContentType is the media type of books imported and exported as newline delimited JSON.
+	bookNDJSONContentType = "application/x-ndjson"
+
+	// bookImportBatchSize is the number of books written per transaction of an import.
+	bookImportBatchSize = 100
+
+	// bookExportFlushSize is the number of books written before the export stream is flushed.
+	bookExportFlushSize = 100
+
+	// bookImportDefaultMaxSizeMB is the biggest import accepted when BOOK_IMPORT_MAX_SIZE_MB is not set.
+	bookImportDefaultMaxSizeMB = 50
+)
+
+// bookCSVColumns lists columns of exported CSV files, imported files may use any of them in any order.
+// The `id` and `book_status` columns are accepted for round trips but imported books always get a new ID
+// and start as drafts.
+var bookCSVColumns = []string{"id", "title", "author", "isbn", "book_status", "picture", "description", "rating"}
+
+// ImportBooks godoc
+// @Description Create many books from a CSV file with a header row, or from newline delimited JSON books
+// @Description Valid rows are saved in batches even if other rows fail, `row` of an error is the line in the file
+// @Description Imported books always start as drafts, an ISBN already used by another book or an earlier row fails the row
+// @Description The file may be at most BOOK_IMPORT_MAX_SIZE_MB, reading stops at the limit and rows read so far are kept
+// @Description Require valid user token with `book:create` credential
+// @Summary Import books
+// @Tags Book
+// @Accept text/csv
+// @Accept application/x-ndjson
+// @Produce json
+// @Security ApiKeyAuth
+// @Param books body string true "CSV with `title,author,isbn,book_status,picture,description,rating` columns, or one JSON book per line"
+// @Success 200 {object} models.BookImportReport
+// @Failure 400 {object} response.HTTPError
+// @Failure 401 {object} response.HTTPError
+// @Failure 403 {object} response.HTTPError
+// @Failure 413 {object} response.HTTPError
+// @Failure 415 {object} response.HTTPError
+// @Failure 500 {object} response.HTTPError
+// @Router /v1/books/import [post]
+func ImportBooks(c *fiber.Ctx) error {
+	// Get claims from JWT.
+	claims, err := utils.ExtractTokenMetadata(c)
+	if err != nil {
+		// Return status 500 and JWT parse error.
+		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	if isError, errorCode, errorMessage := claimCheck(claims, repository.BookCreateCredential); isError {
+		return response.RespondError(c, errorCode, errorMessage)
+	}
+
+	// Read the body as it arrives, it is the only one streamed past the server body limit so it has its own.
+	maxSizeMB, _ := strconv.Atoi(os.Getenv("BOOK_IMPORT_MAX_SIZE_MB"))
+	if maxSizeMB <= 0 {
+		maxSizeMB = bookImportDefaultMaxSizeMB
+	}
+	maxSize := int64(maxSizeMB) << 20
+	if int64(c.Request().Header.ContentLength()) > maxSize {
+		// Return status 413 and error message.
+		return response.RespondError(c, fiber.StatusRequestEntityTooLarge, fmt.Sprintf("import must be at most %d MB", maxSizeMB))
+	}
+
+	stream := c.Context().RequestBodyStream()
+	if stream == nil {
+		stream = bytes.NewReader(c.Body())
+	}
+	body := &bookImportLimitReader{reader: stream, left: maxSize, maxSizeMB: maxSizeMB}
+
+	var reader bookImportReader
+	switch strings.TrimSpace(strings.Split(string(c.Request().Header.ContentType()), ";")[0]) {
+	case bookCSVContentType:
+		reader, err = newBookCSVReader(body)
+	case bookNDJSONContentType:
+		reader = newBookNDJSONReader(body)
+	default:
+		// Return status 415 and error message.
+		return response.RespondError(c, fiber.StatusUnsupportedMediaType,
+			"content type must be "+bookCSVContentType+" or "+bookNDJSONContentType)
+	}
+	if err != nil {
+		// Return status 400 and error message.
+		return response.RespondError(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	db := database.BookDB()
+	validate := utils.NewValidator()
+
+	// Extra attributes of all rows are checked against the same definitions.
+	attrDefinitions, err := database.BookAttrDB().GetBookAttrDefinitions()
+	if err != nil {
+		// Return status 500 and error message.
+		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+	}
+	report := &models.BookImportReport{Errors: []models.BookImportError{}}
+
+	batch := make([]*models.Book, 0, bookImportBatchSize)
+	batchRows := make([]int, 0, bookImportBatchSize)
+	seenISBNs := map[string]int{}
+
+	// Save collected books in one transaction, a failed batch fails all of its rows.
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := db.CreateBooks(batch, claims.UserID); err != nil {
+			for _, row := range batchRows {
+				report.Errors = append(report.Errors, models.BookImportError{Row: row, Error: "unable to save book: " + err.Error()})
+			}
+			report.Failed += len(batch)
+		} else {
+			report.Imported += len(batch)
+		}
+		batch = batch[:0]
+		batchRows = batchRows[:0]
+	}
+
+	for {
+		book, row, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+
+		rowError := &bookRowError{}
+		if errors.As(err, &rowError) {
+			report.Errors = append(report.Errors, models.BookImportError{Row: row, Error: rowError.Error()})
+			report.Failed++
+			continue
+		}
+		if err != nil {
+			// The rest of the file can not be read, keep what was read so far.
+			report.Errors = append(report.Errors, models.BookImportError{Row: row, Error: "unable to read rest of the file: " + err.Error()})
+			break
+		}
+
+		// Set initialized default data for book, as CreateBook does:
+		book.ID = uuid.New()
+		book.CreatedAt = time.Now()
+		book.UserID = claims.UserID
+		book.BookStatus = repository.BookStatusDraft
+
+		// Validate book fields.
+		if err := validate.Struct(book); err != nil {
+			report.Errors = append(report.Errors, models.BookImportError{Row: row, Fields: utils.ValidatorErrors(err)})
+			report.Failed++
+			continue
+		}
+		if fields := bookAttrsErrors(&book.BookAttrs, attrDefinitions); len(fields) > 0 {
+			report.Errors = append(report.Errors, models.BookImportError{Row: row, Fields: fields})
+			report.Failed++
+			continue
+		}
+
+		// Checking, if the ISBN is taken by a saved book or an earlier row.
+		if isError, _, errorMessage := bookISBNCheck(database.BookDB(), book, book.ID); isError {
+			report.Errors = append(report.Errors, models.BookImportError{Row: row, Error: fmt.Sprint(errorMessage)})
+			report.Failed++
+			continue
+		}
+		if book.ISBN != nil {
+			if seenRow, ok := seenISBNs[*book.ISBN]; ok {
+				report.Errors = append(report.Errors, models.BookImportError{Row: row, Error: fmt.Sprintf("isbn is already used in row %d", seenRow)})
+				report.Failed++
+				continue
+			}
+			seenISBNs[*book.ISBN] = row
+		}
+
+		batch = append(batch, book)
+		batchRows = append(batchRows, row)
+		if len(batch) == bookImportBatchSize {
+			flush()
+		}
+	}
+	flush()
+
+	// Return status 200 OK.
+	return response.RespondSuccess(c, fiber.StatusOK, report)
+}
+
+// ExportBooks godoc
+// @Description Will stream all published books matching the filters, in the same format accepted by the import
+// @Description Accepts the same filters and sort as the books list, paging params are ignored
+// @Description Require Basic Auth
+// @Summary Export books
+// @Tags Book
+// @Accept json
+// @Produce text/csv
+// @Produce application/x-ndjson
+// @Security BasicAuth
+// @Param format query string false "Export format, `csv` by default" Enums(csv, ndjson)
+// @Param author query string false "Exact author name"
+// @Param title query string false "Title substring"
+// @Param user_id query string false "Creator user ID"
+// @Param rating_min query int false "Minimum rating"
+// @Param rating_max query int false "Maximum rating"
+// @Param sort query string false "Comma separated columns, prefix with `-` for descending, e.g. `-created_at,title`"
+// @Success 200 {string} string
+// @Failure 400 {object} response.HTTPError
+// @Router /v1/books/export [get]
+func ExportBooks(c *fiber.Ctx) error {
+	// Read filters and sort from query params.
+	filter, err := parsePublicBookFilter(c)
+	if err != nil {
+		// Return status 400 and error message.
+		return response.RespondError(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	var newWriter func(w *bufio.Writer) (bookExportWriter, error)
+	switch format := c.Query("format", "csv"); format {
+	case "csv":
+		c.Set(fiber.HeaderContentType, bookCSVContentType)
+		newWriter = newBookCSVWriter
+	case "ndjson":
+		c.Set(fiber.HeaderContentType, bookNDJSONContentType)
+		newWriter = newBookNDJSONWriter
+	default:
+		// Return status 400 and error message.
+		return response.RespondError(c, fiber.StatusBadRequest, fmt.Sprintf("export format '%v' is not supported", format))
+	}
+	c.Set(fiber.HeaderContentDisposition, `attachment; filename="books.`+c.Query("format", "csv")+`"`)
+
+	// Query values point into the request buffer, keep copies for the stream.
+	copyBookFilter(filter)
+
+	// Rows are read and written one by one after the handler returns, so errors can only end the stream early.
+	db := database.BookDB()
+	c.Status(fiber.StatusOK).Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		count := 0
+		writer, err := newWriter(w)
+		if err == nil {
+			err = db.ExportBooks(filter, func(book *models.Book) error {
+				err := writer.Write(&models.BookForPublic{
+					ID:            book.ID,
+					Title:         book.Title,
+					Author:        book.Author,
+					ISBN:          book.ISBN,
+					BookStatus:    book.BookStatus,
+					BookAttrs:     book.BookAttrs,
+					RatingAverage: book.RatingAverage,
+					RatingCount:   book.RatingCount,
+				})
+				if err != nil {
+					return err
+				}
+
+				count++
+				if count%bookExportFlushSize == 0 {
+					return w.Flush()
+				}
+				return nil
+			})
+		}
+		if err != nil {
+			log.Printf("books export stopped after %d books: %v", count, err)
+		}
+		_ = w.Flush()
+	})
+
+	return nil
+}
+
+// copyBookFilter func for replacing the strings of a filter with copies, so it outlives the request.
+func copyBookFilter(f *models.BookFilter) {
+	f.Author = fiberutils.CopyString(f.Author)
+	f.Title = fiberutils.CopyString(f.Title)
+	f.BookStatus = fiberutils.CopyString(f.BookStatus)
+	f.Category = fiberutils.CopyString(f.Category)
+
+	tags := make([]string, len(f.Tags))
+	for i, tag := range f.Tags {
+		tags[i] = fiberutils.CopyString(tag)
+	}
+	f.Tags = tags
+
+	if f.Attrs != nil {
+		attrs := make(map[string]interface{}, len(f.Attrs))
+		for name, value := range f.Attrs {
+			if s, ok := value.(string); ok {
+				value = fiberutils.CopyString(s)
+			}
+			attrs[fiberutils.CopyString(name)] = value
+		}
+		f.Attrs = attrs
+	}
+
+	sort := make([]models.SortField, len(f.Sort))
+	for i, field := range f.Sort {
+		sort[i] = models.SortField{Column: fiberutils.CopyString(field.Column), Desc: field.Desc}
+	}
+	f.Sort = sort
+}
+
+// bookImportLimitReader struct for reading an import body, failing once it grows past the size limit.
+type bookImportLimitReader struct {
+	reader    io.Reader
+	left      int64
+	maxSizeMB int
+}
+
+// Read method for reading the body while it stays within the size limit.
+func (r *bookImportLimitReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	r.left -= int64(n)
+	if r.left < 0 {
+		return n + int(r.left), fmt.Errorf("import must be at most %d MB", r.maxSizeMB)
+	}
+	return n, err
+}
+
+// bookRowError is returned by import readers for a row that is skipped while the rest of the file is read.
+type bookRowError struct {
+	message string
+}
+
+func (e *bookRowError) Error() string {
+	return e.message
+}
+
+// bookImportReader reads books of an import one row at a time.
+// Next returns the book with its line in the file, io.EOF at the end, a *bookRowError for a bad row,
+// or any other error if the file can not be read any further.
+type bookImportReader interface {
+	Next() (*models.Book, int, error)
+}
+
+// bookCSVReader struct for reading imported books from CSV with a header row.
+type bookCSVReader struct {
+	reader  *csv.Reader
+	columns map[string]int
+}
+
+// newBookCSVReader func for reading the header row and checking its columns.
+func newBookCSVReader(r io.Reader) (*bookCSVReader, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, fmt.Errorf("csv header row is missing")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	columns := map[string]int{}
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if !bookCSVColumn(name) {
+			return nil, fmt.Errorf("csv column '%v' is not supported", name)
+		}
+		columns[name] = i
+	}
+	for _, name := range []string{"title", "author"} {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("csv column '%v' is required", name)
+		}
+	}
+
+	return &bookCSVReader{reader: reader, columns: columns}, nil
+}
+
+// Next method for reading the next CSV row as a book.
+func (r *bookCSVReader) Next() (*models.Book, int, error) {
+	record, err := r.reader.Read()
+	if err == io.EOF {
+		return nil, 0, io.EOF
+	}
+	if err != nil {
+		parseError := &csv.ParseError{}
+		if errors.As(err, &parseError) {
+			return nil, parseError.StartLine, err
+		}
+		return nil, 0, err
+	}
+	row, _ := r.reader.FieldPos(0)
+
+	value := func(column string) string {
+		i, ok := r.columns[column]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	book := &models.Book{
+		Title:  value("title"),
+		Author: value("author"),
+		BookAttrs: models.BookAttrs{
+			Picture:     value("picture"),
+			Description: value("description"),
+		},
+	}
+	if isbn := value("isbn"); isbn != "" {
+		book.ISBN = &isbn
+	}
+	if rating := value("rating"); rating != "" {
+		if book.BookAttrs.Rating, err = strconv.Atoi(rating); err != nil {
+			return nil, row, &bookRowError{message: "rating must be a number"}
+		}
+	}
+
+	return book, row, nil
+}
+
+// bookCSVColumn func for checking if a CSV column is known.
+func bookCSVColumn(name string) bool {
+	for _, column := range bookCSVColumns {
+		if column == name {
+			return true
+		}
+	}
+	return false
+}
+
+// bookNDJSONReader struct for reading imported books from newline delimited JSON.
+type bookNDJSONReader struct {
+	scanner *bufio.Scanner
+	line    int
+}
+
+// newBookNDJSONReader func for reading books of up to 1 MB per line.
+func newBookNDJSONReader(r io.Reader) *bookNDJSONReader {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	return &bookNDJSONReader{scanner: scanner}
+}
+
+// Next method for reading the next non-empty line as a book.
+func (r *bookNDJSONReader) Next() (*models.Book, int, error) {
+	for r.scanner.Scan() {
+		r.line++
+
+		line := bytes.TrimSpace(r.scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		book := &models.Book{}
+		if err := json.Unmarshal(line, book); err != nil {
+			return nil, r.line, &bookRowError{message: "line is not a valid book: " + err.Error()}
+		}
+
+		return book, r.line, nil
+	}
+
+	if err := r.scanner.Err(); err != nil {
+		return nil, r.line + 1, err
+	}
+
+	return nil, 0, io.EOF
+}
+
+// bookExportWriter writes exported books one at a time.
+type bookExportWriter interface {
+	Write(book *models.BookForPublic) error
+}
+
+// bookCSVWriter struct for writing exported books as CSV rows.
+type bookCSVWriter struct {
+	writer *csv.Writer
+}
+
+// newBookCSVWriter func for starting a CSV export with the header row.
+func newBookCSVWriter(w *bufio.Writer) (bookExportWriter, error) {
+	writer := &bookCSVWriter{writer: csv.NewWriter(w)}
+	if err := writer.writer.Write(bookCSVColumns); err != nil {
+		return nil, err
+	}
+
+	return writer, nil
+}
+
+// Write method for writing an exported book as one CSV row.
+func (w *bookCSVWriter) Write(book *models.BookForPublic) error {
+	isbn := ""
+	if book.ISBN != nil {
+		isbn = *book.ISBN
+	}
+
+	err := w.writer.Write([]string{
+		book.ID.String(),
+		book.Title,
+		book.Author,
+		isbn,
+		book.BookStatus,
+		book.BookAttrs.Picture,
+		book.BookAttrs.Description,
+		strconv.Itoa(book.BookAttrs.Rating),
+	})
+	if err != nil {
+		return err
+	}
+
+	// Hand the row over to the stream, which decides when to flush.
+	w.writer.Flush()
+	return w.writer.Error()
+}
+
+// bookNDJSONWriter struct for writing exported books as newline delimited JSON.
+type bookNDJSONWriter struct {
+	writer *bufio.Writer
+}
+
+// newBookNDJSONWriter func for starting a newline delimited JSON export.
+func newBookNDJSONWriter(w *bufio.Writer) (bookExportWriter, error) {
+	return &bookNDJSONWriter{writer: w}, nil
+}
+
+// Write method for writing an exported book as one line of JSON.
+func (w *bookNDJSONWriter) Write(book *models.BookForPublic) error {
+	line, err := json.Marshal(book)
+	if err != nil {
+		return err
+	}
+	if _, err := w.writer.Write(line); err != nil {
+		return err
+	}
+	return w.writer.WriteByte('\n')
+}
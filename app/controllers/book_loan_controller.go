@@ -0,0 +1,659 @@
+package controllers
+
+import (
+	"errors"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/aryanicosa/go-fiber-rest-api/app/models"
+	"github.com/aryanicosa/go-fiber-rest-api/app/queries"
+	"github.com/aryanicosa/go-fiber-rest-api/pkg/repository"
+	"github.com/aryanicosa/go-fiber-rest-api/pkg/response"
+	"github.com/aryanicosa/go-fiber-rest-api/pkg/utils"
+	"github.com/aryanicosa/go-fiber-rest-api/platform/database"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+const (
+	// bookLoanDefaultPeriodDays is the loan period used when BOOK_LOAN_PERIOD_DAYS is not set.
+	bookLoanDefaultPeriodDays = 14
+
+	// bookLoanDefaultMaxRenewals is the number of renewals used when BOOK_LOAN_MAX_RENEWALS is not set.
+	bookLoanDefaultMaxRenewals = 2
+)
+
+// GetBookCopies godoc
+// @Description Will display the copies of a book with whether each is `available`, `on_loan` or `on_hold`, and the length of its holds queue
+// @Description Require Basic Auth
+// @Description Unpublished books are only shown to the owner, collaborators and users with `book:publish` credential, sending their token instead
+// @Summary Get copies of a book
+// @Tags Lending
+// @Accept json
+// @Produce json
+// @Security BasicAuth
+// @Security ApiKeyAuth
+// @Param book_id path string true "Book ID"
+// @Success 200 {object} models.BookAvailability
+// @Failure 400 {object} response.HTTPError
+// @Failure 404 {object} response.HTTPError
+// @Failure 500 {object} response.HTTPError
+// @Router /v1/book/{book_id}/copies [get]
+func GetBookCopies(c *fiber.Ctx) error {
+	// Catch book ID from URL.
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return response.RespondError(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	// Checking, if book with given ID is exists.
+	foundedBook, err := database.BookDB().GetBookById(id)
+	if err != nil {
+		// Return status 404 and book not found error.
+		return response.RespondError(c, fiber.StatusNotFound, "book with given ID not found")
+	}
+
+	// Checking, if caller may see the book.
+	if isError, errorCode, errorMessage := bookVisibleCheck(c, &foundedBook); isError {
+		return response.RespondError(c, errorCode, errorMessage)
+	}
+
+	db := database.BookLoanDB()
+	copies, err := db.GetBookCopies(foundedBook.ID)
+	if err != nil {
+		// Return status 500 and error message.
+		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+	}
+	holds, err := db.CountWaitingHolds(foundedBook.ID)
+	if err != nil {
+		// Return status 500 and error message.
+		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	availability := &models.BookAvailability{BookID: foundedBook.ID, Copies: copies, Holds: holds}
+	for _, bookCopy := range copies {
+		if bookCopy.Status == repository.BookCopyAvailable {
+			availability.Available++
+		}
+	}
+
+	// Return status 200 OK.
+	return response.RespondSuccess(c, fiber.StatusOK, availability)
+}
+
+// CreateBookCopy godoc
+// @Description Add a physical copy of a book to the library, it goes to the oldest hold if users are waiting
+// @Description Require valid user token with `book:lending` credential
+// @Summary Add copy of a book
+// @Tags Lending
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param book_id path string true "Book ID"
+// @Param models.SaveBookCopy body models.SaveBookCopy true "Copy data"
+// @Success 201 {object} models.BookCopy
+// @Failure 400 {object} response.HTTPError
+// @Failure 401 {object} response.HTTPError
+// @Failure 403 {object} response.HTTPError
+// @Failure 404 {object} response.HTTPError
+// @Failure 409 {object} response.HTTPError
+// @Failure 500 {object} response.HTTPError
+// @Router /v1/book/{book_id}/copies [post]
+func CreateBookCopy(c *fiber.Ctx) error {
+	// Catch book ID from URL.
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return response.RespondError(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	// Get claims from JWT.
+	claims, err := utils.ExtractTokenMetadata(c)
+	if err != nil {
+		// Return status 500 and JWT parse error.
+		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	if isError, errorCode, errorMessage := claimCheck(claims, repository.BookLendingCredential); isError {
+		return response.RespondError(c, errorCode, errorMessage)
+	}
+
+	// Create new SaveBookCopy struct
+	saveCopy := &models.SaveBookCopy{}
+
+	// Check, if received JSON data is valid.
+	if err := c.BodyParser(saveCopy); err != nil {
+		// Return status 400 and error message.
+		return response.RespondError(c, fiber.StatusBadRequest, "unable to parse request body")
+	}
+
+	// Validate copy fields.
+	validate := utils.NewValidator()
+	if err := validate.Struct(saveCopy); err != nil {
+		// Return, if some fields are not valid.
+		return response.RespondError(c, fiber.StatusBadRequest, utils.ValidatorErrors(err))
+	}
+
+	// Checking, if book with given ID is exists.
+	foundedBook, err := database.BookDB().GetBookById(id)
+	if err != nil {
+		// Return status 404 and book not found error.
+		return response.RespondError(c, fiber.StatusNotFound, "book with given ID not found")
+	}
+
+	// Set initialized default data for copy:
+	bookCopy := &models.BookCopy{
+		ID:        uuid.New(),
+		CreatedAt: time.Now(),
+		BookID:    foundedBook.ID,
+		Barcode:   saveCopy.Barcode,
+		Notes:     saveCopy.Notes,
+	}
+
+	if err := database.BookLoanDB().CreateBookCopy(bookCopy); err != nil {
+		// Return status 409 and error message, barcodes are unique.
+		return response.RespondError(c, fiber.StatusConflict, "unable to add copy, the barcode may already be used")
+	}
+
+	// Return status 201.
+	return response.RespondSuccess(c, fiber.StatusCreated, bookCopy)
+}
+
+// DeleteBookCopy godoc
+// @Description Take a copy out of the library, it must not be checked out
+// @Description Require valid user token with `book:lending` credential
+// @Summary Delete copy of a book
+// @Tags Lending
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param copy_id path string true "Copy ID"
+// @Success 204
+// @Failure 400 {object} response.HTTPError
+// @Failure 401 {object} response.HTTPError
+// @Failure 403 {object} response.HTTPError
+// @Failure 404 {object} response.HTTPError
+// @Failure 409 {object} response.HTTPError
+// @Router /v1/copy/{copy_id} [delete]
+func DeleteBookCopy(c *fiber.Ctx) error {
+	// Catch copy ID from URL.
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return response.RespondError(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	// Get claims from JWT.
+	claims, err := utils.ExtractTokenMetadata(c)
+	if err != nil {
+		// Return status 500 and JWT parse error.
+		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	if isError, errorCode, errorMessage := claimCheck(claims, repository.BookLendingCredential); isError {
+		return response.RespondError(c, errorCode, errorMessage)
+	}
+
+	if err := database.BookLoanDB().DeleteBookCopy(id); err != nil {
+		if errors.Is(err, queries.ErrBookCopyOnLoan) {
+			// Return status 409 and error message.
+			return response.RespondError(c, fiber.StatusConflict, err.Error())
+		}
+		// Return status 404 and error message.
+		return response.RespondError(c, fiber.StatusNotFound, err.Error())
+	}
+
+	// Return status 204 no content.
+	return response.RespondSuccess(c, fiber.StatusNoContent, "")
+}
+
+// CheckoutBook godoc
+// @Description Check out a copy of a published book, due back after the loan period
+// @Description A copy set aside for a hold of the user is lent first, when all copies are out place a hold instead
+// @Description Require valid user token
+// @Summary Borrow a book
+// @Tags Lending
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param book_id path string true "Book ID"
+// @Success 201 {object} models.BookLoan
+// @Failure 400 {object} response.HTTPError
+// @Failure 401 {object} response.HTTPError
+// @Failure 404 {object} response.HTTPError
+// @Failure 409 {object} response.HTTPError
+// @Failure 500 {object} response.HTTPError
+// @Router /v1/book/{book_id}/loans [post]
+func CheckoutBook(c *fiber.Ctx) error {
+	// Catch book ID from URL.
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return response.RespondError(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	// Get claims from JWT.
+	claims, err := utils.ExtractTokenMetadata(c)
+	if err != nil {
+		// Return status 500 and JWT parse error.
+		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	if isError, errorCode, errorMessage := lendingBookCheck(id, claims.UserID); isError {
+		return response.RespondError(c, errorCode, errorMessage)
+	}
+
+	// Set initialized default data for loan:
+	now := time.Now()
+	loan := &models.BookLoan{
+		ID:       uuid.New(),
+		BookID:   id,
+		UserID:   claims.UserID,
+		LoanedAt: now,
+		DueAt:    now.Add(bookLoanPeriod()),
+	}
+
+	if err := database.BookLoanDB().CheckoutBook(loan); err != nil {
+		if errors.Is(err, queries.ErrNoCopyAvailable) {
+			// Return status 409 and error message.
+			return response.RespondError(c, fiber.StatusConflict, err.Error())
+		}
+		// Return status 500 and error message.
+		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	// Return status 201.
+	return response.RespondSuccess(c, fiber.StatusCreated, loan)
+}
+
+// GetLoans godoc
+// @Description Will display the books the current user has borrowed and not returned yet, soonest due first, and their holds
+// @Description Require valid user token
+// @Summary Get own loans and holds
+// @Tags Lending
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} models.UserLoans
+// @Failure 401 {object} response.HTTPError
+// @Failure 500 {object} response.HTTPError
+// @Router /v1/loans [get]
+func GetLoans(c *fiber.Ctx) error {
+	// Get claims from JWT.
+	claims, err := utils.ExtractTokenMetadata(c)
+	if err != nil {
+		// Return status 500 and JWT parse error.
+		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	db := database.BookLoanDB()
+	loans, err := db.GetUserLoans(claims.UserID)
+	if err != nil {
+		// Return status 500 and error message.
+		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+	}
+	holds, err := db.GetUserHolds(claims.UserID)
+	if err != nil {
+		// Return status 500 and error message.
+		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	// Return status 200 OK.
+	return response.RespondSuccess(c, fiber.StatusOK, &models.UserLoans{Loans: loans, Holds: holds})
+}
+
+// GetOverdueLoans godoc
+// @Description Will display the loans not returned by their due date, longest overdue first
+// @Description Require valid user token with `book:lending` credential
+// @Summary Get overdue loans
+// @Tags Lending
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param page query int false "Page number, starts from 1"
+// @Param limit query int false "Page size, up to 100"
+// @Success 200 {object} models.AllBookLoans
+// @Failure 400 {object} response.HTTPError
+// @Failure 401 {object} response.HTTPError
+// @Failure 403 {object} response.HTTPError
+// @Failure 500 {object} response.HTTPError
+// @Router /v1/loans/overdue [get]
+func GetOverdueLoans(c *fiber.Ctx) error {
+	// Get claims from JWT.
+	claims, err := utils.ExtractTokenMetadata(c)
+	if err != nil {
+		// Return status 500 and JWT parse error.
+		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	if isError, errorCode, errorMessage := claimCheck(claims, repository.BookLendingCredential); isError {
+		return response.RespondError(c, errorCode, errorMessage)
+	}
+
+	pagination, err := utils.ParsePagination(c)
+	if err != nil {
+		// Return status 400 and error message.
+		return response.RespondError(c, fiber.StatusBadRequest, err.Error())
+	}
+	if pagination.UseCursor {
+		return response.RespondError(c, fiber.StatusBadRequest, "cursor paging is not supported for loans")
+	}
+
+	loans, total, err := database.BookLoanDB().GetOverdueLoans(time.Now(), pagination)
+	if err != nil {
+		// Return status 500 and error message.
+		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	allLoans := &models.AllBookLoans{
+		Loans: loans,
+		Count: total,
+		Page:  pagination.Page,
+		Limit: pagination.Limit,
+		Links: utils.BuildPageLinks(c, pagination, total, false, nil, nil),
+	}
+
+	// Return status 200 OK.
+	return response.RespondSuccess(c, fiber.StatusOK, allLoans)
+}
+
+// ReturnLoan godoc
+// @Description Return a borrowed copy, it goes to the oldest hold if users are waiting
+// @Description Require valid user token of the borrower, or with `book:lending` credential
+// @Summary Return a book
+// @Tags Lending
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param loan_id path string true "Loan ID"
+// @Success 200 {object} models.BookLoan
+// @Failure 400 {object} response.HTTPError
+// @Failure 401 {object} response.HTTPError
+// @Failure 403 {object} response.HTTPError
+// @Failure 404 {object} response.HTTPError
+// @Failure 409 {object} response.HTTPError
+// @Failure 500 {object} response.HTTPError
+// @Router /v1/loan/{loan_id}/return [post]
+func ReturnLoan(c *fiber.Ctx) error {
+	loan, isError, errorCode, errorMessage := getLoanOfBorrower(c, true)
+	if isError {
+		return response.RespondError(c, errorCode, errorMessage)
+	}
+
+	returnedAt := time.Now()
+	loan.ReturnedAt = &returnedAt
+
+	if err := database.BookLoanDB().ReturnLoan(loan); err != nil {
+		if errors.Is(err, queries.ErrBookLoanReturned) {
+			// Return status 409 and error message.
+			return response.RespondError(c, fiber.StatusConflict, err.Error())
+		}
+		// Return status 500 and error message.
+		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	// Return status 200 OK.
+	return response.RespondSuccess(c, fiber.StatusOK, loan)
+}
+
+// RenewLoan godoc
+// @Description Move the due date of a loan by another loan period
+// @Description Overdue loans, loans renewed too often and books other users are waiting for can not be renewed
+// @Description Require valid user token of the borrower
+// @Summary Renew a loan
+// @Tags Lending
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param loan_id path string true "Loan ID"
+// @Success 200 {object} models.BookLoan
+// @Failure 400 {object} response.HTTPError
+// @Failure 401 {object} response.HTTPError
+// @Failure 404 {object} response.HTTPError
+// @Failure 409 {object} response.HTTPError
+// @Failure 500 {object} response.HTTPError
+// @Router /v1/loan/{loan_id}/renew [post]
+func RenewLoan(c *fiber.Ctx) error {
+	loan, isError, errorCode, errorMessage := getLoanOfBorrower(c, false)
+	if isError {
+		return response.RespondError(c, errorCode, errorMessage)
+	}
+
+	// Checking, if the loan may be renewed.
+	switch {
+	case loan.ReturnedAt != nil:
+		// Return status 409 and error message.
+		return response.RespondError(c, fiber.StatusConflict, queries.ErrBookLoanReturned.Error())
+	case time.Now().After(loan.DueAt):
+		// Return status 409 and error message.
+		return response.RespondError(c, fiber.StatusConflict, "overdue loans can not be renewed, return the book instead")
+	case loan.Renewals >= bookLoanMaxRenewals():
+		// Return status 409 and error message.
+		return response.RespondError(c, fiber.StatusConflict, "loan was renewed too often, return the book instead")
+	}
+
+	db := database.BookLoanDB()
+	holds, err := db.CountWaitingHolds(loan.BookID)
+	if err != nil {
+		// Return status 500 and error message.
+		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+	}
+	if holds > 0 {
+		// Return status 409 and error message.
+		return response.RespondError(c, fiber.StatusConflict, "other users are waiting for this book, return it instead")
+	}
+
+	loan.DueAt = loan.DueAt.Add(bookLoanPeriod())
+	loan.Renewals++
+
+	if err := db.RenewLoan(loan); err != nil {
+		if errors.Is(err, queries.ErrBookLoanReturned) {
+			// Return status 409 and error message.
+			return response.RespondError(c, fiber.StatusConflict, err.Error())
+		}
+		// Return status 500 and error message.
+		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	// Return status 200 OK.
+	return response.RespondSuccess(c, fiber.StatusOK, loan)
+}
+
+// PlaceBookHold godoc
+// @Description Queue for a published book while all its copies are out
+// @Description The next returned copy is set aside for the oldest hold, the user then checks it out as usual
+// @Description Require valid user token
+// @Summary Place a hold on a book
+// @Tags Lending
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param book_id path string true "Book ID"
+// @Success 201 {object} models.BookHold
+// @Failure 400 {object} response.HTTPError
+// @Failure 401 {object} response.HTTPError
+// @Failure 404 {object} response.HTTPError
+// @Failure 409 {object} response.HTTPError
+// @Failure 500 {object} response.HTTPError
+// @Router /v1/book/{book_id}/holds [post]
+func PlaceBookHold(c *fiber.Ctx) error {
+	// Catch book ID from URL.
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return response.RespondError(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	// Get claims from JWT.
+	claims, err := utils.ExtractTokenMetadata(c)
+	if err != nil {
+		// Return status 500 and JWT parse error.
+		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	if isError, errorCode, errorMessage := lendingBookCheck(id, claims.UserID); isError {
+		return response.RespondError(c, errorCode, errorMessage)
+	}
+
+	// Checking, if all copies of the book are out.
+	db := database.BookLoanDB()
+	copies, err := db.GetBookCopies(id)
+	if err != nil {
+		// Return status 500 and error message.
+		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+	}
+	if len(copies) == 0 {
+		// Return status 409 and error message.
+		return response.RespondError(c, fiber.StatusConflict, "the library has no copies of this book")
+	}
+	for _, bookCopy := range copies {
+		if bookCopy.Status == repository.BookCopyAvailable {
+			// Return status 409 and error message.
+			return response.RespondError(c, fiber.StatusConflict, "a copy of this book is available, check it out instead")
+		}
+	}
+
+	// Set initialized default data for hold:
+	hold := &models.BookHold{
+		ID:        uuid.New(),
+		CreatedAt: time.Now(),
+		BookID:    id,
+		UserID:    claims.UserID,
+	}
+
+	if err := db.CreateHold(hold); err != nil {
+		if errors.Is(err, queries.ErrBookHoldExists) {
+			// Return status 409 and error message.
+			return response.RespondError(c, fiber.StatusConflict, err.Error())
+		}
+		// Return status 500 and error message.
+		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	// Read the hold back with its place in the queue.
+	placedHold, err := db.GetHold(id, claims.UserID)
+	if err != nil {
+		// Return status 500 and error message.
+		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	// Return status 201.
+	return response.RespondSuccess(c, fiber.StatusCreated, placedHold)
+}
+
+// CancelBookHold godoc
+// @Description Leave the holds queue of a book, a copy set aside for the hold goes to the next user waiting
+// @Description Require valid user token
+// @Summary Cancel own hold on a book
+// @Tags Lending
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param book_id path string true "Book ID"
+// @Success 204
+// @Failure 400 {object} response.HTTPError
+// @Failure 401 {object} response.HTTPError
+// @Failure 404 {object} response.HTTPError
+// @Failure 500 {object} response.HTTPError
+// @Router /v1/book/{book_id}/hold [delete]
+func CancelBookHold(c *fiber.Ctx) error {
+	// Catch book ID from URL.
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return response.RespondError(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	// Get claims from JWT.
+	claims, err := utils.ExtractTokenMetadata(c)
+	if err != nil {
+		// Return status 500 and JWT parse error.
+		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	// Checking, if the user holds the book.
+	db := database.BookLoanDB()
+	hold, err := db.GetHold(id, claims.UserID)
+	if err != nil {
+		// Return status 404 and error message.
+		return response.RespondError(c, fiber.StatusNotFound, err.Error())
+	}
+
+	if err := db.DeleteHold(&hold); err != nil {
+		// Return status 500 and error message.
+		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	// Return status 204 no content.
+	return response.RespondSuccess(c, fiber.StatusNoContent, "")
+}
+
+// lendingBookCheck func for checking that a book exists, is published and is not already borrowed by given user.
+func lendingBookCheck(bookID, userID uuid.UUID) (bool, int, interface{}) {
+	// Checking, if book with given ID is exists.
+	foundedBook, err := database.BookDB().GetBookById(bookID)
+	if err != nil {
+		// Return status 404 and book not found error.
+		return true, fiber.StatusNotFound, "book with given ID not found"
+	}
+	if foundedBook.BookStatus != repository.BookStatusPublished {
+		// Return status 409 and error message.
+		return true, fiber.StatusConflict, "only published books can be borrowed"
+	}
+
+	if _, err := database.BookLoanDB().GetActiveLoan(bookID, userID); err == nil {
+		// Return status 409 and error message.
+		return true, fiber.StatusConflict, "you already borrowed this book"
+	}
+
+	return false, 0, ""
+}
+
+// getLoanOfBorrower func for getting the loan with the ID from URL, if the current user borrowed it.
+// With lending set, users with `book:lending` credential may act on loans of anyone.
+func getLoanOfBorrower(c *fiber.Ctx, lending bool) (*models.BookLoan, bool, int, interface{}) {
+	// Catch loan ID from URL.
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return nil, true, fiber.StatusBadRequest, err.Error()
+	}
+
+	// Get claims from JWT.
+	claims, err := utils.ExtractTokenMetadata(c)
+	if err != nil {
+		// Return status 500 and JWT parse error.
+		return nil, true, fiber.StatusInternalServerError, err.Error()
+	}
+
+	loan, err := database.BookLoanDB().GetLoan(id)
+	if err != nil {
+		// Return status 404 and loan not found error.
+		return nil, true, fiber.StatusNotFound, "loan with given ID not found"
+	}
+
+	if loan.UserID != claims.UserID {
+		if !lending {
+			// Return status 404 and loan not found error.
+			return nil, true, fiber.StatusNotFound, "loan with given ID not found"
+		}
+		if isError, errorCode, errorMessage := claimCheck(claims, repository.BookLendingCredential); isError {
+			return nil, true, errorCode, errorMessage
+		}
+	}
+
+	return &loan, false, fiber.StatusOK, nil
+}
+
+// bookLoanPeriod func for getting how long a loan or renewal lasts, from BOOK_LOAN_PERIOD_DAYS.
+func bookLoanPeriod() time.Duration {
+	days, _ := strconv.Atoi(os.Getenv("BOOK_LOAN_PERIOD_DAYS"))
+	if days <= 0 {
+		days = bookLoanDefaultPeriodDays
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// bookLoanMaxRenewals func for getting how often a loan may be renewed, from BOOK_LOAN_MAX_RENEWALS.
+func bookLoanMaxRenewals() int {
+	renewals, err := strconv.Atoi(os.Getenv("BOOK_LOAN_MAX_RENEWALS"))
+	if err != nil || renewals < 0 {
+		renewals = bookLoanDefaultMaxRenewals
+	}
+	return renewals
+}
@@ -0,0 +1,360 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/aryanicosa/go-fiber-rest-api/app/models"
+	"github.com/aryanicosa/go-fiber-rest-api/app/queries"
+	"github.com/aryanicosa/go-fiber-rest-api/pkg/repository"
+	"github.com/aryanicosa/go-fiber-rest-api/pkg/response"
+	"github.com/aryanicosa/go-fiber-rest-api/pkg/utils"
+	"github.com/aryanicosa/go-fiber-rest-api/platform/database"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// GetBookRevisions godoc
+// @Description Will display the revisions of a book, newest first, including trashed books
+// @Description The history of a book is deleted when it is purged from the trash
+// @Description Require valid user token of the owner or a collaborator
+// @Summary Get book revisions
+// @Tags Book
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param book_id path string true "Book ID"
+// @Param page query int false "Page number, starts from 1"
+// @Param limit query int false "Page size, up to 100"
+// @Success 200 {object} models.AllBookRevisions
+// @Failure 400 {object} response.HTTPError
+// @Failure 401 {object} response.HTTPError
+// @Failure 403 {object} response.HTTPError
+// @Failure 404 {object} response.HTTPError
+// @Failure 500 {object} response.HTTPError
+// @Router /v1/book/{book_id}/revisions [get]
+func GetBookRevisions(c *fiber.Ctx) error {
+	// Catch book ID from URL.
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return response.RespondError(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	pagination, err := utils.ParsePagination(c)
+	if err != nil {
+		// Return status 400 and error message.
+		return response.RespondError(c, fiber.StatusBadRequest, err.Error())
+	}
+	if pagination.UseCursor {
+		// Revisions are numbered, so offset paging is enough.
+		return response.RespondError(c, fiber.StatusBadRequest, "cursor paging is not supported for revisions")
+	}
+
+	// Only the owner and collaborators can read the history.
+	foundedBook, isError, errorCode, errorMessage := bookRevisionAccessCheck(c, id)
+	if isError {
+		return response.RespondError(c, errorCode, errorMessage)
+	}
+
+	// Get one page of revisions.
+	revisions, total, err := database.BookRevisionDB().GetBookRevisions(foundedBook.ID, pagination)
+	if err != nil {
+		// Return status 500 and error message.
+		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	allRevisions := &models.AllBookRevisions{
+		Revisions: revisions,
+		Count:     total,
+		Page:      pagination.Page,
+		Limit:     pagination.Limit,
+		Links:     utils.BuildPageLinks(c, pagination, total, false, nil, nil),
+	}
+
+	// Return status 200 OK.
+	return response.RespondSuccess(c, fiber.StatusOK, allRevisions)
+}
+
+// GetBookRevision godoc
+// @Description Will display one revision of a book with the full snapshot of the row
+// @Description Require valid user token of the owner or a collaborator
+// @Summary Get book revision
+// @Tags Book
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param book_id path string true "Book ID"
+// @Param rev path int true "Revision number"
+// @Success 200 {object} models.BookRevision
+// @Failure 400 {object} response.HTTPError
+// @Failure 401 {object} response.HTTPError
+// @Failure 403 {object} response.HTTPError
+// @Failure 404 {object} response.HTTPError
+// @Failure 500 {object} response.HTTPError
+// @Router /v1/book/{book_id}/revisions/{rev} [get]
+func GetBookRevision(c *fiber.Ctx) error {
+	// Catch book ID and revision from URL.
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return response.RespondError(c, fiber.StatusBadRequest, err.Error())
+	}
+	rev, err := strconv.Atoi(c.Params("rev"))
+	if err != nil || rev < 1 {
+		return response.RespondError(c, fiber.StatusBadRequest, "revision must be a positive number")
+	}
+
+	// Only the owner and collaborators can read the history.
+	foundedBook, isError, errorCode, errorMessage := bookRevisionAccessCheck(c, id)
+	if isError {
+		return response.RespondError(c, errorCode, errorMessage)
+	}
+
+	revision, err := database.BookRevisionDB().GetBookRevision(foundedBook.ID, rev)
+	if err != nil {
+		// Return status 404 and error message.
+		return response.RespondError(c, fiber.StatusNotFound, err.Error())
+	}
+
+	// Return status 200 OK.
+	return response.RespondSuccess(c, fiber.StatusOK, revision)
+}
+
+// GetBookRevisionDiff godoc
+// @Description Will display the JSON Patch operations turning revision `from` into revision `rev`
+// @Description Without `from` the revision is compared with the one before it, revision 1 with an empty book
+// @Description Require valid user token of the owner or a collaborator
+// @Summary Diff book revisions
+// @Tags Book
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param book_id path string true "Book ID"
+// @Param rev path int true "Revision number"
+// @Param from query int false "Revision number to compare with"
+// @Success 200 {object} models.BookRevisionDiff
+// @Failure 400 {object} response.HTTPError
+// @Failure 401 {object} response.HTTPError
+// @Failure 403 {object} response.HTTPError
+// @Failure 404 {object} response.HTTPError
+// @Failure 500 {object} response.HTTPError
+// @Router /v1/book/{book_id}/revisions/{rev}/diff [get]
+func GetBookRevisionDiff(c *fiber.Ctx) error {
+	// Catch book ID and revisions from URL.
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return response.RespondError(c, fiber.StatusBadRequest, err.Error())
+	}
+	rev, err := strconv.Atoi(c.Params("rev"))
+	if err != nil || rev < 1 {
+		return response.RespondError(c, fiber.StatusBadRequest, "revision must be a positive number")
+	}
+	from := rev - 1
+	if c.Query("from") != "" {
+		from, err = strconv.Atoi(c.Query("from"))
+		if err != nil || from < 1 {
+			return response.RespondError(c, fiber.StatusBadRequest, "from must be a positive number")
+		}
+	}
+
+	// Only the owner and collaborators can read the history.
+	foundedBook, isError, errorCode, errorMessage := bookRevisionAccessCheck(c, id)
+	if isError {
+		return response.RespondError(c, errorCode, errorMessage)
+	}
+
+	db := database.BookRevisionDB()
+	toRevision, err := db.GetBookRevision(foundedBook.ID, rev)
+	if err != nil {
+		// Return status 404 and error message.
+		return response.RespondError(c, fiber.StatusNotFound, err.Error())
+	}
+	target, err := json.Marshal(toRevision.Snapshot)
+	if err != nil {
+		// Return status 500 and error message.
+		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	// Revision 0 is the book before it was created.
+	source := []byte("{}")
+	if from > 0 {
+		fromRevision, err := db.GetBookRevision(foundedBook.ID, from)
+		if err != nil {
+			// Return status 404 and error message.
+			return response.RespondError(c, fiber.StatusNotFound, err.Error())
+		}
+		if source, err = json.Marshal(fromRevision.Snapshot); err != nil {
+			// Return status 500 and error message.
+			return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+		}
+	}
+
+	operations, err := utils.DiffJSON(source, target)
+	if err != nil {
+		// Return status 500 and error message.
+		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+	}
+	patch, err := json.Marshal(operations)
+	if err != nil {
+		// Return status 500 and error message.
+		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	// Return status 200 OK.
+	return response.RespondSuccess(c, fiber.StatusOK, &models.BookRevisionDiff{From: from, To: rev, Operations: patch})
+}
+
+// RevertBook godoc
+// @Description Set title, author, ISBN and attributes of a book back to one of its revisions, recorded as a new revision, the status is kept
+// @Description Require valid user token with `book:update` credential
+// @Summary Revert a book to a revision
+// @Tags Book
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param book_id path string true "Book ID"
+// @Param rev path int true "Revision number"
+// @Param reason query string false "Required when reverting a book of another user with `book:update:any`"
+// @Param If-Match header string false "ETag of the reverted version, answered with 412 if outdated"
+// @Success 200 {object} models.Book
+// @Failure 400 {object} response.HTTPError
+// @Failure 401 {object} response.HTTPError
+// @Failure 403 {object} response.HTTPError
+// @Failure 404 {object} response.HTTPError
+// @Failure 409 {object} response.HTTPError
+// @Failure 412 {object} response.HTTPError
+// @Failure 500 {object} response.HTTPError
+// @Router /v1/book/{book_id}/revisions/{rev}/revert [post]
+func RevertBook(c *fiber.Ctx) error {
+	// Catch book ID and revision from URL.
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return response.RespondError(c, fiber.StatusBadRequest, err.Error())
+	}
+	rev, err := strconv.Atoi(c.Params("rev"))
+	if err != nil || rev < 1 {
+		return response.RespondError(c, fiber.StatusBadRequest, "revision must be a positive number")
+	}
+
+	// Get claims from JWT.
+	claims, err := utils.ExtractTokenMetadata(c)
+	if err != nil {
+		// Return status 500 and JWT parse error.
+		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	if isError, errorCode, errorMessage := claimCheck(claims, repository.BookUpdateCredential); isError {
+		return response.RespondError(c, errorCode, errorMessage)
+	}
+
+	// Checking, if book with given ID is exists, trashed books must be restored first.
+	db := database.BookDB()
+	foundedBook, err := db.GetBookById(id)
+	if err != nil {
+		// Return status 404 and book not found error.
+		return response.RespondError(c, fiber.StatusNotFound, "book with given ID not found")
+	}
+
+	revision, err := database.BookRevisionDB().GetBookRevision(foundedBook.ID, rev)
+	if err != nil {
+		// Return status 404 and error message.
+		return response.RespondError(c, fiber.StatusNotFound, err.Error())
+	}
+
+	// Check, if client reverts the latest version.
+	if isError, errorCode, errorMessage := bookPreconditionCheck(c, &foundedBook); isError {
+		return response.RespondError(c, errorCode, errorMessage)
+	}
+
+	// Only the owner and editors can update the book, others need an audited override.
+	auditLog, isError, errorCode, errorMessage := bookPolicyCheck(c, &foundedBook, claims, bookActionUpdate)
+	if isError {
+		return response.RespondError(c, errorCode, errorMessage)
+	}
+
+	// Take the editable fields from the snapshot, the rest stays as is, including the status.
+	book := foundedBook
+	book.Title = revision.Snapshot.Title
+	book.Author = revision.Snapshot.Author
+	book.ISBN = revision.Snapshot.ISBN
+	book.BookAttrs = revision.Snapshot.BookAttrs
+
+	// The ISBN may have been given to another book since.
+	if isError, errorCode, errorMessage := bookISBNCheck(database.BookDB(), &book, foundedBook.ID); isError {
+		return response.RespondError(c, errorCode, errorMessage)
+	}
+
+	columns := bookChangedColumns(&foundedBook, &book)
+	if len(columns) == 0 {
+		// Return status 200 OK, the book already matches the revision.
+		c.Set(fiber.HeaderETag, utils.VersionETag(book.Version))
+		return response.RespondSuccess(c, fiber.StatusOK, book)
+	}
+
+	book.UpdatedAt = time.Now()
+	columns["updated_at"] = book.UpdatedAt
+
+	// Write the changed columns and record the override with them.
+	err = database.Transaction(func(tx *gorm.DB) error {
+		version, err := (&queries.BookQueries{DB: tx}).PatchBook(foundedBook.ID, foundedBook.Version, columns)
+		if err != nil {
+			return err
+		}
+		book.Version = version
+		if err := recreditBookAuthor(tx, &foundedBook, book.Author); err != nil {
+			return err
+		}
+		if err := recordBookRevision(tx, book.ID, claims.UserID, repository.BookRevertAction); err != nil {
+			return err
+		}
+		return createBookAuditLog(tx, auditLog)
+	})
+	if err != nil {
+		if errors.Is(err, queries.ErrBookVersionMismatch) {
+			// Return status 412 and error message.
+			return response.RespondError(c, fiber.StatusPreconditionFailed, err.Error())
+		}
+		if errors.Is(err, queries.ErrBookISBNExists) {
+			// Return status 409 and error message.
+			return response.RespondError(c, fiber.StatusConflict, err.Error())
+		}
+		// Return status 500 and error message.
+		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	// Return status 200 OK.
+	c.Set(fiber.HeaderETag, utils.VersionETag(book.Version))
+	return response.RespondSuccess(c, fiber.StatusOK, book)
+}
+
+// recordBookRevision func for snapshotting a book with given transaction, after a change made by given user.
+func recordBookRevision(tx *gorm.DB, bookID, userID uuid.UUID, action string) error {
+	return (&queries.BookRevisionQueries{DB: tx}).CreateBookRevision(bookID, userID, action)
+}
+
+// bookRevisionAccessCheck func for finding a book, trashed or not, and checking the user may view its history.
+func bookRevisionAccessCheck(c *fiber.Ctx, id uuid.UUID) (*models.Book, bool, int, interface{}) {
+	// Get claims from JWT.
+	claims, err := utils.ExtractTokenMetadata(c)
+	if err != nil {
+		// Return status 500 and JWT parse error.
+		return nil, true, fiber.StatusInternalServerError, err.Error()
+	}
+
+	db := database.BookDB()
+	foundedBook, err := db.GetBookById(id)
+	if err != nil {
+		if foundedBook, err = db.GetTrashedBookById(id); err != nil {
+			// Return status 404 and book not found error.
+			return nil, true, fiber.StatusNotFound, "book with given ID not found"
+		}
+	}
+
+	if isError, errorCode, errorMessage := bookAccessCheck(&foundedBook, claims.UserID, bookActionView); isError {
+		return nil, isError, errorCode, errorMessage
+	}
+
+	return &foundedBook, false, 0, ""
+}
@@ -0,0 +1,200 @@
+package controllers
+
+import (
+	"errors"
+	"time"
+
+	"github.com/aryanicosa/go-fiber-rest-api/app/models"
+	"github.com/aryanicosa/go-fiber-rest-api/app/queries"
+	"github.com/aryanicosa/go-fiber-rest-api/pkg/repository"
+	"github.com/aryanicosa/go-fiber-rest-api/pkg/response"
+	"github.com/aryanicosa/go-fiber-rest-api/pkg/utils"
+	"github.com/aryanicosa/go-fiber-rest-api/platform/database"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// TransitionBook godoc
+// @Description Move a book through the workflow `draft` → `review` → `published` → `archived`
+// @Description Owners and editors submit, withdraw, archive and rework with `book:update`, others need `book:update:any` and a `reason`
+// @Description Publishing and unpublishing need the `book:publish` credential, on books of any user
+// @Summary Change the status of a book
+// @Tags Book
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param book_id path string true "Book ID"
+// @Param models.BookTransition body models.BookTransition true "Target status"
+// @Param reason query string false "Required when updating a book of another user with `book:update:any`"
+// @Param If-Match header string false "ETag of the edited version, answered with 412 if outdated"
+// @Success 200 {object} models.Book
+// @Failure 400 {object} response.HTTPError
+// @Failure 401 {object} response.HTTPError
+// @Failure 403 {object} response.HTTPError
+// @Failure 404 {object} response.HTTPError
+// @Failure 409 {object} response.HTTPError
+// @Failure 412 {object} response.HTTPError
+// @Failure 500 {object} response.HTTPError
+// @Router /v1/book/{book_id}/transition [post]
+func TransitionBook(c *fiber.Ctx) error {
+	// Catch book ID from URL.
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return response.RespondError(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	// Get claims from JWT.
+	claims, err := utils.ExtractTokenMetadata(c)
+	if err != nil {
+		// Return status 500 and JWT parse error.
+		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	// Create new BookTransition struct
+	transition := &models.BookTransition{}
+
+	// Check, if received JSON data is valid.
+	if err := c.BodyParser(transition); err != nil {
+		// Return status 400 and error message.
+		return response.RespondError(c, fiber.StatusBadRequest, "unable to parse request body")
+	}
+
+	// Validate transition fields.
+	validate := utils.NewValidator()
+	if err := validate.Struct(transition); err != nil {
+		// Return, if some fields are not valid.
+		return response.RespondError(c, fiber.StatusBadRequest, utils.ValidatorErrors(err))
+	}
+
+	// Checking, if book with given ID is exists.
+	db := database.BookDB()
+	foundedBook, err := db.GetBookById(id)
+	if err != nil {
+		// Return status 404 and book not found error.
+		return response.RespondError(c, fiber.StatusNotFound, "book with given ID not found")
+	}
+
+	// Checking, if the workflow allows the move from the current status.
+	credentialNeed, ok := repository.BookStatusTransitions[foundedBook.BookStatus][transition.BookStatus]
+	if !ok {
+		// Return status 409 and error message.
+		return response.RespondError(c, fiber.StatusConflict,
+			"book can not move from "+foundedBook.BookStatus+" to "+transition.BookStatus)
+	}
+
+	if isError, errorCode, errorMessage := claimCheck(claims, credentialNeed); isError {
+		return response.RespondError(c, errorCode, errorMessage)
+	}
+
+	// Check, if client moves the latest version.
+	if isError, errorCode, errorMessage := bookPreconditionCheck(c, &foundedBook); isError {
+		return response.RespondError(c, errorCode, errorMessage)
+	}
+
+	// Publishers act on any book, other moves are limited to the owner and editors.
+	var auditLog *models.BookAuditLog
+	if credentialNeed != repository.BookPublishCredential {
+		var isError bool
+		var errorCode int
+		var errorMessage interface{}
+		auditLog, isError, errorCode, errorMessage = bookPolicyCheck(c, &foundedBook, claims, bookActionUpdate)
+		if isError {
+			return response.RespondError(c, errorCode, errorMessage)
+		}
+	}
+
+	book := foundedBook
+	book.BookStatus = transition.BookStatus
+	book.UpdatedAt = time.Now()
+	err = database.Transaction(func(tx *gorm.DB) error {
+		version, err := (&queries.BookQueries{DB: tx}).PatchBook(foundedBook.ID, foundedBook.Version, map[string]interface{}{
+			"book_status": book.BookStatus,
+			"updated_at":  book.UpdatedAt,
+		})
+		if err != nil {
+			return err
+		}
+		book.Version = version
+		if err := recordBookRevision(tx, book.ID, claims.UserID, repository.BookTransitionAction); err != nil {
+			return err
+		}
+		return createBookAuditLog(tx, auditLog)
+	})
+	if err != nil {
+		if errors.Is(err, queries.ErrBookVersionMismatch) {
+			// Return status 412 and error message.
+			return response.RespondError(c, fiber.StatusPreconditionFailed, err.Error())
+		}
+		// Return status 500 and error message.
+		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	// Return status 200 OK.
+	c.Set(fiber.HeaderETag, utils.VersionETag(book.Version))
+	return response.RespondSuccess(c, fiber.StatusOK, book)
+}
+
+// GetWorkflowBooks godoc
+// @Description Will display books of the current user in any status, or of everyone with `book:publish` credential
+// @Description Use `book_status=review` for the queue of books waiting to be published
+// @Description Require valid user token
+// @Summary Get books in the workflow
+// @Tags Book
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param page query int false "Page number, starts from 1"
+// @Param limit query int false "Page size, up to 100"
+// @Param book_status query string false "Book status" Enums(draft, review, published, archived)
+// @Param user_id query string false "Owner user ID, only with `book:publish` credential"
+// @Param sort query string false "Comma separated columns, prefix with `-` for descending, e.g. `-created_at,title`"
+// @Success 200 {object} models.AllBooks
+// @Failure 400 {object} response.HTTPError
+// @Failure 401 {object} response.HTTPError
+// @Failure 403 {object} response.HTTPError
+// @Failure 500 {object} response.HTTPError
+// @Router /v1/books/workflow [get]
+func GetWorkflowBooks(c *fiber.Ctx) error {
+	// Get claims from JWT.
+	claims, err := utils.ExtractTokenMetadata(c)
+	if err != nil {
+		// Return status 500 and JWT parse error.
+		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	// Publishers see every book, authors only their own.
+	credentialNeed := repository.BookCreateCredential
+	if claims.Credentials[repository.BookPublishCredential] {
+		credentialNeed = repository.BookPublishCredential
+	}
+
+	if isError, errorCode, errorMessage := claimCheck(claims, credentialNeed); isError {
+		return response.RespondError(c, errorCode, errorMessage)
+	}
+
+	// Read filters, sort and paging from query params.
+	filter, err := parseBookFilter(c)
+	if err != nil {
+		// Return status 400 and error message.
+		return response.RespondError(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	if credentialNeed != repository.BookPublishCredential {
+		if filter.UserID != nil && *filter.UserID != claims.UserID {
+			// Return status 403 and permission denied error message.
+			return response.RespondError(c, fiber.StatusForbidden, "permission denied, credential not eligible")
+		}
+		filter.UserID = &claims.UserID
+	}
+
+	// Get one page of books.
+	page, err := database.BookDB().GetBooks(filter)
+	if err != nil {
+		// Return status 500 and error message.
+		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	// Return status 200 OK.
+	return response.RespondSuccess(c, fiber.StatusOK, newAllBooks(c, &filter.Pagination, page))
+}
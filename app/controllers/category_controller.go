@@ -0,0 +1,435 @@
+package controllers
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/aryanicosa/go-fiber-rest-api/app/models"
+	"github.com/aryanicosa/go-fiber-rest-api/app/queries"
+	"github.com/aryanicosa/go-fiber-rest-api/pkg/repository"
+	"github.com/aryanicosa/go-fiber-rest-api/pkg/response"
+	"github.com/aryanicosa/go-fiber-rest-api/pkg/utils"
+	"github.com/aryanicosa/go-fiber-rest-api/platform/database"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// GetCategories godoc
+// @Description Will display the categories tree, siblings sorted by name
+// @Description Require Basic Auth
+// @Summary Get all categories
+// @Tags Category
+// @Accept json
+// @Produce json
+// @Security BasicAuth
+// @Success 200 {array} models.CategoryNode
+// @Failure 500 {object} response.HTTPError
+// @Router /v1/categories [get]
+func GetCategories(c *fiber.Ctx) error {
+	// Get all categories.
+	categories, err := database.CategoryDB().GetCategories()
+	if err != nil {
+		// Return status 500 and error message.
+		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	// Return status 200 OK.
+	return response.RespondSuccess(c, fiber.StatusOK, buildCategoryTree(categories))
+}
+
+// CreateCategory godoc
+// @Description The slug is made from the name when left empty, `parent_id` nests it under another category
+// @Description Require valid user token with `book:taxonomy` credential
+// @Summary Create new category
+// @Tags Category
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param models.Category body models.Category true "Category data"
+// @Success 201 {object} models.Category
+// @Failure 400 {object} response.HTTPError
+// @Failure 401 {object} response.HTTPError
+// @Failure 403 {object} response.HTTPError
+// @Failure 409 {object} response.HTTPError
+// @Failure 500 {object} response.HTTPError
+// @Router /v1/category [post]
+func CreateCategory(c *fiber.Ctx) error {
+	// Get claims from JWT.
+	claims, err := utils.ExtractTokenMetadata(c)
+	if err != nil {
+		// Return status 500 and JWT parse error.
+		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	if isError, errorCode, errorMessage := claimCheck(claims, repository.BookTaxonomyCredential); isError {
+		return response.RespondError(c, errorCode, errorMessage)
+	}
+
+	// Create new Category struct
+	category := &models.Category{}
+
+	// Check, if received JSON data is valid.
+	if err := c.BodyParser(category); err != nil {
+		// Return status 400 and error message.
+		return response.RespondError(c, fiber.StatusBadRequest, "unable to parse request body")
+	}
+
+	// Set initialized default data for category:
+	category.ID = uuid.New()
+	category.CreatedAt = time.Now()
+	category.UpdatedAt = category.CreatedAt
+
+	if isError, errorCode, errorMessage := categoryValidationCheck(category); isError {
+		return response.RespondError(c, errorCode, errorMessage)
+	}
+
+	if err := database.CategoryDB().CreateCategory(category); err != nil {
+		// Return status 500 and error message.
+		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	// Return status 201.
+	return response.RespondSuccess(c, fiber.StatusCreated, category)
+}
+
+// UpdateCategory godoc
+// @Description Rename a category or move it under another parent, a category can not be moved under its own subcategories
+// @Description Require valid user token with `book:taxonomy` credential
+// @Summary Update category
+// @Tags Category
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param category_id path string true "Category ID"
+// @Param models.Category body models.Category true "Category data"
+// @Success 200 {object} models.Category
+// @Failure 400 {object} response.HTTPError
+// @Failure 401 {object} response.HTTPError
+// @Failure 403 {object} response.HTTPError
+// @Failure 404 {object} response.HTTPError
+// @Failure 409 {object} response.HTTPError
+// @Failure 500 {object} response.HTTPError
+// @Router /v1/category/{category_id} [put]
+func UpdateCategory(c *fiber.Ctx) error {
+	// Catch category ID from URL.
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return response.RespondError(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	// Get claims from JWT.
+	claims, err := utils.ExtractTokenMetadata(c)
+	if err != nil {
+		// Return status 500 and JWT parse error.
+		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	if isError, errorCode, errorMessage := claimCheck(claims, repository.BookTaxonomyCredential); isError {
+		return response.RespondError(c, errorCode, errorMessage)
+	}
+
+	// Checking, if category with given ID is exists.
+	db := database.CategoryDB()
+	foundedCategory, err := db.GetCategory(id)
+	if err != nil {
+		// Return status 404 and category not found error.
+		return response.RespondError(c, fiber.StatusNotFound, "category with given ID not found")
+	}
+
+	// Create new Category struct
+	category := &models.Category{}
+
+	// Check, if received JSON data is valid.
+	if err := c.BodyParser(category); err != nil {
+		// Return status 400 and error message.
+		return response.RespondError(c, fiber.StatusBadRequest, "unable to parse request body")
+	}
+
+	// Keep data that can not be changed.
+	category.ID = foundedCategory.ID
+	category.CreatedAt = foundedCategory.CreatedAt
+	category.UpdatedAt = time.Now()
+
+	if isError, errorCode, errorMessage := categoryValidationCheck(category); isError {
+		return response.RespondError(c, errorCode, errorMessage)
+	}
+
+	// A category can not become its own ancestor.
+	if category.ParentID != nil {
+		isCycle, err := db.IsCategoryInSubtree(*category.ParentID, &foundedCategory)
+		if err != nil {
+			// Return status 500 and error message.
+			return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+		}
+		if isCycle {
+			// Return status 400 and error message.
+			return response.RespondError(c, fiber.StatusBadRequest, "category can not be moved under itself or its subcategories")
+		}
+	}
+
+	if err := db.UpdateCategory(category); err != nil {
+		// Return status 500 and error message.
+		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	// Return status 200 OK.
+	return response.RespondSuccess(c, fiber.StatusOK, category)
+}
+
+// DeleteCategory godoc
+// @Description Books keep existing but lose the category, categories with subcategories can not be deleted
+// @Description Require valid user token with `book:taxonomy` credential
+// @Summary Delete a category
+// @Tags Category
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param category_id path string true "Category ID"
+// @Success 204
+// @Failure 400 {object} response.HTTPError
+// @Failure 401 {object} response.HTTPError
+// @Failure 403 {object} response.HTTPError
+// @Failure 404 {object} response.HTTPError
+// @Failure 409 {object} response.HTTPError
+// @Router /v1/category/{category_id} [delete]
+func DeleteCategory(c *fiber.Ctx) error {
+	// Catch category ID from URL.
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return response.RespondError(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	// Get claims from JWT.
+	claims, err := utils.ExtractTokenMetadata(c)
+	if err != nil {
+		// Return status 500 and JWT parse error.
+		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	if isError, errorCode, errorMessage := claimCheck(claims, repository.BookTaxonomyCredential); isError {
+		return response.RespondError(c, errorCode, errorMessage)
+	}
+
+	if err := database.CategoryDB().DeleteCategory(id); err != nil {
+		if errors.Is(err, queries.ErrCategoryHasChildren) {
+			// Return status 409 and error message.
+			return response.RespondError(c, fiber.StatusConflict, err.Error())
+		}
+		// Return status 404 and error message.
+		return response.RespondError(c, fiber.StatusNotFound, err.Error())
+	}
+
+	// Return status 204 no content.
+	return response.RespondSuccess(c, fiber.StatusNoContent, "")
+}
+
+// GetBookCategories godoc
+// @Description Will display categories of a book, sorted by name
+// @Description Require Basic Auth
+// @Description Unpublished books are only shown to the owner, collaborators and users with `book:publish` credential, sending their token instead
+// @Summary Get categories of a book
+// @Tags Category
+// @Accept json
+// @Produce json
+// @Security BasicAuth
+// @Security ApiKeyAuth
+// @Param book_id path string true "Book ID"
+// @Success 200 {array} models.Category
+// @Failure 400 {object} response.HTTPError
+// @Failure 404 {object} response.HTTPError
+// @Failure 500 {object} response.HTTPError
+// @Router /v1/book/{book_id}/categories [get]
+func GetBookCategories(c *fiber.Ctx) error {
+	// Catch book ID from URL.
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return response.RespondError(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	// Checking, if book with given ID is exists.
+	foundedBook, err := database.BookDB().GetBookById(id)
+	if err != nil {
+		// Return status 404 and book not found error.
+		return response.RespondError(c, fiber.StatusNotFound, "book with given ID not found")
+	}
+
+	// Checking, if caller may see the book.
+	if isError, errorCode, errorMessage := bookVisibleCheck(c, &foundedBook); isError {
+		return response.RespondError(c, errorCode, errorMessage)
+	}
+
+	categories, err := database.CategoryDB().GetBookCategories(foundedBook.ID)
+	if err != nil {
+		// Return status 500 and error message.
+		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	// Return status 200 OK.
+	return response.RespondSuccess(c, fiber.StatusOK, categories)
+}
+
+// SetBookCategories godoc
+// @Description Replace all categories of a book with the given existing categories
+// @Description Require valid user token with `book:update` credential
+// @Summary Set categories of a book
+// @Tags Category
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param book_id path string true "Book ID"
+// @Param models.BookCategories body models.BookCategories true "Category slugs"
+// @Param reason query string false "Required when updating a book of another user with `book:update:any`"
+// @Success 200 {array} models.Category
+// @Failure 400 {object} response.HTTPError
+// @Failure 401 {object} response.HTTPError
+// @Failure 403 {object} response.HTTPError
+// @Failure 404 {object} response.HTTPError
+// @Failure 500 {object} response.HTTPError
+// @Router /v1/book/{book_id}/categories [put]
+func SetBookCategories(c *fiber.Ctx) error {
+	// Catch book ID from URL.
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return response.RespondError(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	// Get claims from JWT.
+	claims, err := utils.ExtractTokenMetadata(c)
+	if err != nil {
+		// Return status 500 and JWT parse error.
+		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	if isError, errorCode, errorMessage := claimCheck(claims, repository.BookUpdateCredential); isError {
+		return response.RespondError(c, errorCode, errorMessage)
+	}
+
+	// Create new BookCategories struct
+	bookCategories := &models.BookCategories{}
+
+	// Check, if received JSON data is valid.
+	if err := c.BodyParser(bookCategories); err != nil {
+		// Return status 400 and error message.
+		return response.RespondError(c, fiber.StatusBadRequest, "unable to parse request body")
+	}
+
+	// Validate categories fields.
+	validate := utils.NewValidator()
+	if err := validate.Struct(bookCategories); err != nil {
+		// Return, if some fields are not valid.
+		return response.RespondError(c, fiber.StatusBadRequest, utils.ValidatorErrors(err))
+	}
+
+	// Checking, if book with given ID is exists.
+	foundedBook, err := database.BookDB().GetBookById(id)
+	if err != nil {
+		// Return status 404 and book not found error.
+		return response.RespondError(c, fiber.StatusNotFound, "book with given ID not found")
+	}
+
+	// Only the owner and editors can update the book, others need an audited override.
+	auditLog, isError, errorCode, errorMessage := bookPolicyCheck(c, &foundedBook, claims, bookActionUpdate)
+	if isError {
+		return response.RespondError(c, errorCode, errorMessage)
+	}
+
+	// Checking, if all categories exist.
+	db := database.CategoryDB()
+	categories, err := db.GetCategoriesBySlugs(bookCategories.Categories)
+	if err != nil {
+		// Return status 500 and error message.
+		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+	}
+	slugs := make([]string, 0, len(categories))
+	categoryIDs := make([]uuid.UUID, 0, len(categories))
+	for _, category := range categories {
+		slugs = append(slugs, category.Slug)
+		categoryIDs = append(categoryIDs, category.ID)
+	}
+	if missing := missingSlugs(bookCategories.Categories, slugs); len(missing) > 0 {
+		// Return status 400 and error message.
+		return response.RespondError(c, fiber.StatusBadRequest, "categories not found: "+strings.Join(missing, ", "))
+	}
+
+	// Replace the categories of the book and record the override with them.
+	err = database.Transaction(func(tx *gorm.DB) error {
+		if err := (&queries.CategoryQueries{DB: tx}).SetBookCategories(foundedBook.ID, categoryIDs); err != nil {
+			return err
+		}
+		return createBookAuditLog(tx, auditLog)
+	})
+	if err != nil {
+		// Return status 500 and error message.
+		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	// Return status 200 OK.
+	return response.RespondSuccess(c, fiber.StatusOK, categories)
+}
+
+// categoryValidationCheck func for validating a category before saving it,
+// its slug must be free and its parent must exist.
+func categoryValidationCheck(category *models.Category) (bool, int, interface{}) {
+	if category.Slug == "" {
+		category.Slug = utils.Slugify(category.Name)
+	}
+
+	// Validate category fields.
+	validate := utils.NewValidator()
+	if err := validate.Struct(category); err != nil {
+		// Return, if some fields are not valid.
+		return true, fiber.StatusBadRequest, utils.ValidatorErrors(err)
+	}
+	if category.Slug != utils.Slugify(category.Slug) {
+		// Return status 400 and error message.
+		return true, fiber.StatusBadRequest, "slug must be lower case letters and digits separated by dashes"
+	}
+
+	db := database.CategoryDB()
+	if category.ParentID != nil {
+		if *category.ParentID == category.ID {
+			// Return status 400 and error message.
+			return true, fiber.StatusBadRequest, "category can not be moved under itself or its subcategories"
+		}
+		if _, err := db.GetCategory(*category.ParentID); err != nil {
+			// Return status 400 and error message.
+			return true, fiber.StatusBadRequest, "parent category not found"
+		}
+	}
+
+	// Checking, if the slug is taken by another category.
+	existing, err := db.GetCategoriesBySlugs([]string{category.Slug})
+	if err != nil {
+		// Return status 500 and error message.
+		return true, fiber.StatusInternalServerError, err.Error()
+	}
+	if len(existing) > 0 && existing[0].ID != category.ID {
+		// Return status 409 and error message.
+		return true, fiber.StatusConflict, "category with given slug already exists"
+	}
+
+	return false, fiber.StatusOK, nil
+}
+
+// buildCategoryTree func for nesting categories under their parents, keeping their order.
+func buildCategoryTree(categories []models.Category) []*models.CategoryNode {
+	nodes := make(map[uuid.UUID]*models.CategoryNode, len(categories))
+	for _, category := range categories {
+		nodes[category.ID] = &models.CategoryNode{Category: category, Children: []*models.CategoryNode{}}
+	}
+
+	roots := []*models.CategoryNode{}
+	for _, category := range categories {
+		node := nodes[category.ID]
+		if category.ParentID == nil {
+			roots = append(roots, node)
+			continue
+		}
+		if parent, ok := nodes[*category.ParentID]; ok {
+			parent.Children = append(parent.Children, node)
+		}
+	}
+	return roots
+}
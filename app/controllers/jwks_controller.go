@@ -0,0 +1,24 @@
+package controllers
+
+import (
+	"github.com/aryanicosa/go-fiber-rest-api/pkg/response"
+	"github.com/aryanicosa/go-fiber-rest-api/pkg/utils"
+	"github.com/gofiber/fiber/v2"
+)
+
+// GetJWKS godoc
+// @Summary 	Get public keys of access tokens
+// @Description Public keys to verify access tokens with, picked by the kid header of a token
+// @Description Keys being rotated out stay in the set until the tokens they signed have expired
+// @Accept 		json
+// @Produce 	json
+// @Tags 						Auth
+// @Success 200 {object} utils.JSONWebKeySet
+// @Router /.well-known/jwks.json [get]
+func GetJWKS(c *fiber.Ctx) error {
+	// Let other services cache the keys for a while, a new key is published before it signs.
+	c.Set(fiber.HeaderCacheControl, "public, max-age=300")
+
+	// Return status 200 OK.
+	return response.RespondSuccess(c, fiber.StatusOK, utils.SigningKeySet())
+}
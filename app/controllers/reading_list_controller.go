@@ -0,0 +1,592 @@
+package controllers
+
+import (
+	"errors"
+	"time"
+
+	"github.com/aryanicosa/go-fiber-rest-api/app/models"
+	"github.com/aryanicosa/go-fiber-rest-api/app/queries"
+	"github.com/aryanicosa/go-fiber-rest-api/pkg/repository"
+	"github.com/aryanicosa/go-fiber-rest-api/pkg/response"
+	"github.com/aryanicosa/go-fiber-rest-api/pkg/utils"
+	"github.com/aryanicosa/go-fiber-rest-api/platform/database"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// readingListShareTokenLength is the length of the random tokens of shared reading list links.
+const readingListShareTokenLength = 32
+
+// GetReadingLists godoc
+// @Description Will display the reading lists of the current user, the built-in `want_to_read`, `reading` and `finished` lists first
+// @Description Require valid user token
+// @Summary Get own reading lists
+// @Tags Reading list
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {array} models.ReadingList
+// @Failure 401 {object} response.HTTPError
+// @Failure 500 {object} response.HTTPError
+// @Router /v1/reading-lists [get]
+func GetReadingLists(c *fiber.Ctx) error {
+	// Get claims from JWT.
+	claims, err := utils.ExtractTokenMetadata(c)
+	if err != nil {
+		// Return status 500 and JWT parse error.
+		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	// Every user has the built-in lists, they are created on first use.
+	db := database.ReadingListDB()
+	if err := db.CreateDefaultReadingLists(claims.UserID); err != nil {
+		// Return status 500 and error message.
+		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	lists, err := db.GetReadingLists(claims.UserID)
+	if err != nil {
+		// Return status 500 and error message.
+		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	// Return status 200 OK.
+	return response.RespondSuccess(c, fiber.StatusOK, lists)
+}
+
+// CreateReadingList godoc
+// @Description Create a custom named reading list, private unless `is_public` is set
+// @Description A public list gets a `share_token`, anyone can read the list at /v1/reading-lists/shared/{token}
+// @Description Require valid user token
+// @Summary Create reading list
+// @Tags Reading list
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param models.SaveReadingList body models.SaveReadingList true "Reading list data"
+// @Success 201 {object} models.ReadingList
+// @Failure 400 {object} response.HTTPError
+// @Failure 401 {object} response.HTTPError
+// @Failure 500 {object} response.HTTPError
+// @Router /v1/reading-list [post]
+func CreateReadingList(c *fiber.Ctx) error {
+	// Get claims from JWT.
+	claims, err := utils.ExtractTokenMetadata(c)
+	if err != nil {
+		// Return status 500 and JWT parse error.
+		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	saveList, isError, errorCode, errorMessage := parseSaveReadingList(c)
+	if isError {
+		return response.RespondError(c, errorCode, errorMessage)
+	}
+
+	// Set initialized default data for list:
+	list := &models.ReadingList{
+		ID:        uuid.New(),
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+		UserID:    claims.UserID,
+		Kind:      repository.ReadingListCustom,
+	}
+	setReadingList(list, saveList)
+
+	if err := database.ReadingListDB().CreateReadingList(list); err != nil {
+		// Return status 500 and error message.
+		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	// Return status 201.
+	return response.RespondSuccess(c, fiber.StatusCreated, list)
+}
+
+// GetReadingList godoc
+// @Description Will display one reading list of the current user with its books in order and the progress in them
+// @Description Require valid user token
+// @Summary Get own reading list
+// @Tags Reading list
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param list_id path string true "Reading list ID"
+// @Success 200 {object} models.ReadingListWithBooks
+// @Failure 400 {object} response.HTTPError
+// @Failure 401 {object} response.HTTPError
+// @Failure 404 {object} response.HTTPError
+// @Failure 500 {object} response.HTTPError
+// @Router /v1/reading-list/{list_id} [get]
+func GetReadingList(c *fiber.Ctx) error {
+	list, isError, errorCode, errorMessage := getOwnReadingList(c)
+	if isError {
+		return response.RespondError(c, errorCode, errorMessage)
+	}
+
+	return respondReadingListWithBooks(c, list, false)
+}
+
+// UpdateReadingList godoc
+// @Description Change the name and privacy of a reading list of the current user
+// @Description Making a list public gives it a new `share_token`, making it private again revokes the token
+// @Description Require valid user token
+// @Summary Edit own reading list
+// @Tags Reading list
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param list_id path string true "Reading list ID"
+// @Param models.SaveReadingList body models.SaveReadingList true "Reading list data"
+// @Success 200 {object} models.ReadingList
+// @Failure 400 {object} response.HTTPError
+// @Failure 401 {object} response.HTTPError
+// @Failure 404 {object} response.HTTPError
+// @Failure 500 {object} response.HTTPError
+// @Router /v1/reading-list/{list_id} [put]
+func UpdateReadingList(c *fiber.Ctx) error {
+	list, isError, errorCode, errorMessage := getOwnReadingList(c)
+	if isError {
+		return response.RespondError(c, errorCode, errorMessage)
+	}
+
+	saveList, isError, errorCode, errorMessage := parseSaveReadingList(c)
+	if isError {
+		return response.RespondError(c, errorCode, errorMessage)
+	}
+
+	setReadingList(list, saveList)
+	list.UpdatedAt = time.Now()
+
+	if err := database.ReadingListDB().UpdateReadingList(list); err != nil {
+		// Return status 500 and error message.
+		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	// Return status 200 OK.
+	return response.RespondSuccess(c, fiber.StatusOK, list)
+}
+
+// DeleteReadingList godoc
+// @Description Delete a custom reading list of the current user, the built-in lists can only be emptied
+// @Description Require valid user token
+// @Summary Delete own reading list
+// @Tags Reading list
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param list_id path string true "Reading list ID"
+// @Success 204
+// @Failure 400 {object} response.HTTPError
+// @Failure 401 {object} response.HTTPError
+// @Failure 404 {object} response.HTTPError
+// @Failure 409 {object} response.HTTPError
+// @Failure 500 {object} response.HTTPError
+// @Router /v1/reading-list/{list_id} [delete]
+func DeleteReadingList(c *fiber.Ctx) error {
+	list, isError, errorCode, errorMessage := getOwnReadingList(c)
+	if isError {
+		return response.RespondError(c, errorCode, errorMessage)
+	}
+	if list.Kind != repository.ReadingListCustom {
+		// Return status 409 and error message.
+		return response.RespondError(c, fiber.StatusConflict, "built-in reading lists can not be deleted")
+	}
+
+	if err := database.ReadingListDB().DeleteReadingList(list.ID); err != nil {
+		// Return status 500 and error message.
+		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	// Return status 204 no content.
+	return response.RespondSuccess(c, fiber.StatusNoContent, "")
+}
+
+// AddReadingListBook godoc
+// @Description Add a book to the end of a reading list of the current user
+// @Description The book must be published, or one the user owns or collaborates on
+// @Description Require valid user token
+// @Summary Add book to own reading list
+// @Tags Reading list
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param list_id path string true "Reading list ID"
+// @Param models.AddReadingListBook body models.AddReadingListBook true "Book to add"
+// @Success 200 {object} models.ReadingListWithBooks
+// @Failure 400 {object} response.HTTPError
+// @Failure 401 {object} response.HTTPError
+// @Failure 403 {object} response.HTTPError
+// @Failure 404 {object} response.HTTPError
+// @Failure 409 {object} response.HTTPError
+// @Failure 500 {object} response.HTTPError
+// @Router /v1/reading-list/{list_id}/books [post]
+func AddReadingListBook(c *fiber.Ctx) error {
+	list, isError, errorCode, errorMessage := getOwnReadingList(c)
+	if isError {
+		return response.RespondError(c, errorCode, errorMessage)
+	}
+
+	// Create new AddReadingListBook struct
+	addBook := &models.AddReadingListBook{}
+
+	// Check, if received JSON data is valid.
+	if err := c.BodyParser(addBook); err != nil {
+		// Return status 400 and error message.
+		return response.RespondError(c, fiber.StatusBadRequest, "unable to parse request body")
+	}
+
+	// Validate fields.
+	validate := utils.NewValidator()
+	if err := validate.Struct(addBook); err != nil {
+		// Return, if some fields are not valid.
+		return response.RespondError(c, fiber.StatusBadRequest, utils.ValidatorErrors(err))
+	}
+
+	// Checking, if the user may read the book.
+	if isError, errorCode, errorMessage := readingBookCheck(addBook.BookID, list.UserID); isError {
+		return response.RespondError(c, errorCode, errorMessage)
+	}
+
+	if err := database.ReadingListDB().AddReadingListBook(list.ID, addBook.BookID); err != nil {
+		if errors.Is(err, queries.ErrReadingListBookExists) {
+			// Return status 409 and error message.
+			return response.RespondError(c, fiber.StatusConflict, err.Error())
+		}
+		// Return status 500 and error message.
+		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	return respondReadingListWithBooks(c, list, false)
+}
+
+// RemoveReadingListBook godoc
+// @Description Remove a book from a reading list of the current user
+// @Description Require valid user token
+// @Summary Remove book from own reading list
+// @Tags Reading list
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param list_id path string true "Reading list ID"
+// @Param book_id path string true "Book ID"
+// @Success 204
+// @Failure 400 {object} response.HTTPError
+// @Failure 401 {object} response.HTTPError
+// @Failure 404 {object} response.HTTPError
+// @Failure 500 {object} response.HTTPError
+// @Router /v1/reading-list/{list_id}/book/{book_id} [delete]
+func RemoveReadingListBook(c *fiber.Ctx) error {
+	// Catch book ID from URL.
+	bookID, err := uuid.Parse(c.Params("book_id"))
+	if err != nil {
+		return response.RespondError(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	list, isError, errorCode, errorMessage := getOwnReadingList(c)
+	if isError {
+		return response.RespondError(c, errorCode, errorMessage)
+	}
+
+	if err := database.ReadingListDB().RemoveReadingListBook(list.ID, bookID); err != nil {
+		// Return status 404 and error message.
+		return response.RespondError(c, fiber.StatusNotFound, err.Error())
+	}
+
+	// Return status 204 no content.
+	return response.RespondSuccess(c, fiber.StatusNoContent, "")
+}
+
+// OrderReadingList godoc
+// @Description Reorder the books of a reading list of the current user
+// @Description `book_ids` must hold every book of the list exactly once, in the new order
+// @Description Require valid user token
+// @Summary Reorder own reading list
+// @Tags Reading list
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param list_id path string true "Reading list ID"
+// @Param models.OrderReadingList body models.OrderReadingList true "Book IDs in the new order"
+// @Success 200 {object} models.ReadingListWithBooks
+// @Failure 400 {object} response.HTTPError
+// @Failure 401 {object} response.HTTPError
+// @Failure 404 {object} response.HTTPError
+// @Failure 500 {object} response.HTTPError
+// @Router /v1/reading-list/{list_id}/order [put]
+func OrderReadingList(c *fiber.Ctx) error {
+	list, isError, errorCode, errorMessage := getOwnReadingList(c)
+	if isError {
+		return response.RespondError(c, errorCode, errorMessage)
+	}
+
+	// Create new OrderReadingList struct
+	order := &models.OrderReadingList{}
+
+	// Check, if received JSON data is valid.
+	if err := c.BodyParser(order); err != nil {
+		// Return status 400 and error message.
+		return response.RespondError(c, fiber.StatusBadRequest, "unable to parse request body")
+	}
+
+	// Validate fields.
+	validate := utils.NewValidator()
+	if err := validate.Struct(order); err != nil {
+		// Return, if some fields are not valid.
+		return response.RespondError(c, fiber.StatusBadRequest, utils.ValidatorErrors(err))
+	}
+
+	// Checking, if the new order holds exactly the books of the list.
+	db := database.ReadingListDB()
+	bookIDs, err := db.GetReadingListBookIDs(list.ID)
+	if err != nil {
+		// Return status 500 and error message.
+		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+	}
+	inList := make(map[uuid.UUID]bool, len(bookIDs))
+	for _, id := range bookIDs {
+		inList[id] = true
+	}
+	seen := make(map[uuid.UUID]bool, len(order.BookIDs))
+	for _, id := range order.BookIDs {
+		if !inList[id] || seen[id] {
+			// Return status 400 and error message.
+			return response.RespondError(c, fiber.StatusBadRequest, "book_ids must hold every book of the list exactly once")
+		}
+		seen[id] = true
+	}
+	if len(seen) != len(inList) {
+		// Return status 400 and error message.
+		return response.RespondError(c, fiber.StatusBadRequest, "book_ids must hold every book of the list exactly once")
+	}
+
+	if err := db.OrderReadingList(list.ID, order.BookIDs); err != nil {
+		// Return status 500 and error message.
+		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	return respondReadingListWithBooks(c, list, false)
+}
+
+// GetSharedReadingList godoc
+// @Description Will display a public reading list by its share token, with its published books in order
+// @Description The token stops working when the owner makes the list private
+// @Summary Get shared reading list
+// @Tags Reading list
+// @Accept json
+// @Produce json
+// @Param token path string true "Share token"
+// @Success 200 {object} models.ReadingListWithBooks
+// @Failure 404 {object} response.HTTPError
+// @Failure 500 {object} response.HTTPError
+// @Router /v1/reading-lists/shared/{token} [get]
+func GetSharedReadingList(c *fiber.Ctx) error {
+	list, err := database.ReadingListDB().GetSharedReadingList(c.Params("token"))
+	if err != nil {
+		// Return status 404 and reading list not found error.
+		return response.RespondError(c, fiber.StatusNotFound, "shared reading list not found")
+	}
+
+	return respondReadingListWithBooks(c, &list, true)
+}
+
+// GetBookProgress godoc
+// @Description Will display how far the current user got in a book
+// @Description Require valid user token
+// @Summary Get own reading progress
+// @Tags Reading list
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param book_id path string true "Book ID"
+// @Success 200 {object} models.ReadingProgress
+// @Failure 400 {object} response.HTTPError
+// @Failure 401 {object} response.HTTPError
+// @Failure 404 {object} response.HTTPError
+// @Failure 500 {object} response.HTTPError
+// @Router /v1/book/{book_id}/progress [get]
+func GetBookProgress(c *fiber.Ctx) error {
+	// Catch book ID from URL.
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return response.RespondError(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	// Get claims from JWT.
+	claims, err := utils.ExtractTokenMetadata(c)
+	if err != nil {
+		// Return status 500 and JWT parse error.
+		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	progress, err := database.ReadingListDB().GetReadingProgress(claims.UserID, id)
+	if err != nil {
+		// Return status 404 and error message.
+		return response.RespondError(c, fiber.StatusNotFound, err.Error())
+	}
+
+	// Return status 200 OK.
+	return response.RespondSuccess(c, fiber.StatusOK, progress)
+}
+
+// SaveBookProgress godoc
+// @Description Record how far the current user got in a book, as a percentage and optionally a page
+// @Description The book must be published, or one the user owns or collaborates on
+// @Description Require valid user token
+// @Summary Save own reading progress
+// @Tags Reading list
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param book_id path string true "Book ID"
+// @Param models.SaveReadingProgress body models.SaveReadingProgress true "Reading progress"
+// @Success 200 {object} models.ReadingProgress
+// @Failure 400 {object} response.HTTPError
+// @Failure 401 {object} response.HTTPError
+// @Failure 403 {object} response.HTTPError
+// @Failure 404 {object} response.HTTPError
+// @Failure 500 {object} response.HTTPError
+// @Router /v1/book/{book_id}/progress [put]
+func SaveBookProgress(c *fiber.Ctx) error {
+	// Catch book ID from URL.
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return response.RespondError(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	// Get claims from JWT.
+	claims, err := utils.ExtractTokenMetadata(c)
+	if err != nil {
+		// Return status 500 and JWT parse error.
+		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	// Create new SaveReadingProgress struct
+	saveProgress := &models.SaveReadingProgress{}
+
+	// Check, if received JSON data is valid.
+	if err := c.BodyParser(saveProgress); err != nil {
+		// Return status 400 and error message.
+		return response.RespondError(c, fiber.StatusBadRequest, "unable to parse request body")
+	}
+
+	// Validate fields.
+	validate := utils.NewValidator()
+	if err := validate.Struct(saveProgress); err != nil {
+		// Return, if some fields are not valid.
+		return response.RespondError(c, fiber.StatusBadRequest, utils.ValidatorErrors(err))
+	}
+
+	// Checking, if the user may read the book.
+	if isError, errorCode, errorMessage := readingBookCheck(id, claims.UserID); isError {
+		return response.RespondError(c, errorCode, errorMessage)
+	}
+
+	progress := &models.ReadingProgress{
+		UserID:    claims.UserID,
+		BookID:    id,
+		UpdatedAt: time.Now(),
+		Percent:   *saveProgress.Percent,
+		Page:      saveProgress.Page,
+	}
+
+	if err := database.ReadingListDB().SaveReadingProgress(progress); err != nil {
+		// Return status 500 and error message.
+		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	// Return status 200 OK.
+	return response.RespondSuccess(c, fiber.StatusOK, progress)
+}
+
+// getOwnReadingList func for getting the reading list with the ID from URL, if it belongs to the current user.
+// Lists of other users are reported as not found, so their existence is not revealed.
+func getOwnReadingList(c *fiber.Ctx) (*models.ReadingList, bool, int, interface{}) {
+	// Catch list ID from URL.
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return nil, true, fiber.StatusBadRequest, err.Error()
+	}
+
+	// Get claims from JWT.
+	claims, err := utils.ExtractTokenMetadata(c)
+	if err != nil {
+		// Return status 500 and JWT parse error.
+		return nil, true, fiber.StatusInternalServerError, err.Error()
+	}
+
+	list, err := database.ReadingListDB().GetReadingList(id)
+	if err != nil || list.UserID != claims.UserID {
+		// Return status 404 and reading list not found error.
+		return nil, true, fiber.StatusNotFound, "reading list with given ID not found"
+	}
+
+	return &list, false, fiber.StatusOK, nil
+}
+
+// parseSaveReadingList func for reading and validating a reading list from the request body.
+func parseSaveReadingList(c *fiber.Ctx) (*models.SaveReadingList, bool, int, interface{}) {
+	// Create new SaveReadingList struct
+	saveList := &models.SaveReadingList{}
+
+	// Check, if received JSON data is valid.
+	if err := c.BodyParser(saveList); err != nil {
+		// Return status 400 and error message.
+		return nil, true, fiber.StatusBadRequest, "unable to parse request body"
+	}
+
+	// Validate list fields.
+	validate := utils.NewValidator()
+	if err := validate.Struct(saveList); err != nil {
+		// Return, if some fields are not valid.
+		return nil, true, fiber.StatusBadRequest, utils.ValidatorErrors(err)
+	}
+
+	return saveList, false, fiber.StatusOK, nil
+}
+
+// setReadingList func for applying name and privacy to a list, a list turning public gets a new share token.
+func setReadingList(list *models.ReadingList, saveList *models.SaveReadingList) {
+	list.Name = saveList.Name
+	switch {
+	case saveList.IsPublic && list.ShareToken == nil:
+		token := utils.String(readingListShareTokenLength)
+		list.ShareToken = &token
+	case !saveList.IsPublic:
+		list.ShareToken = nil
+	}
+	list.IsPublic = saveList.IsPublic
+}
+
+// readingBookCheck func for checking that a book exists and may be read by given user.
+func readingBookCheck(bookID, userID uuid.UUID) (bool, int, interface{}) {
+	// Checking, if book with given ID is exists.
+	foundedBook, err := database.BookDB().GetBookById(bookID)
+	if err != nil {
+		// Return status 404 and book not found error.
+		return true, fiber.StatusNotFound, "book with given ID not found"
+	}
+	if foundedBook.BookStatus == repository.BookStatusPublished {
+		return false, 0, ""
+	}
+
+	return bookAccessCheck(&foundedBook, userID, bookActionView)
+}
+
+// respondReadingListWithBooks func for returning a reading list with its books, shared lists hide unpublished books and progress.
+func respondReadingListWithBooks(c *fiber.Ctx, list *models.ReadingList, shared bool) error {
+	books, err := database.ReadingListDB().GetReadingListBooks(list, shared)
+	if err != nil {
+		// Return status 500 and error message.
+		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	listWithBooks := &models.ReadingListWithBooks{ReadingList: *list, Books: books}
+	if shared {
+		// The token is already known to the reader, the owner stays anonymous.
+		listWithBooks.ShareToken = nil
+		listWithBooks.UserID = uuid.Nil
+	}
+	listWithBooks.BookCount = int64(len(books))
+
+	// Return status 200 OK.
+	return response.RespondSuccess(c, fiber.StatusOK, listWithBooks)
+}
@@ -0,0 +1,282 @@
+package controllers
+
+import (
+	"errors"
+	"time"
+
+	"github.com/aryanicosa/go-fiber-rest-api/app/models"
+	"github.com/aryanicosa/go-fiber-rest-api/app/queries"
+	"github.com/aryanicosa/go-fiber-rest-api/pkg/repository"
+	"github.com/aryanicosa/go-fiber-rest-api/pkg/response"
+	"github.com/aryanicosa/go-fiber-rest-api/pkg/utils"
+	"github.com/aryanicosa/go-fiber-rest-api/platform/database"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// GetBookReviews godoc
+// @Description Will display the reviews of a book, newest first, with the average rating of all of them
+// @Description Require Basic Auth
+// @Description Unpublished books are only shown to the owner, collaborators and users with `book:publish` credential, sending their token instead
+// @Summary Get book reviews
+// @Tags Review
+// @Accept json
+// @Produce json
+// @Security BasicAuth
+// @Security ApiKeyAuth
+// @Param book_id path string true "Book ID"
+// @Param page query int false "Page number, starts from 1"
+// @Param limit query int false "Page size, up to 100"
+// @Success 200 {object} models.AllReviews
+// @Failure 400 {object} response.HTTPError
+// @Failure 404 {object} response.HTTPError
+// @Failure 500 {object} response.HTTPError
+// @Router /v1/book/{book_id}/reviews [get]
+func GetBookReviews(c *fiber.Ctx) error {
+	// Catch book ID from URL.
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return response.RespondError(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	pagination, err := utils.ParsePagination(c)
+	if err != nil {
+		// Return status 400 and error message.
+		return response.RespondError(c, fiber.StatusBadRequest, err.Error())
+	}
+	if pagination.UseCursor {
+		return response.RespondError(c, fiber.StatusBadRequest, "cursor paging is not supported for reviews")
+	}
+
+	// Checking, if book with given ID is exists.
+	foundedBook, err := database.BookDB().GetBookById(id)
+	if err != nil {
+		// Return status 404 and book not found error.
+		return response.RespondError(c, fiber.StatusNotFound, "book with given ID not found")
+	}
+
+	// Checking, if caller may see the book.
+	if isError, errorCode, errorMessage := bookVisibleCheck(c, &foundedBook); isError {
+		return response.RespondError(c, errorCode, errorMessage)
+	}
+
+	// Get one page of reviews.
+	reviews, total, err := database.ReviewDB().GetReviews(foundedBook.ID, pagination)
+	if err != nil {
+		// Return status 500 and error message.
+		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	allReviews := &models.AllReviews{
+		Reviews:       reviews,
+		Count:         total,
+		RatingAverage: foundedBook.RatingAverage,
+		Page:          pagination.Page,
+		Limit:         pagination.Limit,
+		Links:         utils.BuildPageLinks(c, pagination, total, false, nil, nil),
+	}
+
+	// Return status 200 OK.
+	return response.RespondSuccess(c, fiber.StatusOK, allReviews)
+}
+
+// CreateBookReview godoc
+// @Description Rate a published book from 0 to 10 with an optional review, once per user
+// @Description Require valid user token
+// @Summary Review a book
+// @Tags Review
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param book_id path string true "Book ID"
+// @Param models.SaveReview body models.SaveReview true "Review data"
+// @Success 201 {object} models.Review
+// @Failure 400 {object} response.HTTPError
+// @Failure 401 {object} response.HTTPError
+// @Failure 404 {object} response.HTTPError
+// @Failure 409 {object} response.HTTPError
+// @Failure 500 {object} response.HTTPError
+// @Router /v1/book/{book_id}/review [post]
+func CreateBookReview(c *fiber.Ctx) error {
+	// Catch book ID from URL.
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return response.RespondError(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	// Get claims from JWT.
+	claims, err := utils.ExtractTokenMetadata(c)
+	if err != nil {
+		// Return status 500 and JWT parse error.
+		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	saveReview, isError, errorCode, errorMessage := parseSaveReview(c)
+	if isError {
+		return response.RespondError(c, errorCode, errorMessage)
+	}
+
+	// Checking, if book with given ID is exists.
+	foundedBook, err := database.BookDB().GetBookById(id)
+	if err != nil {
+		// Return status 404 and book not found error.
+		return response.RespondError(c, fiber.StatusNotFound, "book with given ID not found")
+	}
+	if foundedBook.BookStatus != repository.BookStatusPublished {
+		// Return status 409 and error message.
+		return response.RespondError(c, fiber.StatusConflict, "only published books can be reviewed")
+	}
+
+	// Set initialized default data for review:
+	review := &models.Review{
+		ID:        uuid.New(),
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+		BookID:    foundedBook.ID,
+		UserID:    claims.UserID,
+		Rating:    *saveReview.Rating,
+		Body:      saveReview.Body,
+	}
+
+	if err := database.ReviewDB().CreateReview(review); err != nil {
+		if errors.Is(err, queries.ErrReviewExists) {
+			// Return status 409 and error message.
+			return response.RespondError(c, fiber.StatusConflict, err.Error())
+		}
+		// Return status 500 and error message.
+		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	// Return status 201.
+	return response.RespondSuccess(c, fiber.StatusCreated, review)
+}
+
+// UpdateBookReview godoc
+// @Description Change the rating and review of a book given by the current user
+// @Description Require valid user token
+// @Summary Edit own book review
+// @Tags Review
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param book_id path string true "Book ID"
+// @Param models.SaveReview body models.SaveReview true "Review data"
+// @Success 200 {object} models.Review
+// @Failure 400 {object} response.HTTPError
+// @Failure 401 {object} response.HTTPError
+// @Failure 404 {object} response.HTTPError
+// @Failure 500 {object} response.HTTPError
+// @Router /v1/book/{book_id}/review [put]
+func UpdateBookReview(c *fiber.Ctx) error {
+	// Catch book ID from URL.
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return response.RespondError(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	// Get claims from JWT.
+	claims, err := utils.ExtractTokenMetadata(c)
+	if err != nil {
+		// Return status 500 and JWT parse error.
+		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	saveReview, isError, errorCode, errorMessage := parseSaveReview(c)
+	if isError {
+		return response.RespondError(c, errorCode, errorMessage)
+	}
+
+	// Checking, if book with given ID is exists.
+	foundedBook, err := database.BookDB().GetBookById(id)
+	if err != nil {
+		// Return status 404 and book not found error.
+		return response.RespondError(c, fiber.StatusNotFound, "book with given ID not found")
+	}
+
+	// Checking, if the user reviewed the book.
+	db := database.ReviewDB()
+	review, err := db.GetReview(foundedBook.ID, claims.UserID)
+	if err != nil {
+		// Return status 404 and review not found error.
+		return response.RespondError(c, fiber.StatusNotFound, "you have not reviewed this book")
+	}
+
+	review.Rating = *saveReview.Rating
+	review.Body = saveReview.Body
+	review.UpdatedAt = time.Now()
+
+	if err := db.UpdateReview(&review); err != nil {
+		// Return status 500 and error message.
+		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	// Return status 200 OK.
+	return response.RespondSuccess(c, fiber.StatusOK, review)
+}
+
+// DeleteBookReview godoc
+// @Description Remove the rating and review of a book given by the current user
+// @Description Require valid user token
+// @Summary Delete own book review
+// @Tags Review
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param book_id path string true "Book ID"
+// @Success 204
+// @Failure 400 {object} response.HTTPError
+// @Failure 401 {object} response.HTTPError
+// @Failure 404 {object} response.HTTPError
+// @Failure 500 {object} response.HTTPError
+// @Router /v1/book/{book_id}/review [delete]
+func DeleteBookReview(c *fiber.Ctx) error {
+	// Catch book ID from URL.
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return response.RespondError(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	// Get claims from JWT.
+	claims, err := utils.ExtractTokenMetadata(c)
+	if err != nil {
+		// Return status 500 and JWT parse error.
+		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	// Checking, if the user reviewed the book.
+	db := database.ReviewDB()
+	review, err := db.GetReview(id, claims.UserID)
+	if err != nil {
+		// Return status 404 and review not found error.
+		return response.RespondError(c, fiber.StatusNotFound, "you have not reviewed this book")
+	}
+
+	if err := db.DeleteReview(review.ID); err != nil {
+		// Return status 500 and error message.
+		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	// Return status 204 no content.
+	return response.RespondSuccess(c, fiber.StatusNoContent, "")
+}
+
+// parseSaveReview func for reading and validating a review from the request body.
+func parseSaveReview(c *fiber.Ctx) (*models.SaveReview, bool, int, interface{}) {
+	// Create new SaveReview struct
+	saveReview := &models.SaveReview{}
+
+	// Check, if received JSON data is valid.
+	if err := c.BodyParser(saveReview); err != nil {
+		// Return status 400 and error message.
+		return nil, true, fiber.StatusBadRequest, "unable to parse request body"
+	}
+
+	// Validate review fields.
+	validate := utils.NewValidator()
+	if err := validate.Struct(saveReview); err != nil {
+		// Return, if some fields are not valid.
+		return nil, true, fiber.StatusBadRequest, utils.ValidatorErrors(err)
+	}
+
+	return saveReview, false, fiber.StatusOK, nil
+}
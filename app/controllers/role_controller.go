@@ -0,0 +1,585 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/aryanicosa/go-fiber-rest-api/app/models"
+	"github.com/aryanicosa/go-fiber-rest-api/app/queries"
+	"github.com/aryanicosa/go-fiber-rest-api/pkg/repository"
+	"github.com/aryanicosa/go-fiber-rest-api/pkg/response"
+	"github.com/aryanicosa/go-fiber-rest-api/pkg/utils"
+	"github.com/aryanicosa/go-fiber-rest-api/platform/database"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// permissionNamePattern allows names like `book:update:any`, as carried in access tokens.
+var permissionNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_]*(:[a-z0-9_]+)*$`)
+
+// GetRoles godoc
+// @Description Will display all roles with their permissions, sorted by name
+// @Description Require valid user token with `role:manage` credential
+// @Summary Get all roles
+// @Tags Role
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {array} models.Role
+// @Failure 401 {object} response.HTTPError
+// @Failure 403 {object} response.HTTPError
+// @Failure 500 {object} response.HTTPError
+// @Router /v1/roles [get]
+func GetRoles(c *fiber.Ctx) error {
+	if isError, errorCode, errorMessage := roleManageCheck(c); isError {
+		return response.RespondError(c, errorCode, errorMessage)
+	}
+
+	// Get all roles.
+	roles, err := database.RoleDB().GetRoles()
+	if err != nil {
+		// Return status 500 and error message.
+		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	// Return status 200 OK.
+	return response.RespondSuccess(c, fiber.StatusOK, roles)
+}
+
+// CreateRole godoc
+// @Description Permissions are given by name and have to exist
+// @Description Require valid user token with `role:manage` credential
+// @Summary Create new role
+// @Tags Role
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param models.SaveRole body models.SaveRole true "Role data"
+// @Success 201 {object} models.Role
+// @Failure 400 {object} response.HTTPError
+// @Failure 401 {object} response.HTTPError
+// @Failure 403 {object} response.HTTPError
+// @Failure 409 {object} response.HTTPError
+// @Failure 500 {object} response.HTTPError
+// @Router /v1/role [post]
+func CreateRole(c *fiber.Ctx) error {
+	if isError, errorCode, errorMessage := roleManageCheck(c); isError {
+		return response.RespondError(c, errorCode, errorMessage)
+	}
+
+	role := &models.Role{ID: uuid.New(), CreatedAt: time.Now()}
+	if isError, errorCode, errorMessage := setRole(c, role); isError {
+		return response.RespondError(c, errorCode, errorMessage)
+	}
+
+	if err := database.RoleDB().CreateRole(role); err != nil {
+		// Return status 500 and error message.
+		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	// Return status 201.
+	return response.RespondSuccess(c, fiber.StatusCreated, role)
+}
+
+// UpdateRole godoc
+// @Description The permissions of the role are replaced, users get them on their next sign in or token renewal
+// @Description Users of a role losing permissions are signed out everywhere, the last role or active user with `role:manage` keeps it
+// @Description Require valid user token with `role:manage` credential
+// @Summary Update role
+// @Tags Role
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param role_id path string true "Role ID"
+// @Param models.SaveRole body models.SaveRole true "Role data"
+// @Success 200 {object} models.Role
+// @Failure 400 {object} response.HTTPError
+// @Failure 401 {object} response.HTTPError
+// @Failure 403 {object} response.HTTPError
+// @Failure 404 {object} response.HTTPError
+// @Failure 409 {object} response.HTTPError
+// @Failure 500 {object} response.HTTPError
+// @Router /v1/role/{role_id} [put]
+func UpdateRole(c *fiber.Ctx) error {
+	// Catch role ID from URL.
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return response.RespondError(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	if isError, errorCode, errorMessage := roleManageCheck(c); isError {
+		return response.RespondError(c, errorCode, errorMessage)
+	}
+
+	// Checking, if role with given ID is exists.
+	db := database.RoleDB()
+	role, err := db.GetRole(id)
+	if errors.Is(err, queries.ErrRoleNotFound) {
+		// Return status 404 and role not found error.
+		return response.RespondError(c, fiber.StatusNotFound, err.Error())
+	}
+	if err != nil {
+		// Return status 500 and error message.
+		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+	}
+	permissions := role.Permissions
+
+	if isError, errorCode, errorMessage := setRole(c, &role); isError {
+		return response.RespondError(c, errorCode, errorMessage)
+	}
+
+	// Users of the role still carry the lost permissions in their tokens.
+	userIDs := []uuid.UUID{}
+	if lostNames(permissions, role.Permissions) {
+		if userIDs, err = db.GetRoleUserIDs(role.ID); err != nil {
+			// Return status 500 and error message.
+			return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+		}
+	}
+
+	if err := db.UpdateRole(&role); err != nil {
+		if errors.Is(err, queries.ErrRoleNotFound) {
+			// Return status 404 and role not found error.
+			return response.RespondError(c, fiber.StatusNotFound, err.Error())
+		}
+		if errors.Is(err, queries.ErrLastRoleManager) {
+			// Return status 409 and error message.
+			return response.RespondError(c, fiber.StatusConflict, err.Error())
+		}
+		// Return status 500 and error message.
+		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	if err := revokeUsersTokens(userIDs); err != nil {
+		// Return status 500 and Redis connection error.
+		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	// Return status 200 OK.
+	return response.RespondSuccess(c, fiber.StatusOK, role)
+}
+
+// DeleteRole godoc
+// @Description Users keep existing but lose the role and are signed out everywhere
+// @Description The last role with `role:manage`, or the one of the last active user holding it, can not be deleted
+// @Description Require valid user token with `role:manage` credential
+// @Summary Delete a role
+// @Tags Role
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param role_id path string true "Role ID"
+// @Success 204
+// @Failure 400 {object} response.HTTPError
+// @Failure 401 {object} response.HTTPError
+// @Failure 403 {object} response.HTTPError
+// @Failure 404 {object} response.HTTPError
+// @Failure 409 {object} response.HTTPError
+// @Failure 500 {object} response.HTTPError
+// @Router /v1/role/{role_id} [delete]
+func DeleteRole(c *fiber.Ctx) error {
+	// Catch role ID from URL.
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return response.RespondError(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	if isError, errorCode, errorMessage := roleManageCheck(c); isError {
+		return response.RespondError(c, errorCode, errorMessage)
+	}
+
+	// Users of the role still carry its permissions in their tokens.
+	db := database.RoleDB()
+	userIDs, err := db.GetRoleUserIDs(id)
+	if err != nil {
+		// Return status 500 and error message.
+		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	if err := db.DeleteRole(id); err != nil {
+		if errors.Is(err, queries.ErrRoleNotFound) {
+			// Return status 404 and error message.
+			return response.RespondError(c, fiber.StatusNotFound, err.Error())
+		}
+		if errors.Is(err, queries.ErrLastRoleManager) {
+			// Return status 409 and error message.
+			return response.RespondError(c, fiber.StatusConflict, err.Error())
+		}
+		// Return status 500 and error message.
+		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	if err := revokeUsersTokens(userIDs); err != nil {
+		// Return status 500 and Redis connection error.
+		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	// Return status 204 no content.
+	return response.RespondSuccess(c, fiber.StatusNoContent, "")
+}
+
+// GetPermissions godoc
+// @Description Will display all permissions, sorted by name
+// @Description Require valid user token with `role:manage` credential
+// @Summary Get all permissions
+// @Tags Role
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {array} models.Permission
+// @Failure 401 {object} response.HTTPError
+// @Failure 403 {object} response.HTTPError
+// @Failure 500 {object} response.HTTPError
+// @Router /v1/permissions [get]
+func GetPermissions(c *fiber.Ctx) error {
+	if isError, errorCode, errorMessage := roleManageCheck(c); isError {
+		return response.RespondError(c, errorCode, errorMessage)
+	}
+
+	// Get all permissions.
+	permissions, err := database.RoleDB().GetPermissions()
+	if err != nil {
+		// Return status 500 and error message.
+		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	// Return status 200 OK.
+	return response.RespondSuccess(c, fiber.StatusOK, permissions)
+}
+
+// CreatePermission godoc
+// @Description Names are lower case words separated by colons, e.g. `book:update:any`
+// @Description Permissions not checked by this service are still carried in access tokens, for other services
+// @Description Require valid user token with `role:manage` credential
+// @Summary Create new permission
+// @Tags Role
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param models.Permission body models.Permission true "Permission data"
+// @Success 201 {object} models.Permission
+// @Failure 400 {object} response.HTTPError
+// @Failure 401 {object} response.HTTPError
+// @Failure 403 {object} response.HTTPError
+// @Failure 409 {object} response.HTTPError
+// @Failure 500 {object} response.HTTPError
+// @Router /v1/permission [post]
+func CreatePermission(c *fiber.Ctx) error {
+	if isError, errorCode, errorMessage := roleManageCheck(c); isError {
+		return response.RespondError(c, errorCode, errorMessage)
+	}
+
+	// Create new Permission struct
+	permission := &models.Permission{}
+
+	// Check, if received JSON data is valid.
+	if err := c.BodyParser(permission); err != nil {
+		// Return status 400 and error message.
+		return response.RespondError(c, fiber.StatusBadRequest, "unable to parse request body")
+	}
+
+	// Set initialized default data for permission:
+	permission.ID = uuid.New()
+	permission.CreatedAt = time.Now()
+
+	// Validate permission fields.
+	if err := utils.NewValidator().Struct(permission); err != nil {
+		// Return, if some fields are not valid.
+		return response.RespondError(c, fiber.StatusBadRequest, utils.ValidatorErrors(err))
+	}
+	if !permissionNamePattern.MatchString(permission.Name) {
+		// Return status 400 and error message.
+		return response.RespondError(c, fiber.StatusBadRequest, "name must be lower case words separated by colons")
+	}
+
+	// Checking, if the name is taken.
+	db := database.RoleDB()
+	existing, err := db.GetPermissionsByNames([]string{permission.Name})
+	if err != nil {
+		// Return status 500 and error message.
+		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+	}
+	if len(existing) > 0 {
+		// Return status 409 and error message.
+		return response.RespondError(c, fiber.StatusConflict, "permission with given name already exists")
+	}
+
+	if err := db.CreatePermission(permission); err != nil {
+		// Return status 500 and error message.
+		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	// Return status 201.
+	return response.RespondSuccess(c, fiber.StatusCreated, permission)
+}
+
+// DeletePermission godoc
+// @Description Roles keep existing but lose the permission, users holding it are signed out everywhere
+// @Description Permissions checked by this service, like `book:create`, can not be deleted
+// @Description Require valid user token with `role:manage` credential
+// @Summary Delete a permission
+// @Tags Role
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param permission_id path string true "Permission ID"
+// @Success 204
+// @Failure 400 {object} response.HTTPError
+// @Failure 401 {object} response.HTTPError
+// @Failure 403 {object} response.HTTPError
+// @Failure 404 {object} response.HTTPError
+// @Failure 409 {object} response.HTTPError
+// @Failure 500 {object} response.HTTPError
+// @Router /v1/permission/{permission_id} [delete]
+func DeletePermission(c *fiber.Ctx) error {
+	// Catch permission ID from URL.
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return response.RespondError(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	if isError, errorCode, errorMessage := roleManageCheck(c); isError {
+		return response.RespondError(c, errorCode, errorMessage)
+	}
+
+	// Checking, if permission with given ID is exists.
+	db := database.RoleDB()
+	permission, err := db.GetPermission(id)
+	if errors.Is(err, queries.ErrPermissionNotFound) {
+		// Return status 404 and permission not found error.
+		return response.RespondError(c, fiber.StatusNotFound, err.Error())
+	}
+	if err != nil {
+		// Return status 500 and error message.
+		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	// Checking, if the permission is checked by this service.
+	for _, credential := range repository.BuiltInCredentials {
+		if permission.Name == credential {
+			// Return status 409 and error message.
+			return response.RespondError(c, fiber.StatusConflict, "permission is checked by this service and can not be deleted")
+		}
+	}
+
+	// Users holding the permission still carry it in their tokens.
+	userIDs, err := db.GetPermissionUserIDs(permission.ID)
+	if err != nil {
+		// Return status 500 and error message.
+		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	if err := db.DeletePermission(permission.ID); err != nil {
+		if errors.Is(err, queries.ErrPermissionNotFound) {
+			// Return status 404 and error message.
+			return response.RespondError(c, fiber.StatusNotFound, err.Error())
+		}
+		// Return status 500 and error message.
+		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	if err := revokeUsersTokens(userIDs); err != nil {
+		// Return status 500 and Redis connection error.
+		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	// Return status 204 no content.
+	return response.RespondSuccess(c, fiber.StatusNoContent, "")
+}
+
+// SetUserRoles godoc
+// @Description Replace the roles of a user, by name, the user gets their permissions on the next sign in or token renewal
+// @Description A user losing permissions is signed out everywhere, the last active user with `role:manage` keeps it
+// @Description Require valid user token with `role:manage` credential
+// @Summary Set roles of user
+// @Tags Role
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path string true "User ID"
+// @Param models.UserRoles body models.UserRoles true "Role names"
+// @Success 200 {object} models.User
+// @Failure 400 {object} response.HTTPError
+// @Failure 401 {object} response.HTTPError
+// @Failure 403 {object} response.HTTPError
+// @Failure 404 {object} response.HTTPError
+// @Failure 409 {object} response.HTTPError
+// @Failure 500 {object} response.HTTPError
+// @Router /v1/user/{id}/roles [put]
+func SetUserRoles(c *fiber.Ctx) error {
+	// Catch user ID from URL.
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return response.RespondError(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	if isError, errorCode, errorMessage := roleManageCheck(c); isError {
+		return response.RespondError(c, errorCode, errorMessage)
+	}
+
+	// Create a new user roles struct.
+	userRoles := &models.UserRoles{}
+
+	// Checking received data from JSON body.
+	if err := c.BodyParser(userRoles); err != nil {
+		// Return status 400 and error message.
+		return response.RespondError(c, fiber.StatusBadRequest, "unable to parse request body")
+	}
+
+	// Validate user roles fields.
+	if err := utils.NewValidator().Struct(userRoles); err != nil {
+		// Return, if some fields are not valid.
+		return response.RespondError(c, fiber.StatusBadRequest, utils.ValidatorErrors(err))
+	}
+
+	// Checking, if user with given ID is exists.
+	foundedUser, err := database.UserDB().GetUserByID(id)
+	if err != nil || foundedUser.ID == uuid.Nil {
+		// Return status 404 and user not found error.
+		return response.RespondError(c, fiber.StatusNotFound, "user with the given ID is not found")
+	}
+
+	// Checking, if all roles exist.
+	db := database.RoleDB()
+	roles, err := db.GetRolesByNames(userRoles.Roles)
+	if err != nil {
+		// Return status 500 and error message.
+		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+	}
+	if missing := missingNames(userRoles.Roles, len(roles), func(i int) string { return roles[i].Name }); missing != "" {
+		// Return status 400 and error message.
+		return response.RespondError(c, fiber.StatusBadRequest, fmt.Sprintf("role '%v' does not exist", missing))
+	}
+
+	// The tokens of the user carry the permissions it has now.
+	permissions, err := db.GetUserPermissions(foundedUser.ID)
+	if err != nil {
+		// Return status 500 and error message.
+		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	if err := db.SetUserRoles(foundedUser.ID, userRoles.Roles); err != nil {
+		if errors.Is(err, queries.ErrLastRoleManager) {
+			// Return status 409 and error message.
+			return response.RespondError(c, fiber.StatusConflict, err.Error())
+		}
+		// Return status 500 and error message.
+		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	newPermissions, err := db.GetUserPermissions(foundedUser.ID)
+	if err != nil {
+		// Return status 500 and error message.
+		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+	}
+	if lostNames(permissions, newPermissions) {
+		if err := revokeUserTokens(foundedUser.ID); err != nil {
+			// Return status 500 and Redis connection error.
+			return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+		}
+	}
+
+	// Delete password hash field from JSON view.
+	foundedUser.UserRoles = userRoles.Roles
+	foundedUser.PasswordHash = ""
+
+	// Return status 200 OK.
+	return response.RespondSuccess(c, fiber.StatusOK, foundedUser)
+}
+
+// roleManageCheck func for checking the token of the request has the `role:manage` credential.
+func roleManageCheck(c *fiber.Ctx) (bool, int, interface{}) {
+	// Get claims from JWT.
+	claims, err := utils.ExtractTokenMetadata(c)
+	if err != nil {
+		// Return status 500 and JWT parse error.
+		return true, fiber.StatusInternalServerError, err.Error()
+	}
+
+	return claimCheck(claims, repository.RoleManageCredential)
+}
+
+// setRole func for setting the name, description and permissions of given role from the request body.
+func setRole(c *fiber.Ctx, role *models.Role) (bool, int, interface{}) {
+	// Create new SaveRole struct
+	saveRole := &models.SaveRole{}
+
+	// Check, if received JSON data is valid.
+	if err := c.BodyParser(saveRole); err != nil {
+		// Return status 400 and error message.
+		return true, fiber.StatusBadRequest, "unable to parse request body"
+	}
+
+	// Validate role fields.
+	if err := utils.NewValidator().Struct(saveRole); err != nil {
+		// Return, if some fields are not valid.
+		return true, fiber.StatusBadRequest, utils.ValidatorErrors(err)
+	}
+
+	// Checking, if the name is taken by another role.
+	db := database.RoleDB()
+	existing, err := db.GetRolesByNames([]string{saveRole.Name})
+	if err != nil {
+		// Return status 500 and error message.
+		return true, fiber.StatusInternalServerError, err.Error()
+	}
+	if len(existing) > 0 && existing[0].ID != role.ID {
+		// Return status 409 and error message.
+		return true, fiber.StatusConflict, "role with given name already exists"
+	}
+
+	// Checking, if all permissions exist.
+	permissions, err := db.GetPermissionsByNames(saveRole.Permissions)
+	if err != nil {
+		// Return status 500 and error message.
+		return true, fiber.StatusInternalServerError, err.Error()
+	}
+	if missing := missingNames(saveRole.Permissions, len(permissions), func(i int) string { return permissions[i].Name }); missing != "" {
+		// Return status 400 and error message.
+		return true, fiber.StatusBadRequest, fmt.Sprintf("permission '%v' does not exist", missing)
+	}
+
+	// Set the role data, permissions sorted by name like they are read back.
+	role.UpdatedAt = time.Now()
+	role.Name = saveRole.Name
+	role.Description = saveRole.Description
+	role.Permissions = make([]string, len(permissions))
+	for i := range permissions {
+		role.Permissions[i] = permissions[i].Name
+	}
+
+	return false, 0, ""
+}
+
+// missingNames func for the first of given names that is not among the found ones, empty if all were found.
+func missingNames(names []string, foundCount int, found func(i int) string) string {
+	foundNames := map[string]bool{}
+	for i := 0; i < foundCount; i++ {
+		foundNames[found(i)] = true
+	}
+	for _, name := range names {
+		if !foundNames[name] {
+			return name
+		}
+	}
+
+	return ""
+}
+
+// lostNames func for checking, if any of the names before is missing from the names after.
+func lostNames(before, after []string) bool {
+	return missingNames(before, len(after), func(i int) string { return after[i] }) != ""
+}
+
+// revokeUsersTokens func for signing out given users everywhere, their tokens carry permissions they lost.
+func revokeUsersTokens(userIDs []uuid.UUID) error {
+	for _, userID := range userIDs {
+		if err := revokeUserTokens(userID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
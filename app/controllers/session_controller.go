@@ -0,0 +1,185 @@
+package controllers
+
+import (
+	"github.com/aryanicosa/go-fiber-rest-api/app/models"
+	"github.com/aryanicosa/go-fiber-rest-api/pkg/response"
+	"github.com/aryanicosa/go-fiber-rest-api/pkg/utils"
+	"github.com/aryanicosa/go-fiber-rest-api/platform/cache"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// GetUserSessions godoc
+// @Description Will display the devices the current user is signed in on, most recently used first
+// @Description The session of the token used is marked `current`
+// @Description Require valid user token
+// @Summary Get own sessions
+// @Tags User
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {array} models.UserSession
+// @Failure 401 {object} response.HTTPError
+// @Failure 500 {object} response.HTTPError
+// @Router /v1/user/sessions [get]
+func GetUserSessions(c *fiber.Ctx) error {
+	// Get claims from JWT.
+	claims, err := utils.ExtractTokenMetadata(c)
+	if err != nil {
+		// Return status 500 and JWT parse error.
+		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	// Create a new Redis connection.
+	sessions, err := cache.SessionCache()
+	if err != nil {
+		// Return status 500 and Redis connection error.
+		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	activeSessions, err := sessions.GetUserSessions(claims.UserID)
+	if err != nil {
+		// Return status 500 and error message.
+		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	userSessions := make([]models.UserSession, len(activeSessions))
+	for i, session := range activeSessions {
+		userSessions[i] = models.UserSession{Session: session, Current: session.ID == claims.SessionID}
+	}
+
+	// Return status 200 OK.
+	return response.RespondSuccess(c, fiber.StatusOK, userSessions)
+}
+
+// DeleteUserSession godoc
+// @Description Sign out one device of the current user, its refresh token stops working
+// @Description Require valid user token
+// @Summary Revoke own session
+// @Tags User
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param session_id path string true "Session ID"
+// @Success 204
+// @Failure 400 {object} response.HTTPError
+// @Failure 401 {object} response.HTTPError
+// @Failure 404 {object} response.HTTPError
+// @Failure 500 {object} response.HTTPError
+// @Router /v1/user/sessions/{session_id} [delete]
+func DeleteUserSession(c *fiber.Ctx) error {
+	// Catch session ID from URL.
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return response.RespondError(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	// Get claims from JWT.
+	claims, err := utils.ExtractTokenMetadata(c)
+	if err != nil {
+		// Return status 500 and JWT parse error.
+		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	// Create a new Redis connection.
+	sessions, err := cache.SessionCache()
+	if err != nil {
+		// Return status 500 and Redis connection error.
+		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	// Checking, if the session belongs to the current user.
+	session, err := sessions.GetSession(id)
+	if err != nil || session.UserID != claims.UserID {
+		// Return status 404 and session not found error.
+		return response.RespondError(c, fiber.StatusNotFound, "session with given ID not found")
+	}
+
+	if err := revokeSessions(session); err != nil {
+		// Return status 500 and Redis deletion error.
+		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	// Return status 204 no content.
+	return response.RespondSuccess(c, fiber.StatusNoContent, "")
+}
+
+// DeleteUserSessions godoc
+// @Description Sign out the current user everywhere, including the device of the token used
+// @Description Require valid user token
+// @Summary Revoke all own sessions
+// @Tags User
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 204
+// @Failure 401 {object} response.HTTPError
+// @Failure 500 {object} response.HTTPError
+// @Router /v1/user/sessions [delete]
+func DeleteUserSessions(c *fiber.Ctx) error {
+	// Get claims from JWT.
+	claims, err := utils.ExtractTokenMetadata(c)
+	if err != nil {
+		// Return status 500 and JWT parse error.
+		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	if err := revokeUserTokens(claims.UserID); err != nil {
+		// Return status 500 and Redis deletion error.
+		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	// Return status 204 no content.
+	return response.RespondSuccess(c, fiber.StatusNoContent, "")
+}
+
+// revokeSessions func for ending given sessions, their refresh tokens stop working and their access tokens are denied.
+func revokeSessions(revoked ...models.Session) error {
+	// Create a new Redis connection.
+	sessions, err := cache.SessionCache()
+	if err != nil {
+		return err
+	}
+	denylist, err := cache.TokenDenylistCache()
+	if err != nil {
+		return err
+	}
+
+	for i := range revoked {
+		if err := denylist.DenySessionTokens(revoked[i].ID, utils.AccessTokenLifetime()); err != nil {
+			return err
+		}
+		if err := sessions.DeleteSession(&revoked[i]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// revokeUserTokens func for signing a user out everywhere, every token issued to the user so far stops working.
+func revokeUserTokens(userID uuid.UUID) error {
+	// Create a new Redis connection.
+	sessions, err := cache.SessionCache()
+	if err != nil {
+		return err
+	}
+
+	activeSessions, err := sessions.GetUserSessions(userID)
+	if err != nil {
+		return err
+	}
+	if err := revokeSessions(activeSessions...); err != nil {
+		return err
+	}
+	if err := sessions.DeleteUserSessions(userID); err != nil {
+		return err
+	}
+
+	// Catch the tokens issued without a session too.
+	denylist, err := cache.TokenDenylistCache()
+	if err != nil {
+		return err
+	}
+	return denylist.DenyUserTokens(userID, utils.AccessTokenLifetime())
+}
@@ -0,0 +1,328 @@
+package controllers
+
+import (
+	"strings"
+	"time"
+
+	"github.com/aryanicosa/go-fiber-rest-api/app/models"
+	"github.com/aryanicosa/go-fiber-rest-api/app/queries"
+	"github.com/aryanicosa/go-fiber-rest-api/pkg/repository"
+	"github.com/aryanicosa/go-fiber-rest-api/pkg/response"
+	"github.com/aryanicosa/go-fiber-rest-api/pkg/utils"
+	"github.com/aryanicosa/go-fiber-rest-api/platform/database"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// bookTagFacetsLimit is the number of most used tags counted in a books list.
+const bookTagFacetsLimit = 50
+
+// GetTags godoc
+// @Description Will display all tags, sorted by name
+// @Description Require Basic Auth
+// @Summary Get all tags
+// @Tags Tag
+// @Accept json
+// @Produce json
+// @Security BasicAuth
+// @Success 200 {array} models.Tag
+// @Failure 500 {object} response.HTTPError
+// @Router /v1/tags [get]
+func GetTags(c *fiber.Ctx) error {
+	// Get all tags.
+	tags, err := database.TagDB().GetTags()
+	if err != nil {
+		// Return status 500 and error message.
+		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	// Return status 200 OK.
+	return response.RespondSuccess(c, fiber.StatusOK, tags)
+}
+
+// CreateTag godoc
+// @Description The slug is made from the name when left empty
+// @Description Require valid user token with `book:taxonomy` credential
+// @Summary Create new tag
+// @Tags Tag
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param models.Tag body models.Tag true "Tag data"
+// @Success 201 {object} models.Tag
+// @Failure 400 {object} response.HTTPError
+// @Failure 401 {object} response.HTTPError
+// @Failure 403 {object} response.HTTPError
+// @Failure 409 {object} response.HTTPError
+// @Failure 500 {object} response.HTTPError
+// @Router /v1/tag [post]
+func CreateTag(c *fiber.Ctx) error {
+	// Get claims from JWT.
+	claims, err := utils.ExtractTokenMetadata(c)
+	if err != nil {
+		// Return status 500 and JWT parse error.
+		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	if isError, errorCode, errorMessage := claimCheck(claims, repository.BookTaxonomyCredential); isError {
+		return response.RespondError(c, errorCode, errorMessage)
+	}
+
+	// Create new Tag struct
+	tag := &models.Tag{}
+
+	// Check, if received JSON data is valid.
+	if err := c.BodyParser(tag); err != nil {
+		// Return status 400 and error message.
+		return response.RespondError(c, fiber.StatusBadRequest, "unable to parse request body")
+	}
+
+	// Set initialized default data for tag:
+	tag.ID = uuid.New()
+	tag.CreatedAt = time.Now()
+	if tag.Slug == "" {
+		tag.Slug = utils.Slugify(tag.Name)
+	}
+
+	// Validate tag fields.
+	validate := utils.NewValidator()
+	if err := validate.Struct(tag); err != nil {
+		// Return, if some fields are not valid.
+		return response.RespondError(c, fiber.StatusBadRequest, utils.ValidatorErrors(err))
+	}
+	if tag.Slug != utils.Slugify(tag.Slug) {
+		// Return status 400 and error message.
+		return response.RespondError(c, fiber.StatusBadRequest, "slug must be lower case letters and digits separated by dashes")
+	}
+
+	// Checking, if the slug is taken.
+	db := database.TagDB()
+	existing, err := db.GetTagsBySlugs([]string{tag.Slug})
+	if err != nil {
+		// Return status 500 and error message.
+		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+	}
+	if len(existing) > 0 {
+		// Return status 409 and error message.
+		return response.RespondError(c, fiber.StatusConflict, "tag with given slug already exists")
+	}
+
+	if err := db.CreateTag(tag); err != nil {
+		// Return status 500 and error message.
+		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	// Return status 201.
+	return response.RespondSuccess(c, fiber.StatusCreated, tag)
+}
+
+// DeleteTag godoc
+// @Description Books keep existing but lose the tag
+// @Description Require valid user token with `book:taxonomy` credential
+// @Summary Delete a tag
+// @Tags Tag
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param tag_id path string true "Tag ID"
+// @Success 204
+// @Failure 400 {object} response.HTTPError
+// @Failure 401 {object} response.HTTPError
+// @Failure 403 {object} response.HTTPError
+// @Failure 404 {object} response.HTTPError
+// @Router /v1/tag/{tag_id} [delete]
+func DeleteTag(c *fiber.Ctx) error {
+	// Catch tag ID from URL.
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return response.RespondError(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	// Get claims from JWT.
+	claims, err := utils.ExtractTokenMetadata(c)
+	if err != nil {
+		// Return status 500 and JWT parse error.
+		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	if isError, errorCode, errorMessage := claimCheck(claims, repository.BookTaxonomyCredential); isError {
+		return response.RespondError(c, errorCode, errorMessage)
+	}
+
+	if err := database.TagDB().DeleteTag(id); err != nil {
+		// Return status 404 and error message.
+		return response.RespondError(c, fiber.StatusNotFound, err.Error())
+	}
+
+	// Return status 204 no content.
+	return response.RespondSuccess(c, fiber.StatusNoContent, "")
+}
+
+// GetBookTags godoc
+// @Description Will display tags of a book, sorted by name
+// @Description Require Basic Auth
+// @Description Unpublished books are only shown to the owner, collaborators and users with `book:publish` credential, sending their token instead
+// @Summary Get tags of a book
+// @Tags Tag
+// @Accept json
+// @Produce json
+// @Security BasicAuth
+// @Security ApiKeyAuth
+// @Param book_id path string true "Book ID"
+// @Success 200 {array} models.Tag
+// @Failure 400 {object} response.HTTPError
+// @Failure 404 {object} response.HTTPError
+// @Failure 500 {object} response.HTTPError
+// @Router /v1/book/{book_id}/tags [get]
+func GetBookTags(c *fiber.Ctx) error {
+	// Catch book ID from URL.
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return response.RespondError(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	// Checking, if book with given ID is exists.
+	foundedBook, err := database.BookDB().GetBookById(id)
+	if err != nil {
+		// Return status 404 and book not found error.
+		return response.RespondError(c, fiber.StatusNotFound, "book with given ID not found")
+	}
+
+	// Checking, if caller may see the book.
+	if isError, errorCode, errorMessage := bookVisibleCheck(c, &foundedBook); isError {
+		return response.RespondError(c, errorCode, errorMessage)
+	}
+
+	tags, err := database.TagDB().GetBookTags(foundedBook.ID)
+	if err != nil {
+		// Return status 500 and error message.
+		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	// Return status 200 OK.
+	return response.RespondSuccess(c, fiber.StatusOK, tags)
+}
+
+// SetBookTags godoc
+// @Description Replace all tags of a book with the given existing tags
+// @Description Require valid user token with `book:update` credential
+// @Summary Set tags of a book
+// @Tags Tag
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param book_id path string true "Book ID"
+// @Param models.BookTags body models.BookTags true "Tag slugs"
+// @Param reason query string false "Required when updating a book of another user with `book:update:any`"
+// @Success 200 {array} models.Tag
+// @Failure 400 {object} response.HTTPError
+// @Failure 401 {object} response.HTTPError
+// @Failure 403 {object} response.HTTPError
+// @Failure 404 {object} response.HTTPError
+// @Failure 500 {object} response.HTTPError
+// @Router /v1/book/{book_id}/tags [put]
+func SetBookTags(c *fiber.Ctx) error {
+	// Catch book ID from URL.
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return response.RespondError(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	// Get claims from JWT.
+	claims, err := utils.ExtractTokenMetadata(c)
+	if err != nil {
+		// Return status 500 and JWT parse error.
+		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	if isError, errorCode, errorMessage := claimCheck(claims, repository.BookUpdateCredential); isError {
+		return response.RespondError(c, errorCode, errorMessage)
+	}
+
+	// Create new BookTags struct
+	bookTags := &models.BookTags{}
+
+	// Check, if received JSON data is valid.
+	if err := c.BodyParser(bookTags); err != nil {
+		// Return status 400 and error message.
+		return response.RespondError(c, fiber.StatusBadRequest, "unable to parse request body")
+	}
+
+	// Validate tags fields.
+	validate := utils.NewValidator()
+	if err := validate.Struct(bookTags); err != nil {
+		// Return, if some fields are not valid.
+		return response.RespondError(c, fiber.StatusBadRequest, utils.ValidatorErrors(err))
+	}
+
+	// Checking, if book with given ID is exists.
+	foundedBook, err := database.BookDB().GetBookById(id)
+	if err != nil {
+		// Return status 404 and book not found error.
+		return response.RespondError(c, fiber.StatusNotFound, "book with given ID not found")
+	}
+
+	// Only the owner and editors can update the book, others need an audited override.
+	auditLog, isError, errorCode, errorMessage := bookPolicyCheck(c, &foundedBook, claims, bookActionUpdate)
+	if isError {
+		return response.RespondError(c, errorCode, errorMessage)
+	}
+
+	// Checking, if all tags exist.
+	db := database.TagDB()
+	tags, err := db.GetTagsBySlugs(bookTags.Tags)
+	if err != nil {
+		// Return status 500 and error message.
+		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+	}
+	if missing := missingSlugs(bookTags.Tags, tagSlugs(tags)); len(missing) > 0 {
+		// Return status 400 and error message.
+		return response.RespondError(c, fiber.StatusBadRequest, "tags not found: "+strings.Join(missing, ", "))
+	}
+
+	tagIDs := make([]uuid.UUID, 0, len(tags))
+	for _, tag := range tags {
+		tagIDs = append(tagIDs, tag.ID)
+	}
+	// Replace the tags of the book and record the override with them.
+	err = database.Transaction(func(tx *gorm.DB) error {
+		if err := (&queries.TagQueries{DB: tx}).SetBookTags(foundedBook.ID, tagIDs); err != nil {
+			return err
+		}
+		return createBookAuditLog(tx, auditLog)
+	})
+	if err != nil {
+		// Return status 500 and error message.
+		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	// Return status 200 OK.
+	return response.RespondSuccess(c, fiber.StatusOK, tags)
+}
+
+// tagSlugs func for listing slugs of given tags.
+func tagSlugs(tags []models.Tag) []string {
+	slugs := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		slugs = append(slugs, tag.Slug)
+	}
+	return slugs
+}
+
+// missingSlugs func for listing wanted slugs that were not found.
+func missingSlugs(wanted, found []string) []string {
+	foundSet := make(map[string]bool, len(found))
+	for _, slug := range found {
+		foundSet[slug] = true
+	}
+
+	missing := []string{}
+	for _, slug := range wanted {
+		if !foundSet[slug] {
+			missing = append(missing, slug)
+			foundSet[slug] = true
+		}
+	}
+	return missing
+}
@@ -0,0 +1,177 @@
+package controllers
+
+import (
+	"errors"
+	"time"
+
+	"github.com/aryanicosa/go-fiber-rest-api/app/models"
+	"github.com/aryanicosa/go-fiber-rest-api/app/queries"
+	"github.com/aryanicosa/go-fiber-rest-api/pkg/repository"
+	"github.com/aryanicosa/go-fiber-rest-api/pkg/response"
+	"github.com/aryanicosa/go-fiber-rest-api/pkg/utils"
+	"github.com/aryanicosa/go-fiber-rest-api/platform/database"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// errUserBlocked message for users that are not allowed to sign in anymore.
+const errUserBlocked = "user is blocked"
+
+// ChangeUserPassword godoc
+// @Description Change the password of the current user
+// @Description Every token issued to the user so far stops working, the user has to sign in again
+// @Description Require valid user token
+// @Summary Change own password
+// @Tags User
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param models.ChangePassword body models.ChangePassword true "Current and new password"
+// @Success 204
+// @Failure 400 {object} response.HTTPError
+// @Failure 401 {object} response.HTTPError
+// @Failure 404 {object} response.HTTPError
+// @Failure 500 {object} response.HTTPError
+// @Router /v1/user/password [put]
+func ChangeUserPassword(c *fiber.Ctx) error {
+	// Get claims from JWT.
+	claims, err := utils.ExtractTokenMetadata(c)
+	if err != nil {
+		// Return status 500 and JWT parse error.
+		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	// Create a new change password struct.
+	changePassword := &models.ChangePassword{}
+
+	// Checking received data from JSON body.
+	if err := c.BodyParser(changePassword); err != nil {
+		// Return status 400 and error message.
+		return response.RespondError(c, fiber.StatusBadRequest, "unable to parse request body")
+	}
+
+	// Validate change password fields.
+	if err := utils.NewValidator().Struct(changePassword); err != nil {
+		// Return, if some fields are not valid.
+		return response.RespondError(c, fiber.StatusBadRequest, utils.ValidatorErrors(err))
+	}
+
+	// Get user by ID.
+	db := database.UserDB()
+	foundedUser, err := db.GetUserByID(claims.UserID)
+	if err != nil {
+		// Return, if user not found.
+		return response.RespondError(c, fiber.StatusNotFound, "user with the given ID is not found")
+	}
+
+	// Compare given current password with stored in found user.
+	if !utils.ComparePasswords(foundedUser.PasswordHash, changePassword.CurrentPassword) {
+		// Return status 401 and error message.
+		return response.RespondError(c, fiber.StatusUnauthorized, "wrong current password")
+	}
+
+	// Set the new password hash.
+	foundedUser.PasswordHash = utils.GeneratePassword(changePassword.NewPassword)
+	foundedUser.UpdatedAt = time.Now()
+	if err := db.UpdateUserPassword(&foundedUser); err != nil {
+		// Return status 500 and error message.
+		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	// Sign the user out everywhere, tokens issued with the old password stop working.
+	if err := revokeUserTokens(foundedUser.ID); err != nil {
+		// Return status 500 and Redis connection error.
+		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	// Return status 204 no content.
+	return response.RespondSuccess(c, fiber.StatusNoContent, "")
+}
+
+// UpdateUserStatus godoc
+// @Description Block or unblock a user, a blocked user is signed out everywhere and can not sign in
+// @Description The last active user with `role:manage` can not be blocked
+// @Description Require valid user token with user:manage credential
+// @Summary Block or unblock user
+// @Tags User
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path string true "User ID"
+// @Param models.UpdateUserStatus body models.UpdateUserStatus true "User status"
+// @Success 200 {object} models.User
+// @Failure 400 {object} response.HTTPError
+// @Failure 401 {object} response.HTTPError
+// @Failure 403 {object} response.HTTPError
+// @Failure 404 {object} response.HTTPError
+// @Failure 409 {object} response.HTTPError
+// @Failure 500 {object} response.HTTPError
+// @Router /v1/user/{id}/status [put]
+func UpdateUserStatus(c *fiber.Ctx) error {
+	// Get claims from JWT.
+	claims, err := utils.ExtractTokenMetadata(c)
+	if err != nil {
+		// Return status 500 and JWT parse error.
+		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	// Set credential `user:manage` from JWT data of current user.
+	if isError, errorCode, errorMessage := claimCheck(claims, repository.UserManageCredential); isError {
+		return response.RespondError(c, errorCode, errorMessage)
+	}
+
+	// Catch user ID from URL.
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return response.RespondError(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	// Create a new user status struct.
+	status := &models.UpdateUserStatus{}
+
+	// Checking received data from JSON body.
+	if err := c.BodyParser(status); err != nil {
+		// Return status 400 and error message.
+		return response.RespondError(c, fiber.StatusBadRequest, "unable to parse request body")
+	}
+
+	// Validate user status fields.
+	if err := utils.NewValidator().Struct(status); err != nil {
+		// Return, if some fields are not valid.
+		return response.RespondError(c, fiber.StatusBadRequest, utils.ValidatorErrors(err))
+	}
+
+	// Get user by ID.
+	db := database.UserDB()
+	foundedUser, err := db.GetUserByID(id)
+	if err != nil {
+		// Return, if user not found.
+		return response.RespondError(c, fiber.StatusNotFound, "user with the given ID is not found")
+	}
+
+	// Set the new user status.
+	foundedUser.UserStatus = *status.UserStatus
+	foundedUser.UpdatedAt = time.Now()
+	if err := db.UpdateUserStatus(&foundedUser); err != nil {
+		if errors.Is(err, queries.ErrLastRoleManager) {
+			// Return status 409 and error message.
+			return response.RespondError(c, fiber.StatusConflict, err.Error())
+		}
+		// Return status 500 and error message.
+		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	// Sign a blocked user out everywhere, its tokens stop working at once.
+	if foundedUser.UserStatus == 0 {
+		if err := revokeUserTokens(foundedUser.ID); err != nil {
+			// Return status 500 and Redis connection error.
+			return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+		}
+	}
+
+	// Delete password hash field from JSON view.
+	foundedUser.PasswordHash = ""
+
+	// Return status 200 OK.
+	return response.RespondSuccess(c, fiber.StatusOK, foundedUser)
+}
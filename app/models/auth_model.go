@@ -11,4 +11,16 @@ type SignUp struct {
 type SignIn struct {
 	Email    string `json:"email" validate:"required,email,lte=255"`
 	Password string `json:"password" validate:"required,lte=255"`
+	Device   string `json:"device" validate:"lte=100"` // name of the session, the User-Agent if empty
+}
+
+// ChangePassword struct to describe changing the password of the current user.
+type ChangePassword struct {
+	CurrentPassword string `json:"current_password" validate:"required,lte=255"`
+	NewPassword     string `json:"new_password" validate:"required,lte=255"`
+}
+
+// UpdateUserStatus struct to describe blocking or unblocking a user.
+type UpdateUserStatus struct {
+	UserStatus *int `json:"user_status" validate:"required,oneof=0 1"` // 0 == blocked, 1 == active
 }
@@ -0,0 +1,51 @@
+package models
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+// Author struct to describe a person credited on books.
+type Author struct {
+	ID        uuid.UUID `json:"id" validate:"uuid"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Name      string    `json:"name" validate:"required,lte=255"`
+	Bio       string    `json:"bio" validate:"lte=5000"`
+	Website   string    `json:"website" validate:"omitempty,url,lte=255"`
+	BirthYear *int      `json:"birth_year,omitempty" validate:"omitempty,min=0,max=9999"`
+}
+
+// BookAuthor struct to describe an author credited on a book, in the order of the credits.
+type BookAuthor struct {
+	Author
+	Role     string `json:"role"`
+	Position int    `json:"position"`
+}
+
+// BookAuthorCredit struct to describe one credit of a book by author ID.
+type BookAuthorCredit struct {
+	AuthorID uuid.UUID `json:"author_id" validate:"required"`
+	Role     string    `json:"role" validate:"required,oneof=author editor translator"`
+}
+
+// BookAuthors struct to describe all credits of a book, in order.
+type BookAuthors struct {
+	Authors []BookAuthorCredit `json:"authors" validate:"required,min=1,max=50,dive"`
+}
+
+// AuthorSummary struct to describe an author and the number of published books credited to them.
+type AuthorSummary struct {
+	ID        uuid.UUID `json:"id"`
+	Name      string    `json:"name"`
+	BookCount int64     `json:"book_count"`
+}
+
+// AllAuthors struct to return all authors.
+type AllAuthors struct {
+	Authors []AuthorSummary `json:"authors"`
+	Count   int64
+	Page    int       `json:"page"`
+	Limit   int       `json:"limit"`
+	Links   PageLinks `json:"links"`
+}
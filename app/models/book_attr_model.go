@@ -0,0 +1,42 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"github.com/google/uuid"
+	"time"
+)
+
+// BookAttrDefinition struct to describe an extra attribute of books, registered by admins.
+type BookAttrDefinition struct {
+	ID         uuid.UUID  `json:"id" validate:"uuid"`
+	CreatedAt  time.Time  `json:"created_at"`
+	Name       string     `json:"name" validate:"required,lte=50"`
+	Type       string     `json:"type" validate:"required,oneof=string number integer boolean enum"`
+	Required   bool       `json:"required"`
+	EnumValues EnumValues `json:"enum_values" validate:"lte=100,dive,required,lte=255"`
+}
+
+// EnumValues type to describe the values allowed for an enum attribute.
+type EnumValues []string
+
+// Value make the EnumValues type implement the driver.Valuer interface.
+// This method simply returns the JSON-encoded representation of the list.
+func (e EnumValues) Value() (driver.Value, error) {
+	if e == nil {
+		return []byte("[]"), nil
+	}
+	return json.Marshal([]string(e))
+}
+
+// Scan make the EnumValues type implement the sql.Scanner interface.
+// This method simply decodes a JSON-encoded value into the list.
+func (e *EnumValues) Scan(value interface{}) error {
+	j, ok := value.([]byte)
+	if !ok {
+		return errors.New("type assertion to []byte failed")
+	}
+
+	return json.Unmarshal(j, (*[]string)(e))
+}
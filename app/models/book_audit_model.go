@@ -0,0 +1,18 @@
+package models
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+// BookAuditLog struct to describe a user acting on a book of another user.
+type BookAuditLog struct {
+	ID         uuid.UUID `json:"id" validate:"uuid"`
+	CreatedAt  time.Time `json:"created_at"`
+	BookID     uuid.UUID `json:"book_id" validate:"uuid"`
+	OwnerID    uuid.UUID `json:"owner_id" validate:"uuid"`
+	ActorID    uuid.UUID `json:"actor_id" validate:"uuid"`
+	Action     string    `json:"action" validate:"required,lte=25"`
+	Credential string    `json:"credential" validate:"required,lte=50"`
+	Reason     string    `json:"reason" validate:"required"`
+}
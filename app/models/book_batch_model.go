@@ -0,0 +1,40 @@
+package models
+
+import (
+	"github.com/google/uuid"
+)
+
+// BookBatch struct to describe many book operations sent in one request.
+type BookBatch struct {
+	Atomic     bool                 `json:"atomic"` // apply all operations or none, otherwise each one on its own
+	Operations []BookBatchOperation `json:"operations" validate:"required,min=1,max=100,dive"`
+}
+
+// BookBatchOperation struct to describe one create, update or delete of a batch.
+type BookBatchOperation struct {
+	Op      string     `json:"op" validate:"required,oneof=create update delete"`
+	ID      *uuid.UUID `json:"id,omitempty"`      // book to update or delete
+	Version *int       `json:"version,omitempty"` // expected version of the book, like `If-Match`
+	Reason  string     `json:"reason,omitempty"`  // like the `reason` query param, for books of other users
+	Book    *Book      `json:"book,omitempty" validate:"-"`
+}
+
+// BookBatchReport struct to describe the outcome of a batch, one result per operation in the same order.
+type BookBatchReport struct {
+	Atomic     bool              `json:"atomic"`
+	RolledBack bool              `json:"rolled_back"`
+	Succeeded  int               `json:"succeeded"`
+	Failed     int               `json:"failed"`
+	Results    []BookBatchResult `json:"results"`
+}
+
+// BookBatchResult struct to describe the outcome of one operation of a batch.
+// Status is the HTTP status the single book endpoint would have answered with.
+type BookBatchResult struct {
+	Index  int         `json:"index"`
+	Op     string      `json:"op"`
+	ID     *uuid.UUID  `json:"id,omitempty"`
+	Status int         `json:"status"`
+	Error  interface{} `json:"error,omitempty"`
+	Book   *Book       `json:"book,omitempty"`
+}
@@ -0,0 +1,26 @@
+package models
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+// BookCollaborator struct to describe a user sharing a book with its owner.
+type BookCollaborator struct {
+	BookID           uuid.UUID `json:"book_id" validate:"uuid"`
+	UserID           uuid.UUID `json:"user_id" validate:"uuid"`
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
+	CollaboratorRole string    `json:"collaborator_role" validate:"required,oneof=editor viewer"`
+}
+
+// AddCollaborator struct to describe adding a collaborator to a book.
+type AddCollaborator struct {
+	UserID           uuid.UUID `json:"user_id" validate:"required"`
+	CollaboratorRole string    `json:"collaborator_role" validate:"required,oneof=editor viewer"`
+}
+
+// TransferOwnership struct to describe handing a book over to another user.
+type TransferOwnership struct {
+	UserID uuid.UUID `json:"user_id" validate:"required"`
+}
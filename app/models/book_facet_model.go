@@ -0,0 +1,18 @@
+package models
+
+import "github.com/google/uuid"
+
+// BookFacets struct to describe how many listed books fall in each tag and category.
+type BookFacets struct {
+	Tags       []FacetCount `json:"tags"`
+	Categories []FacetCount `json:"categories"`
+}
+
+// FacetCount struct to describe the number of listed books with one value of a facet.
+// Category counts include books of subcategories, ParentID lets clients nest them.
+type FacetCount struct {
+	Slug     string     `json:"slug"`
+	Name     string     `json:"name"`
+	ParentID *uuid.UUID `json:"parent_id,omitempty"`
+	Count    int64      `json:"count"`
+}
@@ -0,0 +1,15 @@
+package models
+
+// BookImportReport struct to describe the outcome of a bulk books import.
+type BookImportReport struct {
+	Imported int               `json:"imported"`
+	Failed   int               `json:"failed"`
+	Errors   []BookImportError `json:"errors"`
+}
+
+// BookImportError struct to describe why one row of an import was rejected.
+type BookImportError struct {
+	Row    int               `json:"row"`
+	Error  string            `json:"error,omitempty"`
+	Fields map[string]string `json:"fields,omitempty"`
+}
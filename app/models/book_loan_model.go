@@ -0,0 +1,75 @@
+package models
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+// BookCopy struct to describe one physical copy of a book the library lends.
+type BookCopy struct {
+	ID        uuid.UUID `json:"id" validate:"uuid"`
+	CreatedAt time.Time `json:"created_at"`
+	BookID    uuid.UUID `json:"book_id" validate:"uuid"`
+	Barcode   *string   `json:"barcode,omitempty" validate:"omitempty,lte=64"`
+	Notes     string    `json:"notes" validate:"lte=255"`
+}
+
+// SaveBookCopy struct to describe adding a copy of a book.
+type SaveBookCopy struct {
+	Barcode *string `json:"barcode" validate:"omitempty,lte=64"`
+	Notes   string  `json:"notes" validate:"lte=255"`
+}
+
+// BookCopyStatus struct to describe whether a copy is on the shelf, out or set aside.
+type BookCopyStatus struct {
+	BookCopy
+	Status string     `json:"status"`
+	DueAt  *time.Time `json:"due_at,omitempty"`
+}
+
+// BookAvailability struct to return the copies of a book with their status and the length of its holds queue.
+type BookAvailability struct {
+	BookID    uuid.UUID        `json:"book_id"`
+	Copies    []BookCopyStatus `json:"copies"`
+	Available int              `json:"available"`
+	Holds     int64            `json:"holds"`
+}
+
+// BookLoan struct to describe a copy checked out by a user, returned loans are kept as history.
+type BookLoan struct {
+	ID         uuid.UUID  `json:"id" validate:"uuid"`
+	CopyID     uuid.UUID  `json:"copy_id" validate:"uuid"`
+	BookID     uuid.UUID  `json:"book_id" validate:"uuid"`
+	UserID     uuid.UUID  `json:"user_id" validate:"uuid"`
+	LoanedAt   time.Time  `json:"loaned_at"`
+	DueAt      time.Time  `json:"due_at"`
+	ReturnedAt *time.Time `json:"returned_at,omitempty"`
+	Renewals   int        `json:"renewals"`
+}
+
+// BookHold struct to describe a user queueing for a book while all its copies are out.
+// Once a copy is returned it is set aside for the oldest hold, which is then ready.
+type BookHold struct {
+	ID        uuid.UUID  `json:"id" validate:"uuid"`
+	CreatedAt time.Time  `json:"created_at"`
+	BookID    uuid.UUID  `json:"book_id" validate:"uuid"`
+	UserID    uuid.UUID  `json:"user_id" validate:"uuid"`
+	CopyID    *uuid.UUID `json:"copy_id,omitempty"`
+	ReadyAt   *time.Time `json:"ready_at,omitempty"`
+	Position  int64      `json:"position" gorm:"->"` // place in the queue, 0 once ready
+}
+
+// UserLoans struct to return the current loans and holds of a user.
+type UserLoans struct {
+	Loans []BookLoan `json:"loans"`
+	Holds []BookHold `json:"holds"`
+}
+
+// AllBookLoans struct to return one page of loans.
+type AllBookLoans struct {
+	Loans []BookLoan `json:"loans"`
+	Count int64
+	Page  int       `json:"page"`
+	Limit int       `json:"limit"`
+	Links PageLinks `json:"links"`
+}
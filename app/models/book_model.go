@@ -12,26 +12,94 @@ import (
 type AllBooks struct {
 	Books []BookForPublic `json:"books"`
 	Count int64
+	Page  int       `json:"page,omitempty"`
+	Limit int       `json:"limit"`
+	Links PageLinks `json:"links"`
+
+	// Facets are counted over all books matching the filters, not only the page.
+	Facets *BookFacets `json:"facets,omitempty"`
+}
+
+// BookFilter struct to describe filters, sort and paging of a books list.
+type BookFilter struct {
+	Trashed    bool
+	Author     string
+	Title      string
+	BookStatus string // empty for any status
+	UserID     *uuid.UUID
+	AuthorID   *uuid.UUID // credited author, in any role
+	RatingMin  *int
+	RatingMax  *int
+	Tags       []string               // books must have all of these tag slugs
+	Category   string                 // slug, books of subcategories match too
+	Attrs      map[string]interface{} // registered attributes books must have these values of
+	Sort       []SortField
+	Pagination
+}
+
+// BookPage struct to describe one page of books returned by a query.
+type BookPage struct {
+	Books   []*Book
+	Total   int64
+	HasMore bool
 }
 
 type BookForPublic struct {
-	ID         uuid.UUID `json:"id" validate:"required,uuid"`
-	Title      string    `json:"title" validate:"required,lte=255"`
-	Author     string    `json:"author" validate:"required,lte=255"`
-	BookStatus int       `json:"book_status" validate:"required,len=1"`
-	BookAttrs  BookAttrs `json:"book_attrs" validate:"required,dive"`
+	ID            uuid.UUID  `json:"id" validate:"required,uuid"`
+	Title         string     `json:"title" validate:"required,lte=255"`
+	Author        string     `json:"author" validate:"required,lte=255"`
+	ISBN          *string    `json:"isbn,omitempty" validate:"omitempty,isbn"`
+	BookStatus    string     `json:"book_status" validate:"required,oneof=draft review published archived"`
+	BookAttrs     BookAttrs  `json:"book_attrs" validate:"required,dive"`
+	RatingAverage float64    `json:"rating_average"`
+	RatingCount   int        `json:"rating_count"`
+	DeletedAt     *time.Time `json:"deleted_at,omitempty"`
+}
+
+// BookSearch struct to describe a full-text search over books.
+type BookSearch struct {
+	Query string
+	Pagination
+}
+
+// BookSearchResult struct to describe one book matched by a full-text search.
+type BookSearchResult struct {
+	BookForPublic
+	Rank    float32 `json:"rank"`
+	Snippet string  `json:"snippet"`
+}
+
+// BookSearchResults struct to return books matched by a full-text search.
+type BookSearchResults struct {
+	Books []BookSearchResult `json:"books"`
+	Count int64
+	Page  int       `json:"page"`
+	Limit int       `json:"limit"`
+	Links PageLinks `json:"links"`
 }
 
 // Book struct to describe book object.
 type Book struct {
-	ID         uuid.UUID `json:"id" validate:"uuid"`
-	CreatedAt  time.Time `json:"created_at"`
-	UpdatedAt  time.Time `json:"updated_at"`
-	UserID     uuid.UUID `json:"user_id" validate:"uuid"`
-	Title      string    `json:"title" validate:"required,lte=255"`
-	Author     string    `json:"author" validate:"required,lte=255"`
-	BookStatus int       `json:"book_status" validate:"required,len=1"`
-	BookAttrs  BookAttrs `json:"book_attrs" validate:"required,dive"`
+	ID         uuid.UUID  `json:"id" validate:"uuid"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+	UserID     uuid.UUID  `json:"user_id" validate:"uuid"`
+	Title      string     `json:"title" validate:"required,lte=255"`
+	Author     string     `json:"author" validate:"required,lte=255"`
+	ISBN       *string    `json:"isbn,omitempty" validate:"omitempty,isbn"` // stored as ISBN-13, ISBN-10 is accepted
+	BookStatus string     `json:"book_status" validate:"required,oneof=draft review published archived"`
+	BookAttrs  BookAttrs  `json:"book_attrs" validate:"required,dive"`
+	Version    int        `json:"version"`
+	DeletedAt  *time.Time `json:"deleted_at,omitempty"`
+
+	// Rating aggregate of user reviews, maintained by the database and never written by clients.
+	RatingAverage float64 `json:"-"`
+	RatingCount   int     `json:"-"`
+}
+
+// BookTransition struct to describe moving a book to another status of the workflow.
+type BookTransition struct {
+	BookStatus string `json:"book_status" validate:"required,oneof=draft review published archived"`
 }
 
 // BookAttrs struct to describe book attributes.
@@ -39,6 +107,66 @@ type BookAttrs struct {
 	Picture     string `json:"picture"`
 	Description string `json:"description"`
 	Rating      int    `json:"rating" validate:"min=0,max=10"`
+
+	// Thumbnails maps size names to URLs of scaled down covers, set by cover uploads.
+	Thumbnails map[string]string `json:"thumbnails,omitempty"`
+
+	// Extra holds the attributes registered as BookAttrDefinition, next to the built-in ones in JSON.
+	Extra map[string]interface{} `json:"-"`
+}
+
+// BookAttrsBuiltIn lists JSON names of the attributes every book has, they can not be registered.
+var BookAttrsBuiltIn = []string{"picture", "description", "rating", "thumbnails"}
+
+// bookAttrsFields has the fields of BookAttrs without its JSON methods.
+type bookAttrsFields BookAttrs
+
+// MarshalJSON make the BookAttrs struct write its extra attributes next to the built-in ones.
+func (b BookAttrs) MarshalJSON() ([]byte, error) {
+	data, err := json.Marshal(bookAttrsFields(b))
+	if err != nil || len(b.Extra) == 0 {
+		return data, err
+	}
+
+	attrs := map[string]interface{}{}
+	for name, value := range b.Extra {
+		attrs[name] = value
+	}
+	if err := json.Unmarshal(data, &attrs); err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(attrs)
+}
+
+// UnmarshalJSON make the BookAttrs struct keep attributes other than the built-in ones in Extra.
+// Attributes set to null are left out.
+func (b *BookAttrs) UnmarshalJSON(data []byte) error {
+	fields := bookAttrsFields{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+
+	extra := map[string]interface{}{}
+	if err := json.Unmarshal(data, &extra); err != nil {
+		return err
+	}
+	for _, name := range BookAttrsBuiltIn {
+		delete(extra, name)
+	}
+	for name, value := range extra {
+		if value == nil {
+			delete(extra, name)
+		}
+	}
+
+	fields.Extra = nil
+	if len(extra) > 0 {
+		fields.Extra = extra
+	}
+
+	*b = BookAttrs(fields)
+	return nil
 }
 
 // Value make the BookAttrs struct implement the driver.Valuer interface.
@@ -0,0 +1,56 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"github.com/google/uuid"
+	"time"
+)
+
+// BookRevision struct to describe the state of a book after one change.
+type BookRevision struct {
+	ID        uuid.UUID    `json:"id" validate:"uuid"`
+	CreatedAt time.Time    `json:"created_at"`
+	BookID    uuid.UUID    `json:"book_id" validate:"uuid"`
+	Revision  int          `json:"revision"`
+	UserID    uuid.UUID    `json:"user_id" validate:"uuid"`
+	Action    string       `json:"action"`
+	Snapshot  BookSnapshot `json:"snapshot"`
+}
+
+// AllBookRevisions struct to return all revisions of a book.
+type AllBookRevisions struct {
+	Revisions []BookRevision `json:"revisions"`
+	Count     int64
+	Page      int       `json:"page"`
+	Limit     int       `json:"limit"`
+	Links     PageLinks `json:"links"`
+}
+
+// BookRevisionDiff struct to describe changes between two revisions as JSON Patch operations.
+type BookRevisionDiff struct {
+	From       int             `json:"from"`
+	To         int             `json:"to"`
+	Operations json.RawMessage `json:"operations" swaggertype:"array,object"`
+}
+
+// BookSnapshot struct to describe the full row of a book stored in a revision.
+type BookSnapshot Book
+
+// Value make the BookSnapshot struct implement the driver.Valuer interface.
+// This method simply returns the JSON-encoded representation of the struct.
+func (b BookSnapshot) Value() (driver.Value, error) {
+	return json.Marshal(b)
+}
+
+// Scan make the BookSnapshot struct implement the sql.Scanner interface.
+// This method simply decodes a JSON-encoded value into the struct fields.
+func (b *BookSnapshot) Scan(value interface{}) error {
+	j, ok := value.([]byte)
+	if !ok {
+		return errors.New("type assertion to []byte failed")
+	}
+
+	return json.Unmarshal(j, &b)
+}
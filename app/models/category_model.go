@@ -0,0 +1,27 @@
+package models
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+// Category struct to describe a node of the categories tree.
+type Category struct {
+	ID        uuid.UUID  `json:"id" validate:"uuid"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	ParentID  *uuid.UUID `json:"parent_id,omitempty"`
+	Name      string     `json:"name" validate:"required,lte=100"`
+	Slug      string     `json:"slug" validate:"required,lte=100"`
+}
+
+// CategoryNode struct to describe a category with its subcategories.
+type CategoryNode struct {
+	Category
+	Children []*CategoryNode `json:"children"`
+}
+
+// BookCategories struct to describe the categories set on a book, by slug.
+type BookCategories struct {
+	Categories []string `json:"categories" validate:"lte=50,dive,required"`
+}
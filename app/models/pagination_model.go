@@ -0,0 +1,34 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Pagination struct to describe requested page of a list.
+type Pagination struct {
+	Page      int
+	Limit     int
+	UseCursor bool
+	Cursor    *Cursor
+}
+
+// Cursor struct to describe keyset position on `created_at,id`.
+type Cursor struct {
+	CreatedAt time.Time `json:"created_at"`
+	ID        uuid.UUID `json:"id"`
+	Backward  bool      `json:"backward,omitempty"`
+}
+
+// PageLinks struct to describe navigation links of a paginated list.
+type PageLinks struct {
+	Next string `json:"next,omitempty"`
+	Prev string `json:"prev,omitempty"`
+}
+
+// SortField struct to describe one column of a sort expression.
+type SortField struct {
+	Column string
+	Desc   bool
+}
@@ -0,0 +1,70 @@
+package models
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+// ReadingList struct to describe a list of books kept by one user.
+// Every user has the built-in `want_to_read`, `reading` and `finished` lists, and any number of `custom` ones.
+type ReadingList struct {
+	ID         uuid.UUID `json:"id" validate:"uuid"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+	UserID     uuid.UUID `json:"user_id" validate:"uuid"`
+	Name       string    `json:"name" validate:"required,lte=100"`
+	Kind       string    `json:"kind" validate:"required,oneof=want_to_read reading finished custom"`
+	IsPublic   bool      `json:"is_public"`
+	ShareToken *string   `json:"share_token,omitempty"`
+	BookCount  int64     `json:"book_count" gorm:"->"`
+}
+
+// SaveReadingList struct to describe creating a custom list or editing the name and privacy of a list.
+// A public list can be read by anyone knowing its share token.
+type SaveReadingList struct {
+	Name     string `json:"name" validate:"required,lte=100"`
+	IsPublic bool   `json:"is_public"`
+}
+
+// ReadingListBook struct to describe one book in a reading list.
+type ReadingListBook struct {
+	BookID     uuid.UUID `json:"book_id"`
+	Title      string    `json:"title"`
+	Author     string    `json:"author"`
+	BookStatus string    `json:"book_status"`
+	BookAttrs  BookAttrs `json:"book_attrs"`
+	Position   int       `json:"position"`
+	AddedAt    time.Time `json:"added_at"`
+	Percent    *int      `json:"percent,omitempty"` // reading progress of the owner, not shared
+}
+
+// ReadingListWithBooks struct to return a reading list with its books in order.
+type ReadingListWithBooks struct {
+	ReadingList
+	Books []ReadingListBook `json:"books"`
+}
+
+// AddReadingListBook struct to describe adding a book to the end of a reading list.
+type AddReadingListBook struct {
+	BookID uuid.UUID `json:"book_id" validate:"required"`
+}
+
+// OrderReadingList struct to describe the new order of all books of a reading list.
+type OrderReadingList struct {
+	BookIDs []uuid.UUID `json:"book_ids" validate:"required,max=1000"`
+}
+
+// ReadingProgress struct to describe how far a user got in a book.
+type ReadingProgress struct {
+	UserID    uuid.UUID `json:"user_id"`
+	BookID    uuid.UUID `json:"book_id"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Percent   int       `json:"percent" validate:"min=0,max=100"`
+	Page      *int      `json:"page,omitempty" validate:"omitempty,min=0"`
+}
+
+// SaveReadingProgress struct to describe recording the progress of a book.
+type SaveReadingProgress struct {
+	Percent *int `json:"percent" validate:"required,min=0,max=100"`
+	Page    *int `json:"page" validate:"omitempty,min=0"`
+}
@@ -0,0 +1,33 @@
+package models
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+// Review struct to describe the rating and review of a book by one user.
+type Review struct {
+	ID        uuid.UUID `json:"id" validate:"uuid"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	BookID    uuid.UUID `json:"book_id" validate:"uuid"`
+	UserID    uuid.UUID `json:"user_id" validate:"uuid"`
+	Rating    int       `json:"rating" validate:"min=0,max=10"`
+	Body      string    `json:"body" validate:"lte=5000"`
+}
+
+// SaveReview struct to describe creating or editing a review.
+type SaveReview struct {
+	Rating *int   `json:"rating" validate:"required,min=0,max=10"`
+	Body   string `json:"body" validate:"lte=5000"`
+}
+
+// AllReviews struct to return all reviews of a book with their aggregate.
+type AllReviews struct {
+	Reviews       []Review  `json:"reviews"`
+	Count         int64     `json:"count"`
+	RatingAverage float64   `json:"rating_average"`
+	Page          int       `json:"page"`
+	Limit         int       `json:"limit"`
+	Links         PageLinks `json:"links"`
+}
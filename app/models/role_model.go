@@ -0,0 +1,36 @@
+package models
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+// Role struct to describe a named set of permissions, users get the permissions of all their roles.
+type Role struct {
+	ID          uuid.UUID `json:"id" validate:"uuid"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	Name        string    `json:"name" validate:"required,lte=25"`
+	Description string    `json:"description" validate:"lte=255"`
+	Permissions []string  `json:"permissions" gorm:"-"`
+}
+
+// SaveRole struct to describe creating or updating a role, permissions by name.
+type SaveRole struct {
+	Name        string   `json:"name" validate:"required,lte=25"`
+	Description string   `json:"description" validate:"lte=255"`
+	Permissions []string `json:"permissions" validate:"lte=100,dive,required,lte=50"`
+}
+
+// Permission struct to describe a permission carried by name in access tokens.
+type Permission struct {
+	ID          uuid.UUID `json:"id" validate:"uuid"`
+	CreatedAt   time.Time `json:"created_at"`
+	Name        string    `json:"name" validate:"required,lte=50"`
+	Description string    `json:"description" validate:"lte=255"`
+}
+
+// UserRoles struct to describe the roles set on a user, by name.
+type UserRoles struct {
+	Roles []string `json:"roles" validate:"lte=25,dive,required,lte=25"`
+}
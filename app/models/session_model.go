@@ -0,0 +1,24 @@
+package models
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+// Session struct to describe one signed in device of a user, kept in Redis until its refresh token expires.
+// Every renew rotates the refresh token of the session, reusing a rotated one revokes the session.
+type Session struct {
+	ID         uuid.UUID `json:"id"`
+	UserID     uuid.UUID `json:"user_id"`
+	Device     string    `json:"device"`
+	IP         string    `json:"ip"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+// UserSession struct to describe a session listed to its user, marking the one of the current token.
+type UserSession struct {
+	Session
+	Current bool `json:"current"`
+}
@@ -0,0 +1,19 @@
+package models
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+// Tag struct to describe a flat label of books.
+type Tag struct {
+	ID        uuid.UUID `json:"id" validate:"uuid"`
+	CreatedAt time.Time `json:"created_at"`
+	Name      string    `json:"name" validate:"required,lte=50"`
+	Slug      string    `json:"slug" validate:"required,lte=50"`
+}
+
+// BookTags struct to describe the tags set on a book, by slug.
+type BookTags struct {
+	Tags []string `json:"tags" validate:"lte=50,dive,required"`
+}
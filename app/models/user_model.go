@@ -13,5 +13,5 @@ type User struct {
 	Email        string    `json:"email" validate:"required,email,lte=255"`
 	PasswordHash string    `json:"password_hash,omitempty" validate:"required,lte=255"`
 	UserStatus   int       `json:"user_status" validate:"required,len=1"`
-	UserRole     string    `json:"user_role" validate:"required,lte=25"`
+	UserRoles    []string  `json:"user_roles" gorm:"-" validate:"required,dive,lte=25"`
 }
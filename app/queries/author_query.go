@@ -0,0 +1,204 @@
+package queries
+
+import (
+	"errors"
+	"github.com/aryanicosa/go-fiber-rest-api/app/models"
+	"github.com/aryanicosa/go-fiber-rest-api/pkg/repository"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// AuthorQueries struct for queries from Author model.
+type AuthorQueries struct {
+	*gorm.DB
+}
+
+// GetAuthors method for getting one page of authors, sorted by name, with the number of their published books.
+func (q *AuthorQueries) GetAuthors(p *models.Pagination) ([]models.AuthorSummary, int64, error) {
+	// Define authors variables.
+	authors := []models.AuthorSummary{}
+	var total int64
+
+	// Count all authors.
+	err := q.DB.Table("authors").Count(&total).Error
+	if err != nil {
+		// Return empty object and error.
+		return nil, 0, err
+	}
+
+	// Send query to database.
+	err = q.DB.Table("authors").
+		Select(`authors.id, authors.name, (SELECT count(DISTINCT books.id) FROM book_authors
+			JOIN books ON books.id = book_authors.book_id
+			WHERE book_authors.author_id = authors.id AND books.deleted_at IS NULL AND books.book_status = ?) AS book_count`,
+			repository.BookStatusPublished).
+		Order("lower(authors.name) ASC").
+		Order("authors.id ASC").
+		Offset((p.Page - 1) * p.Limit).
+		Limit(p.Limit).
+		Scan(&authors).Error
+	if err != nil {
+		// Return empty object and error.
+		return nil, 0, err
+	}
+
+	// Return query result.
+	return authors, total, nil
+}
+
+// GetAuthor method for getting one author by given ID.
+func (q *AuthorQueries) GetAuthor(id uuid.UUID) (models.Author, error) {
+	// Define author variable.
+	author := models.Author{}
+
+	// Send query to database.
+	result := q.DB.Table("authors").Where("id = ?", id).Limit(1).Find(&author)
+	if result.Error != nil {
+		// Return empty object and error.
+		return author, result.Error
+	}
+	if result.RowsAffected == 0 {
+		// Return empty object and error.
+		return author, errors.New("author not found")
+	}
+
+	// Return query result.
+	return author, nil
+}
+
+// GetAuthorsByIDs method for getting authors with given IDs, missing ones are left out.
+func (q *AuthorQueries) GetAuthorsByIDs(ids []uuid.UUID) ([]models.Author, error) {
+	// Define authors variable.
+	authors := []models.Author{}
+
+	// Send query to database.
+	err := q.DB.Table("authors").Where("id IN ?", ids).Find(&authors).Error
+	if err != nil {
+		// Return empty object and error.
+		return nil, err
+	}
+
+	// Return query result.
+	return authors, nil
+}
+
+// CreateAuthor method for creating author by given Author object.
+func (q *AuthorQueries) CreateAuthor(a *models.Author) error {
+	// Send query to database.
+	err := q.DB.Table("authors").Create(a).Error
+	if err != nil {
+		// Return only error.
+		return err
+	}
+
+	// This query returns nothing.
+	return nil
+}
+
+// UpdateAuthor method for changing the profile of author by given Author object.
+func (q *AuthorQueries) UpdateAuthor(a *models.Author) error {
+	// Send query to database.
+	result := q.DB.Table("authors").Where("id = ?", a.ID).Updates(map[string]interface{}{
+		"updated_at": a.UpdatedAt,
+		"name":       a.Name,
+		"bio":        a.Bio,
+		"website":    a.Website,
+		"birth_year": a.BirthYear,
+	})
+	if result.Error != nil {
+		// Return only error.
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("author not found")
+	}
+
+	// This query returns nothing.
+	return nil
+}
+
+// DeleteAuthor method for deleting author by given ID, books lose the credit but keep their author name.
+func (q *AuthorQueries) DeleteAuthor(id uuid.UUID) error {
+	// Send query to database.
+	result := q.DB.Table("authors").Where("id = ?", id).Delete(&models.Author{})
+	if result.Error != nil {
+		// Return only error.
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("author not found")
+	}
+
+	// This query returns nothing.
+	return nil
+}
+
+// GetBookAuthors method for getting authors credited on given book, in the order of the credits.
+func (q *AuthorQueries) GetBookAuthors(bookID uuid.UUID) ([]models.BookAuthor, error) {
+	// Define authors variable.
+	authors := []models.BookAuthor{}
+
+	// Send query to database.
+	err := q.DB.Table("authors").
+		Joins("JOIN book_authors ON book_authors.author_id = authors.id").
+		Where("book_authors.book_id = ?", bookID).
+		Order("book_authors.position ASC").
+		Order("authors.name ASC").
+		Select("authors.*, book_authors.role, book_authors.position").
+		Find(&authors).Error
+	if err != nil {
+		// Return empty object and error.
+		return nil, err
+	}
+
+	// Return query result.
+	return authors, nil
+}
+
+// SetBookAuthors method for replacing the credits of given book, positions follow the order of credits.
+func (q *AuthorQueries) SetBookAuthors(bookID uuid.UUID, credits []models.BookAuthorCredit) error {
+	// Send query to database.
+	return q.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("DELETE FROM book_authors WHERE book_id = ?", bookID).Error; err != nil {
+			return err
+		}
+		for i, credit := range credits {
+			err := tx.Exec(`INSERT INTO book_authors (book_id, author_id, role, position) VALUES (?, ?, ?, ?)
+				ON CONFLICT DO NOTHING`, bookID, credit.AuthorID, credit.Role, i+1).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// CreditBookAuthorByName method for crediting given book to the author with given name, ignoring case.
+// The oldest author of that name is credited, or a new author is created if there is none.
+func (q *AuthorQueries) CreditBookAuthorByName(bookID uuid.UUID, name string) error {
+	// Send query to database.
+	return q.DB.Exec(`WITH found AS (
+			SELECT id FROM authors WHERE lower(name) = lower(?) ORDER BY created_at ASC, id ASC LIMIT 1
+		), created AS (
+			INSERT INTO authors (id, created_at, updated_at, name)
+			SELECT ?, NOW(), NOW(), ? WHERE NOT EXISTS (SELECT 1 FROM found)
+			RETURNING id
+		)
+		INSERT INTO book_authors (book_id, author_id, role, position)
+		SELECT ?, id, ?, 1 FROM (SELECT id FROM found UNION ALL SELECT id FROM created) AS credited
+		ON CONFLICT DO NOTHING`,
+		name, uuid.New(), name, bookID, repository.AuthorRoleAuthor).Error
+}
+
+// RecreditBookAuthorByName method for replacing the credits with role author of given book by the author with
+// given name, as CreditBookAuthorByName does. Credits in other roles are kept.
+func (q *AuthorQueries) RecreditBookAuthorByName(bookID uuid.UUID, name string) error {
+	// Send query to database.
+	return q.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Exec("DELETE FROM book_authors WHERE book_id = ? AND role = ?", bookID, repository.AuthorRoleAuthor).Error
+		if err != nil {
+			return err
+		}
+		return (&AuthorQueries{DB: tx}).CreditBookAuthorByName(bookID, name)
+	})
+}
@@ -0,0 +1,70 @@
+package queries
+
+import (
+	"errors"
+	"github.com/aryanicosa/go-fiber-rest-api/app/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrBookAttrDefinitionExists is returned when an attribute is registered twice.
+var ErrBookAttrDefinitionExists = errors.New("book attribute with given name already exists")
+
+// BookAttrQueries struct for queries from BookAttrDefinition model.
+type BookAttrQueries struct {
+	*gorm.DB
+}
+
+// GetBookAttrDefinitions method for getting all registered book attributes, sorted by name.
+func (q *BookAttrQueries) GetBookAttrDefinitions() ([]models.BookAttrDefinition, error) {
+	// Define definitions variable.
+	definitions := []models.BookAttrDefinition{}
+
+	// Send query to database.
+	err := q.DB.Table("book_attr_definitions").Order("name ASC").Find(&definitions).Error
+	if err != nil {
+		// Return empty object and error.
+		return nil, err
+	}
+
+	// Return query result.
+	return definitions, nil
+}
+
+// CreateBookAttrDefinition method for registering book attribute by given BookAttrDefinition object.
+func (q *BookAttrQueries) CreateBookAttrDefinition(d *models.BookAttrDefinition) error {
+	// Send query to database, names are unique.
+	result := q.DB.Table("book_attr_definitions").
+		Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "name"}}, DoNothing: true}).
+		Create(d)
+	if result.Error != nil {
+		// Return only error.
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrBookAttrDefinitionExists
+	}
+
+	// This query returns nothing.
+	return nil
+}
+
+// DeleteBookAttrDefinition method for deleting registered book attribute by given ID,
+// books lose their value of the attribute.
+func (q *BookAttrQueries) DeleteBookAttrDefinition(id uuid.UUID) error {
+	// Send query to database.
+	return q.DB.Transaction(func(tx *gorm.DB) error {
+		definition := models.BookAttrDefinition{}
+		if err := tx.Table("book_attr_definitions").Where("id = ?", id).Take(&definition).Error; err != nil {
+			return errors.New("book attribute not found")
+		}
+		if err := tx.Table("book_attr_definitions").Where("id = ?", id).Delete(&definition).Error; err != nil {
+			return err
+		}
+
+		// A new version keeps ETags of changed books from matching.
+		return tx.Exec(`UPDATE books SET book_attrs = book_attrs - ?, version = version + 1
+			WHERE book_attrs -> ? IS NOT NULL`, definition.Name, definition.Name).Error
+	})
+}
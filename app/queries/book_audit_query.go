@@ -0,0 +1,41 @@
+package queries
+
+import (
+	"github.com/aryanicosa/go-fiber-rest-api/app/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// BookAuditQueries struct for queries from BookAuditLog model.
+type BookAuditQueries struct {
+	*gorm.DB
+}
+
+// CreateBookAuditLog method for recording a user acting on a book of another user.
+func (q *BookAuditQueries) CreateBookAuditLog(a *models.BookAuditLog) error {
+	// Send query to database.
+	err := q.DB.Table("book_audit_logs").Create(a).Error
+	if err != nil {
+		// Return only error.
+		return err
+	}
+
+	// This query returns nothing.
+	return nil
+}
+
+// GetBookAuditLogs method for getting audit entries of given book, newest first.
+func (q *BookAuditQueries) GetBookAuditLogs(bookID uuid.UUID) ([]models.BookAuditLog, error) {
+	// Define audit logs variable.
+	logs := []models.BookAuditLog{}
+
+	// Send query to database.
+	err := q.DB.Table("book_audit_logs").Where("book_id = ?", bookID).Order("created_at DESC").Find(&logs).Error
+	if err != nil {
+		// Return empty object and error.
+		return nil, err
+	}
+
+	// Return query result.
+	return logs, nil
+}
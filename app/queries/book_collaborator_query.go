@@ -0,0 +1,112 @@
+package queries
+
+import (
+	"errors"
+	"github.com/aryanicosa/go-fiber-rest-api/app/models"
+	"github.com/aryanicosa/go-fiber-rest-api/pkg/repository"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+// BookCollaboratorQueries struct for queries from BookCollaborator model.
+type BookCollaboratorQueries struct {
+	*gorm.DB
+}
+
+// GetCollaborators method for getting all collaborators of given book.
+func (q *BookCollaboratorQueries) GetCollaborators(bookID uuid.UUID) ([]models.BookCollaborator, error) {
+	// Define collaborators variable.
+	collaborators := []models.BookCollaborator{}
+
+	// Send query to database.
+	err := q.DB.Table("book_collaborators").Where("book_id = ?", bookID).Order("created_at ASC").Find(&collaborators).Error
+	if err != nil {
+		// Return empty object and error.
+		return nil, err
+	}
+
+	// Return query result.
+	return collaborators, nil
+}
+
+// GetCollaborator method for getting one collaborator of given book.
+// It returns nil without error if the user does not collaborate on the book.
+func (q *BookCollaboratorQueries) GetCollaborator(bookID, userID uuid.UUID) (*models.BookCollaborator, error) {
+	// Define collaborators variable.
+	collaborators := []models.BookCollaborator{}
+
+	// Send query to database.
+	err := q.DB.Table("book_collaborators").Where("book_id = ? AND user_id = ?", bookID, userID).Limit(1).Find(&collaborators).Error
+	if err != nil {
+		// Return empty object and error.
+		return nil, err
+	}
+	if len(collaborators) == 0 {
+		return nil, nil
+	}
+
+	// Return query result.
+	return &collaborators[0], nil
+}
+
+// SaveCollaborator method for adding a collaborator or changing the role of an existing one.
+func (q *BookCollaboratorQueries) SaveCollaborator(c *models.BookCollaborator) error {
+	// Send query to database.
+	err := q.DB.Table("book_collaborators").Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "book_id"}, {Name: "user_id"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{"collaborator_role": c.CollaboratorRole, "updated_at": time.Now()}),
+	}).Create(c).Error
+	if err != nil {
+		// Return only error.
+		return err
+	}
+
+	// This query returns nothing.
+	return nil
+}
+
+// DeleteCollaborator method for removing a collaborator from given book.
+func (q *BookCollaboratorQueries) DeleteCollaborator(bookID, userID uuid.UUID) error {
+	// Send query to database.
+	result := q.DB.Table("book_collaborators").Where("book_id = ? AND user_id = ?", bookID, userID).Delete(&models.BookCollaborator{})
+	if result.Error != nil {
+		// Return only error.
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("collaborator not found")
+	}
+
+	// This query returns nothing.
+	return nil
+}
+
+// TransferOwnership method for handing a book over to another user.
+// The new owner stops being a collaborator, the previous owner stays on as an editor.
+func (q *BookCollaboratorQueries) TransferOwnership(bookID, fromUserID, toUserID uuid.UUID) error {
+	return q.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Table("books").Where("id = ? AND user_id = ?", bookID, fromUserID).
+			Updates(map[string]interface{}{"user_id": toUserID, "updated_at": time.Now()})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errors.New("book is not owned by given user")
+		}
+
+		err := tx.Table("book_collaborators").Where("book_id = ? AND user_id = ?", bookID, toUserID).
+			Delete(&models.BookCollaborator{}).Error
+		if err != nil {
+			return err
+		}
+
+		return (&BookCollaboratorQueries{DB: tx}).SaveCollaborator(&models.BookCollaborator{
+			BookID:           bookID,
+			UserID:           fromUserID,
+			CreatedAt:        time.Now(),
+			CollaboratorRole: repository.BookEditorRoleName,
+		})
+	})
+}
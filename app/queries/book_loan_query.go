@@ -0,0 +1,391 @@
+package queries
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/aryanicosa/go-fiber-rest-api/app/models"
+	"github.com/aryanicosa/go-fiber-rest-api/pkg/repository"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrNoCopyAvailable is returned when all copies of a book are out or set aside for holds.
+	ErrNoCopyAvailable = errors.New("all copies of this book are out, place a hold instead")
+
+	// ErrBookCopyOnLoan is returned when a copy that is checked out is deleted.
+	ErrBookCopyOnLoan = errors.New("copy is checked out, it must be returned first")
+
+	// ErrBookHoldExists is returned when a user holds the same book twice.
+	ErrBookHoldExists = errors.New("you already hold this book")
+
+	// ErrBookLoanReturned is returned when a loan that was already returned is changed.
+	ErrBookLoanReturned = errors.New("loan was already returned")
+)
+
+// bookHoldPosition is the select of a hold with its place in the queue of its book, 0 once a copy is set aside.
+const bookHoldPosition = `book_holds.*, CASE WHEN book_holds.copy_id IS NOT NULL THEN 0 ELSE
+	(SELECT count(*) FROM book_holds AS ahead WHERE ahead.book_id = book_holds.book_id AND ahead.copy_id IS NULL
+		AND (ahead.created_at, ahead.id) <= (book_holds.created_at, book_holds.id)) END AS position`
+
+// BookLoanQueries struct for queries from BookCopy, BookLoan and BookHold models.
+type BookLoanQueries struct {
+	*gorm.DB
+}
+
+// GetBookCopies method for getting all copies of given book with their status, oldest first.
+func (q *BookLoanQueries) GetBookCopies(bookID uuid.UUID) ([]models.BookCopyStatus, error) {
+	// Define copies variable.
+	copies := []models.BookCopyStatus{}
+
+	// Send query to database.
+	err := q.DB.Raw(`SELECT book_copies.*, book_loans.due_at,
+			CASE WHEN book_loans.id IS NOT NULL THEN ? WHEN book_holds.id IS NOT NULL THEN ? ELSE ? END AS status
+		FROM book_copies
+		LEFT JOIN book_loans ON book_loans.copy_id = book_copies.id AND book_loans.returned_at IS NULL
+		LEFT JOIN book_holds ON book_holds.copy_id = book_copies.id
+		WHERE book_copies.book_id = ?
+		ORDER BY book_copies.created_at ASC, book_copies.id ASC`,
+		repository.BookCopyOnLoan, repository.BookCopyOnHold, repository.BookCopyAvailable, bookID).
+		Scan(&copies).Error
+	if err != nil {
+		// Return empty object and error.
+		return nil, err
+	}
+
+	// Return query result.
+	return copies, nil
+}
+
+// GetBookCopy method for getting one copy by given ID.
+func (q *BookLoanQueries) GetBookCopy(id uuid.UUID) (models.BookCopy, error) {
+	// Define copy variable.
+	bookCopy := models.BookCopy{}
+
+	// Send query to database.
+	result := q.DB.Table("book_copies").Where("id = ?", id).Limit(1).Find(&bookCopy)
+	if result.Error != nil {
+		// Return empty object and error.
+		return bookCopy, result.Error
+	}
+	if result.RowsAffected == 0 {
+		// Return empty object and error.
+		return bookCopy, errors.New("copy not found")
+	}
+
+	// Return query result.
+	return bookCopy, nil
+}
+
+// CreateBookCopy method for creating copy by given BookCopy object, the copy goes to the oldest hold if there is one.
+func (q *BookLoanQueries) CreateBookCopy(c *models.BookCopy) error {
+	// Send query to database.
+	return q.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Table("book_copies").Create(c).Error; err != nil {
+			return err
+		}
+		return passCopyToNextHold(tx, c.ID, c.BookID)
+	})
+}
+
+// DeleteBookCopy method for deleting copy by given ID, a hold it was set aside for goes back to waiting.
+func (q *BookLoanQueries) DeleteBookCopy(id uuid.UUID) error {
+	// Send query to database.
+	return q.DB.Transaction(func(tx *gorm.DB) error {
+		var loans int64
+		err := tx.Table("book_loans").Where("copy_id = ? AND returned_at IS NULL", id).Count(&loans).Error
+		if err != nil {
+			return err
+		}
+		if loans > 0 {
+			return ErrBookCopyOnLoan
+		}
+
+		err = tx.Exec("UPDATE book_holds SET copy_id = NULL, ready_at = NULL WHERE copy_id = ?", id).Error
+		if err != nil {
+			return err
+		}
+
+		result := tx.Table("book_copies").Where("id = ?", id).Delete(&models.BookCopy{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errors.New("copy not found")
+		}
+		return nil
+	})
+}
+
+// CountAvailableCopies method for counting copies of given book that can be checked out right now.
+func (q *BookLoanQueries) CountAvailableCopies(bookID uuid.UUID) (int64, error) {
+	// Define count variable.
+	var count int64
+
+	// Send query to database.
+	err := q.DB.Table("book_copies").Where("book_id = ?", bookID).
+		Where("NOT EXISTS (SELECT 1 FROM book_loans WHERE book_loans.copy_id = book_copies.id AND book_loans.returned_at IS NULL)").
+		Where("NOT EXISTS (SELECT 1 FROM book_holds WHERE book_holds.copy_id = book_copies.id)").
+		Count(&count).Error
+	if err != nil {
+		// Return empty object and error.
+		return 0, err
+	}
+
+	// Return query result.
+	return count, nil
+}
+
+// CountWaitingHolds method for counting holds of given book still waiting for a copy.
+func (q *BookLoanQueries) CountWaitingHolds(bookID uuid.UUID) (int64, error) {
+	// Define count variable.
+	var count int64
+
+	// Send query to database.
+	err := q.DB.Table("book_holds").Where("book_id = ? AND copy_id IS NULL", bookID).Count(&count).Error
+	if err != nil {
+		// Return empty object and error.
+		return 0, err
+	}
+
+	// Return query result.
+	return count, nil
+}
+
+// GetLoan method for getting one loan by given ID.
+func (q *BookLoanQueries) GetLoan(id uuid.UUID) (models.BookLoan, error) {
+	return q.getLoan("id = ?", id)
+}
+
+// GetActiveLoan method for getting the loan of given book by given user that is not returned yet.
+func (q *BookLoanQueries) GetActiveLoan(bookID, userID uuid.UUID) (models.BookLoan, error) {
+	return q.getLoan("book_id = ? AND user_id = ? AND returned_at IS NULL", bookID, userID)
+}
+
+func (q *BookLoanQueries) getLoan(query string, args ...interface{}) (models.BookLoan, error) {
+	// Define loan variable.
+	loan := models.BookLoan{}
+
+	// Send query to database.
+	result := q.DB.Table("book_loans").Where(query, args...).Limit(1).Find(&loan)
+	if result.Error != nil {
+		// Return empty object and error.
+		return loan, result.Error
+	}
+	if result.RowsAffected == 0 {
+		// Return empty object and error.
+		return loan, errors.New("loan not found")
+	}
+
+	// Return query result.
+	return loan, nil
+}
+
+// GetUserLoans method for getting the loans of given user that are not returned yet, soonest due first.
+func (q *BookLoanQueries) GetUserLoans(userID uuid.UUID) ([]models.BookLoan, error) {
+	// Define loans variable.
+	loans := []models.BookLoan{}
+
+	// Send query to database.
+	err := q.DB.Table("book_loans").Where("user_id = ? AND returned_at IS NULL", userID).
+		Order("due_at ASC").
+		Order("id ASC").
+		Find(&loans).Error
+	if err != nil {
+		// Return empty object and error.
+		return nil, err
+	}
+
+	// Return query result.
+	return loans, nil
+}
+
+// GetOverdueLoans method for getting one page of loans not returned by their due date, longest overdue first.
+func (q *BookLoanQueries) GetOverdueLoans(now time.Time, p *models.Pagination) ([]models.BookLoan, int64, error) {
+	// Define loans variables.
+	loans := []models.BookLoan{}
+	var total int64
+
+	// Count all overdue loans.
+	err := q.DB.Table("book_loans").Where("returned_at IS NULL AND due_at < ?", now).Count(&total).Error
+	if err != nil {
+		// Return empty object and error.
+		return nil, 0, err
+	}
+
+	// Send query to database.
+	err = q.DB.Table("book_loans").Where("returned_at IS NULL AND due_at < ?", now).
+		Order("due_at ASC").
+		Order("id ASC").
+		Offset((p.Page - 1) * p.Limit).
+		Limit(p.Limit).
+		Find(&loans).Error
+	if err != nil {
+		// Return empty object and error.
+		return nil, 0, err
+	}
+
+	// Return query result.
+	return loans, total, nil
+}
+
+// CheckoutBook method for lending a copy of a book by given BookLoan object, the copy is chosen here.
+// A copy set aside for a hold of the borrower is lent first, otherwise any copy on the shelf.
+func (q *BookLoanQueries) CheckoutBook(l *models.BookLoan) error {
+	// Send query to database.
+	return q.DB.Transaction(func(tx *gorm.DB) error {
+		// Take the copy set aside for the borrower, if any.
+		hold := models.BookHold{}
+		result := tx.Table("book_holds").
+			Where("book_id = ? AND user_id = ? AND copy_id IS NOT NULL", l.BookID, l.UserID).
+			Limit(1).Find(&hold)
+		if result.Error != nil {
+			return result.Error
+		}
+
+		var row *sql.Row
+		if result.RowsAffected > 0 {
+			row = tx.Raw(`INSERT INTO book_loans (id, copy_id, book_id, user_id, loaned_at, due_at, renewals)
+				VALUES (?, ?, ?, ?, ?, ?, 0) ON CONFLICT DO NOTHING RETURNING copy_id`,
+				l.ID, hold.CopyID, l.BookID, l.UserID, l.LoanedAt, l.DueAt).Row()
+		} else {
+			row = tx.Raw(`INSERT INTO book_loans (id, copy_id, book_id, user_id, loaned_at, due_at, renewals)
+				SELECT ?, book_copies.id, book_copies.book_id, ?, ?, ?, 0 FROM book_copies
+				WHERE book_copies.book_id = ?
+					AND NOT EXISTS (SELECT 1 FROM book_loans WHERE book_loans.copy_id = book_copies.id AND book_loans.returned_at IS NULL)
+					AND NOT EXISTS (SELECT 1 FROM book_holds WHERE book_holds.copy_id = book_copies.id)
+				ORDER BY book_copies.created_at ASC, book_copies.id ASC
+				LIMIT 1
+				ON CONFLICT DO NOTHING RETURNING copy_id`,
+				l.ID, l.UserID, l.LoanedAt, l.DueAt, l.BookID).Row()
+		}
+		if err := row.Scan(&l.CopyID); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrNoCopyAvailable
+			}
+			return err
+		}
+
+		// The borrower leaves the holds queue.
+		return tx.Exec("DELETE FROM book_holds WHERE book_id = ? AND user_id = ?", l.BookID, l.UserID).Error
+	})
+}
+
+// ReturnLoan method for returning the copy of given loan, the copy goes to the oldest hold if there is one.
+func (q *BookLoanQueries) ReturnLoan(l *models.BookLoan) error {
+	// Send query to database.
+	return q.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Table("book_loans").Where("id = ? AND returned_at IS NULL", l.ID).
+			Update("returned_at", l.ReturnedAt)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrBookLoanReturned
+		}
+		return passCopyToNextHold(tx, l.CopyID, l.BookID)
+	})
+}
+
+// RenewLoan method for moving the due date of given loan, counting the renewal.
+func (q *BookLoanQueries) RenewLoan(l *models.BookLoan) error {
+	// Send query to database.
+	result := q.DB.Table("book_loans").Where("id = ? AND returned_at IS NULL", l.ID).Updates(map[string]interface{}{
+		"due_at":   l.DueAt,
+		"renewals": l.Renewals,
+	})
+	if result.Error != nil {
+		// Return only error.
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrBookLoanReturned
+	}
+
+	// This query returns nothing.
+	return nil
+}
+
+// GetUserHolds method for getting the holds of given user with their place in the queue, oldest first.
+func (q *BookLoanQueries) GetUserHolds(userID uuid.UUID) ([]models.BookHold, error) {
+	// Define holds variable.
+	holds := []models.BookHold{}
+
+	// Send query to database.
+	err := q.DB.Table("book_holds").Select(bookHoldPosition).Where("user_id = ?", userID).
+		Order("created_at ASC").
+		Order("id ASC").
+		Find(&holds).Error
+	if err != nil {
+		// Return empty object and error.
+		return nil, err
+	}
+
+	// Return query result.
+	return holds, nil
+}
+
+// GetHold method for getting the hold of given book by given user with its place in the queue.
+func (q *BookLoanQueries) GetHold(bookID, userID uuid.UUID) (models.BookHold, error) {
+	// Define hold variable.
+	hold := models.BookHold{}
+
+	// Send query to database.
+	result := q.DB.Table("book_holds").Select(bookHoldPosition).
+		Where("book_id = ? AND user_id = ?", bookID, userID).Limit(1).Find(&hold)
+	if result.Error != nil {
+		// Return empty object and error.
+		return hold, result.Error
+	}
+	if result.RowsAffected == 0 {
+		// Return empty object and error.
+		return hold, errors.New("you do not hold this book")
+	}
+
+	// Return query result.
+	return hold, nil
+}
+
+// CreateHold method for queueing for a book by given BookHold object.
+func (q *BookLoanQueries) CreateHold(h *models.BookHold) error {
+	// Send query to database, a user holds a book at most once.
+	result := q.DB.Exec(`INSERT INTO book_holds (id, created_at, book_id, user_id) VALUES (?, ?, ?, ?)
+		ON CONFLICT (book_id, user_id) DO NOTHING`, h.ID, h.CreatedAt, h.BookID, h.UserID)
+	if result.Error != nil {
+		// Return only error.
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrBookHoldExists
+	}
+
+	// This query returns nothing.
+	return nil
+}
+
+// DeleteHold method for leaving the queue by given BookHold object, a copy set aside goes to the next hold.
+func (q *BookLoanQueries) DeleteHold(h *models.BookHold) error {
+	// Send query to database.
+	return q.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Table("book_holds").Where("id = ?", h.ID).Delete(&models.BookHold{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errors.New("you do not hold this book")
+		}
+		if h.CopyID == nil {
+			return nil
+		}
+		return passCopyToNextHold(tx, *h.CopyID, h.BookID)
+	})
+}
+
+// passCopyToNextHold func for setting given copy aside for the oldest hold of its book still waiting.
+func passCopyToNextHold(tx *gorm.DB, copyID, bookID uuid.UUID) error {
+	return tx.Exec(`UPDATE book_holds SET copy_id = ?, ready_at = NOW() WHERE id = (
+			SELECT id FROM book_holds WHERE book_id = ? AND copy_id IS NULL ORDER BY created_at ASC, id ASC LIMIT 1 FOR UPDATE
+		)`, copyID, bookID).Error
+}
@@ -1,10 +1,16 @@
 package queries
 
 import (
+	"encoding/json"
 	"errors"
 	"github.com/aryanicosa/go-fiber-rest-api/app/models"
+	"github.com/aryanicosa/go-fiber-rest-api/pkg/repository"
 	"github.com/google/uuid"
+	"github.com/jackc/pgconn"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"strings"
+	"time"
 )
 
 // BookQueries struct for queries from Book model.
@@ -12,98 +18,474 @@ type BookQueries struct {
 	*gorm.DB
 }
 
-// CreateBook method for creating book by given Book object.
+// ErrBookISBNExists is returned when a book is saved with the ISBN of another book.
+var ErrBookISBNExists = errors.New("book with given ISBN already exists")
+
+// isbnExists func for mapping a unique violation of the `books_isbn` index to ErrBookISBNExists,
+// another writer may take the ISBN after it was checked.
+func isbnExists(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" && pgErr.ConstraintName == "books_isbn" {
+		return ErrBookISBNExists
+	}
+	return err
+}
+
+// CreateBook method for creating book by given Book object, credited to the author of its author name.
+// It returns ErrBookISBNExists if another book has the same ISBN.
 func (q *BookQueries) CreateBook(b *models.Book) error {
 	// Send query to database.
-	err := q.DB.Table("books").Create(&models.Book{
-		ID:         b.ID,
-		CreatedAt:  b.CreatedAt,
-		UpdatedAt:  b.UpdatedAt,
-		UserID:     b.UserID,
-		Title:      b.Title,
-		Author:     b.Author,
-		BookStatus: b.BookStatus,
-		BookAttrs:  b.BookAttrs,
-	}).Error
+	return q.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Table("books").Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "isbn"}},
+			DoNothing: true,
+		}).Create(&models.Book{
+			ID:         b.ID,
+			CreatedAt:  b.CreatedAt,
+			UpdatedAt:  b.UpdatedAt,
+			UserID:     b.UserID,
+			Title:      b.Title,
+			Author:     b.Author,
+			ISBN:       b.ISBN,
+			BookStatus: b.BookStatus,
+			BookAttrs:  b.BookAttrs,
+			Version:    1,
+		})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrBookISBNExists
+		}
+
+		authors := &AuthorQueries{DB: tx}
+		return authors.CreditBookAuthorByName(b.ID, b.Author)
+	})
+}
+
+// CreateBooks method for creating many books at once by given user.
+// Books, their author credits and first revisions are written in one transaction, so either all of them are created or none.
+func (q *BookQueries) CreateBooks(books []*models.Book, userID uuid.UUID) error {
+	rows := make([]models.Book, 0, len(books))
+	ids := make([]uuid.UUID, 0, len(books))
+	for _, b := range books {
+		rows = append(rows, models.Book{
+			ID:         b.ID,
+			CreatedAt:  b.CreatedAt,
+			UpdatedAt:  b.UpdatedAt,
+			UserID:     b.UserID,
+			Title:      b.Title,
+			Author:     b.Author,
+			ISBN:       b.ISBN,
+			BookStatus: b.BookStatus,
+			BookAttrs:  b.BookAttrs,
+			Version:    1,
+		})
+		ids = append(ids, b.ID)
+	}
+
+	// Send query to database.
+	return q.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Table("books").Create(&rows).Error; err != nil {
+			return err
+		}
+
+		authors := &AuthorQueries{DB: tx}
+		for _, b := range books {
+			if err := authors.CreditBookAuthorByName(b.ID, b.Author); err != nil {
+				return err
+			}
+		}
+
+		revisions := &BookRevisionQueries{DB: tx}
+		return revisions.CreateBookRevisions(ids, userID, repository.BookCreateAction)
+	})
+}
+
+// ExportBooks method for reading all books matching given filter one by one, without holding them in memory.
+// Paging of the filter is ignored, each book is passed to fn in sort order until fn returns an error.
+func (q *BookQueries) ExportBooks(f *models.BookFilter, fn func(*models.Book) error) error {
+	// Send query to database.
+	rows, err := q.DB.Table("books").Scopes(bookFilterScope(f), bookSortScope(f)).Rows()
 	if err != nil {
 		// Return only error.
 		return err
 	}
+	defer rows.Close()
 
-	// This query returns nothing.
-	return nil
+	for rows.Next() {
+		book := &models.Book{}
+		if err := q.DB.ScanRows(rows, book); err != nil {
+			return err
+		}
+		if err := fn(book); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
 }
 
-// GetBooks method for getting all books.
-func (q *BookQueries) GetBooks() ([]*models.Book, error) {
-	// Define books variable.
-	books := []*models.Book{}
+// BookSortColumns maps sortable fields of a books list to SQL expressions.
+var BookSortColumns = map[string]string{
+	"title":          "title",
+	"author":         "author",
+	"book_status":    "book_status",
+	"created_at":     "created_at",
+	"updated_at":     "updated_at",
+	"rating":         "(book_attrs->>'rating')::int",
+	"rating_average": "rating_average",
+}
 
-	// Send query to database.
-	err := q.DB.Table("books").Find(&books).Error
+// GetBooks method for getting one page of books by given filter.
+func (q *BookQueries) GetBooks(f *models.BookFilter) (*models.BookPage, error) {
+	// Define page variable.
+	page := &models.BookPage{Books: []*models.Book{}}
+
+	// Count all books matching the filter.
+	err := q.DB.Table("books").Scopes(bookFilterScope(f)).Count(&page.Total).Error
 	if err != nil {
 		// Return empty object and error.
 		return nil, err
 	}
 
+	tx := q.DB.Table("books").Scopes(bookFilterScope(f))
+
+	if f.UseCursor {
+		// Keyset paging walks `created_at,id` and fetches one extra row to know if there are more.
+		desc := len(f.Sort) > 0 && f.Sort[0].Desc
+		backward := f.Cursor != nil && f.Cursor.Backward
+		if backward {
+			desc = !desc
+		}
+
+		if f.Cursor != nil {
+			operator := ">"
+			if desc {
+				operator = "<"
+			}
+			tx = tx.Where("(created_at, id) "+operator+" (?, ?)", f.Cursor.CreatedAt, f.Cursor.ID)
+		}
+
+		direction := "ASC"
+		if desc {
+			direction = "DESC"
+		}
+		tx = tx.Order("created_at " + direction).Order("id " + direction).Limit(f.Limit + 1)
+
+		if err := tx.Find(&page.Books).Error; err != nil {
+			return nil, err
+		}
+
+		if len(page.Books) > f.Limit {
+			page.HasMore = true
+			page.Books = page.Books[:f.Limit]
+		}
+
+		// Rows were read in reverse when walking back, restore the requested order.
+		if backward {
+			for i, j := 0, len(page.Books)-1; i < j; i, j = i+1, j-1 {
+				page.Books[i], page.Books[j] = page.Books[j], page.Books[i]
+			}
+		}
+
+		return page, nil
+	}
+
+	err = tx.Scopes(bookSortScope(f)).Offset((f.Page - 1) * f.Limit).Limit(f.Limit).Find(&page.Books).Error
+	if err != nil {
+		return nil, err
+	}
+
 	// Return query result.
-	return books, nil
+	return page, nil
+}
+
+// bookFilterScope func for applying list filters of a books query.
+func bookFilterScope(f *models.BookFilter) func(*gorm.DB) *gorm.DB {
+	return func(tx *gorm.DB) *gorm.DB {
+		if f.Trashed {
+			tx = tx.Where("deleted_at IS NOT NULL")
+		} else {
+			tx = tx.Where("deleted_at IS NULL")
+		}
+		if f.Author != "" {
+			tx = tx.Where("lower(author) = lower(?)", f.Author)
+		}
+		if f.Title != "" {
+			tx = tx.Where("title ILIKE ?", "%"+escapeLike(f.Title)+"%")
+		}
+		if f.BookStatus != "" {
+			tx = tx.Where("book_status = ?", f.BookStatus)
+		}
+		if f.UserID != nil {
+			tx = tx.Where("user_id = ?", *f.UserID)
+		}
+		if f.RatingMin != nil {
+			tx = tx.Where("(book_attrs->>'rating')::int >= ?", *f.RatingMin)
+		}
+		if f.RatingMax != nil {
+			tx = tx.Where("(book_attrs->>'rating')::int <= ?", *f.RatingMax)
+		}
+		for _, tag := range f.Tags {
+			tx = tx.Where(`EXISTS (SELECT 1 FROM book_tags JOIN tags ON tags.id = book_tags.tag_id
+				WHERE book_tags.book_id = books.id AND tags.slug = ?)`, tag)
+		}
+		if len(f.Attrs) > 0 {
+			// Values are plain strings, numbers and booleans, which always encode.
+			attrs, _ := json.Marshal(f.Attrs)
+			tx = tx.Where("book_attrs @> CAST(? AS jsonb)", string(attrs))
+		}
+		if f.AuthorID != nil {
+			tx = tx.Where("EXISTS (SELECT 1 FROM book_authors WHERE book_authors.book_id = books.id AND book_authors.author_id = ?)", *f.AuthorID)
+		}
+		if f.Category != "" {
+			tx = tx.Where(`EXISTS (SELECT 1 FROM book_categories
+				WHERE book_categories.book_id = books.id AND book_categories.category_id IN (`+categorySubtreeSQL+`))`, f.Category)
+		}
+		return tx
+	}
+}
+
+// bookSortScope func for ordering a books query by the sort of given filter.
+func bookSortScope(f *models.BookFilter) func(*gorm.DB) *gorm.DB {
+	return func(tx *gorm.DB) *gorm.DB {
+		for _, field := range f.Sort {
+			direction := "ASC"
+			if field.Desc {
+				direction = "DESC"
+			}
+			tx = tx.Order(BookSortColumns[field.Column] + " " + direction)
+		}
+
+		// Always finish with ID to keep the order stable between pages.
+		return tx.Order("id ASC")
+	}
+}
+
+// escapeLike func for escaping wildcard characters of a LIKE pattern.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
 }
 
-// GetBookById method for getting one book by given ID.
+// SearchBooks method for getting published books matching a full-text query, best match first.
+func (q *BookQueries) SearchBooks(s *models.BookSearch) ([]models.BookSearchResult, int64, error) {
+	// Define results variables.
+	results := []models.BookSearchResult{}
+	var total int64
+
+	// Count all books matching the query.
+	err := q.DB.Table("books").
+		Where("deleted_at IS NULL AND book_status = ?", repository.BookStatusPublished).
+		Where("search_vector @@ websearch_to_tsquery('english', ?)", s.Query).
+		Count(&total).Error
+	if err != nil {
+		// Return empty object and error.
+		return nil, 0, err
+	}
+
+	// Send query to database.
+	rows, err := q.DB.Table("books, websearch_to_tsquery('english', ?) query", s.Query).
+		Select(`books.id, books.title, books.author, books.book_status, books.book_attrs, books.rating_average, books.rating_count,
+			ts_rank(books.search_vector, query) AS rank,
+			ts_headline('english', books.title || ' ' || coalesce(books.book_attrs->>'description', ''), query,
+				'StartSel=<mark>, StopSel=</mark>, MaxFragments=2, MaxWords=30, MinWords=10') AS snippet`).
+		Where("books.deleted_at IS NULL AND books.book_status = ?", repository.BookStatusPublished).
+		Where("books.search_vector @@ query").
+		Order("rank DESC").
+		Order("books.id ASC").
+		Offset((s.Page - 1) * s.Limit).
+		Limit(s.Limit).
+		Rows()
+	if err != nil {
+		// Return empty object and error.
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		result := models.BookSearchResult{}
+		err := rows.Scan(
+			&result.ID,
+			&result.Title,
+			&result.Author,
+			&result.BookStatus,
+			&result.BookAttrs,
+			&result.RatingAverage,
+			&result.RatingCount,
+			&result.Rank,
+			&result.Snippet,
+		)
+		if err != nil {
+			return nil, 0, err
+		}
+		results = append(results, result)
+	}
+
+	// Return query result.
+	return results, total, rows.Err()
+}
+
+// GetBookById method for getting one book by given ID, trashed books are not found.
 func (q *BookQueries) GetBookById(id uuid.UUID) (models.Book, error) {
+	return q.getBook(id, false)
+}
+
+// GetTrashedBookById method for getting one trashed book by given ID.
+func (q *BookQueries) GetTrashedBookById(id uuid.UUID) (models.Book, error) {
+	return q.getBook(id, true)
+}
+
+func (q *BookQueries) getBook(id uuid.UUID, trashed bool) (models.Book, error) {
 	// Define book variable.
 	book := models.Book{}
 
 	// Send query to database.
-	err := q.DB.Table("books", q.DB.Model(&book)).Where("id = ?", id).Find(&book).Error
-	if err != nil {
+	result := q.DB.Table("books").Scopes(bookFilterScope(&models.BookFilter{Trashed: trashed})).
+		Where("id = ?", id).Limit(1).Find(&book)
+	if result.Error != nil {
 		// Return empty object and error.
-		return book, errors.New("unable get user, DB error")
+		return book, errors.New("unable get book, DB error")
+	}
+	if result.RowsAffected == 0 {
+		// Return empty object and error.
+		return book, errors.New("book not found")
 	}
 
 	// Return query result.
 	return book, nil
 }
 
-// GetBooksByAuthor method for getting all books by given author.
-func (q *BookQueries) GetBooksByAuthor(author string) ([]models.Book, error) {
-	// Define books variable.
-	books := []models.Book{}
+// GetBookByISBN method for getting one book by given ISBN-13, trashed books are not found.
+func (q *BookQueries) GetBookByISBN(isbn string) (models.Book, error) {
+	// Define book variable.
+	book := models.Book{}
 
 	// Send query to database.
-	err := q.DB.Table("books", q.DB.Model(&books)).Where("author = ?", author).Find(&books).Error
-	if err != nil {
+	result := q.DB.Table("books").Scopes(bookFilterScope(&models.BookFilter{})).
+		Where("isbn = ?", isbn).Limit(1).Find(&book)
+	if result.Error != nil {
+		// Return empty object and error.
+		return book, errors.New("unable get book, DB error")
+	}
+	if result.RowsAffected == 0 {
 		// Return empty object and error.
-		return books, errors.New("unable get user, DB error")
+		return book, errors.New("book not found")
 	}
 
 	// Return query result.
-	return books, nil
+	return book, nil
 }
 
-// UpdateBook method for updating book by given Book object.
-func (q *BookQueries) UpdateBook(id uuid.UUID, b *models.Book) error {
+// IsISBNTaken method for checking if a book other than given one has given ISBN-13, trashed books included.
+func (q *BookQueries) IsISBNTaken(isbn string, exceptID uuid.UUID) (bool, error) {
+	var count int64
+
 	// Send query to database.
-	err := q.DB.Table("books").Where("id = ?", id).Updates(b).Error
+	err := q.DB.Table("books").Where("isbn = ? AND id <> ?", isbn, exceptID).Count(&count).Error
 	if err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
+// GetBooksByAuthor method for getting one page of books by given author, ignoring case.
+func (q *BookQueries) GetBooksByAuthor(author string, f *models.BookFilter) (*models.BookPage, error) {
+	// Narrow a copy of the filter down to the author.
+	byAuthor := *f
+	byAuthor.Author = author
+
+	return q.GetBooks(&byAuthor)
+}
+
+// ErrBookVersionMismatch is returned when a book was changed since the given version was read.
+var ErrBookVersionMismatch = errors.New("book was modified by someone else, reload it and try again")
+
+// UpdateBook method for updating book by given Book object.
+// The write only happens if the stored version still equals given version and bumps it,
+// b.Version is set to the new version on success. It returns ErrBookISBNExists if another book has the same ISBN.
+func (q *BookQueries) UpdateBook(id uuid.UUID, version int, b *models.Book) error {
+	b.Version = version + 1
+
+	// Send query to database.
+	result := q.DB.Table("books").Where("id = ? AND version = ? AND deleted_at IS NULL", id, version).
+		Omit("id", "user_id", "created_at", "deleted_at", "book_status", "rating_average", "rating_count").Updates(b)
+	if result.Error != nil {
 		// Return only error.
-		return err
+		return isbnExists(result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrBookVersionMismatch
 	}
 
 	// This query returns nothing.
 	return nil
 }
 
-// DeleteBook method for delete book by given ID.
-func (q *BookQueries) DeleteBook(id uuid.UUID) error {
+// PatchBook method for writing only given columns of a book, zero values included.
+// Like UpdateBook it checks and bumps the version, returning the new one, and returns ErrBookISBNExists.
+func (q *BookQueries) PatchBook(id uuid.UUID, version int, columns map[string]interface{}) (int, error) {
+	columns["version"] = gorm.Expr("version + 1")
+
 	// Send query to database.
-	err := q.Table("books").Where("id = ?", id).Delete(&models.Book{}).Error
-	if err != nil {
+	result := q.DB.Table("books").Where("id = ? AND version = ? AND deleted_at IS NULL", id, version).Updates(columns)
+	if result.Error != nil {
 		// Return only error.
-		return err
+		return 0, isbnExists(result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return 0, ErrBookVersionMismatch
+	}
+
+	// Return the new version.
+	return version + 1, nil
+}
+
+// DeleteBook method for moving a book to the trash by given ID.
+// Like UpdateBook it only happens if the stored version still equals given version and bumps it.
+func (q *BookQueries) DeleteBook(id uuid.UUID, version int) error {
+	// Send query to database.
+	result := q.DB.Table("books").Where("id = ? AND version = ? AND deleted_at IS NULL", id, version).
+		Updates(map[string]interface{}{"deleted_at": time.Now(), "version": gorm.Expr("version + 1")})
+	if result.Error != nil {
+		// Return only error.
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrBookVersionMismatch
+	}
+
+	// This query returns nothing.
+	return nil
+}
+
+// RestoreBook method for taking a book out of the trash by given ID.
+func (q *BookQueries) RestoreBook(id uuid.UUID) error {
+	// Send query to database.
+	result := q.DB.Table("books").Where("id = ? AND deleted_at IS NOT NULL", id).Update("deleted_at", nil)
+	if result.Error != nil {
+		// Return only error.
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("book is not in the trash")
 	}
 
 	// This query returns nothing.
 	return nil
 }
+
+// PurgeTrashedBooks method for permanently deleting books trashed before given time.
+// Their revision history is deleted with them, only the audit logs outlive the books.
+func (q *BookQueries) PurgeTrashedBooks(before time.Time) (int64, error) {
+	// Send query to database.
+	result := q.DB.Table("books").Where("deleted_at < ?", before).Delete(&models.Book{})
+	if result.Error != nil {
+		// Return only error.
+		return 0, result.Error
+	}
+
+	// Return the number of purged books.
+	return result.RowsAffected, nil
+}
@@ -0,0 +1,93 @@
+package queries
+
+import (
+	"errors"
+	"github.com/aryanicosa/go-fiber-rest-api/app/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// BookRevisionQueries struct for queries from BookRevision model.
+type BookRevisionQueries struct {
+	*gorm.DB
+}
+
+// CreateBookRevision method for snapshotting the current row of a book as its next revision.
+func (q *BookRevisionQueries) CreateBookRevision(bookID, userID uuid.UUID, action string) error {
+	return q.CreateBookRevisions([]uuid.UUID{bookID}, userID, action)
+}
+
+// CreateBookRevisions method for snapshotting the current rows of books as their next revisions.
+// The books are locked first, so concurrent changes wait for each other instead of taking the same number.
+// The `timestamp` column is tagged as UTC so the snapshot reads back like the row itself,
+// columns maintained by the database are left out as they are not edits.
+func (q *BookRevisionQueries) CreateBookRevisions(bookIDs []uuid.UUID, userID uuid.UUID, action string) error {
+	// Send query to database.
+	return q.DB.Transaction(func(tx *gorm.DB) error {
+		// The lock has to be taken by its own statement, the numbering one reads revisions committed meanwhile.
+		var locked int64
+		err := tx.Raw("SELECT count(*) FROM (SELECT id FROM books WHERE id IN ? ORDER BY id FOR UPDATE) AS locked_books", bookIDs).
+			Scan(&locked).Error
+		if err != nil {
+			return err
+		}
+		if locked != int64(len(bookIDs)) {
+			return errors.New("book not found")
+		}
+
+		return tx.Exec(`INSERT INTO book_revisions (book_id, revision, user_id, action, snapshot)
+			SELECT books.id,
+				coalesce((SELECT max(revision) FROM book_revisions WHERE book_id = books.id), 0) + 1,
+				?, ?,
+				to_jsonb(books) - 'search_vector' - 'rating_average' - 'rating_count' || jsonb_build_object('updated_at', books.updated_at AT TIME ZONE 'UTC')
+			FROM books WHERE books.id IN ?`, userID, action, bookIDs).Error
+	})
+}
+
+// GetBookRevisions method for getting one page of revisions of given book, newest first.
+func (q *BookRevisionQueries) GetBookRevisions(bookID uuid.UUID, p *models.Pagination) ([]models.BookRevision, int64, error) {
+	// Define revisions variables.
+	revisions := []models.BookRevision{}
+	var total int64
+
+	// Count all revisions of the book.
+	err := q.DB.Table("book_revisions").Where("book_id = ?", bookID).Count(&total).Error
+	if err != nil {
+		// Return empty object and error.
+		return nil, 0, err
+	}
+
+	// Send query to database.
+	err = q.DB.Table("book_revisions").Where("book_id = ?", bookID).
+		Order("revision DESC").
+		Offset((p.Page - 1) * p.Limit).
+		Limit(p.Limit).
+		Find(&revisions).Error
+	if err != nil {
+		// Return empty object and error.
+		return nil, 0, err
+	}
+
+	// Return query result.
+	return revisions, total, nil
+}
+
+// GetBookRevision method for getting one revision of given book.
+func (q *BookRevisionQueries) GetBookRevision(bookID uuid.UUID, revision int) (models.BookRevision, error) {
+	// Define revision variable.
+	bookRevision := models.BookRevision{}
+
+	// Send query to database.
+	result := q.DB.Table("book_revisions").Where("book_id = ? AND revision = ?", bookID, revision).Limit(1).Find(&bookRevision)
+	if result.Error != nil {
+		// Return empty object and error.
+		return bookRevision, errors.New("unable get book revision, DB error")
+	}
+	if result.RowsAffected == 0 {
+		// Return empty object and error.
+		return bookRevision, errors.New("book revision not found")
+	}
+
+	// Return query result.
+	return bookRevision, nil
+}
@@ -0,0 +1,210 @@
+package queries
+
+import (
+	"errors"
+	"github.com/aryanicosa/go-fiber-rest-api/app/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// categorySubtreeSQL selects IDs of the category with given slug and of all its subcategories.
+const categorySubtreeSQL = `WITH RECURSIVE subtree AS (
+		SELECT id FROM categories WHERE slug = ?
+		UNION ALL
+		SELECT categories.id FROM categories JOIN subtree ON categories.parent_id = subtree.id
+	) SELECT id FROM subtree`
+
+// ErrCategoryHasChildren is returned when deleting a category that still has subcategories.
+var ErrCategoryHasChildren = errors.New("category has subcategories")
+
+// CategoryQueries struct for queries from Category model.
+type CategoryQueries struct {
+	*gorm.DB
+}
+
+// GetCategories method for getting all categories, sorted by name.
+func (q *CategoryQueries) GetCategories() ([]models.Category, error) {
+	// Define categories variable.
+	categories := []models.Category{}
+
+	// Send query to database.
+	err := q.DB.Table("categories").Order("name ASC").Find(&categories).Error
+	if err != nil {
+		// Return empty object and error.
+		return nil, err
+	}
+
+	// Return query result.
+	return categories, nil
+}
+
+// GetCategory method for getting one category by given ID.
+func (q *CategoryQueries) GetCategory(id uuid.UUID) (models.Category, error) {
+	// Define category variable.
+	category := models.Category{}
+
+	// Send query to database.
+	result := q.DB.Table("categories").Where("id = ?", id).Limit(1).Find(&category)
+	if result.Error != nil {
+		// Return empty object and error.
+		return category, result.Error
+	}
+	if result.RowsAffected == 0 {
+		// Return empty object and error.
+		return category, errors.New("category not found")
+	}
+
+	// Return query result.
+	return category, nil
+}
+
+// GetCategoriesBySlugs method for getting categories with given slugs, missing ones are left out.
+func (q *CategoryQueries) GetCategoriesBySlugs(slugs []string) ([]models.Category, error) {
+	// Define categories variable.
+	categories := []models.Category{}
+
+	// Send query to database.
+	err := q.DB.Table("categories").Where("slug IN ?", slugs).Order("name ASC").Find(&categories).Error
+	if err != nil {
+		// Return empty object and error.
+		return nil, err
+	}
+
+	// Return query result.
+	return categories, nil
+}
+
+// IsCategoryInSubtree method for checking if a category is the root of a subtree or one of its subcategories.
+func (q *CategoryQueries) IsCategoryInSubtree(id uuid.UUID, root *models.Category) (bool, error) {
+	var count int64
+
+	// Send query to database.
+	err := q.DB.Raw("SELECT count(*) FROM ("+categorySubtreeSQL+") subtree WHERE id = ?", root.Slug, id).Scan(&count).Error
+	if err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
+// CreateCategory method for creating category by given Category object.
+func (q *CategoryQueries) CreateCategory(c *models.Category) error {
+	// Send query to database.
+	err := q.DB.Table("categories").Create(c).Error
+	if err != nil {
+		// Return only error.
+		return err
+	}
+
+	// This query returns nothing.
+	return nil
+}
+
+// UpdateCategory method for renaming or moving category by given Category object.
+func (q *CategoryQueries) UpdateCategory(c *models.Category) error {
+	// Send query to database.
+	err := q.DB.Table("categories").Where("id = ?", c.ID).Updates(map[string]interface{}{
+		"name":       c.Name,
+		"slug":       c.Slug,
+		"parent_id":  c.ParentID,
+		"updated_at": c.UpdatedAt,
+	}).Error
+	if err != nil {
+		// Return only error.
+		return err
+	}
+
+	// This query returns nothing.
+	return nil
+}
+
+// DeleteCategory method for deleting category by given ID, books lose the category.
+// Categories with subcategories can not be deleted.
+func (q *CategoryQueries) DeleteCategory(id uuid.UUID) error {
+	var children int64
+	if err := q.DB.Table("categories").Where("parent_id = ?", id).Count(&children).Error; err != nil {
+		return err
+	}
+	if children > 0 {
+		return ErrCategoryHasChildren
+	}
+
+	// Send query to database.
+	result := q.DB.Table("categories").Where("id = ?", id).Delete(&models.Category{})
+	if result.Error != nil {
+		// Return only error.
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("category not found")
+	}
+
+	// This query returns nothing.
+	return nil
+}
+
+// GetBookCategories method for getting categories of given book, sorted by name.
+func (q *CategoryQueries) GetBookCategories(bookID uuid.UUID) ([]models.Category, error) {
+	// Define categories variable.
+	categories := []models.Category{}
+
+	// Send query to database.
+	err := q.DB.Table("categories").
+		Joins("JOIN book_categories ON book_categories.category_id = categories.id").
+		Where("book_categories.book_id = ?", bookID).
+		Order("categories.name ASC").
+		Select("categories.*").
+		Find(&categories).Error
+	if err != nil {
+		// Return empty object and error.
+		return nil, err
+	}
+
+	// Return query result.
+	return categories, nil
+}
+
+// SetBookCategories method for replacing the categories of given book.
+func (q *CategoryQueries) SetBookCategories(bookID uuid.UUID, categoryIDs []uuid.UUID) error {
+	// Send query to database.
+	return q.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("DELETE FROM book_categories WHERE book_id = ?", bookID).Error; err != nil {
+			return err
+		}
+		for _, categoryID := range categoryIDs {
+			if err := tx.Exec("INSERT INTO book_categories (book_id, category_id) VALUES (?, ?)", bookID, categoryID).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// GetCategoryFacets method for counting books matching given filter per category.
+// A book counts once for its categories and each of their parents.
+func (q *CategoryQueries) GetCategoryFacets(f *models.BookFilter) ([]models.FacetCount, error) {
+	// Define facets variable.
+	facets := []models.FacetCount{}
+
+	// Send query to database.
+	err := q.DB.Raw(`WITH RECURSIVE closure AS (
+			SELECT id AS ancestor_id, id AS category_id FROM categories
+			UNION ALL
+			SELECT closure.ancestor_id, categories.id FROM categories JOIN closure ON categories.parent_id = closure.category_id
+		)
+		SELECT categories.slug, categories.name, categories.parent_id, count(DISTINCT book_categories.book_id) AS count
+		FROM closure
+		JOIN book_categories ON book_categories.category_id = closure.category_id
+		JOIN categories ON categories.id = closure.ancestor_id
+		WHERE book_categories.book_id IN (?)
+		GROUP BY categories.id
+		ORDER BY categories.name ASC`, q.DB.Table("books").Select("id").Scopes(bookFilterScope(f))).
+		Scan(&facets).Error
+	if err != nil {
+		// Return empty object and error.
+		return nil, err
+	}
+
+	// Return query result.
+	return facets, nil
+}
@@ -0,0 +1,281 @@
+package queries
+
+import (
+	"errors"
+	"time"
+
+	"github.com/aryanicosa/go-fiber-rest-api/app/models"
+	"github.com/aryanicosa/go-fiber-rest-api/pkg/repository"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ErrReadingListBookExists is returned when a book is added to a reading list it is already in.
+var ErrReadingListBookExists = errors.New("book is already in this reading list")
+
+// readingListBookCount is the select of a reading list with the number of its books.
+const readingListBookCount = `reading_lists.*,
+	(SELECT count(*) FROM reading_list_books WHERE reading_list_books.list_id = reading_lists.id) AS book_count`
+
+// ReadingListQueries struct for queries from ReadingList model.
+type ReadingListQueries struct {
+	*gorm.DB
+}
+
+// CreateDefaultReadingLists method for creating the built-in reading lists given user does not have yet.
+func (q *ReadingListQueries) CreateDefaultReadingLists(userID uuid.UUID) error {
+	for _, list := range repository.ReadingListDefaults {
+		// Send query to database, a user has one list of every built-in kind.
+		err := q.DB.Exec(`INSERT INTO reading_lists (id, created_at, updated_at, user_id, name, kind)
+			VALUES (?, NOW(), NOW(), ?, ?, ?) ON CONFLICT DO NOTHING`,
+			uuid.New(), userID, list.Name, list.Kind).Error
+		if err != nil {
+			// Return only error.
+			return err
+		}
+	}
+
+	// This query returns nothing.
+	return nil
+}
+
+// GetReadingLists method for getting all reading lists of given user, built-in lists first.
+func (q *ReadingListQueries) GetReadingLists(userID uuid.UUID) ([]models.ReadingList, error) {
+	// Define lists variable.
+	lists := []models.ReadingList{}
+
+	// Send query to database.
+	err := q.DB.Table("reading_lists").
+		Select(readingListBookCount).
+		Where("user_id = ?", userID).
+		Order("kind = 'custom' ASC").
+		Order("created_at ASC").
+		Order("id ASC").
+		Find(&lists).Error
+	if err != nil {
+		// Return empty object and error.
+		return nil, err
+	}
+
+	// Return query result.
+	return lists, nil
+}
+
+// GetReadingList method for getting one reading list by given ID.
+func (q *ReadingListQueries) GetReadingList(id uuid.UUID) (models.ReadingList, error) {
+	return q.getReadingList("reading_lists.id = ?", id)
+}
+
+// GetSharedReadingList method for getting one public reading list by given share token.
+func (q *ReadingListQueries) GetSharedReadingList(token string) (models.ReadingList, error) {
+	return q.getReadingList("reading_lists.share_token = ? AND reading_lists.is_public", token)
+}
+
+func (q *ReadingListQueries) getReadingList(query string, arg interface{}) (models.ReadingList, error) {
+	// Define list variable.
+	list := models.ReadingList{}
+
+	// Send query to database.
+	result := q.DB.Table("reading_lists").Select(readingListBookCount).Where(query, arg).Limit(1).Find(&list)
+	if result.Error != nil {
+		// Return empty object and error.
+		return list, result.Error
+	}
+	if result.RowsAffected == 0 {
+		// Return empty object and error.
+		return list, errors.New("reading list not found")
+	}
+
+	// Return query result.
+	return list, nil
+}
+
+// CreateReadingList method for creating reading list by given ReadingList object.
+func (q *ReadingListQueries) CreateReadingList(l *models.ReadingList) error {
+	// Send query to database.
+	err := q.DB.Table("reading_lists").Create(l).Error
+	if err != nil {
+		// Return only error.
+		return err
+	}
+
+	// This query returns nothing.
+	return nil
+}
+
+// UpdateReadingList method for changing the name and privacy of reading list by given ReadingList object.
+func (q *ReadingListQueries) UpdateReadingList(l *models.ReadingList) error {
+	// Send query to database.
+	result := q.DB.Table("reading_lists").Where("id = ?", l.ID).Updates(map[string]interface{}{
+		"updated_at":  l.UpdatedAt,
+		"name":        l.Name,
+		"is_public":   l.IsPublic,
+		"share_token": l.ShareToken,
+	})
+	if result.Error != nil {
+		// Return only error.
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("reading list not found")
+	}
+
+	// This query returns nothing.
+	return nil
+}
+
+// DeleteReadingList method for deleting reading list by given ID, the books themselves are kept.
+func (q *ReadingListQueries) DeleteReadingList(id uuid.UUID) error {
+	// Send query to database.
+	result := q.DB.Table("reading_lists").Where("id = ?", id).Delete(&models.ReadingList{})
+	if result.Error != nil {
+		// Return only error.
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("reading list not found")
+	}
+
+	// This query returns nothing.
+	return nil
+}
+
+// GetReadingListBooks method for getting the books of given reading list in order, trashed books are left out.
+// Shared lists only show published books, the owner also sees the books they can view and their progress.
+func (q *ReadingListQueries) GetReadingListBooks(l *models.ReadingList, shared bool) ([]models.ReadingListBook, error) {
+	// Define books variable.
+	books := []models.ReadingListBook{}
+
+	query := q.DB.Table("reading_list_books").
+		Joins("JOIN books ON books.id = reading_list_books.book_id").
+		Where("reading_list_books.list_id = ? AND books.deleted_at IS NULL", l.ID)
+	if shared {
+		query = query.
+			Select(`books.id AS book_id, books.title, books.author, books.book_status, books.book_attrs,
+				reading_list_books.position, reading_list_books.added_at`).
+			Where("books.book_status = ?", repository.BookStatusPublished)
+	} else {
+		query = query.
+			Select(`books.id AS book_id, books.title, books.author, books.book_status, books.book_attrs,
+				reading_list_books.position, reading_list_books.added_at, reading_progress.percent`).
+			Joins("LEFT JOIN reading_progress ON reading_progress.book_id = books.id AND reading_progress.user_id = ?", l.UserID).
+			Where(`(books.book_status = ? OR books.user_id = ? OR EXISTS (SELECT 1 FROM book_collaborators
+				WHERE book_collaborators.book_id = books.id AND book_collaborators.user_id = ?))`,
+				repository.BookStatusPublished, l.UserID, l.UserID)
+	}
+
+	// Send query to database.
+	err := query.Order("reading_list_books.position ASC").Find(&books).Error
+	if err != nil {
+		// Return empty object and error.
+		return nil, err
+	}
+
+	// Return query result.
+	return books, nil
+}
+
+// GetReadingListBookIDs method for getting the IDs of all books of given reading list, in order.
+func (q *ReadingListQueries) GetReadingListBookIDs(listID uuid.UUID) ([]uuid.UUID, error) {
+	// Define IDs variable.
+	ids := []uuid.UUID{}
+
+	// Send query to database.
+	err := q.DB.Table("reading_list_books").Where("list_id = ?", listID).
+		Order("position ASC").Pluck("book_id", &ids).Error
+	if err != nil {
+		// Return empty object and error.
+		return nil, err
+	}
+
+	// Return query result.
+	return ids, nil
+}
+
+// AddReadingListBook method for adding given book to the end of given reading list.
+func (q *ReadingListQueries) AddReadingListBook(listID, bookID uuid.UUID) error {
+	// Send query to database.
+	result := q.DB.Exec(`INSERT INTO reading_list_books (list_id, book_id, position, added_at)
+		SELECT ?, ?, COALESCE(MAX(position), 0) + 1, NOW() FROM reading_list_books WHERE list_id = ?
+		ON CONFLICT DO NOTHING`, listID, bookID, listID)
+	if result.Error != nil {
+		// Return only error.
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrReadingListBookExists
+	}
+
+	// Keep the list marked as changed.
+	return q.touchReadingList(listID)
+}
+
+// RemoveReadingListBook method for removing given book from given reading list.
+func (q *ReadingListQueries) RemoveReadingListBook(listID, bookID uuid.UUID) error {
+	// Send query to database.
+	result := q.DB.Exec("DELETE FROM reading_list_books WHERE list_id = ? AND book_id = ?", listID, bookID)
+	if result.Error != nil {
+		// Return only error.
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("book is not in this reading list")
+	}
+
+	// Keep the list marked as changed.
+	return q.touchReadingList(listID)
+}
+
+// OrderReadingList method for placing the books of given reading list in the order of given IDs.
+func (q *ReadingListQueries) OrderReadingList(listID uuid.UUID, bookIDs []uuid.UUID) error {
+	// Send query to database.
+	return q.DB.Transaction(func(tx *gorm.DB) error {
+		for i, bookID := range bookIDs {
+			err := tx.Exec("UPDATE reading_list_books SET position = ? WHERE list_id = ? AND book_id = ?",
+				i+1, listID, bookID).Error
+			if err != nil {
+				return err
+			}
+		}
+		return (&ReadingListQueries{DB: tx}).touchReadingList(listID)
+	})
+}
+
+func (q *ReadingListQueries) touchReadingList(listID uuid.UUID) error {
+	return q.DB.Table("reading_lists").Where("id = ?", listID).Update("updated_at", time.Now()).Error
+}
+
+// GetReadingProgress method for getting the progress of given user in given book.
+func (q *ReadingListQueries) GetReadingProgress(userID, bookID uuid.UUID) (models.ReadingProgress, error) {
+	// Define progress variable.
+	progress := models.ReadingProgress{}
+
+	// Send query to database.
+	result := q.DB.Table("reading_progress").Where("user_id = ? AND book_id = ?", userID, bookID).Limit(1).Find(&progress)
+	if result.Error != nil {
+		// Return empty object and error.
+		return progress, result.Error
+	}
+	if result.RowsAffected == 0 {
+		// Return empty object and error.
+		return progress, errors.New("no reading progress for this book")
+	}
+
+	// Return query result.
+	return progress, nil
+}
+
+// SaveReadingProgress method for recording progress by given ReadingProgress object, replacing the previous one.
+func (q *ReadingListQueries) SaveReadingProgress(p *models.ReadingProgress) error {
+	// Send query to database.
+	err := q.DB.Exec(`INSERT INTO reading_progress (user_id, book_id, updated_at, percent, page) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (user_id, book_id) DO UPDATE SET updated_at = EXCLUDED.updated_at, percent = EXCLUDED.percent, page = EXCLUDED.page`,
+		p.UserID, p.BookID, p.UpdatedAt, p.Percent, p.Page).Error
+	if err != nil {
+		// Return only error.
+		return err
+	}
+
+	// This query returns nothing.
+	return nil
+}
@@ -0,0 +1,120 @@
+package queries
+
+import (
+	"errors"
+	"github.com/aryanicosa/go-fiber-rest-api/app/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ErrReviewExists is returned when a user reviews the same book twice.
+var ErrReviewExists = errors.New("book was already reviewed by this user, edit the review instead")
+
+// ReviewQueries struct for queries from Review model.
+// The rating aggregate of books is kept up to date by a database trigger on every write.
+type ReviewQueries struct {
+	*gorm.DB
+}
+
+// GetReviews method for getting one page of reviews of given book, newest first.
+func (q *ReviewQueries) GetReviews(bookID uuid.UUID, p *models.Pagination) ([]models.Review, int64, error) {
+	// Define reviews variables.
+	reviews := []models.Review{}
+	var total int64
+
+	// Count all reviews of the book.
+	err := q.DB.Table("reviews").Where("book_id = ?", bookID).Count(&total).Error
+	if err != nil {
+		// Return empty object and error.
+		return nil, 0, err
+	}
+
+	// Send query to database.
+	err = q.DB.Table("reviews").Where("book_id = ?", bookID).
+		Order("created_at DESC").
+		Order("id ASC").
+		Offset((p.Page - 1) * p.Limit).
+		Limit(p.Limit).
+		Find(&reviews).Error
+	if err != nil {
+		// Return empty object and error.
+		return nil, 0, err
+	}
+
+	// Return query result.
+	return reviews, total, nil
+}
+
+// GetReview method for getting the review of given book by given user.
+func (q *ReviewQueries) GetReview(bookID, userID uuid.UUID) (models.Review, error) {
+	// Define review variable.
+	review := models.Review{}
+
+	// Send query to database.
+	result := q.DB.Table("reviews").Where("book_id = ? AND user_id = ?", bookID, userID).Limit(1).Find(&review)
+	if result.Error != nil {
+		// Return empty object and error.
+		return review, result.Error
+	}
+	if result.RowsAffected == 0 {
+		// Return empty object and error.
+		return review, errors.New("review not found")
+	}
+
+	// Return query result.
+	return review, nil
+}
+
+// CreateReview method for creating review by given Review object.
+func (q *ReviewQueries) CreateReview(r *models.Review) error {
+	// Send query to database, a user reviews a book at most once.
+	result := q.DB.Exec(`INSERT INTO reviews (id, created_at, updated_at, book_id, user_id, rating, body)
+		VALUES (?, ?, ?, ?, ?, ?, ?) ON CONFLICT (book_id, user_id) DO NOTHING`,
+		r.ID, r.CreatedAt, r.UpdatedAt, r.BookID, r.UserID, r.Rating, r.Body)
+	if result.Error != nil {
+		// Return only error.
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrReviewExists
+	}
+
+	// This query returns nothing.
+	return nil
+}
+
+// UpdateReview method for changing rating and body of review by given Review object.
+func (q *ReviewQueries) UpdateReview(r *models.Review) error {
+	// Send query to database.
+	result := q.DB.Table("reviews").Where("id = ?", r.ID).Updates(map[string]interface{}{
+		"rating":     r.Rating,
+		"body":       r.Body,
+		"updated_at": r.UpdatedAt,
+	})
+	if result.Error != nil {
+		// Return only error.
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("review not found")
+	}
+
+	// This query returns nothing.
+	return nil
+}
+
+// DeleteReview method for deleting review by given ID.
+func (q *ReviewQueries) DeleteReview(id uuid.UUID) error {
+	// Send query to database.
+	result := q.DB.Table("reviews").Where("id = ?", id).Delete(&models.Review{})
+	if result.Error != nil {
+		// Return only error.
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("review not found")
+	}
+
+	// This query returns nothing.
+	return nil
+}
@@ -0,0 +1,449 @@
+package queries
+
+import (
+	"errors"
+	"github.com/aryanicosa/go-fiber-rest-api/app/models"
+	"github.com/aryanicosa/go-fiber-rest-api/pkg/repository"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrRoleNotFound error for a role with given ID that does not exist.
+	ErrRoleNotFound = errors.New("role not found")
+
+	// ErrPermissionNotFound error for a permission with given ID that does not exist.
+	ErrPermissionNotFound = errors.New("permission not found")
+
+	// ErrLastRoleManager error for taking `role:manage` from the last role or active user holding it.
+	ErrLastRoleManager = errors.New("the last role or user with `role:manage` can not lose it")
+)
+
+// RoleQueries struct for queries from Role and Permission models.
+type RoleQueries struct {
+	*gorm.DB
+}
+
+// GetRoles method for getting all roles with their permissions, sorted by name.
+func (q *RoleQueries) GetRoles() ([]models.Role, error) {
+	// Define roles variable.
+	roles := []models.Role{}
+
+	// Send query to database.
+	err := q.DB.Table("roles").Order("name ASC").Find(&roles).Error
+	if err != nil {
+		// Return empty object and error.
+		return nil, err
+	}
+
+	if err := q.loadRolePermissions(roles); err != nil {
+		// Return empty object and error.
+		return nil, err
+	}
+
+	// Return query result.
+	return roles, nil
+}
+
+// GetRolesByNames method for getting roles with given names, missing ones are left out.
+func (q *RoleQueries) GetRolesByNames(names []string) ([]models.Role, error) {
+	// Define roles variable.
+	roles := []models.Role{}
+
+	// Send query to database.
+	err := q.DB.Table("roles").Where("name IN ?", names).Order("name ASC").Find(&roles).Error
+	if err != nil {
+		// Return empty object and error.
+		return nil, err
+	}
+
+	// Return query result.
+	return roles, nil
+}
+
+// GetRole method for getting one role with its permissions by given ID.
+func (q *RoleQueries) GetRole(id uuid.UUID) (models.Role, error) {
+	// Define role variable.
+	role := models.Role{}
+
+	// Send query to database.
+	result := q.DB.Table("roles").Where("id = ?", id).Limit(1).Find(&role)
+	if result.Error != nil {
+		// Return empty object and error.
+		return role, result.Error
+	}
+	if result.RowsAffected == 0 {
+		// Return empty object and error.
+		return role, ErrRoleNotFound
+	}
+
+	roles := []models.Role{role}
+	if err := q.loadRolePermissions(roles); err != nil {
+		// Return empty object and error.
+		return role, err
+	}
+
+	// Return query result.
+	return roles[0], nil
+}
+
+// CreateRole method for creating role by given Role object, with its permissions.
+func (q *RoleQueries) CreateRole(r *models.Role) error {
+	// Send query to database.
+	return q.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Exec("INSERT INTO roles (id, created_at, updated_at, name, description) VALUES (?, ?, ?, ?, ?)",
+			r.ID, r.CreatedAt, r.UpdatedAt, r.Name, r.Description).Error
+		if err != nil {
+			return err
+		}
+		return setRolePermissions(tx, r)
+	})
+}
+
+// UpdateRole method for updating role by given Role object, its permissions are replaced.
+func (q *RoleQueries) UpdateRole(r *models.Role) error {
+	// Send query to database.
+	return q.DB.Transaction(func(tx *gorm.DB) error {
+		return keepUserManager(tx, func() error {
+			result := tx.Exec("UPDATE roles SET updated_at = ?, name = ?, description = ? WHERE id = ?",
+				r.UpdatedAt, r.Name, r.Description, r.ID)
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				return ErrRoleNotFound
+			}
+			if !containsName(r.Permissions, repository.RoleManageCredential) {
+				if err := keepRoleManager(tx, r.ID); err != nil {
+					return err
+				}
+			}
+			if err := tx.Exec("DELETE FROM role_permissions WHERE role_id = ?", r.ID).Error; err != nil {
+				return err
+			}
+			return setRolePermissions(tx, r)
+		})
+	})
+}
+
+// DeleteRole method for deleting role by given ID, users lose the role.
+func (q *RoleQueries) DeleteRole(id uuid.UUID) error {
+	// Send query to database.
+	return q.DB.Transaction(func(tx *gorm.DB) error {
+		return keepUserManager(tx, func() error {
+			if err := keepRoleManager(tx, id); err != nil {
+				return err
+			}
+			result := tx.Exec("DELETE FROM roles WHERE id = ?", id)
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				return ErrRoleNotFound
+			}
+			return nil
+		})
+	})
+}
+
+// GetRoleUserIDs method for getting the IDs of the users holding given role.
+func (q *RoleQueries) GetRoleUserIDs(roleID uuid.UUID) ([]uuid.UUID, error) {
+	// Define user IDs variable.
+	userIDs := []uuid.UUID{}
+
+	// Send query to database.
+	err := q.DB.Table("user_roles").Where("role_id = ?", roleID).Pluck("user_id", &userIDs).Error
+	if err != nil {
+		// Return empty object and error.
+		return nil, err
+	}
+
+	// Return query result.
+	return userIDs, nil
+}
+
+// GetPermissions method for getting all permissions, sorted by name.
+func (q *RoleQueries) GetPermissions() ([]models.Permission, error) {
+	// Define permissions variable.
+	permissions := []models.Permission{}
+
+	// Send query to database.
+	err := q.DB.Table("permissions").Order("name ASC").Find(&permissions).Error
+	if err != nil {
+		// Return empty object and error.
+		return nil, err
+	}
+
+	// Return query result.
+	return permissions, nil
+}
+
+// GetPermissionsByNames method for getting permissions with given names, missing ones are left out.
+func (q *RoleQueries) GetPermissionsByNames(names []string) ([]models.Permission, error) {
+	// Define permissions variable.
+	permissions := []models.Permission{}
+
+	// Send query to database.
+	err := q.DB.Table("permissions").Where("name IN ?", names).Order("name ASC").Find(&permissions).Error
+	if err != nil {
+		// Return empty object and error.
+		return nil, err
+	}
+
+	// Return query result.
+	return permissions, nil
+}
+
+// GetPermission method for getting one permission by given ID.
+func (q *RoleQueries) GetPermission(id uuid.UUID) (models.Permission, error) {
+	// Define permission variable.
+	permission := models.Permission{}
+
+	// Send query to database.
+	result := q.DB.Table("permissions").Where("id = ?", id).Limit(1).Find(&permission)
+	if result.Error != nil {
+		// Return empty object and error.
+		return permission, result.Error
+	}
+	if result.RowsAffected == 0 {
+		// Return empty object and error.
+		return permission, ErrPermissionNotFound
+	}
+
+	// Return query result.
+	return permission, nil
+}
+
+// GetPermissionUserIDs method for getting the IDs of the users holding given permission through any role.
+func (q *RoleQueries) GetPermissionUserIDs(permissionID uuid.UUID) ([]uuid.UUID, error) {
+	// Define user IDs variable.
+	userIDs := []uuid.UUID{}
+
+	// Send query to database.
+	err := q.DB.Table("user_roles").
+		Joins("JOIN role_permissions ON role_permissions.role_id = user_roles.role_id").
+		Where("role_permissions.permission_id = ?", permissionID).
+		Distinct().
+		Pluck("user_roles.user_id", &userIDs).Error
+	if err != nil {
+		// Return empty object and error.
+		return nil, err
+	}
+
+	// Return query result.
+	return userIDs, nil
+}
+
+// CreatePermission method for creating permission by given Permission object.
+func (q *RoleQueries) CreatePermission(p *models.Permission) error {
+	// Send query to database.
+	err := q.DB.Table("permissions").Create(p).Error
+	if err != nil {
+		// Return only error.
+		return err
+	}
+
+	// This query returns nothing.
+	return nil
+}
+
+// DeletePermission method for deleting permission by given ID, roles lose the permission.
+func (q *RoleQueries) DeletePermission(id uuid.UUID) error {
+	// Send query to database.
+	result := q.DB.Table("permissions").Where("id = ?", id).Delete(&models.Permission{})
+	if result.Error != nil {
+		// Return only error.
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrPermissionNotFound
+	}
+
+	// This query returns nothing.
+	return nil
+}
+
+// GetUserRoles method for getting the role names of given user, sorted by name.
+func (q *RoleQueries) GetUserRoles(userID uuid.UUID) ([]string, error) {
+	// Define names variable.
+	names := []string{}
+
+	// Send query to database.
+	err := q.DB.Table("roles").
+		Joins("JOIN user_roles ON user_roles.role_id = roles.id").
+		Where("user_roles.user_id = ?", userID).
+		Order("roles.name ASC").
+		Pluck("roles.name", &names).Error
+	if err != nil {
+		// Return empty object and error.
+		return nil, err
+	}
+
+	// Return query result.
+	return names, nil
+}
+
+// GetUserPermissions method for getting the permission names of all roles of given user, sorted by name.
+func (q *RoleQueries) GetUserPermissions(userID uuid.UUID) ([]string, error) {
+	// Define names variable.
+	names := []string{}
+
+	// Send query to database.
+	err := q.DB.Table("permissions").
+		Where("id IN (?)", q.DB.Table("role_permissions").
+			Joins("JOIN user_roles ON user_roles.role_id = role_permissions.role_id").
+			Where("user_roles.user_id = ?", userID).
+			Select("role_permissions.permission_id")).
+		Order("name ASC").
+		Pluck("name", &names).Error
+	if err != nil {
+		// Return empty object and error.
+		return nil, err
+	}
+
+	// Return query result.
+	return names, nil
+}
+
+// SetUserRoles method for replacing the roles of given user, by role names.
+func (q *RoleQueries) SetUserRoles(userID uuid.UUID, names []string) error {
+	// Send query to database.
+	return q.DB.Transaction(func(tx *gorm.DB) error {
+		return keepUserManager(tx, func() error {
+			return setUserRoles(tx, userID, names)
+		})
+	})
+}
+
+// loadRolePermissions method for loading the permission names of given roles.
+func (q *RoleQueries) loadRolePermissions(roles []models.Role) error {
+	if len(roles) == 0 {
+		return nil
+	}
+
+	ids := make([]uuid.UUID, len(roles))
+	for i := range roles {
+		ids[i] = roles[i].ID
+		roles[i].Permissions = []string{}
+	}
+
+	// Define role permissions variable.
+	rolePermissions := []struct {
+		RoleID uuid.UUID
+		Name   string
+	}{}
+
+	// Send query to database.
+	err := q.DB.Table("role_permissions").
+		Joins("JOIN permissions ON permissions.id = role_permissions.permission_id").
+		Where("role_permissions.role_id IN ?", ids).
+		Order("permissions.name ASC").
+		Select("role_permissions.role_id, permissions.name").
+		Find(&rolePermissions).Error
+	if err != nil {
+		return err
+	}
+
+	for _, rolePermission := range rolePermissions {
+		for i := range roles {
+			if roles[i].ID == rolePermission.RoleID {
+				roles[i].Permissions = append(roles[i].Permissions, rolePermission.Name)
+			}
+		}
+	}
+
+	return nil
+}
+
+// setRolePermissions func for adding the permissions of given role in a transaction, by permission names.
+func setRolePermissions(tx *gorm.DB, r *models.Role) error {
+	if len(r.Permissions) == 0 {
+		return nil
+	}
+	return tx.Exec(`INSERT INTO role_permissions (role_id, permission_id)
+		SELECT ?, id FROM permissions WHERE name IN ? ON CONFLICT DO NOTHING`, r.ID, r.Permissions).Error
+}
+
+// setUserRoles func for replacing the roles of given user in a transaction, by role names.
+func setUserRoles(tx *gorm.DB, userID uuid.UUID, names []string) error {
+	if err := tx.Exec("DELETE FROM user_roles WHERE user_id = ?", userID).Error; err != nil {
+		return err
+	}
+	if len(names) == 0 {
+		return nil
+	}
+	return tx.Exec(`INSERT INTO user_roles (user_id, role_id)
+		SELECT ?, id FROM roles WHERE name IN ?`, userID, names).Error
+}
+
+// keepRoleManager func for refusing in a transaction to take `role:manage` from given role, if no other role
+// holds it. The roles holding it are locked, so two of them can not lose it at once.
+func keepRoleManager(tx *gorm.DB, roleID uuid.UUID) error {
+	roleIDs := []uuid.UUID{}
+	err := tx.Raw(`SELECT roles.id FROM roles
+		JOIN role_permissions ON role_permissions.role_id = roles.id
+		JOIN permissions ON permissions.id = role_permissions.permission_id
+		WHERE permissions.name = ? FOR UPDATE OF roles`, repository.RoleManageCredential).Scan(&roleIDs).Error
+	if err != nil {
+		return err
+	}
+
+	holds, others := false, false
+	for _, id := range roleIDs {
+		if id == roleID {
+			holds = true
+		} else {
+			others = true
+		}
+	}
+	if holds && !others {
+		return ErrLastRoleManager
+	}
+
+	return nil
+}
+
+// keepUserManager func for making given change in a transaction, refused if it leaves no active user holding
+// `role:manage`. The users holding it are locked first, so two changes can not take it from them at once.
+func keepUserManager(tx *gorm.DB, change func() error) error {
+	before, err := lockUserManagers(tx)
+	if err != nil {
+		return err
+	}
+	if err := change(); err != nil {
+		return err
+	}
+	after, err := lockUserManagers(tx)
+	if err != nil {
+		return err
+	}
+	if before > 0 && after == 0 {
+		return ErrLastRoleManager
+	}
+
+	return nil
+}
+
+// lockUserManagers func for locking the roles of active users that grant `role:manage` in a transaction, returns
+// how many there are.
+func lockUserManagers(tx *gorm.DB) (int, error) {
+	userIDs := []uuid.UUID{}
+	err := tx.Raw(`SELECT user_roles.user_id FROM user_roles
+		JOIN users ON users.id = user_roles.user_id
+		JOIN role_permissions ON role_permissions.role_id = user_roles.role_id
+		JOIN permissions ON permissions.id = role_permissions.permission_id
+		WHERE permissions.name = ? AND users.user_status = 1 FOR UPDATE OF user_roles, users`,
+		repository.RoleManageCredential).Scan(&userIDs).Error
+
+	return len(userIDs), err
+}
+
+// containsName func for checking given name is among the names.
+func containsName(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,290 @@
+package queries
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aryanicosa/go-fiber-rest-api/app/models"
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrRefreshTokenInvalid is returned when a refresh token is unknown or expired.
+	ErrRefreshTokenInvalid = errors.New("unauthorized, your session was ended earlier")
+
+	// ErrRefreshTokenReused is returned when a rotated refresh token is used again, its session is revoked.
+	ErrRefreshTokenReused = errors.New("unauthorized, refresh token was already used, the session is revoked")
+)
+
+// sessionKeyPrefix is the prefix of the Redis keys of sessions, followed by their ID.
+const sessionKeyPrefix = "session:"
+
+// rotatedRefreshTokenPrefix marks refresh tokens that were exchanged already, kept to detect reuse.
+const rotatedRefreshTokenPrefix = "rotated:"
+
+// useRefreshTokenScript marks a refresh token as rotated and returns the session it belonged to, in one step.
+// Rotated tokens are returned with their mark, so reuse is detected even when two renews race.
+var useRefreshTokenScript = redis.NewScript(`
+local value = redis.call('GET', KEYS[1])
+if not value then
+	return false
+end
+if string.sub(value, 1, string.len(ARGV[1])) == ARGV[1] then
+	return value
+end
+local ttl = redis.call('PTTL', KEYS[1])
+if ttl > 0 then
+	redis.call('SET', KEYS[1], ARGV[1] .. value, 'PX', ttl)
+else
+	redis.call('SET', KEYS[1], ARGV[1] .. value)
+end
+return value
+`)
+
+// addUserSessionScript adds a session to the set of sessions of a user, the set only ever lives longer.
+// It must not expire before the longest living session of the user, whichever session was saved last.
+var addUserSessionScript = redis.NewScript(`
+redis.call('SADD', KEYS[1], ARGV[1])
+if redis.call('PTTL', KEYS[1]) < tonumber(ARGV[2]) then
+	redis.call('PEXPIRE', KEYS[1], ARGV[2])
+end
+return 1
+`)
+
+// SessionQueries struct for queries from Session model.
+type SessionQueries struct {
+	*redis.Client
+}
+
+// GetSession method for getting one session by given ID.
+func (q *SessionQueries) GetSession(id uuid.UUID) (models.Session, error) {
+	// Define session variable.
+	session := models.Session{}
+
+	// Send query to Redis.
+	value, err := q.Client.Get(context.Background(), sessionKey(id)).Bytes()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			// Return empty object and error.
+			return session, errors.New("session not found")
+		}
+		// Return empty object and error.
+		return session, err
+	}
+	if err := json.Unmarshal(value, &session); err != nil {
+		// Return empty object and error.
+		return session, err
+	}
+
+	// Return query result.
+	return session, nil
+}
+
+// GetUserSessions method for getting the active sessions of given user, most recently used first.
+func (q *SessionQueries) GetUserSessions(userID uuid.UUID) ([]models.Session, error) {
+	// Define sessions variable.
+	sessions := []models.Session{}
+
+	// Send query to Redis.
+	ids, err := q.Client.SMembers(context.Background(), userSessionsKey(userID)).Result()
+	if err != nil {
+		// Return empty object and error.
+		return nil, err
+	}
+	if len(ids) == 0 {
+		// Return query result.
+		return sessions, nil
+	}
+
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		keys[i] = sessionKeyPrefix + id
+	}
+	values, err := q.Client.MGet(context.Background(), keys...).Result()
+	if err != nil {
+		// Return empty object and error.
+		return nil, err
+	}
+
+	// Forget sessions that expired meanwhile.
+	expired := []interface{}{}
+	for i, value := range values {
+		s, ok := value.(string)
+		if !ok {
+			expired = append(expired, ids[i])
+			continue
+		}
+		session := models.Session{}
+		if err := json.Unmarshal([]byte(s), &session); err != nil {
+			// Return empty object and error.
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+	if len(expired) > 0 {
+		if err := q.Client.SRem(context.Background(), userSessionsKey(userID), expired...).Err(); err != nil {
+			// Return empty object and error.
+			return nil, err
+		}
+	}
+
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].LastUsedAt.After(sessions[j].LastUsedAt)
+	})
+
+	// Return query result.
+	return sessions, nil
+}
+
+// SaveSession method for storing session by given Session object with its current refresh token.
+// Both expire with the session, the previous refresh token stays marked as rotated until it would have expired.
+// The set of sessions of the user is kept until its last session expires.
+func (q *SessionQueries) SaveSession(s *models.Session, refreshToken string) error {
+	value, err := json.Marshal(s)
+	if err != nil {
+		// Return only error.
+		return err
+	}
+	ttl := time.Until(s.ExpiresAt)
+
+	// Send query to Redis.
+	_, err = q.Client.TxPipelined(context.Background(), func(pipe redis.Pipeliner) error {
+		pipe.Set(context.Background(), sessionKey(s.ID), value, ttl)
+		pipe.Set(context.Background(), refreshTokenKey(refreshToken), s.ID.String(), ttl)
+		addUserSessionScript.Eval(context.Background(), pipe,
+			[]string{userSessionsKey(s.UserID)}, s.ID.String(), ttl.Milliseconds())
+		return nil
+	})
+	if err != nil {
+		// Return only error.
+		return err
+	}
+
+	// This query returns nothing.
+	return nil
+}
+
+// DeleteSession method for revoking given session, its refresh tokens stop working.
+func (q *SessionQueries) DeleteSession(s *models.Session) error {
+	// Send query to Redis.
+	_, err := q.Client.TxPipelined(context.Background(), func(pipe redis.Pipeliner) error {
+		pipe.Del(context.Background(), sessionKey(s.ID))
+		pipe.SRem(context.Background(), userSessionsKey(s.UserID), s.ID.String())
+		return nil
+	})
+	if err != nil {
+		// Return only error.
+		return err
+	}
+
+	// This query returns nothing.
+	return nil
+}
+
+// DeleteUserSessions method for revoking all sessions of given user.
+func (q *SessionQueries) DeleteUserSessions(userID uuid.UUID) error {
+	// Send query to Redis.
+	ids, err := q.Client.SMembers(context.Background(), userSessionsKey(userID)).Result()
+	if err != nil {
+		// Return only error.
+		return err
+	}
+
+	keys := []string{userSessionsKey(userID)}
+	for _, id := range ids {
+		keys = append(keys, sessionKeyPrefix+id)
+	}
+	if err := q.Client.Del(context.Background(), keys...).Err(); err != nil {
+		// Return only error.
+		return err
+	}
+
+	// This query returns nothing.
+	return nil
+}
+
+// UseRefreshToken method for exchanging given refresh token once, returning its session.
+// The token is only exchanged if its session belongs to given user and, unless nil, has given ID, so a request
+// carrying the access token of another user or session does not use it up.
+// A token that was exchanged before revokes its session, as it was likely stolen, its ID is returned with ErrRefreshTokenReused.
+func (q *SessionQueries) UseRefreshToken(refreshToken string, userID, sessionID uuid.UUID) (models.Session, error) {
+	key := refreshTokenKey(refreshToken)
+
+	// Checking, who the session belongs to before the token is used up.
+	session := models.Session{}
+	value, err := q.Client.Get(context.Background(), key).Result()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			// Return empty object and error.
+			return session, ErrRefreshTokenInvalid
+		}
+		// Return empty object and error.
+		return session, err
+	}
+	if !strings.HasPrefix(value, rotatedRefreshTokenPrefix) {
+		id, err := uuid.Parse(value)
+		if err != nil {
+			// Return empty object and error.
+			return session, ErrRefreshTokenInvalid
+		}
+		if session, err = q.GetSession(id); err != nil || session.UserID != userID ||
+			(sessionID != uuid.Nil && sessionID != session.ID) {
+			// Return empty object and error.
+			return models.Session{}, ErrRefreshTokenInvalid
+		}
+	}
+
+	// Send query to Redis.
+	value, err = useRefreshTokenScript.Run(context.Background(), q.Client, []string{key}, rotatedRefreshTokenPrefix).Text()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			// Return empty object and error.
+			return models.Session{}, ErrRefreshTokenInvalid
+		}
+		// Return empty object and error.
+		return models.Session{}, err
+	}
+
+	if strings.HasPrefix(value, rotatedRefreshTokenPrefix) {
+		// Revoke the whole session, every token issued to it since is rejected too.
+		id, err := uuid.Parse(strings.TrimPrefix(value, rotatedRefreshTokenPrefix))
+		if err != nil {
+			// Return empty object and error.
+			return models.Session{}, ErrRefreshTokenReused
+		}
+		if session, err := q.GetSession(id); err == nil {
+			if err := q.DeleteSession(&session); err != nil {
+				// Return empty object and error.
+				return models.Session{}, err
+			}
+		}
+		// Return the revoked session and error.
+		return models.Session{ID: id}, ErrRefreshTokenReused
+	}
+
+	// Return query result.
+	return session, nil
+}
+
+// sessionKey func for the Redis key of a session.
+func sessionKey(id uuid.UUID) string {
+	return sessionKeyPrefix + id.String()
+}
+
+// userSessionsKey func for the Redis key of the set of session IDs of a user.
+func userSessionsKey(userID uuid.UUID) string {
+	return "user_sessions:" + userID.String()
+}
+
+// refreshTokenKey func for the Redis key of a refresh token, only its hash is stored.
+func refreshTokenKey(refreshToken string) string {
+	hash := sha256.Sum256([]byte(refreshToken))
+	return "refresh_token:" + hex.EncodeToString(hash[:])
+}
@@ -0,0 +1,135 @@
+package queries
+
+import (
+	"errors"
+	"github.com/aryanicosa/go-fiber-rest-api/app/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// TagQueries struct for queries from Tag model.
+type TagQueries struct {
+	*gorm.DB
+}
+
+// GetTags method for getting all tags, sorted by name.
+func (q *TagQueries) GetTags() ([]models.Tag, error) {
+	// Define tags variable.
+	tags := []models.Tag{}
+
+	// Send query to database.
+	err := q.DB.Table("tags").Order("name ASC").Find(&tags).Error
+	if err != nil {
+		// Return empty object and error.
+		return nil, err
+	}
+
+	// Return query result.
+	return tags, nil
+}
+
+// GetTagsBySlugs method for getting tags with given slugs, missing ones are left out.
+func (q *TagQueries) GetTagsBySlugs(slugs []string) ([]models.Tag, error) {
+	// Define tags variable.
+	tags := []models.Tag{}
+
+	// Send query to database.
+	err := q.DB.Table("tags").Where("slug IN ?", slugs).Order("name ASC").Find(&tags).Error
+	if err != nil {
+		// Return empty object and error.
+		return nil, err
+	}
+
+	// Return query result.
+	return tags, nil
+}
+
+// CreateTag method for creating tag by given Tag object.
+func (q *TagQueries) CreateTag(t *models.Tag) error {
+	// Send query to database.
+	err := q.DB.Table("tags").Create(t).Error
+	if err != nil {
+		// Return only error.
+		return err
+	}
+
+	// This query returns nothing.
+	return nil
+}
+
+// DeleteTag method for deleting tag by given ID, books lose the tag.
+func (q *TagQueries) DeleteTag(id uuid.UUID) error {
+	// Send query to database.
+	result := q.DB.Table("tags").Where("id = ?", id).Delete(&models.Tag{})
+	if result.Error != nil {
+		// Return only error.
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("tag not found")
+	}
+
+	// This query returns nothing.
+	return nil
+}
+
+// GetBookTags method for getting tags of given book, sorted by name.
+func (q *TagQueries) GetBookTags(bookID uuid.UUID) ([]models.Tag, error) {
+	// Define tags variable.
+	tags := []models.Tag{}
+
+	// Send query to database.
+	err := q.DB.Table("tags").
+		Joins("JOIN book_tags ON book_tags.tag_id = tags.id").
+		Where("book_tags.book_id = ?", bookID).
+		Order("tags.name ASC").
+		Select("tags.*").
+		Find(&tags).Error
+	if err != nil {
+		// Return empty object and error.
+		return nil, err
+	}
+
+	// Return query result.
+	return tags, nil
+}
+
+// SetBookTags method for replacing the tags of given book.
+func (q *TagQueries) SetBookTags(bookID uuid.UUID, tagIDs []uuid.UUID) error {
+	// Send query to database.
+	return q.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("DELETE FROM book_tags WHERE book_id = ?", bookID).Error; err != nil {
+			return err
+		}
+		for _, tagID := range tagIDs {
+			if err := tx.Exec("INSERT INTO book_tags (book_id, tag_id) VALUES (?, ?)", bookID, tagID).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// GetTagFacets method for counting books matching given filter per tag, most used first.
+func (q *TagQueries) GetTagFacets(f *models.BookFilter, limit int) ([]models.FacetCount, error) {
+	// Define facets variable.
+	facets := []models.FacetCount{}
+
+	// Send query to database.
+	err := q.DB.Table("book_tags").
+		Joins("JOIN tags ON tags.id = book_tags.tag_id").
+		Where("book_tags.book_id IN (?)", q.DB.Table("books").Select("id").Scopes(bookFilterScope(f))).
+		Group("tags.id").
+		Order("count DESC").
+		Order("tags.name ASC").
+		Limit(limit).
+		Select("tags.slug, tags.name, count(*) AS count").
+		Find(&facets).Error
+	if err != nil {
+		// Return empty object and error.
+		return nil, err
+	}
+
+	// Return query result.
+	return facets, nil
+}
@@ -0,0 +1,155 @@
+package queries
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+)
+
+// tokenLookupTTL is how long a token found not denied is trusted without asking Redis again.
+// Tokens revoked through another instance of the app are rejected here after at most this long.
+const tokenLookupTTL = 5 * time.Second
+
+// tokenLookups caches the denylist lookups of this instance by token ID, revoking anything here clears it.
+var tokenLookups = &tokenLookupCache{entries: map[string]tokenLookup{}}
+
+// TokenDenylistQueries struct for queries from the access token denylist.
+// Tokens are denied one by one, by session, or for a user up to a point in time.
+type TokenDenylistQueries struct {
+	*redis.Client
+}
+
+// DenyToken method for revoking one access token by given ID until it expires.
+func (q *TokenDenylistQueries) DenyToken(tokenID string, expires time.Time) error {
+	return q.deny(deniedTokenKey(tokenID), "1", time.Until(expires))
+}
+
+// DenySessionTokens method for revoking every access token of given session, which issues no more once revoked.
+func (q *TokenDenylistQueries) DenySessionTokens(sessionID uuid.UUID, lifetime time.Duration) error {
+	return q.deny(deniedSessionKey(sessionID), "1", lifetime)
+}
+
+// DenyUserTokens method for revoking every access token issued to given user up to this millisecond.
+// Tokens of sessions are revoked with DenySessionTokens, this catches the tokens issued without a session.
+func (q *TokenDenylistQueries) DenyUserTokens(userID uuid.UUID, lifetime time.Duration) error {
+	return q.deny(deniedUserKey(userID), strconv.FormatInt(time.Now().UnixMilli(), 10), lifetime)
+}
+
+func (q *TokenDenylistQueries) deny(key, value string, ttl time.Duration) error {
+	// Tokens already expired need no entry.
+	if ttl <= 0 {
+		tokenLookups.clear()
+		return nil
+	}
+
+	// Send query to Redis.
+	err := q.Client.Set(context.Background(), key, value, ttl).Err()
+	tokenLookups.clear()
+	if err != nil {
+		// Return only error.
+		return err
+	}
+
+	// This query returns nothing.
+	return nil
+}
+
+// IsTokenDenied method for checking whether an access token was revoked, by its ID, session, user and issue time in milliseconds.
+func (q *TokenDenylistQueries) IsTokenDenied(tokenID string, userID, sessionID uuid.UUID, issuedAt int64) (bool, error) {
+	if denied, ok := tokenLookups.get(tokenID); ok {
+		return denied, nil
+	}
+
+	// Send query to Redis.
+	values, err := q.Client.MGet(context.Background(),
+		deniedTokenKey(tokenID), deniedSessionKey(sessionID), deniedUserKey(userID)).Result()
+	if err != nil {
+		// Return empty object and error.
+		return false, err
+	}
+
+	denied := (tokenID != "" && values[0] != nil) || (sessionID != uuid.Nil && values[1] != nil)
+	if since, ok := values[2].(string); ok {
+		// Tokens issued before the user was revoked are denied, in the same millisecond too.
+		if revokedAt, err := strconv.ParseInt(since, 10, 64); err == nil && issuedAt <= revokedAt {
+			denied = true
+		}
+	}
+	tokenLookups.set(tokenID, denied)
+
+	// Return query result.
+	return denied, nil
+}
+
+// deniedTokenKey func for the Redis key of a denied access token.
+func deniedTokenKey(tokenID string) string {
+	return "denied_token:" + tokenID
+}
+
+// deniedSessionKey func for the Redis key of the time the access tokens of a session were revoked.
+func deniedSessionKey(sessionID uuid.UUID) string {
+	return "denied_session:" + sessionID.String()
+}
+
+// deniedUserKey func for the Redis key of the time the access tokens of a user were revoked.
+func deniedUserKey(userID uuid.UUID) string {
+	return "denied_user:" + userID.String()
+}
+
+// tokenLookup struct to describe one cached denylist lookup.
+type tokenLookup struct {
+	denied  bool
+	expires time.Time
+}
+
+// tokenLookupCache struct to describe the denylist lookups cached by this instance.
+type tokenLookupCache struct {
+	mutex   sync.Mutex
+	entries map[string]tokenLookup
+	swept   time.Time
+}
+
+func (c *tokenLookupCache) get(tokenID string) (bool, bool) {
+	if tokenID == "" {
+		return false, false
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	entry, ok := c.entries[tokenID]
+	if !ok || time.Now().After(entry.expires) {
+		return false, false
+	}
+	return entry.denied, true
+}
+
+func (c *tokenLookupCache) set(tokenID string, denied bool) {
+	if tokenID == "" {
+		return
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	// Drop stale entries now and then, so the cache only holds tokens in use.
+	now := time.Now()
+	if now.Sub(c.swept) > time.Minute {
+		for id, entry := range c.entries {
+			if now.After(entry.expires) {
+				delete(c.entries, id)
+			}
+		}
+		c.swept = now
+	}
+	c.entries[tokenID] = tokenLookup{denied: denied, expires: now.Add(tokenLookupTTL)}
+}
+
+func (c *tokenLookupCache) clear() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.entries = map[string]tokenLookup{}
+}
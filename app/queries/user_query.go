@@ -24,6 +24,12 @@ func (q *UserQueries) GetUserByID(id uuid.UUID) (models.User, error) {
 		return user, errors.New("unable get user, DB error")
 	}
 
+	// Roles of the user, by name.
+	if user.UserRoles, err = (&RoleQueries{DB: q.DB}).GetUserRoles(user.ID); err != nil {
+		// Return empty object and error.
+		return user, errors.New("unable get user roles, DB error")
+	}
+
 	// Return query result.
 	return user, nil
 }
@@ -40,37 +46,81 @@ func (q *UserQueries) GetUserByEmail(email string) (models.User, error) {
 		return user, errors.New("unable get user, DB error")
 	}
 
+	// Roles of the user, by name.
+	if user.UserRoles, err = (&RoleQueries{DB: q.DB}).GetUserRoles(user.ID); err != nil {
+		// Return empty object and error.
+		return user, errors.New("unable get user roles, DB error")
+	}
+
 	// Return query result.
 	return user, nil
 }
 
-// CreateUser query for creating a new user by given email and password hash.
+// CreateUser query for creating a new user by given email, password hash and role names.
 func (q *UserQueries) CreateUser(u *models.User) error {
 	// Send query to database.
-	err := q.DB.Table("users").Create(&models.User{
-		ID:           u.ID,
-		CreatedAt:    u.CreatedAt,
-		UpdatedAt:    u.UpdatedAt,
-		UserRole:     u.UserRole,
-		UserStatus:   u.UserStatus,
-		Email:        u.Email,
-		PasswordHash: u.PasswordHash,
-	}).Error
-	if err != nil {
+	return q.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Table("users").Create(&models.User{
+			ID:           u.ID,
+			CreatedAt:    u.CreatedAt,
+			UpdatedAt:    u.UpdatedAt,
+			UserStatus:   u.UserStatus,
+			Email:        u.Email,
+			PasswordHash: u.PasswordHash,
+		}).Error
+		if err != nil {
+			return err
+		}
+		return setUserRoles(tx, u.ID, u.UserRoles)
+	})
+}
+
+// UpdateUserPassword query for replacing the password hash of user by given User object.
+func (q *UserQueries) UpdateUserPassword(u *models.User) error {
+	return q.updateUser(u.ID, map[string]interface{}{"password_hash": u.PasswordHash, "updated_at": u.UpdatedAt})
+}
+
+// UpdateUserStatus query for blocking or unblocking user by given User object.
+// The last active user with `role:manage` can not be blocked.
+func (q *UserQueries) UpdateUserStatus(u *models.User) error {
+	return q.DB.Transaction(func(tx *gorm.DB) error {
+		return keepUserManager(tx, func() error {
+			return (&UserQueries{DB: tx}).updateUser(u.ID, map[string]interface{}{"user_status": u.UserStatus, "updated_at": u.UpdatedAt})
+		})
+	})
+}
+
+// updateUser method for updating given fields of user by given ID.
+func (q *UserQueries) updateUser(id uuid.UUID, fields map[string]interface{}) error {
+	// Send query to database.
+	result := q.DB.Table("users").Where("id = ?", id).Updates(fields)
+	if result.Error != nil {
 		// Return only error.
-		return err
+		return errors.New("unable update user, DB error")
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("user with the given ID is not found")
 	}
 
 	// This query returns nothing.
 	return nil
 }
 
+// DeleteUser query for deleting user by given ID, the last active user with `role:manage` can not be deleted.
 func (q *UserQueries) DeleteUser(id uuid.UUID) error {
 	// Define User variable.
 	user := models.User{}
 
 	// Send query to database.
-	err := q.DB.Table("users").Where("id = ?", id).Delete(&user).Error
+	err := q.DB.Transaction(func(tx *gorm.DB) error {
+		return keepUserManager(tx, func() error {
+			return tx.Table("users").Where("id = ?", id).Delete(&user).Error
+		})
+	})
+	if errors.Is(err, ErrLastRoleManager) {
+		// Return only error.
+		return err
+	}
 	if err != nil {
 		// Return empty object and error.
 		return errors.New("unable delete user, DB error")
@@ -2,11 +2,13 @@ package main
 
 import (
 	"github.com/aryanicosa/go-fiber-rest-api/pkg/configs"
+	"github.com/aryanicosa/go-fiber-rest-api/pkg/jobs"
 	"github.com/aryanicosa/go-fiber-rest-api/pkg/middleware"
 	"github.com/aryanicosa/go-fiber-rest-api/pkg/routes"
 	"github.com/aryanicosa/go-fiber-rest-api/pkg/utils"
 	"github.com/aryanicosa/go-fiber-rest-api/platform/database"
 	"github.com/aryanicosa/go-fiber-rest-api/platform/migrations"
+	"github.com/aryanicosa/go-fiber-rest-api/platform/storage"
 	"github.com/joho/godotenv"
 	"log"
 	"os"
@@ -45,6 +47,16 @@ func main() {
 		log.Fatal("could not load database")
 	}
 
+	// init file storage
+	if _, err := storage.InitStorage(); err != nil {
+		log.Fatal(err)
+	}
+
+	// load the keys access tokens are signed with
+	if err := utils.InitSigningKeys(); err != nil {
+		log.Fatal(err)
+	}
+
 	// migration
 	migrationFileSource := os.Getenv("SQL_SOURCE_PATH")
 	err = migrations.Migrate(migrationFileSource)
@@ -52,11 +64,16 @@ func main() {
 		log.Fatal("database migration fail")
 	}
 
+	// Background jobs.
+	jobs.StartBookTrashPurge()
+
 	// Routes.
 	routes.SwaggerRoute(app) // Register a route for API Docs (Swagger).
 	routes.UsersRoutes(app)
 	routes.BooksRoutes(app)
 	routes.MiscRoutes(app)
+	routes.WellKnownRoutes(app)
+	routes.MediaRoute(app)
 	routes.NotFoundRoute(app) // Register route for 404 Error.
 
 	// Start server (with or without graceful shutdown).
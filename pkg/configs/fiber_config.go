@@ -14,5 +14,11 @@ func FiberConfig() fiber.Config {
 	// return fiber configuration
 	return fiber.Config{
 		ReadTimeout: time.Second * time.Duration(readTimeoutSecondsCount),
+		// Fit book covers of up to 5 MB with the multipart overhead.
+		BodyLimit: 8 * 1024 * 1024,
+		// Let bulk imports read big bodies as they arrive, middleware.BodyLimit caps all other routes.
+		StreamRequestBody: true,
+		// Do not read streamed multipart forms before the body limit is checked.
+		DisablePreParseMultipartForm: true,
 	}
 }
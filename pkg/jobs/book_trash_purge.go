@@ -0,0 +1,50 @@
+package jobs
+
+import (
+	"github.com/aryanicosa/go-fiber-rest-api/platform/database"
+	"log"
+	"os"
+	"strconv"
+	"time"
+)
+
+// StartBookTrashPurge func for permanently deleting trashed books past the retention period.
+// It runs in the background every BOOK_TRASH_PURGE_INTERVAL_MINUTES. The revision history of purged books is
+// deleted for good, their audit logs are kept.
+func StartBookTrashPurge() {
+	// Set retention hours count for trashed books from .env file.
+	retentionHours, _ := strconv.Atoi(os.Getenv("BOOK_TRASH_RETENTION_HOURS"))
+	if retentionHours <= 0 {
+		log.Println("book trash purge is disabled, BOOK_TRASH_RETENTION_HOURS is not set")
+		return
+	}
+
+	// Set purge interval from .env file, hourly by default.
+	intervalMinutes, _ := strconv.Atoi(os.Getenv("BOOK_TRASH_PURGE_INTERVAL_MINUTES"))
+	if intervalMinutes <= 0 {
+		intervalMinutes = 60
+	}
+
+	go func() {
+		ticker := time.NewTicker(time.Minute * time.Duration(intervalMinutes))
+		defer ticker.Stop()
+
+		for {
+			purgeBookTrash(time.Hour * time.Duration(retentionHours))
+			<-ticker.C
+		}
+	}()
+}
+
+func purgeBookTrash(retention time.Duration) {
+	db := database.BookDB()
+	purged, err := db.PurgeTrashedBooks(time.Now().Add(-retention))
+	if err != nil {
+		log.Printf("fail to purge book trash: %v", err)
+		return
+	}
+
+	if purged > 0 {
+		log.Printf("purged %d trashed books", purged)
+	}
+}
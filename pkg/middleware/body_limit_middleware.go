@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"io"
+
+	"github.com/aryanicosa/go-fiber-rest-api/pkg/response"
+	"github.com/gofiber/fiber/v2"
+)
+
+// streamedBodyPaths lists routes that read the request body as it arrives and cap it themselves.
+var streamedBodyPaths = map[string]bool{
+	"/v1/books/import": true,
+}
+
+// BodyLimit func for keeping streamed request bodies within the body limit of the app.
+//
+// With StreamRequestBody a body bigger than the limit is not rejected by the server but streamed,
+// and reading it would load all of it. Routes of streamedBodyPaths are left alone.
+func BodyLimit() func(*fiber.Ctx) error {
+	return func(c *fiber.Ctx) error {
+		stream := c.Context().RequestBodyStream()
+		if stream == nil || streamedBodyPaths[c.Path()] {
+			return c.Next()
+		}
+
+		limit := c.App().Config().BodyLimit
+		if c.Request().Header.ContentLength() > limit {
+			// Return status 413 and error message.
+			return response.RespondError(c, fiber.StatusRequestEntityTooLarge, fiber.ErrRequestEntityTooLarge.Message)
+		}
+
+		// Read one byte past the limit to tell a body of exactly the limit from a bigger one.
+		body, err := io.ReadAll(io.LimitReader(stream, int64(limit)+1))
+		if err != nil {
+			// Return status 400 and error message.
+			return response.RespondError(c, fiber.StatusBadRequest, "unable to read request body")
+		}
+		if len(body) > limit {
+			// Return status 413 and error message.
+			return response.RespondError(c, fiber.StatusRequestEntityTooLarge, fiber.ErrRequestEntityTooLarge.Message)
+		}
+		c.Request().SetBody(body)
+
+		return c.Next()
+	}
+}
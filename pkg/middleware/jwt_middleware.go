@@ -1,11 +1,15 @@
 package middleware
 
 import (
+	"errors"
 	"github.com/aryanicosa/go-fiber-rest-api/pkg/response"
+	"github.com/aryanicosa/go-fiber-rest-api/pkg/utils"
+	"github.com/aryanicosa/go-fiber-rest-api/platform/cache"
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/basicauth"
-	jwtMiddleware "github.com/gofiber/jwt/v2"
+	"github.com/golang-jwt/jwt/v4"
 	"os"
+	"strings"
 )
 
 func BasicAuth() func(*fiber.Ctx) error {
@@ -29,17 +33,70 @@ func BasicAuth() func(*fiber.Ctx) error {
 	return basicauth.New(config)
 }
 
+// BasicAuthOrJWT func for specify routes readable with Basic Auth, or with a JWT by users who may also
+// see unpublished books, see controllers.bookVisibleCheck.
+func BasicAuthOrJWT() func(*fiber.Ctx) error {
+	basicAuth, jwtProtected := BasicAuth(), JWTProtected()
+	return func(c *fiber.Ctx) error {
+		auth := c.Get(fiber.HeaderAuthorization)
+		if len(auth) > len("Bearer ") && strings.EqualFold(auth[:len("Bearer ")], "Bearer ") {
+			return jwtProtected(c)
+		}
+		return basicAuth(c)
+	}
+}
+
 // JWTProtected func for specify routes group with JWT authentication.
-// See: https://github.com/gofiber/jwt
+// Tokens are verified with the public key named by their kid, see utils.InitSigningKeys.
 func JWTProtected() func(*fiber.Ctx) error {
-	// Create config for JWT authentication middleware.
-	config := jwtMiddleware.Config{
-		SigningKey:   []byte(os.Getenv("JWT_SECRET_KEY")),
-		ContextKey:   "jwt", // used in private routes
-		ErrorHandler: jwtError,
+	return func(c *fiber.Ctx) error {
+		// Take the token from the Authorization header.
+		auth := c.Get(fiber.HeaderAuthorization)
+		if len(auth) <= len("Bearer ") || !strings.EqualFold(auth[:len("Bearer ")], "Bearer ") {
+			return jwtError(c, errors.New("Missing or malformed JWT"))
+		}
+
+		token, err := utils.ParseToken(auth[len("Bearer "):])
+		if err != nil {
+			return jwtError(c, err)
+		}
+
+		// Store the token for private routes.
+		c.Locals("jwt", token)
+
+		return jwtDenylistCheck(c)
+	}
+}
+
+// jwtDenylistCheck func for rejecting tokens revoked before they expired, by sign out, password change or block.
+func jwtDenylistCheck(c *fiber.Ctx) error {
+	token, ok := c.Locals("jwt").(*jwt.Token)
+	if !ok {
+		// Return status 401 and failed authentication error.
+		return response.RespondError(c, fiber.StatusUnauthorized, "invalid or expired JWT")
+	}
+	claims, err := utils.ParseTokenMetadata(token)
+	if err != nil {
+		// Return status 401 and failed authentication error.
+		return response.RespondError(c, fiber.StatusUnauthorized, err.Error())
+	}
+
+	denylist, err := cache.TokenDenylistCache()
+	if err != nil {
+		// Return status 500 and Redis connection error.
+		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+	}
+	denied, err := denylist.IsTokenDenied(claims.TokenID, claims.UserID, claims.SessionID, claims.IssuedAt)
+	if err != nil {
+		// Return status 500 and Redis connection error.
+		return response.RespondError(c, fiber.StatusInternalServerError, err.Error())
+	}
+	if denied {
+		// Return status 401 and failed authentication error.
+		return response.RespondError(c, fiber.StatusUnauthorized, "token was revoked")
 	}
 
-	return jwtMiddleware.New(config)
+	return c.Next()
 }
 
 func jwtError(c *fiber.Ctx, err error) error {
@@ -0,0 +1,18 @@
+package repository
+
+const (
+	// BookAttrTypeString const for attribute holding any text.
+	BookAttrTypeString string = "string"
+
+	// BookAttrTypeNumber const for attribute holding any number.
+	BookAttrTypeNumber string = "number"
+
+	// BookAttrTypeInteger const for attribute holding a whole number.
+	BookAttrTypeInteger string = "integer"
+
+	// BookAttrTypeBoolean const for attribute holding true or false.
+	BookAttrTypeBoolean string = "boolean"
+
+	// BookAttrTypeEnum const for attribute holding one of the enum values of its definition.
+	BookAttrTypeEnum string = "enum"
+)
@@ -0,0 +1,12 @@
+package repository
+
+const (
+	// AuthorRoleAuthor const for a person who wrote the book.
+	AuthorRoleAuthor string = "author"
+
+	// AuthorRoleEditor const for a person who edited the book, not to be confused with BookEditorRoleName.
+	AuthorRoleEditor string = "editor"
+
+	// AuthorRoleTranslator const for a person who translated the book.
+	AuthorRoleTranslator string = "translator"
+)
@@ -0,0 +1,9 @@
+package repository
+
+const (
+	// BookEditorRoleName const for collaborator who can update a book.
+	BookEditorRoleName string = "editor"
+
+	// BookViewerRoleName const for collaborator who can only view a book.
+	BookViewerRoleName string = "viewer"
+)
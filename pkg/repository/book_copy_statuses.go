@@ -0,0 +1,12 @@
+package repository
+
+const (
+	// BookCopyAvailable const for copy on the shelf, free to check out.
+	BookCopyAvailable string = "available"
+
+	// BookCopyOnLoan const for copy checked out by a user.
+	BookCopyOnLoan string = "on_loan"
+
+	// BookCopyOnHold const for returned copy set aside for the next user in the holds queue.
+	BookCopyOnHold string = "on_hold"
+)
@@ -9,4 +9,43 @@ const (
 
 	// BookDeleteCredential const for delete book.
 	BookDeleteCredential string = "book:delete"
+
+	// BookUpdateAnyCredential const for update a book of another user, audited.
+	BookUpdateAnyCredential string = "book:update:any"
+
+	// BookDeleteAnyCredential const for delete a book of another user, audited.
+	BookDeleteAnyCredential string = "book:delete:any"
+
+	// BookTaxonomyCredential const for manage tags and categories of books.
+	BookTaxonomyCredential string = "book:taxonomy"
+
+	// BookPublishCredential const for publish and unpublish books of any user.
+	BookPublishCredential string = "book:publish"
+
+	// BookAttrsCredential const for register extra attributes of books.
+	BookAttrsCredential string = "book:attrs"
+
+	// BookLendingCredential const for manage copies of books and loans of any user.
+	BookLendingCredential string = "book:lending"
+
+	// UserManageCredential const for block and unblock users.
+	UserManageCredential string = "user:manage"
+
+	// RoleManageCredential const for manage roles, permissions and the roles of users.
+	RoleManageCredential string = "role:manage"
 )
+
+// BuiltInCredentials lists the credentials checked by this service, their permissions can not be deleted.
+var BuiltInCredentials = []string{
+	BookCreateCredential,
+	BookUpdateCredential,
+	BookDeleteCredential,
+	BookUpdateAnyCredential,
+	BookDeleteAnyCredential,
+	BookTaxonomyCredential,
+	BookPublishCredential,
+	BookAttrsCredential,
+	BookLendingCredential,
+	UserManageCredential,
+	RoleManageCredential,
+}
@@ -0,0 +1,24 @@
+package repository
+
+const (
+	// BookCreateAction const for revision written when a book is created.
+	BookCreateAction string = "create"
+
+	// BookUpdateAction const for revision written when a book is updated or patched.
+	BookUpdateAction string = "update"
+
+	// BookDeleteAction const for revision written when a book is moved to the trash.
+	BookDeleteAction string = "delete"
+
+	// BookRestoreAction const for revision written when a book is taken out of the trash.
+	BookRestoreAction string = "restore"
+
+	// BookTransferAction const for revision written when a book is handed over to another user.
+	BookTransferAction string = "transfer"
+
+	// BookRevertAction const for revision written when a book is reverted to an earlier revision.
+	BookRevertAction string = "revert"
+
+	// BookTransitionAction const for revision written when the status of a book changes.
+	BookTransitionAction string = "transition"
+)
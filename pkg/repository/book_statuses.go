@@ -0,0 +1,35 @@
+package repository
+
+const (
+	// BookStatusDraft const for book being written, new and imported books start here.
+	BookStatusDraft string = "draft"
+
+	// BookStatusReview const for book waiting for a moderator to publish it.
+	BookStatusReview string = "review"
+
+	// BookStatusPublished const for book shown in public listings.
+	BookStatusPublished string = "published"
+
+	// BookStatusArchived const for book taken out of public listings by its owner.
+	BookStatusArchived string = "archived"
+)
+
+// BookStatusTransitions maps each book status to the statuses it may move to,
+// with the credential needed for the move.
+var BookStatusTransitions = map[string]map[string]string{
+	BookStatusDraft: {
+		BookStatusReview: BookUpdateCredential, // submit for review
+	},
+	BookStatusReview: {
+		BookStatusDraft:     BookUpdateCredential,  // withdraw or reject
+		BookStatusPublished: BookPublishCredential, // publish
+	},
+	BookStatusPublished: {
+		BookStatusArchived: BookUpdateCredential,  // archive
+		BookStatusDraft:    BookPublishCredential, // unpublish
+	},
+	BookStatusArchived: {
+		BookStatusDraft:     BookUpdateCredential,  // rework
+		BookStatusPublished: BookPublishCredential, // republish
+	},
+}
@@ -0,0 +1,25 @@
+package repository
+
+const (
+	// ReadingListWantToRead const for built-in list of books a user plans to read.
+	ReadingListWantToRead string = "want_to_read"
+
+	// ReadingListReading const for built-in list of books a user is reading.
+	ReadingListReading string = "reading"
+
+	// ReadingListFinished const for built-in list of books a user has read.
+	ReadingListFinished string = "finished"
+
+	// ReadingListCustom const for list named by its user, any number of them.
+	ReadingListCustom string = "custom"
+)
+
+// ReadingListDefaults lists the built-in lists every user has, in display order, with their names.
+var ReadingListDefaults = []struct {
+	Kind string
+	Name string
+}{
+	{ReadingListWantToRead, "Want to read"},
+	{ReadingListReading, "Reading"},
+	{ReadingListFinished, "Finished"},
+}
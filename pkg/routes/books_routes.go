@@ -12,15 +12,97 @@ func BooksRoutes(a *fiber.App) {
 	route := a.Group("/v1")
 
 	// Routes for POST method:
-	route.Post("/book", middleware.JWTProtected(), controllers.CreateBook) // create a new book
+	route.Post("/book", middleware.JWTProtected(), controllers.CreateBook)                            // create a new book
+	route.Post("/books/import", middleware.JWTProtected(), controllers.ImportBooks)                   // create many books from CSV or NDJSON
+	route.Post("/books/batch", middleware.JWTProtected(), controllers.BatchBooks)                     // create, update and delete many books at once
+	route.Post("/book/:id/collaborators", middleware.JWTProtected(), controllers.AddBookCollaborator) // add or change a collaborator of a book
+	route.Post("/book/:id/transfer", middleware.JWTProtected(), controllers.TransferBookOwnership)    // hand a book over to another user
+	route.Post("/book/:id/cover", middleware.JWTProtected(), controllers.UploadBookCover)             // upload a cover image of a book
+	route.Post("/book/:id/restore", middleware.JWTProtected(), controllers.RestoreBook)               // take a book out of the trash
+	route.Post("/book/:id/revisions/:rev/revert", middleware.JWTProtected(), controllers.RevertBook)  // set a book back to one of its revisions
+	route.Post("/book/:id/transition", middleware.JWTProtected(), controllers.TransitionBook)         // move a book to another status of the workflow
 
 	// Routes for PUT method:
 	route.Put("/book/:id", middleware.JWTProtected(), controllers.UpdateBook) // update one book by ID
 
+	// Routes for PATCH method:
+	route.Patch("/book/:id", middleware.JWTProtected(), controllers.PatchBook) // patch some fields of one book by ID
+
 	// Routes for DELETE method:
-	route.Delete("/book/:id", middleware.JWTProtected(), controllers.DeleteBook) // delete one book by ID
+	route.Delete("/book/:id", middleware.JWTProtected(), controllers.DeleteBook)                                    // delete one book by ID
+	route.Delete("/book/:id/collaborators/:user_id", middleware.JWTProtected(), controllers.RemoveBookCollaborator) // remove a collaborator of a book
 
 	// Routes for GET method:
-	route.Get("/books", middleware.BasicAuth(), controllers.GetBooks)   // get list of all books
-	route.Get("/book/:id", middleware.BasicAuth(), controllers.GetBook) // get one book by ID
+	route.Get("/books", middleware.BasicAuth(), controllers.GetBooks)                                      // get list of all books
+	route.Get("/books/search", middleware.BasicAuth(), controllers.SearchBooks)                            // full-text search over books
+	route.Get("/books/export", middleware.BasicAuth(), controllers.ExportBooks)                            // stream books as CSV or NDJSON
+	route.Get("/books/trash", middleware.JWTProtected(), controllers.GetTrashedBooks)                      // get list of trashed books
+	route.Get("/books/workflow", middleware.JWTProtected(), controllers.GetWorkflowBooks)                  // get list of books in any status
+	route.Get("/book/isbn/:isbn", middleware.BasicAuthOrJWT(), controllers.GetBookByISBN)                  // get one book by ISBN
+	route.Get("/book/:id", middleware.BasicAuthOrJWT(), controllers.GetBook)                               // get one book by ID
+	route.Get("/book/:id/collaborators", middleware.JWTProtected(), controllers.GetBookCollaborators)      // get collaborators of a book
+	route.Get("/book/:id/audit", middleware.JWTProtected(), controllers.GetBookAuditLogs)                  // get override audit logs of a book
+	route.Get("/book/:id/revisions", middleware.JWTProtected(), controllers.GetBookRevisions)              // get revision history of a book
+	route.Get("/book/:id/revisions/:rev", middleware.JWTProtected(), controllers.GetBookRevision)          // get one revision of a book
+	route.Get("/book/:id/revisions/:rev/diff", middleware.JWTProtected(), controllers.GetBookRevisionDiff) // get changes between two revisions
+
+	// Routes for authors of books:
+	route.Get("/authors", middleware.BasicAuth(), controllers.GetAuthors)                   // get list of authors with book counts
+	route.Get("/authors/:name/books", middleware.BasicAuth(), controllers.GetAuthorBooks)   // get list of books by author name
+	route.Post("/author", middleware.JWTProtected(), controllers.CreateAuthor)              // create a new author
+	route.Get("/author/:id", middleware.BasicAuth(), controllers.GetAuthor)                 // get one author by ID
+	route.Put("/author/:id", middleware.JWTProtected(), controllers.UpdateAuthor)           // update one author by ID
+	route.Delete("/author/:id", middleware.JWTProtected(), controllers.DeleteAuthor)        // delete one author by ID
+	route.Get("/author/:id/books", middleware.BasicAuth(), controllers.GetAuthorBooksByID)  // get list of books credited to an author
+	route.Get("/book/:id/authors", middleware.BasicAuthOrJWT(), controllers.GetBookAuthors) // get authors credited on a book
+	route.Put("/book/:id/authors", middleware.JWTProtected(), controllers.SetBookAuthors)   // replace authors credited on a book
+
+	// Routes for reviews of books:
+	route.Get("/book/:id/reviews", middleware.BasicAuthOrJWT(), controllers.GetBookReviews)   // get reviews of a book with its average rating
+	route.Post("/book/:id/review", middleware.JWTProtected(), controllers.CreateBookReview)   // review a book
+	route.Put("/book/:id/review", middleware.JWTProtected(), controllers.UpdateBookReview)    // edit own review of a book
+	route.Delete("/book/:id/review", middleware.JWTProtected(), controllers.DeleteBookReview) // delete own review of a book
+
+	// Routes for tags and categories of books:
+	route.Get("/tags", middleware.BasicAuth(), controllers.GetTags)                               // get list of all tags
+	route.Post("/tag", middleware.JWTProtected(), controllers.CreateTag)                          // create a new tag
+	route.Delete("/tag/:id", middleware.JWTProtected(), controllers.DeleteTag)                    // delete one tag by ID
+	route.Get("/categories", middleware.BasicAuth(), controllers.GetCategories)                   // get the categories tree
+	route.Post("/category", middleware.JWTProtected(), controllers.CreateCategory)                // create a new category
+	route.Put("/category/:id", middleware.JWTProtected(), controllers.UpdateCategory)             // rename or move one category by ID
+	route.Delete("/category/:id", middleware.JWTProtected(), controllers.DeleteCategory)          // delete one category by ID
+	route.Get("/book/:id/tags", middleware.BasicAuthOrJWT(), controllers.GetBookTags)             // get tags of a book
+	route.Put("/book/:id/tags", middleware.JWTProtected(), controllers.SetBookTags)               // replace tags of a book
+	route.Get("/book/:id/categories", middleware.BasicAuthOrJWT(), controllers.GetBookCategories) // get categories of a book
+	route.Put("/book/:id/categories", middleware.JWTProtected(), controllers.SetBookCategories)   // replace categories of a book
+
+	// Routes for registered attributes of books:
+	route.Get("/attrs", middleware.BasicAuth(), controllers.GetBookAttrDefinitions)            // get list of registered attributes
+	route.Post("/attr", middleware.JWTProtected(), controllers.CreateBookAttrDefinition)       // register a new attribute
+	route.Delete("/attr/:id", middleware.JWTProtected(), controllers.DeleteBookAttrDefinition) // delete one attribute by ID
+
+	// Routes for reading lists of users:
+	route.Get("/reading-lists", middleware.JWTProtected(), controllers.GetReadingLists)                           // get own reading lists
+	route.Get("/reading-lists/shared/:token", controllers.GetSharedReadingList)                                   // get a public reading list by its share token
+	route.Post("/reading-list", middleware.JWTProtected(), controllers.CreateReadingList)                         // create a custom reading list
+	route.Get("/reading-list/:id", middleware.JWTProtected(), controllers.GetReadingList)                         // get own reading list with its books
+	route.Put("/reading-list/:id", middleware.JWTProtected(), controllers.UpdateReadingList)                      // rename or share own reading list
+	route.Delete("/reading-list/:id", middleware.JWTProtected(), controllers.DeleteReadingList)                   // delete own custom reading list
+	route.Post("/reading-list/:id/books", middleware.JWTProtected(), controllers.AddReadingListBook)              // add a book to own reading list
+	route.Delete("/reading-list/:id/book/:book_id", middleware.JWTProtected(), controllers.RemoveReadingListBook) // remove a book from own reading list
+	route.Put("/reading-list/:id/order", middleware.JWTProtected(), controllers.OrderReadingList)                 // reorder books of own reading list
+	route.Get("/book/:id/progress", middleware.JWTProtected(), controllers.GetBookProgress)                       // get own reading progress in a book
+	route.Put("/book/:id/progress", middleware.JWTProtected(), controllers.SaveBookProgress)                      // save own reading progress in a book
+
+	// Routes for lending copies of books:
+	route.Get("/book/:id/copies", middleware.BasicAuthOrJWT(), controllers.GetBookCopies) // get copies of a book with their status
+	route.Post("/book/:id/copies", middleware.JWTProtected(), controllers.CreateBookCopy) // add a copy of a book
+	route.Delete("/copy/:id", middleware.JWTProtected(), controllers.DeleteBookCopy)      // delete one copy by ID
+	route.Post("/book/:id/loans", middleware.JWTProtected(), controllers.CheckoutBook)    // borrow a copy of a book
+	route.Post("/book/:id/holds", middleware.JWTProtected(), controllers.PlaceBookHold)   // queue for a book while all copies are out
+	route.Delete("/book/:id/hold", middleware.JWTProtected(), controllers.CancelBookHold) // leave the queue of a book
+	route.Get("/loans", middleware.JWTProtected(), controllers.GetLoans)                  // get own loans and holds
+	route.Get("/loans/overdue", middleware.JWTProtected(), controllers.GetOverdueLoans)   // get loans past their due date
+	route.Post("/loan/:id/return", middleware.JWTProtected(), controllers.ReturnLoan)     // return a borrowed copy
+	route.Post("/loan/:id/renew", middleware.JWTProtected(), controllers.RenewLoan)       // move the due date of a loan
 }
@@ -5,14 +5,23 @@ import (
 	"encoding/json"
 	"fmt"
 	"github.com/aryanicosa/go-fiber-rest-api/app/models"
+	"github.com/aryanicosa/go-fiber-rest-api/app/queries"
 	"github.com/aryanicosa/go-fiber-rest-api/pkg/repository"
 	"github.com/aryanicosa/go-fiber-rest-api/pkg/utils"
 	"github.com/aryanicosa/go-fiber-rest-api/platform/database"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
+	"image"
+	"image/png"
 	"io"
 	"log"
+	"mime/multipart"
 	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 )
@@ -28,7 +37,7 @@ func TestCreateBook(t *testing.T) {
 		Email:        fmt.Sprintf("test%s@mail.com", suffix),
 		PasswordHash: utils.GeneratePassword("Password123"),
 		UserStatus:   0,
-		UserRole:     repository.AdminRoleName,
+		UserRoles:    []string{repository.AdminRoleName},
 	}
 	err := db.CreateUser(user)
 	if err != nil {
@@ -58,7 +67,7 @@ func TestCreateBook(t *testing.T) {
 	book := &models.Book{
 		Title:      "Test Title",
 		Author:     "John Doe",
-		BookStatus: 0,
+		BookStatus: repository.BookStatusDraft,
 		BookAttrs: models.BookAttrs{
 			Picture:     "Test Pic",
 			Description: "This book is test book",
@@ -86,11 +95,7 @@ func TestCreateBook(t *testing.T) {
 		if err != nil {
 			log.Fatal("fail to delete user")
 		}
-		dbBook := database.BookDB()
-		if err != nil {
-			log.Fatal("fail connect book db")
-		}
-		err = dbBook.DeleteBook(createBookResponse.ID)
+		err = deleteTestBook(createBookResponse.ID)
 		if err != nil {
 			log.Fatal("Fail to delete book")
 		}
@@ -111,7 +116,7 @@ func TestGetBookById(t *testing.T) {
 		Email:        fmt.Sprintf("test%s@mail.com", suffix),
 		PasswordHash: utils.GeneratePassword("Password123"),
 		UserStatus:   0,
-		UserRole:     repository.AdminRoleName,
+		UserRoles:    []string{repository.AdminRoleName},
 	}
 	err := db.CreateUser(user)
 	if err != nil {
@@ -130,7 +135,7 @@ func TestGetBookById(t *testing.T) {
 		UserID:     user.ID,
 		Title:      "Test Title",
 		Author:     "John Doe",
-		BookStatus: 0,
+		BookStatus: repository.BookStatusPublished,
 		BookAttrs: models.BookAttrs{
 			Picture:     "Picture",
 			Description: "Description",
@@ -173,7 +178,7 @@ func TestGetBookById(t *testing.T) {
 		if err != nil {
 			log.Fatal("fail to delete user")
 		}
-		err = dbBook.DeleteBook(getBookResponse.ID)
+		err = deleteTestBook(getBookResponse.ID)
 		if err != nil {
 			log.Fatal("Fail to delete book")
 		}
@@ -194,7 +199,7 @@ func TestGetBookAll(t *testing.T) {
 		Email:        fmt.Sprintf("test%s@mail.com", suffix),
 		PasswordHash: utils.GeneratePassword("Password123"),
 		UserStatus:   0,
-		UserRole:     repository.AdminRoleName,
+		UserRoles:    []string{repository.AdminRoleName},
 	}
 	err := db.CreateUser(user)
 	if err != nil {
@@ -214,7 +219,7 @@ func TestGetBookAll(t *testing.T) {
 			UserID:     user.ID,
 			Title:      "Test Title",
 			Author:     "John Doe",
-			BookStatus: 0,
+			BookStatus: repository.BookStatusPublished,
 			BookAttrs: models.BookAttrs{
 				Picture:     "Picture",
 				Description: "Description",
@@ -228,7 +233,7 @@ func TestGetBookAll(t *testing.T) {
 			UserID:     user.ID,
 			Title:      "Test Title 2",
 			Author:     "John Doe",
-			BookStatus: 0,
+			BookStatus: repository.BookStatusPublished,
 			BookAttrs: models.BookAttrs{
 				Picture:     "Picture 2",
 				Description: "Description 2",
@@ -265,13 +270,13 @@ func TestGetBookAll(t *testing.T) {
 		log.Fatal("fail to sign in user test")
 	}
 
-	var getBooksResponse []models.BookForPublic
+	var getBooksResponse models.AllBooks
 	responseBodyBytes, _ := io.ReadAll(resp.Body)
 	_ = json.Unmarshal(responseBodyBytes, &getBooksResponse)
 
 	defer func() {
 		for _, book := range books {
-			err = dbBook.DeleteBook(book.ID)
+			err = deleteTestBook(book.ID)
 			if err != nil {
 				log.Fatal("Fail to delete book")
 			}
@@ -283,11 +288,145 @@ func TestGetBookAll(t *testing.T) {
 	}()
 
 	assert.Equal(t, test.expectedCode, resp.StatusCode)
-	for _, bookResponse := range getBooksResponse {
+	assert.GreaterOrEqual(t, getBooksResponse.Count, int64(len(books)))
+	for _, bookResponse := range getBooksResponse.Books {
 		assert.NotEmpty(t, bookResponse.ID)
 	}
 }
 
+func TestGetBooksFilterAndPaging(t *testing.T) {
+	db := database.UserDB()
+
+	suffix := utils.String(12)
+	user := &models.User{
+		ID:           uuid.New(),
+		CreatedAt:    time.Now(),
+		UpdatedAt:    time.Now(),
+		Email:        fmt.Sprintf("test%s@mail.com", suffix),
+		PasswordHash: utils.GeneratePassword("Password123"),
+		UserStatus:   0,
+		UserRoles:    []string{repository.AdminRoleName},
+	}
+	err := db.CreateUser(user)
+	if err != nil {
+		log.Fatal("unable to create user")
+	}
+
+	dbBook := database.BookDB()
+
+	// Unique author to keep the list isolated from other tests.
+	author := "Author " + suffix
+
+	var books []models.Book
+	for i := 0; i < 3; i++ {
+		book := models.Book{
+			ID:         uuid.New(),
+			CreatedAt:  time.Now().Add(time.Duration(i) * time.Second),
+			UpdatedAt:  time.Now(),
+			UserID:     user.ID,
+			Title:      fmt.Sprintf("Paged Title %d", i),
+			Author:     author,
+			BookStatus: repository.BookStatusPublished,
+			BookAttrs: models.BookAttrs{
+				Picture:     "Picture",
+				Description: "Description",
+				Rating:      i * 3,
+			},
+		}
+		err = dbBook.CreateBook(&book)
+		if err != nil {
+			log.Fatal("fail to create book")
+		}
+		books = append(books, book)
+	}
+
+	defer func() {
+		for _, book := range books {
+			err = deleteTestBook(book.ID)
+			if err != nil {
+				log.Fatal("Fail to delete book")
+			}
+		}
+		err = db.DeleteUser(user.ID)
+		if err != nil {
+			log.Fatal("fail to delete user")
+		}
+	}()
+
+	// Define a structure for specifying input and output data of test cases.
+	tests := []struct {
+		description   string
+		route         string // input route
+		expectedCode  int
+		expectedCount int64
+		expectedLen   int
+		expectNext    bool
+	}{
+		{
+			description:   "first page sorted by title",
+			route:         "/v1/books?limit=2&sort=-title&author=" + url.QueryEscape(author),
+			expectedCode:  200,
+			expectedCount: 3,
+			expectedLen:   2,
+			expectNext:    true,
+		},
+		{
+			description:   "last page",
+			route:         "/v1/books?limit=2&page=2&author=" + url.QueryEscape(author),
+			expectedCode:  200,
+			expectedCount: 3,
+			expectedLen:   1,
+			expectNext:    false,
+		},
+		{
+			description:   "rating range",
+			route:         "/v1/books?rating_min=3&rating_max=6&author=" + url.QueryEscape(author),
+			expectedCode:  200,
+			expectedCount: 2,
+			expectedLen:   2,
+			expectNext:    false,
+		},
+		{
+			description:   "first keyset page",
+			route:         "/v1/books?limit=2&cursor=&author=" + url.QueryEscape(author),
+			expectedCode:  200,
+			expectedCount: 3,
+			expectedLen:   2,
+			expectNext:    true,
+		},
+		{
+			description:  "unknown sort column",
+			route:        "/v1/books?sort=password_hash",
+			expectedCode: 400,
+		},
+	}
+
+	for _, test := range tests {
+		req := httptest.NewRequest("GET", test.route, nil)
+		req.Header.Add("Content-Type", "application/json")
+		req.Header.Add("Authorization", "Basic YWRtaW46c2VjcmV0")
+
+		// Perform the request plain with the AppTest.
+		resp, err := AppTest.Test(req, -1) // the -1 disables request latency
+		if err != nil {
+			log.Fatal("fail to get books test")
+		}
+
+		assert.Equalf(t, test.expectedCode, resp.StatusCode, test.description)
+		if test.expectedCode != 200 {
+			continue
+		}
+
+		var getBooksResponse models.AllBooks
+		responseBodyBytes, _ := io.ReadAll(resp.Body)
+		_ = json.Unmarshal(responseBodyBytes, &getBooksResponse)
+
+		assert.Equalf(t, test.expectedCount, getBooksResponse.Count, test.description)
+		assert.Lenf(t, getBooksResponse.Books, test.expectedLen, test.description)
+		assert.Equalf(t, test.expectNext, getBooksResponse.Links.Next != "", test.description)
+	}
+}
+
 func TestUpdateBookById(t *testing.T) {
 	db := database.UserDB()
 
@@ -299,7 +438,7 @@ func TestUpdateBookById(t *testing.T) {
 		Email:        fmt.Sprintf("test%s@mail.com", suffix),
 		PasswordHash: utils.GeneratePassword("Password123"),
 		UserStatus:   0,
-		UserRole:     repository.AdminRoleName,
+		UserRoles:    []string{repository.AdminRoleName},
 	}
 	err := db.CreateUser(user)
 	if err != nil {
@@ -318,7 +457,7 @@ func TestUpdateBookById(t *testing.T) {
 		UserID:     user.ID,
 		Title:      "Test Title",
 		Author:     "John Doe",
-		BookStatus: 0,
+		BookStatus: repository.BookStatusDraft,
 		BookAttrs: models.BookAttrs{
 			Picture:     "Picture",
 			Description: "Description",
@@ -358,7 +497,7 @@ func TestUpdateBookById(t *testing.T) {
 		UpdatedAt:  time.Now(),
 		Title:      "Test Title Update",
 		Author:     "John Doe",
-		BookStatus: 1,
+		BookStatus: repository.BookStatusDraft,
 		BookAttrs: models.BookAttrs{
 			Picture:     "Test Pic update",
 			Description: "This book is test book update",
@@ -386,7 +525,7 @@ func TestUpdateBookById(t *testing.T) {
 		if err != nil {
 			log.Fatal("fail to delete user")
 		}
-		err = dbBook.DeleteBook(updateBookResponse.ID)
+		err = deleteTestBook(updateBookResponse.ID)
 		if err != nil {
 			log.Fatal("Fail to delete book")
 		}
@@ -407,7 +546,7 @@ func TestDeleteBookById(t *testing.T) {
 		Email:        fmt.Sprintf("test%s@mail.com", suffix),
 		PasswordHash: utils.GeneratePassword("Password123"),
 		UserStatus:   0,
-		UserRole:     repository.AdminRoleName,
+		UserRoles:    []string{repository.AdminRoleName},
 	}
 	err := db.CreateUser(user)
 	if err != nil {
@@ -426,7 +565,7 @@ func TestDeleteBookById(t *testing.T) {
 		UserID:     user.ID,
 		Title:      "Test Title",
 		Author:     "John Doe",
-		BookStatus: 0,
+		BookStatus: repository.BookStatusDraft,
 		BookAttrs: models.BookAttrs{
 			Picture:     "Picture",
 			Description: "Description",
@@ -478,3 +617,2075 @@ func TestDeleteBookById(t *testing.T) {
 
 	assert.Equal(t, test.expectedCode, resp.StatusCode)
 }
+
+func TestSearchBooks(t *testing.T) {
+	db := database.UserDB()
+
+	suffix := utils.String(12)
+	user := &models.User{
+		ID:           uuid.New(),
+		CreatedAt:    time.Now(),
+		UpdatedAt:    time.Now(),
+		Email:        fmt.Sprintf("test%s@mail.com", suffix),
+		PasswordHash: utils.GeneratePassword("Password123"),
+		UserStatus:   0,
+		UserRoles:    []string{repository.AdminRoleName},
+	}
+	err := db.CreateUser(user)
+	if err != nil {
+		log.Fatal("unable to create user")
+	}
+
+	dbBook := database.BookDB()
+
+	// Unique word to keep the search isolated from other tests.
+	word := "word" + strings.ToLower(suffix)
+
+	book := &models.Book{
+		ID:         uuid.New(),
+		CreatedAt:  time.Now(),
+		UpdatedAt:  time.Now(),
+		UserID:     user.ID,
+		Title:      "Searchable Title",
+		Author:     "John Doe",
+		BookStatus: repository.BookStatusPublished,
+		BookAttrs: models.BookAttrs{
+			Picture:     "Picture",
+			Description: "A description mentioning " + word + " once",
+			Rating:      5,
+		},
+	}
+
+	err = dbBook.CreateBook(book)
+	if err != nil {
+		log.Fatal("fail to create book")
+	}
+
+	defer func() {
+		err = deleteTestBook(book.ID)
+		if err != nil {
+			log.Fatal("Fail to delete book")
+		}
+		err = db.DeleteUser(user.ID)
+		if err != nil {
+			log.Fatal("fail to delete user")
+		}
+	}()
+
+	// Define a structure for specifying input and output data of test cases.
+	tests := []struct {
+		description   string
+		route         string // input route
+		expectedCode  int
+		expectedCount int64
+	}{
+		{
+			description:   "match in description",
+			route:         "/v1/books/search?q=" + word,
+			expectedCode:  200,
+			expectedCount: 1,
+		},
+		{
+			description:   "excluded word",
+			route:         "/v1/books/search?q=" + url.QueryEscape(word+" -searchable"),
+			expectedCode:  200,
+			expectedCount: 0,
+		},
+		{
+			description:  "missing query",
+			route:        "/v1/books/search",
+			expectedCode: 400,
+		},
+	}
+
+	for _, test := range tests {
+		req := httptest.NewRequest("GET", test.route, nil)
+		req.Header.Add("Content-Type", "application/json")
+		req.Header.Add("Authorization", "Basic YWRtaW46c2VjcmV0")
+
+		// Perform the request plain with the AppTest.
+		resp, err := AppTest.Test(req, -1) // the -1 disables request latency
+		if err != nil {
+			log.Fatal("fail to search books test")
+		}
+
+		assert.Equalf(t, test.expectedCode, resp.StatusCode, test.description)
+		if test.expectedCode != 200 {
+			continue
+		}
+
+		var searchResponse models.BookSearchResults
+		responseBodyBytes, _ := io.ReadAll(resp.Body)
+		_ = json.Unmarshal(responseBodyBytes, &searchResponse)
+
+		assert.Equalf(t, test.expectedCount, searchResponse.Count, test.description)
+		for _, result := range searchResponse.Books {
+			assert.Equal(t, book.ID, result.ID)
+			assert.Contains(t, result.Snippet, "<mark>")
+		}
+	}
+}
+
+func TestGetAuthorsAndAuthorBooks(t *testing.T) {
+	db := database.UserDB()
+
+	suffix := utils.String(12)
+	user := &models.User{
+		ID:           uuid.New(),
+		CreatedAt:    time.Now(),
+		UpdatedAt:    time.Now(),
+		Email:        fmt.Sprintf("test%s@mail.com", suffix),
+		PasswordHash: utils.GeneratePassword("Password123"),
+		UserStatus:   0,
+		UserRoles:    []string{repository.AdminRoleName},
+	}
+	err := db.CreateUser(user)
+	if err != nil {
+		log.Fatal("unable to create user")
+	}
+
+	dbBook := database.BookDB()
+
+	// Same author spelled with different case.
+	author := "Case Author " + suffix
+
+	var books []models.Book
+	for _, name := range []string{author, strings.ToLower(author)} {
+		book := models.Book{
+			ID:         uuid.New(),
+			CreatedAt:  time.Now(),
+			UpdatedAt:  time.Now(),
+			UserID:     user.ID,
+			Title:      "Author Title",
+			Author:     name,
+			BookStatus: repository.BookStatusPublished,
+			BookAttrs: models.BookAttrs{
+				Picture:     "Picture",
+				Description: "Description",
+				Rating:      5,
+			},
+		}
+		err = dbBook.CreateBook(&book)
+		if err != nil {
+			log.Fatal("fail to create book")
+		}
+		books = append(books, book)
+	}
+
+	defer func() {
+		for _, book := range books {
+			err = deleteTestBook(book.ID)
+			if err != nil {
+				log.Fatal("Fail to delete book")
+			}
+		}
+		err = db.DeleteUser(user.ID)
+		if err != nil {
+			log.Fatal("fail to delete user")
+		}
+	}()
+
+	req := httptest.NewRequest("GET", "/v1/authors?limit=100", nil)
+	req.Header.Add("Authorization", "Basic YWRtaW46c2VjcmV0")
+
+	// Perform the request plain with the AppTest.
+	resp, err := AppTest.Test(req, -1) // the -1 disables request latency
+	if err != nil {
+		log.Fatal("fail to get authors test")
+	}
+
+	var getAuthorsResponse models.AllAuthors
+	responseBodyBytes, _ := io.ReadAll(resp.Body)
+	_ = json.Unmarshal(responseBodyBytes, &getAuthorsResponse)
+
+	assert.Equal(t, 200, resp.StatusCode)
+	assert.GreaterOrEqual(t, getAuthorsResponse.Count, int64(1))
+
+	req = httptest.NewRequest("GET", "/v1/authors/"+url.PathEscape(strings.ToUpper(author))+"/books", nil)
+	req.Header.Add("Authorization", "Basic YWRtaW46c2VjcmV0")
+
+	// Perform the request plain with the AppTest.
+	resp, err = AppTest.Test(req, -1) // the -1 disables request latency
+	if err != nil {
+		log.Fatal("fail to get author books test")
+	}
+
+	var getBooksResponse models.AllBooks
+	responseBodyBytes, _ = io.ReadAll(resp.Body)
+	_ = json.Unmarshal(responseBodyBytes, &getBooksResponse)
+
+	assert.Equal(t, 200, resp.StatusCode)
+	assert.Equal(t, int64(len(books)), getBooksResponse.Count)
+}
+
+func TestBookCollaborators(t *testing.T) {
+	owner := createTestUser(repository.UserRoleName)
+	collaborator := createTestUser(repository.ModeratorRoleName)
+	book := createTestBook(owner.ID)
+
+	defer func() {
+		err := deleteTestBook(book.ID)
+		if err != nil {
+			log.Fatal("Fail to delete book")
+		}
+		for _, user := range []*models.User{owner, collaborator} {
+			err = database.UserDB().DeleteUser(user.ID)
+			if err != nil {
+				log.Fatal("fail to delete user")
+			}
+		}
+	}()
+
+	ownerTokens, err := utils.GenerateNewTokens(owner.ID.String(), []string{"book:create", "book:update"})
+	if err != nil {
+		log.Fatal(err)
+	}
+	collaboratorTokens, err := utils.GenerateNewTokens(collaborator.ID.String(), []string{"book:create", "book:update"})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	bookRoute := "/v1/book/" + book.ID.String()
+	bookUpdate := &models.Book{
+		Title:      "Test Title Update",
+		Author:     "John Doe",
+		BookStatus: repository.BookStatusPublished,
+		BookAttrs:  book.BookAttrs,
+	}
+
+	// Not a collaborator yet.
+	resp := sendTestRequest("PUT", bookRoute, collaboratorTokens.AccessToken, bookUpdate)
+	assert.Equal(t, 403, resp.StatusCode)
+
+	// Add as viewer, still can not update.
+	resp = sendTestRequest("POST", bookRoute+"/collaborators", ownerTokens.AccessToken, &models.AddCollaborator{
+		UserID:           collaborator.ID,
+		CollaboratorRole: repository.BookViewerRoleName,
+	})
+	assert.Equal(t, 201, resp.StatusCode)
+	resp = sendTestRequest("PUT", bookRoute, collaboratorTokens.AccessToken, bookUpdate)
+	assert.Equal(t, 403, resp.StatusCode)
+
+	// Promote to editor, can update.
+	resp = sendTestRequest("POST", bookRoute+"/collaborators", ownerTokens.AccessToken, &models.AddCollaborator{
+		UserID:           collaborator.ID,
+		CollaboratorRole: repository.BookEditorRoleName,
+	})
+	assert.Equal(t, 201, resp.StatusCode)
+	resp = sendTestRequest("PUT", bookRoute, collaboratorTokens.AccessToken, bookUpdate)
+	assert.Equal(t, 201, resp.StatusCode)
+
+	// Editors can not manage collaborators.
+	resp = sendTestRequest("POST", bookRoute+"/transfer", collaboratorTokens.AccessToken, &models.TransferOwnership{UserID: collaborator.ID})
+	assert.Equal(t, 403, resp.StatusCode)
+
+	// Transfer to the editor, the previous owner stays on as editor.
+	resp = sendTestRequest("POST", bookRoute+"/transfer", ownerTokens.AccessToken, &models.TransferOwnership{UserID: collaborator.ID})
+	assert.Equal(t, 200, resp.StatusCode)
+
+	var transferredBook models.Book
+	responseBodyBytes, _ := io.ReadAll(resp.Body)
+	_ = json.Unmarshal(responseBodyBytes, &transferredBook)
+	assert.Equal(t, collaborator.ID, transferredBook.UserID)
+
+	previousOwner, err := database.BookCollaboratorDB().GetCollaborator(book.ID, owner.ID)
+	assert.NoError(t, err)
+	if assert.NotNil(t, previousOwner) {
+		assert.Equal(t, repository.BookEditorRoleName, previousOwner.CollaboratorRole)
+	}
+
+	// The previous owner can leave the book.
+	resp = sendTestRequest("DELETE", bookRoute+"/collaborators/"+owner.ID.String(), ownerTokens.AccessToken, nil)
+	assert.Equal(t, 204, resp.StatusCode)
+}
+
+func TestAdminOverrideDeleteBook(t *testing.T) {
+	owner := createTestUser(repository.UserRoleName)
+	admin := createTestUser(repository.AdminRoleName)
+	book := createTestBook(owner.ID)
+	otherBook := createTestBook(owner.ID)
+
+	defer func() {
+		if err := deleteTestBook(otherBook.ID); err != nil {
+			log.Fatal("Fail to delete book")
+		}
+		for _, user := range []*models.User{owner, admin} {
+			err := database.UserDB().DeleteUser(user.ID)
+			if err != nil {
+				log.Fatal("fail to delete user")
+			}
+		}
+	}()
+
+	adminCredentials, err := database.RoleDB().GetUserPermissions(admin.ID)
+	if err != nil {
+		log.Fatal(err)
+	}
+	adminTokens, err := utils.GenerateNewTokens(admin.ID.String(), adminCredentials)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ownerTokens, err := utils.GenerateNewTokens(owner.ID.String(), []string{"book:update"})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	bookRoute := "/v1/book/" + book.ID.String()
+
+	// Acting on a book of another user requires a reason.
+	resp := sendTestRequest("DELETE", bookRoute, adminTokens.AccessToken, nil)
+	assert.Equal(t, 400, resp.StatusCode)
+
+	// Overrides are limited to the configured actions.
+	t.Run("not configured", func(t *testing.T) {
+		t.Setenv("BOOK_OVERRIDE_CREDENTIALS", "update="+repository.BookUpdateAnyCredential)
+		resp := sendTestRequest("DELETE", bookRoute+"?reason="+url.QueryEscape("spam listing"), adminTokens.AccessToken, nil)
+		assert.Equal(t, 403, resp.StatusCode)
+	})
+
+	// An override failing to act is not recorded.
+	resp = sendTestRequest("PATCH", "/v1/book/"+otherBook.ID.String(), ownerTokens.AccessToken, map[string]interface{}{"isbn": "9780262033848"})
+	assert.Equal(t, 200, resp.StatusCode)
+	resp = sendTestRequest("PATCH", bookRoute+"?reason="+url.QueryEscape("wrong isbn"), adminTokens.AccessToken, map[string]interface{}{"isbn": "9780262033848"})
+	assert.Equal(t, 409, resp.StatusCode)
+
+	resp = sendTestRequest("DELETE", bookRoute+"?reason="+url.QueryEscape("spam listing"), adminTokens.AccessToken, nil)
+	assert.Equal(t, 204, resp.StatusCode)
+
+	// The override is in the audit trail.
+	resp = sendTestRequest("GET", bookRoute+"/audit", adminTokens.AccessToken, nil)
+	assert.Equal(t, 200, resp.StatusCode)
+
+	var auditLogs []models.BookAuditLog
+	responseBodyBytes, _ := io.ReadAll(resp.Body)
+	_ = json.Unmarshal(responseBodyBytes, &auditLogs)
+
+	if assert.Len(t, auditLogs, 1) {
+		assert.Equal(t, admin.ID, auditLogs[0].ActorID)
+		assert.Equal(t, owner.ID, auditLogs[0].OwnerID)
+		assert.Equal(t, repository.BookDeleteAnyCredential, auditLogs[0].Credential)
+		assert.Equal(t, "spam listing", auditLogs[0].Reason)
+	}
+}
+
+func TestPatchBook(t *testing.T) {
+	owner := createTestUser(repository.UserRoleName)
+	book := createTestBook(owner.ID)
+
+	defer func() {
+		err := deleteTestBook(book.ID)
+		if err != nil {
+			log.Fatal("Fail to delete book")
+		}
+		err = database.UserDB().DeleteUser(owner.ID)
+		if err != nil {
+			log.Fatal("fail to delete user")
+		}
+	}()
+
+	ownerTokens, err := utils.GenerateNewTokens(owner.ID.String(), []string{"book:create", "book:update"})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Define a structure for specifying input and output data of test cases.
+	tests := []struct {
+		description  string
+		contentType  string
+		body         string
+		expectedCode int
+	}{
+		{
+			description:  "merge patch with zero values",
+			contentType:  utils.MergePatchContentType,
+			body:         `{"book_attrs": {"rating": 0, "description": null}}`,
+			expectedCode: 200,
+		},
+		{
+			description:  "json patch",
+			contentType:  utils.JSONPatchContentType,
+			body:         `[{"op": "test", "path": "/book_attrs/rating", "value": 0}, {"op": "replace", "path": "/title", "value": "Patched Title"}]`,
+			expectedCode: 200,
+		},
+		{
+			description:  "merged result is not valid",
+			contentType:  utils.MergePatchContentType,
+			body:         `{"title": null}`,
+			expectedCode: 400,
+		},
+		{
+			description:  "unsupported content type",
+			contentType:  "text/plain",
+			body:         `title`,
+			expectedCode: 415,
+		},
+	}
+
+	for _, test := range tests {
+		req := httptest.NewRequest("PATCH", "/v1/book/"+book.ID.String(), bytes.NewBufferString(test.body))
+		req.Header.Add("Authorization", "Bearer "+ownerTokens.AccessToken)
+		req.Header.Add("Content-Type", test.contentType)
+
+		// Perform the request plain with the AppTest.
+		resp, err := AppTest.Test(req, -1) // the -1 disables request latency
+		if err != nil {
+			log.Fatal("fail to patch book test")
+		}
+
+		assert.Equalf(t, test.expectedCode, resp.StatusCode, test.description)
+	}
+
+	patchedBook, err := database.BookDB().GetBookById(book.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "Patched Title", patchedBook.Title)
+	assert.Equal(t, 0, patchedBook.BookAttrs.Rating)
+	assert.Equal(t, "", patchedBook.BookAttrs.Description)
+	assert.Equal(t, book.BookAttrs.Picture, patchedBook.BookAttrs.Picture)
+}
+
+func TestBookETag(t *testing.T) {
+	owner := createTestUser(repository.UserRoleName)
+	book := createTestBook(owner.ID)
+
+	defer func() {
+		err := deleteTestBook(book.ID)
+		if err != nil {
+			log.Fatal("Fail to delete book")
+		}
+		err = database.UserDB().DeleteUser(owner.ID)
+		if err != nil {
+			log.Fatal("fail to delete user")
+		}
+	}()
+
+	ownerTokens, err := utils.GenerateNewTokens(owner.ID.String(), []string{"book:create", "book:update"})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	bookRoute := "/v1/book/" + book.ID.String()
+
+	// Get the book and its ETag.
+	req := httptest.NewRequest("GET", bookRoute, nil)
+	req.Header.Add("Authorization", "Basic YWRtaW46c2VjcmV0")
+	resp, err := AppTest.Test(req, -1) // the -1 disables request latency
+	if err != nil {
+		log.Fatal("fail to get book test")
+	}
+	etag := resp.Header.Get("ETag")
+	assert.Equal(t, 200, resp.StatusCode)
+	assert.NotEmpty(t, etag)
+
+	// Cached copy is still current.
+	req = httptest.NewRequest("GET", bookRoute, nil)
+	req.Header.Add("Authorization", "Basic YWRtaW46c2VjcmV0")
+	req.Header.Add("If-None-Match", etag)
+	resp, err = AppTest.Test(req, -1) // the -1 disables request latency
+	if err != nil {
+		log.Fatal("fail to get book test")
+	}
+	assert.Equal(t, 304, resp.StatusCode)
+
+	// First writer wins and gets a new ETag.
+	req = httptest.NewRequest("PATCH", bookRoute, bytes.NewBufferString(`{"title": "First Writer"}`))
+	req.Header.Add("Authorization", "Bearer "+ownerTokens.AccessToken)
+	req.Header.Add("Content-Type", utils.MergePatchContentType)
+	req.Header.Add("If-Match", etag)
+	resp, err = AppTest.Test(req, -1) // the -1 disables request latency
+	if err != nil {
+		log.Fatal("fail to patch book test")
+	}
+	assert.Equal(t, 200, resp.StatusCode)
+	assert.NotEqual(t, etag, resp.Header.Get("ETag"))
+
+	// Second writer still holds the old ETag.
+	bookUpdate := &models.Book{
+		Title:      "Second Writer",
+		Author:     "John Doe",
+		BookStatus: repository.BookStatusPublished,
+		BookAttrs:  book.BookAttrs,
+	}
+	reqBodyStr, _ := json.Marshal(bookUpdate)
+	req = httptest.NewRequest("PUT", bookRoute, bytes.NewBuffer(reqBodyStr))
+	req.Header.Add("Authorization", "Bearer "+ownerTokens.AccessToken)
+	req.Header.Add("Content-Type", "application/json")
+	req.Header.Add("If-Match", etag)
+	resp, err = AppTest.Test(req, -1) // the -1 disables request latency
+	if err != nil {
+		log.Fatal("fail to update book test")
+	}
+	assert.Equal(t, 412, resp.StatusCode)
+
+	storedBook, err := database.BookDB().GetBookById(book.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "First Writer", storedBook.Title)
+
+	// Any strong tag of a list may match.
+	currentETag := utils.VersionETag(storedBook.Version)
+	for ifMatch, status := range map[string]int{
+		etag + ", W/" + currentETag: 412,
+		etag + ", " + currentETag:   200,
+	} {
+		req = httptest.NewRequest("PATCH", bookRoute, bytes.NewBufferString(`{"title": "Listed Writer"}`))
+		req.Header.Add("Authorization", "Bearer "+ownerTokens.AccessToken)
+		req.Header.Add("Content-Type", utils.MergePatchContentType)
+		req.Header.Add("If-Match", ifMatch)
+		resp, err = AppTest.Test(req, -1) // the -1 disables request latency
+		if err != nil {
+			log.Fatal("fail to patch book test")
+		}
+		assert.Equal(t, status, resp.StatusCode, ifMatch)
+	}
+
+	// Deleting an outdated version fails too.
+	assert.ErrorIs(t, database.BookDB().DeleteBook(book.ID, storedBook.Version), queries.ErrBookVersionMismatch)
+}
+
+func TestBookTrashAndRestore(t *testing.T) {
+	owner := createTestUser(repository.UserRoleName)
+	book := createTestBook(owner.ID)
+
+	defer func() {
+		err := database.UserDB().DeleteUser(owner.ID)
+		if err != nil {
+			log.Fatal("fail to delete user")
+		}
+	}()
+
+	ownerTokens, err := utils.GenerateNewTokens(owner.ID.String(), []string{"book:create", "book:update", "book:delete"})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	bookRoute := "/v1/book/" + book.ID.String()
+
+	resp := sendTestRequest("DELETE", bookRoute, ownerTokens.AccessToken, nil)
+	assert.Equal(t, 204, resp.StatusCode)
+
+	// Trashed books are not found.
+	_, err = database.BookDB().GetBookById(book.ID)
+	assert.Error(t, err)
+
+	// But listed in the owner trash.
+	resp = sendTestRequest("GET", "/v1/books/trash", ownerTokens.AccessToken, nil)
+	assert.Equal(t, 200, resp.StatusCode)
+
+	var trashResponse models.AllBooks
+	responseBodyBytes, _ := io.ReadAll(resp.Body)
+	_ = json.Unmarshal(responseBodyBytes, &trashResponse)
+	if assert.Len(t, trashResponse.Books, 1) {
+		assert.Equal(t, book.ID, trashResponse.Books[0].ID)
+		assert.NotNil(t, trashResponse.Books[0].DeletedAt)
+	}
+
+	resp = sendTestRequest("POST", bookRoute+"/restore", ownerTokens.AccessToken, nil)
+	assert.Equal(t, 200, resp.StatusCode)
+
+	_, err = database.BookDB().GetBookById(book.ID)
+	assert.NoError(t, err)
+
+	// Deleting the owner trashes the books instead of wiping them.
+	err = database.UserDB().DeleteUser(owner.ID)
+	assert.NoError(t, err)
+
+	trashedBook, err := database.BookDB().GetTrashedBookById(book.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, uuid.Nil, trashedBook.UserID)
+
+	// Purging the trash deletes the revision history of the book with it.
+	countRevisions := func() int64 {
+		var count int64
+		assert.NoError(t, database.BookDB().Table("book_revisions").Where("book_id = ?", book.ID).Count(&count).Error)
+		return count
+	}
+	assert.NotZero(t, countRevisions())
+	longAgo := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	err = database.BookDB().Exec("UPDATE books SET deleted_at = ? WHERE id = ?", longAgo, book.ID).Error
+	assert.NoError(t, err)
+	purged, err := database.BookDB().PurgeTrashedBooks(longAgo.Add(time.Second))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), purged)
+	assert.Zero(t, countRevisions())
+}
+
+func TestBookRevisions(t *testing.T) {
+	owner := createTestUser(repository.UserRoleName)
+
+	ownerTokens, err := utils.GenerateNewTokens(owner.ID.String(), []string{"book:create", "book:update", "book:delete"})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	resp := sendTestRequest("POST", "/v1/book", ownerTokens.AccessToken, map[string]interface{}{
+		"title":  "Revision Title",
+		"author": "Revision Author",
+		"book_attrs": map[string]interface{}{
+			"picture":     "https://example.com/cover.png",
+			"description": "first revision",
+			"rating":      5,
+		},
+	})
+	assert.Equal(t, 201, resp.StatusCode)
+
+	var book models.Book
+	responseBodyBytes, _ := io.ReadAll(resp.Body)
+	_ = json.Unmarshal(responseBodyBytes, &book)
+
+	defer func() {
+		err := deleteTestBook(book.ID)
+		if err != nil {
+			log.Fatal("Fail to delete book")
+		}
+		err = database.UserDB().DeleteUser(owner.ID)
+		if err != nil {
+			log.Fatal("fail to delete user")
+		}
+	}()
+
+	bookRoute := "/v1/book/" + book.ID.String()
+
+	resp = sendTestRequest("PATCH", bookRoute, ownerTokens.AccessToken, map[string]interface{}{"title": "Changed Title"})
+	assert.Equal(t, 200, resp.StatusCode)
+
+	// Create and patch are both recorded, newest first.
+	resp = sendTestRequest("GET", bookRoute+"/revisions", ownerTokens.AccessToken, nil)
+	assert.Equal(t, 200, resp.StatusCode)
+
+	var revisions models.AllBookRevisions
+	responseBodyBytes, _ = io.ReadAll(resp.Body)
+	_ = json.Unmarshal(responseBodyBytes, &revisions)
+	assert.Equal(t, int64(2), revisions.Count)
+	if assert.Len(t, revisions.Revisions, 2) {
+		assert.Equal(t, 2, revisions.Revisions[0].Revision)
+		assert.Equal(t, repository.BookUpdateAction, revisions.Revisions[0].Action)
+		assert.Equal(t, "Changed Title", revisions.Revisions[0].Snapshot.Title)
+		assert.Equal(t, "Revision Title", revisions.Revisions[1].Snapshot.Title)
+	}
+
+	// The diff of revision 2 shows what the patch changed.
+	resp = sendTestRequest("GET", bookRoute+"/revisions/2/diff", ownerTokens.AccessToken, nil)
+	assert.Equal(t, 200, resp.StatusCode)
+
+	var diff struct {
+		From       int                        `json:"from"`
+		To         int                        `json:"to"`
+		Operations []utils.JSONPatchOperation `json:"operations"`
+	}
+	responseBodyBytes, _ = io.ReadAll(resp.Body)
+	_ = json.Unmarshal(responseBodyBytes, &diff)
+	assert.Equal(t, 1, diff.From)
+	assert.Contains(t, diff.Operations, utils.JSONPatchOperation{Op: "replace", Path: "/title", Value: json.RawMessage(`"Changed Title"`)})
+
+	resp = sendTestRequest("GET", bookRoute+"/revisions/9", ownerTokens.AccessToken, nil)
+	assert.Equal(t, 404, resp.StatusCode)
+
+	// Reverting brings the old title back as a new revision.
+	resp = sendTestRequest("POST", bookRoute+"/revisions/1/revert", ownerTokens.AccessToken, nil)
+	assert.Equal(t, 200, resp.StatusCode)
+
+	revertedBook, err := database.BookDB().GetBookById(book.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "Revision Title", revertedBook.Title)
+
+	revertRevision, err := database.BookRevisionDB().GetBookRevision(book.ID, 3)
+	assert.NoError(t, err)
+	assert.Equal(t, repository.BookRevertAction, revertRevision.Action)
+
+	// Revisions recorded at the same time are numbered one after another.
+	var wg sync.WaitGroup
+	errs := make([]error, 5)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = database.BookRevisionDB().CreateBookRevision(book.ID, owner.ID, repository.BookUpdateAction)
+		}(i)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		assert.NoError(t, err)
+	}
+	_, err = database.BookRevisionDB().GetBookRevision(book.ID, 3+len(errs))
+	assert.NoError(t, err)
+
+	// Other users can not read the history.
+	stranger := createTestUser(repository.UserRoleName)
+	defer func() {
+		err := database.UserDB().DeleteUser(stranger.ID)
+		if err != nil {
+			log.Fatal("fail to delete user")
+		}
+	}()
+
+	strangerTokens, err := utils.GenerateNewTokens(stranger.ID.String(), []string{"book:create", "book:update"})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	resp = sendTestRequest("GET", bookRoute+"/revisions", strangerTokens.AccessToken, nil)
+	assert.Equal(t, 403, resp.StatusCode)
+}
+
+func TestImportAndExportBooks(t *testing.T) {
+	owner := createTestUser(repository.UserRoleName)
+
+	defer func() {
+		err := database.UserDB().DeleteUser(owner.ID)
+		if err != nil {
+			log.Fatal("fail to delete user")
+		}
+	}()
+
+	ownerTokens, err := utils.GenerateNewTokens(owner.ID.String(), []string{"book:create"})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	csvBody := "title,author,rating,description\n" +
+		"Imported One,Import Author,7,first\n" +
+		",Import Author,3,missing title\n" +
+		"Imported Two,Import Author,eleven,bad rating\n" +
+		"\"Imported, Three\",Import Author,11,out of range\n" +
+		"Imported Four,Import Author,2,last\n"
+
+	req := httptest.NewRequest("POST", "/v1/books/import", strings.NewReader(csvBody))
+	req.Header.Add("Content-Type", "text/csv")
+	req.Header.Add("Authorization", "Bearer "+ownerTokens.AccessToken)
+	resp, err := AppTest.Test(req, -1)
+	if err != nil {
+		log.Fatal(err)
+	}
+	assert.Equal(t, 200, resp.StatusCode)
+
+	var report models.BookImportReport
+	responseBodyBytes, _ := io.ReadAll(resp.Body)
+	_ = json.Unmarshal(responseBodyBytes, &report)
+	assert.Equal(t, 2, report.Imported)
+	assert.Equal(t, 3, report.Failed)
+	if assert.Len(t, report.Errors, 3) {
+		assert.Equal(t, 3, report.Errors[0].Row)
+		assert.Contains(t, report.Errors[0].Fields, "Title")
+		assert.Equal(t, 4, report.Errors[1].Row)
+		assert.Equal(t, "rating must be a number", report.Errors[1].Error)
+		assert.Equal(t, 5, report.Errors[2].Row)
+		assert.Contains(t, report.Errors[2].Fields, "Rating")
+	}
+
+	// Unknown columns reject the whole file.
+	req = httptest.NewRequest("POST", "/v1/books/import", strings.NewReader("title,author,publisher\n"))
+	req.Header.Add("Content-Type", "text/csv")
+	req.Header.Add("Authorization", "Bearer "+ownerTokens.AccessToken)
+	resp, _ = AppTest.Test(req, -1)
+	assert.Equal(t, 400, resp.StatusCode)
+
+	ndjsonBody := `{"title": "Imported Five", "author": "Import Author", "book_attrs": {"rating": 1}}` + "\n\n" + `{"title": 5}` + "\n"
+	req = httptest.NewRequest("POST", "/v1/books/import", strings.NewReader(ndjsonBody))
+	req.Header.Add("Content-Type", "application/x-ndjson")
+	req.Header.Add("Authorization", "Bearer "+ownerTokens.AccessToken)
+	resp, _ = AppTest.Test(req, -1)
+	assert.Equal(t, 200, resp.StatusCode)
+
+	report = models.BookImportReport{}
+	responseBodyBytes, _ = io.ReadAll(resp.Body)
+	_ = json.Unmarshal(responseBodyBytes, &report)
+	assert.Equal(t, 1, report.Imported)
+	if assert.Len(t, report.Errors, 1) {
+		assert.Equal(t, 3, report.Errors[0].Row)
+	}
+
+	// Imported books are drafts, publish them to make them public.
+	err = database.BookDB().Exec("UPDATE books SET book_status = ? WHERE user_id = ?", repository.BookStatusPublished, owner.ID).Error
+	assert.NoError(t, err)
+
+	// Export streams only the books of the owner, sorted by title.
+	req = httptest.NewRequest("GET", "/v1/books/export?format=ndjson&sort=title&user_id="+owner.ID.String(), nil)
+	req.Header.Add("Authorization", "Basic YWRtaW46c2VjcmV0")
+	resp, _ = AppTest.Test(req, -1)
+	assert.Equal(t, 200, resp.StatusCode)
+	assert.Equal(t, "application/x-ndjson", resp.Header.Get("Content-Type"))
+
+	var titles []string
+	decoder := json.NewDecoder(resp.Body)
+	for decoder.More() {
+		var book models.BookForPublic
+		if err := decoder.Decode(&book); err != nil {
+			log.Fatal(err)
+		}
+		titles = append(titles, book.Title)
+	}
+	assert.Equal(t, []string{"Imported Five", "Imported Four", "Imported One"}, titles)
+
+	req = httptest.NewRequest("GET", "/v1/books/export?user_id="+owner.ID.String(), nil)
+	req.Header.Add("Authorization", "Basic YWRtaW46c2VjcmV0")
+	resp, _ = AppTest.Test(req, -1)
+	assert.Equal(t, 200, resp.StatusCode)
+
+	responseBodyBytes, _ = io.ReadAll(resp.Body)
+	lines := strings.Split(strings.TrimSpace(string(responseBodyBytes)), "\n")
+	assert.Equal(t, "id,title,author,isbn,book_status,picture,description,rating", lines[0])
+	assert.Len(t, lines, 4)
+
+	// Imports have their own size limit, other routes keep the body limit of the app.
+	t.Setenv("BOOK_IMPORT_MAX_SIZE_MB", "1")
+	req = httptest.NewRequest("POST", "/v1/books/import", bytes.NewReader(bytes.Repeat([]byte("x"), 2<<20)))
+	req.Header.Add("Content-Type", "text/csv")
+	req.Header.Add("Authorization", "Bearer "+ownerTokens.AccessToken)
+	resp, _ = AppTest.Test(req, -1)
+	assert.Equal(t, 413, resp.StatusCode)
+
+	req = httptest.NewRequest("POST", "/v1/book", bytes.NewReader(bytes.Repeat([]byte(" "), AppTest.Config().BodyLimit+1)))
+	req.Header.Add("Content-Type", "application/json")
+	req.Header.Add("Authorization", "Bearer "+ownerTokens.AccessToken)
+	resp, _ = AppTest.Test(req, -1)
+	assert.Equal(t, 413, resp.StatusCode)
+}
+
+func TestUploadBookCover(t *testing.T) {
+	owner := createTestUser(repository.UserRoleName)
+	book := createTestBook(owner.ID)
+
+	defer func() {
+		err := deleteTestBook(book.ID)
+		if err != nil {
+			log.Fatal("Fail to delete book")
+		}
+		err = database.UserDB().DeleteUser(owner.ID)
+		if err != nil {
+			log.Fatal("fail to delete user")
+		}
+	}()
+
+	ownerTokens, err := utils.GenerateNewTokens(owner.ID.String(), []string{"book:create", "book:update"})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	cover := &bytes.Buffer{}
+	_ = png.Encode(cover, image.NewNRGBA(image.Rect(0, 0, 800, 600)))
+
+	// Define a structure for specifying input and output data of test cases.
+	tests := []struct {
+		description  string
+		fileName     string
+		content      []byte
+		expectedCode int
+	}{
+		{
+			description:  "png cover",
+			fileName:     "cover.png",
+			content:      cover.Bytes(),
+			expectedCode: 200,
+		},
+		{
+			description:  "not an image",
+			fileName:     "cover.png",
+			content:      []byte("just some text"),
+			expectedCode: 415,
+		},
+	}
+
+	for _, test := range tests {
+		body := &bytes.Buffer{}
+		writer := multipart.NewWriter(body)
+		part, _ := writer.CreateFormFile("cover", test.fileName)
+		_, _ = part.Write(test.content)
+		_ = writer.Close()
+
+		req := httptest.NewRequest("POST", "/v1/book/"+book.ID.String()+"/cover", body)
+		req.Header.Add("Content-Type", writer.FormDataContentType())
+		req.Header.Add("Authorization", "Bearer "+ownerTokens.AccessToken)
+
+		resp, err := AppTest.Test(req, -1)
+		if err != nil {
+			log.Fatal(err)
+		}
+		assert.Equalf(t, test.expectedCode, resp.StatusCode, test.description)
+	}
+
+	coveredBook, err := database.BookDB().GetBookById(book.ID)
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(coveredBook.BookAttrs.Picture, "/media/books/"+book.ID.String()+"/cover-"))
+	assert.Len(t, coveredBook.BookAttrs.Thumbnails, 2)
+
+	// The cover and its thumbnails are served by the app.
+	for _, url := range []string{coveredBook.BookAttrs.Picture, coveredBook.BookAttrs.Thumbnails["small"]} {
+		resp, err := AppTest.Test(httptest.NewRequest("GET", url, nil), -1)
+		if err != nil {
+			log.Fatal(err)
+		}
+		assert.Equal(t, 200, resp.StatusCode)
+		assert.Equal(t, "image/png", resp.Header.Get("Content-Type"))
+	}
+
+	smallPath := filepath.Join(os.Getenv("STORAGE_LOCAL_PATH"), strings.TrimPrefix(coveredBook.BookAttrs.Thumbnails["small"], "/media/"))
+	small, err := os.Open(smallPath)
+	if assert.NoError(t, err) {
+		defer small.Close()
+		config, _, err := image.DecodeConfig(small)
+		assert.NoError(t, err)
+		assert.Equal(t, 160, config.Width)
+		assert.Equal(t, 120, config.Height)
+	}
+}
+
+func TestBookTagsCategoriesAndFacets(t *testing.T) {
+	owner := createTestUser(repository.UserRoleName)
+	moderator := createTestUser(repository.ModeratorRoleName)
+	book := createTestBook(owner.ID)
+	otherBook := createTestBook(owner.ID)
+
+	suffix := strings.ToLower(utils.String(8))
+	tagSlug := "tag-" + suffix
+	fictionSlug := "fiction-" + suffix
+	fantasySlug := "fantasy-" + suffix
+	shelfName := "shelf_" + utils.StringWithCharset(8, "abcdefghijklmnopqrstuvwxyz")
+
+	var shelf models.BookAttrDefinition
+	defer func() {
+		for _, b := range []*models.Book{book, otherBook} {
+			if err := deleteTestBook(b.ID); err != nil {
+				log.Fatal("Fail to delete book")
+			}
+		}
+		if shelf.ID != uuid.Nil {
+			_ = database.BookAttrDB().DeleteBookAttrDefinition(shelf.ID)
+		}
+		categories, _ := database.CategoryDB().GetCategoriesBySlugs([]string{fantasySlug, fictionSlug})
+		for _, category := range categories {
+			_ = database.CategoryDB().DeleteCategory(category.ID)
+		}
+		tags, _ := database.TagDB().GetTagsBySlugs([]string{tagSlug})
+		for _, tag := range tags {
+			_ = database.TagDB().DeleteTag(tag.ID)
+		}
+		for _, user := range []*models.User{owner, moderator} {
+			if err := database.UserDB().DeleteUser(user.ID); err != nil {
+				log.Fatal("fail to delete user")
+			}
+		}
+	}()
+
+	ownerTokens, err := utils.GenerateNewTokens(owner.ID.String(), []string{"book:create", "book:update", "book:delete"})
+	if err != nil {
+		log.Fatal(err)
+	}
+	moderatorTokens, err := utils.GenerateNewTokens(moderator.ID.String(), []string{"book:taxonomy", "book:attrs"})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Only users with the taxonomy credential manage tags and categories.
+	resp := sendTestRequest("POST", "/v1/tag", ownerTokens.AccessToken, map[string]interface{}{"name": "Tag " + suffix})
+	assert.Equal(t, 403, resp.StatusCode)
+
+	resp = sendTestRequest("POST", "/v1/tag", moderatorTokens.AccessToken, map[string]interface{}{"name": "Tag " + suffix})
+	assert.Equal(t, 201, resp.StatusCode)
+
+	resp = sendTestRequest("POST", "/v1/tag", moderatorTokens.AccessToken, map[string]interface{}{"name": "Tag " + suffix})
+	assert.Equal(t, 409, resp.StatusCode)
+
+	resp = sendTestRequest("POST", "/v1/category", moderatorTokens.AccessToken, map[string]interface{}{"name": "Fiction " + suffix})
+	assert.Equal(t, 201, resp.StatusCode)
+
+	var fiction models.Category
+	responseBodyBytes, _ := io.ReadAll(resp.Body)
+	_ = json.Unmarshal(responseBodyBytes, &fiction)
+	assert.Equal(t, fictionSlug, fiction.Slug)
+
+	resp = sendTestRequest("POST", "/v1/category", moderatorTokens.AccessToken, map[string]interface{}{"name": "Fantasy " + suffix, "parent_id": fiction.ID})
+	assert.Equal(t, 201, resp.StatusCode)
+
+	var fantasy models.Category
+	responseBodyBytes, _ = io.ReadAll(resp.Body)
+	_ = json.Unmarshal(responseBodyBytes, &fantasy)
+
+	// A category can not be moved under its own subcategory, nor deleted while it has one.
+	resp = sendTestRequest("PUT", "/v1/category/"+fiction.ID.String(), moderatorTokens.AccessToken, map[string]interface{}{"name": fiction.Name, "parent_id": fantasy.ID})
+	assert.Equal(t, 400, resp.StatusCode)
+
+	resp = sendTestRequest("DELETE", "/v1/category/"+fiction.ID.String(), moderatorTokens.AccessToken, nil)
+	assert.Equal(t, 409, resp.StatusCode)
+
+	// Owners set tags and categories of their books by slug.
+	resp = sendTestRequest("PUT", "/v1/book/"+book.ID.String()+"/tags", ownerTokens.AccessToken, map[string]interface{}{"tags": []string{tagSlug, "missing-" + suffix}})
+	assert.Equal(t, 400, resp.StatusCode)
+
+	resp = sendTestRequest("PUT", "/v1/book/"+book.ID.String()+"/tags", ownerTokens.AccessToken, map[string]interface{}{"tags": []string{tagSlug}})
+	assert.Equal(t, 200, resp.StatusCode)
+
+	resp = sendTestRequest("PUT", "/v1/book/"+book.ID.String()+"/categories", ownerTokens.AccessToken, map[string]interface{}{"categories": []string{fantasySlug}})
+	assert.Equal(t, 200, resp.StatusCode)
+
+	resp = sendTestRequest("PUT", "/v1/book/"+otherBook.ID.String()+"/categories", ownerTokens.AccessToken, map[string]interface{}{"categories": []string{fictionSlug}})
+	assert.Equal(t, 200, resp.StatusCode)
+
+	// Filtering by a parent category matches books of its subcategories, with facets of the matches.
+	req := httptest.NewRequest("GET", "/v1/books?category="+fictionSlug, nil)
+	req.Header.Add("Authorization", "Basic YWRtaW46c2VjcmV0")
+
+	// Perform the request plain with the AppTest.
+	resp, err = AppTest.Test(req, -1) // the -1 disables request latency
+	if err != nil {
+		log.Fatal("fail to get books test")
+	}
+	assert.Equal(t, 200, resp.StatusCode)
+
+	var getBooksResponse models.AllBooks
+	responseBodyBytes, _ = io.ReadAll(resp.Body)
+	_ = json.Unmarshal(responseBodyBytes, &getBooksResponse)
+	assert.Equal(t, int64(2), getBooksResponse.Count)
+	if assert.NotNil(t, getBooksResponse.Facets) {
+		assert.Contains(t, getBooksResponse.Facets.Tags, models.FacetCount{Slug: tagSlug, Name: "Tag " + suffix, Count: 1})
+		assert.Contains(t, getBooksResponse.Facets.Categories, models.FacetCount{Slug: fictionSlug, Name: fiction.Name, Count: 2})
+		assert.Contains(t, getBooksResponse.Facets.Categories, models.FacetCount{Slug: fantasySlug, Name: fantasy.Name, ParentID: &fiction.ID, Count: 1})
+	}
+
+	req = httptest.NewRequest("GET", "/v1/books?category="+fictionSlug+"&tag="+tagSlug, nil)
+	req.Header.Add("Authorization", "Basic YWRtaW46c2VjcmV0")
+
+	// Perform the request plain with the AppTest.
+	resp, err = AppTest.Test(req, -1) // the -1 disables request latency
+	if err != nil {
+		log.Fatal("fail to get books test")
+	}
+	assert.Equal(t, 200, resp.StatusCode)
+
+	responseBodyBytes, _ = io.ReadAll(resp.Body)
+	_ = json.Unmarshal(responseBodyBytes, &getBooksResponse)
+	assert.Equal(t, int64(1), getBooksResponse.Count)
+
+	// The export streams after the handler returned, every filter value has to outlive the request.
+	resp = sendTestRequest("POST", "/v1/attr", moderatorTokens.AccessToken, map[string]interface{}{"name": shelfName, "type": "string"})
+	assert.Equal(t, 201, resp.StatusCode)
+
+	responseBodyBytes, _ = io.ReadAll(resp.Body)
+	_ = json.Unmarshal(responseBodyBytes, &shelf)
+
+	err = database.BookDB().Exec("UPDATE books SET book_attrs = book_attrs || jsonb_build_object(?::text, 'top') WHERE id IN ?", shelfName, []uuid.UUID{book.ID, otherBook.ID}).Error
+	assert.NoError(t, err)
+
+	req = httptest.NewRequest("GET", "/v1/books/export?format=ndjson&tag="+tagSlug+"&category="+fictionSlug+"&attr."+shelfName+"=top&sort=-title,created_at", nil)
+	req.Header.Add("Authorization", "Basic YWRtaW46c2VjcmV0")
+	resp, _ = AppTest.Test(req, -1)
+	assert.Equal(t, 200, resp.StatusCode)
+
+	var exportedIDs []uuid.UUID
+	decoder := json.NewDecoder(resp.Body)
+	for decoder.More() {
+		var exportedBook models.BookForPublic
+		if err := decoder.Decode(&exportedBook); err != nil {
+			log.Fatal(err)
+		}
+		exportedIDs = append(exportedIDs, exportedBook.ID)
+	}
+	assert.Equal(t, []uuid.UUID{book.ID}, exportedIDs)
+}
+
+func TestBookReviews(t *testing.T) {
+	owner := createTestUser(repository.UserRoleName)
+	reader := createTestUser(repository.UserRoleName)
+	book := createTestBook(owner.ID)
+
+	defer func() {
+		if err := deleteTestBook(book.ID); err != nil {
+			log.Fatal("Fail to delete book")
+		}
+		for _, user := range []*models.User{owner, reader} {
+			if err := database.UserDB().DeleteUser(user.ID); err != nil {
+				log.Fatal("fail to delete user")
+			}
+		}
+	}()
+
+	ownerTokens, err := utils.GenerateNewTokens(owner.ID.String(), []string{"book:create"})
+	if err != nil {
+		log.Fatal(err)
+	}
+	readerTokens, err := utils.GenerateNewTokens(reader.ID.String(), []string{"book:create"})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	reviewRoute := "/v1/book/" + book.ID.String() + "/review"
+
+	resp := sendTestRequest("POST", reviewRoute, readerTokens.AccessToken, map[string]interface{}{"rating": 11})
+	assert.Equal(t, 400, resp.StatusCode)
+
+	resp = sendTestRequest("POST", reviewRoute, readerTokens.AccessToken, map[string]interface{}{"rating": 8, "body": "Great read"})
+	assert.Equal(t, 201, resp.StatusCode)
+
+	// A user reviews a book once.
+	resp = sendTestRequest("POST", reviewRoute, readerTokens.AccessToken, map[string]interface{}{"rating": 2})
+	assert.Equal(t, 409, resp.StatusCode)
+
+	resp = sendTestRequest("POST", reviewRoute, ownerTokens.AccessToken, map[string]interface{}{"rating": 0})
+	assert.Equal(t, 201, resp.StatusCode)
+
+	// The aggregate follows every change.
+	resp = sendTestRequest("PUT", reviewRoute, readerTokens.AccessToken, map[string]interface{}{"rating": 6, "body": "Good read"})
+	assert.Equal(t, 200, resp.StatusCode)
+
+	storedBook, err := database.BookDB().GetBookById(book.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, 3.0, storedBook.RatingAverage)
+	assert.Equal(t, 2, storedBook.RatingCount)
+
+	resp = sendTestRequest("DELETE", reviewRoute, ownerTokens.AccessToken, nil)
+	assert.Equal(t, 204, resp.StatusCode)
+
+	resp = sendTestRequest("DELETE", reviewRoute, ownerTokens.AccessToken, nil)
+	assert.Equal(t, 404, resp.StatusCode)
+
+	req := httptest.NewRequest("GET", "/v1/book/"+book.ID.String()+"/reviews", nil)
+	req.Header.Add("Authorization", "Basic YWRtaW46c2VjcmV0")
+
+	// Perform the request plain with the AppTest.
+	resp, err = AppTest.Test(req, -1) // the -1 disables request latency
+	if err != nil {
+		log.Fatal("fail to get reviews test")
+	}
+	assert.Equal(t, 200, resp.StatusCode)
+
+	var reviews models.AllReviews
+	responseBodyBytes, _ := io.ReadAll(resp.Body)
+	_ = json.Unmarshal(responseBodyBytes, &reviews)
+	assert.Equal(t, int64(1), reviews.Count)
+	assert.Equal(t, 6.0, reviews.RatingAverage)
+	if assert.Len(t, reviews.Reviews, 1) {
+		assert.Equal(t, reader.ID, reviews.Reviews[0].UserID)
+		assert.Equal(t, "Good read", reviews.Reviews[0].Body)
+	}
+}
+
+func TestUnpublishedBookVisibility(t *testing.T) {
+	owner := createTestUser(repository.UserRoleName)
+	collaborator := createTestUser(repository.UserRoleName)
+	stranger := createTestUser(repository.UserRoleName)
+	moderator := createTestUser(repository.ModeratorRoleName)
+	book := createTestBook(owner.ID)
+
+	defer func() {
+		if err := deleteTestBook(book.ID); err != nil {
+			log.Fatal("Fail to delete book")
+		}
+		for _, user := range []*models.User{owner, collaborator, stranger, moderator} {
+			if err := database.UserDB().DeleteUser(user.ID); err != nil {
+				log.Fatal("fail to delete user")
+			}
+		}
+	}()
+
+	ownerTokens, err := utils.GenerateNewTokens(owner.ID.String(), []string{"book:create", "book:update"})
+	if err != nil {
+		log.Fatal(err)
+	}
+	collaboratorTokens, err := utils.GenerateNewTokens(collaborator.ID.String(), []string{"book:create"})
+	if err != nil {
+		log.Fatal(err)
+	}
+	strangerTokens, err := utils.GenerateNewTokens(stranger.ID.String(), []string{"book:create"})
+	if err != nil {
+		log.Fatal(err)
+	}
+	moderatorTokens, err := utils.GenerateNewTokens(moderator.ID.String(), []string{"book:publish"})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	bookRoute := "/v1/book/" + book.ID.String()
+	resp := sendTestRequest("POST", bookRoute+"/collaborators", ownerTokens.AccessToken, &models.AddCollaborator{
+		UserID:           collaborator.ID,
+		CollaboratorRole: repository.BookViewerRoleName,
+	})
+	assert.Equal(t, 201, resp.StatusCode)
+
+	resp = sendTestRequest("PATCH", bookRoute, ownerTokens.AccessToken, map[string]interface{}{"isbn": "9780131103627"})
+	assert.Equal(t, 200, resp.StatusCode)
+
+	err = database.BookDB().Exec("UPDATE books SET book_status = ? WHERE id = ?", repository.BookStatusReview, book.ID).Error
+	assert.NoError(t, err)
+
+	routes := []string{
+		bookRoute,
+		"/v1/book/isbn/9780131103627",
+		bookRoute + "/tags",
+		bookRoute + "/categories",
+		bookRoute + "/reviews",
+		bookRoute + "/authors",
+		bookRoute + "/copies",
+	}
+
+	// Define a structure for specifying input and output data of test cases.
+	tests := []struct {
+		description   string
+		authorization string
+		expectedCode  int
+	}{
+		{
+			description:   "basic auth",
+			authorization: "Basic YWRtaW46c2VjcmV0",
+			expectedCode:  404,
+		},
+		{
+			description:   "other user",
+			authorization: "Bearer " + strangerTokens.AccessToken,
+			expectedCode:  404,
+		},
+		{
+			description:   "owner",
+			authorization: "Bearer " + ownerTokens.AccessToken,
+			expectedCode:  200,
+		},
+		{
+			description:   "collaborator",
+			authorization: "Bearer " + collaboratorTokens.AccessToken,
+			expectedCode:  200,
+		},
+		{
+			description:   "publisher",
+			authorization: "Bearer " + moderatorTokens.AccessToken,
+			expectedCode:  200,
+		},
+	}
+
+	for _, test := range tests {
+		for _, route := range routes {
+			req := httptest.NewRequest("GET", route, nil)
+			req.Header.Add("Authorization", test.authorization)
+
+			// Perform the request plain with the AppTest.
+			resp, err := AppTest.Test(req, -1) // the -1 disables request latency
+			if err != nil {
+				log.Fatal(err)
+			}
+			assert.Equalf(t, test.expectedCode, resp.StatusCode, "%v: %v", test.description, route)
+		}
+	}
+
+	// Published books are shown to everyone again.
+	err = database.BookDB().Exec("UPDATE books SET book_status = ? WHERE id = ?", repository.BookStatusPublished, book.ID).Error
+	assert.NoError(t, err)
+
+	req := httptest.NewRequest("GET", bookRoute, nil)
+	req.Header.Add("Authorization", "Basic YWRtaW46c2VjcmV0")
+	resp, _ = AppTest.Test(req, -1)
+	assert.Equal(t, 200, resp.StatusCode)
+}
+
+func TestBookStatusWorkflow(t *testing.T) {
+	owner := createTestUser(repository.UserRoleName)
+	moderator := createTestUser(repository.ModeratorRoleName)
+
+	ownerTokens, err := utils.GenerateNewTokens(owner.ID.String(), []string{"book:create", "book:update", "book:delete"})
+	if err != nil {
+		log.Fatal(err)
+	}
+	moderatorTokens, err := utils.GenerateNewTokens(moderator.ID.String(), []string{"book:create", "book:publish"})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Unique author to keep the list isolated from other tests.
+	author := "Author " + utils.String(12)
+
+	resp := sendTestRequest("POST", "/v1/book", ownerTokens.AccessToken, map[string]interface{}{
+		"title":      "Workflow Title",
+		"author":     author,
+		"book_attrs": map[string]interface{}{"rating": 5},
+	})
+	assert.Equal(t, 201, resp.StatusCode)
+
+	var book models.Book
+	responseBodyBytes, _ := io.ReadAll(resp.Body)
+	_ = json.Unmarshal(responseBodyBytes, &book)
+	assert.Equal(t, repository.BookStatusDraft, book.BookStatus)
+
+	defer func() {
+		if err := deleteTestBook(book.ID); err != nil {
+			log.Fatal("Fail to delete book")
+		}
+		for _, user := range []*models.User{owner, moderator} {
+			if err := database.UserDB().DeleteUser(user.ID); err != nil {
+				log.Fatal("fail to delete user")
+			}
+		}
+	}()
+
+	countPublicBooks := func() int64 {
+		req := httptest.NewRequest("GET", "/v1/books?author="+url.QueryEscape(author), nil)
+		req.Header.Add("Authorization", "Basic YWRtaW46c2VjcmV0")
+
+		// Perform the request plain with the AppTest.
+		resp, err := AppTest.Test(req, -1) // the -1 disables request latency
+		if err != nil {
+			log.Fatal("fail to get books test")
+		}
+
+		var getBooksResponse models.AllBooks
+		responseBodyBytes, _ := io.ReadAll(resp.Body)
+		_ = json.Unmarshal(responseBodyBytes, &getBooksResponse)
+		return getBooksResponse.Count
+	}
+	assert.Equal(t, int64(0), countPublicBooks())
+
+	transitionRoute := "/v1/book/" + book.ID.String() + "/transition"
+
+	// Drafts go through review before they are published.
+	resp = sendTestRequest("POST", transitionRoute, ownerTokens.AccessToken, map[string]interface{}{"book_status": "published"})
+	assert.Equal(t, 409, resp.StatusCode)
+
+	resp = sendTestRequest("POST", transitionRoute, ownerTokens.AccessToken, map[string]interface{}{"book_status": "review"})
+	assert.Equal(t, 200, resp.StatusCode)
+
+	// Only publishers publish.
+	resp = sendTestRequest("POST", transitionRoute, ownerTokens.AccessToken, map[string]interface{}{"book_status": "published"})
+	assert.Equal(t, 403, resp.StatusCode)
+
+	resp = sendTestRequest("GET", "/v1/books/workflow?book_status=review&user_id="+owner.ID.String(), moderatorTokens.AccessToken, nil)
+	assert.Equal(t, 200, resp.StatusCode)
+
+	var queue models.AllBooks
+	responseBodyBytes, _ = io.ReadAll(resp.Body)
+	_ = json.Unmarshal(responseBodyBytes, &queue)
+	assert.Equal(t, int64(1), queue.Count)
+
+	resp = sendTestRequest("POST", transitionRoute, moderatorTokens.AccessToken, map[string]interface{}{"book_status": "published"})
+	assert.Equal(t, 200, resp.StatusCode)
+	assert.Equal(t, int64(1), countPublicBooks())
+
+	// Public lists only show published books, asking for drafts is refused
+	// rather than answered with the published one.
+	req := httptest.NewRequest("GET", "/v1/books?book_status=draft&author="+url.QueryEscape(author), nil)
+	req.Header.Add("Authorization", "Basic YWRtaW46c2VjcmV0")
+	resp, err = AppTest.Test(req, -1) // the -1 disables request latency
+	if err != nil {
+		log.Fatal("fail to get books test")
+	}
+	assert.Equal(t, 400, resp.StatusCode)
+
+	// Edits keep the status, it only moves through transitions.
+	resp = sendTestRequest("PATCH", "/v1/book/"+book.ID.String(), ownerTokens.AccessToken, map[string]interface{}{"book_status": "draft"})
+	assert.Equal(t, 200, resp.StatusCode)
+
+	storedBook, err := database.BookDB().GetBookById(book.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, repository.BookStatusPublished, storedBook.BookStatus)
+
+	resp = sendTestRequest("POST", transitionRoute, ownerTokens.AccessToken, map[string]interface{}{"book_status": "archived"})
+	assert.Equal(t, 200, resp.StatusCode)
+	assert.Equal(t, int64(0), countPublicBooks())
+
+	revision, err := database.BookRevisionDB().GetBookRevision(book.ID, 4)
+	assert.NoError(t, err)
+	assert.Equal(t, repository.BookTransitionAction, revision.Action)
+	assert.Equal(t, repository.BookStatusArchived, revision.Snapshot.BookStatus)
+}
+
+func TestBookISBN(t *testing.T) {
+	owner := createTestUser(repository.UserRoleName)
+	book := createTestBook(owner.ID)
+
+	defer func() {
+		if err := deleteTestBook(book.ID); err != nil {
+			log.Fatal("Fail to delete book")
+		}
+		if err := database.UserDB().DeleteUser(owner.ID); err != nil {
+			log.Fatal("fail to delete user")
+		}
+	}()
+
+	ownerTokens, err := utils.GenerateNewTokens(owner.ID.String(), []string{"book:create", "book:update"})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	bookRoute := "/v1/book/" + book.ID.String()
+
+	// Checksums are verified.
+	resp := sendTestRequest("PATCH", bookRoute, ownerTokens.AccessToken, map[string]interface{}{"isbn": "0-306-40615-3"})
+	assert.Equal(t, 400, resp.StatusCode)
+
+	// ISBN-10 is stored as ISBN-13.
+	resp = sendTestRequest("PATCH", bookRoute, ownerTokens.AccessToken, map[string]interface{}{"isbn": "0-306-40615-2"})
+	assert.Equal(t, 200, resp.StatusCode)
+
+	var patchedBook models.Book
+	responseBodyBytes, _ := io.ReadAll(resp.Body)
+	_ = json.Unmarshal(responseBodyBytes, &patchedBook)
+	if assert.NotNil(t, patchedBook.ISBN) {
+		assert.Equal(t, "9780306406157", *patchedBook.ISBN)
+	}
+
+	// Another book can not take the same ISBN in any form.
+	resp = sendTestRequest("POST", "/v1/book", ownerTokens.AccessToken, map[string]interface{}{
+		"title":      "Other Title",
+		"author":     "Other Author",
+		"isbn":       "978-0-306-40615-7",
+		"book_attrs": map[string]interface{}{"rating": 5},
+	})
+	assert.Equal(t, 409, resp.StatusCode)
+
+	req := httptest.NewRequest("GET", "/v1/book/isbn/0306406152", nil)
+	req.Header.Add("Authorization", "Basic YWRtaW46c2VjcmV0")
+
+	// Perform the request plain with the AppTest.
+	resp, err = AppTest.Test(req, -1) // the -1 disables request latency
+	if err != nil {
+		log.Fatal("fail to get book by isbn test")
+	}
+	assert.Equal(t, 200, resp.StatusCode)
+
+	var foundBook models.Book
+	responseBodyBytes, _ = io.ReadAll(resp.Body)
+	_ = json.Unmarshal(responseBodyBytes, &foundBook)
+	assert.Equal(t, book.ID, foundBook.ID)
+
+	req = httptest.NewRequest("GET", "/v1/book/isbn/123", nil)
+	req.Header.Add("Authorization", "Basic YWRtaW46c2VjcmV0")
+	resp, _ = AppTest.Test(req, -1)
+	assert.Equal(t, 400, resp.StatusCode)
+}
+
+func TestBookAttrDefinitions(t *testing.T) {
+	admin := createTestUser(repository.AdminRoleName)
+
+	adminTokens, err := utils.GenerateNewTokens(admin.ID.String(), []string{"book:create", "book:update", "book:attrs"})
+	if err != nil {
+		log.Fatal(err)
+	}
+	userTokens, err := utils.GenerateNewTokens(admin.ID.String(), []string{"book:create"})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Unique names to keep other tests free of these attributes.
+	suffix := utils.StringWithCharset(8, "abcdefghijklmnopqrstuvwxyz")
+	pagesName, formatName := "pages_"+suffix, "format_"+suffix
+
+	var definitions []models.BookAttrDefinition
+	var book models.Book
+	defer func() {
+		for _, definition := range definitions {
+			_ = database.BookAttrDB().DeleteBookAttrDefinition(definition.ID)
+		}
+		if book.ID != uuid.Nil {
+			if err := deleteTestBook(book.ID); err != nil {
+				log.Fatal("Fail to delete book")
+			}
+		}
+		if err := database.UserDB().DeleteUser(admin.ID); err != nil {
+			log.Fatal("fail to delete user")
+		}
+	}()
+
+	resp := sendTestRequest("POST", "/v1/attr", userTokens.AccessToken, map[string]interface{}{"name": pagesName, "type": "integer"})
+	assert.Equal(t, 403, resp.StatusCode)
+
+	resp = sendTestRequest("POST", "/v1/attr", adminTokens.AccessToken, map[string]interface{}{"name": "rating", "type": "integer"})
+	assert.Equal(t, 409, resp.StatusCode)
+
+	resp = sendTestRequest("POST", "/v1/attr", adminTokens.AccessToken, map[string]interface{}{"name": formatName, "type": "enum"})
+	assert.Equal(t, 400, resp.StatusCode)
+
+	for _, body := range []map[string]interface{}{
+		{"name": pagesName, "type": "integer"},
+		{"name": formatName, "type": "enum", "enum_values": []string{"hardcover", "paperback"}},
+	} {
+		resp = sendTestRequest("POST", "/v1/attr", adminTokens.AccessToken, body)
+		assert.Equal(t, 201, resp.StatusCode)
+
+		var definition models.BookAttrDefinition
+		responseBodyBytes, _ := io.ReadAll(resp.Body)
+		_ = json.Unmarshal(responseBodyBytes, &definition)
+		definitions = append(definitions, definition)
+	}
+
+	resp = sendTestRequest("POST", "/v1/attr", adminTokens.AccessToken, map[string]interface{}{"name": pagesName, "type": "number"})
+	assert.Equal(t, 409, resp.StatusCode)
+
+	// Values are checked against the type of their attribute.
+	for _, attrs := range []map[string]interface{}{
+		{"rating": 5, pagesName: 320.5},
+		{"rating": 5, formatName: "audiobook"},
+		{"rating": 5, "unknown_" + suffix: true},
+	} {
+		resp = sendTestRequest("POST", "/v1/book", adminTokens.AccessToken, map[string]interface{}{
+			"title":      "Attrs Title",
+			"author":     "Attrs Author",
+			"book_attrs": attrs,
+		})
+		assert.Equal(t, 400, resp.StatusCode)
+	}
+
+	resp = sendTestRequest("POST", "/v1/book", adminTokens.AccessToken, map[string]interface{}{
+		"title":      "Attrs Title",
+		"author":     "Attrs Author",
+		"book_attrs": map[string]interface{}{"rating": 5, pagesName: 320, formatName: "paperback"},
+	})
+	assert.Equal(t, 201, resp.StatusCode)
+
+	responseBodyBytes, _ := io.ReadAll(resp.Body)
+	_ = json.Unmarshal(responseBodyBytes, &book)
+	assert.Equal(t, 320.0, book.BookAttrs.Extra[pagesName])
+	assert.Equal(t, "paperback", book.BookAttrs.Extra[formatName])
+
+	err = database.BookDB().Exec("UPDATE books SET book_status = ? WHERE id = ?", repository.BookStatusPublished, book.ID).Error
+	assert.NoError(t, err)
+
+	countBooks := func(query string) (int, int64) {
+		req := httptest.NewRequest("GET", "/v1/books?"+query, nil)
+		req.Header.Add("Authorization", "Basic YWRtaW46c2VjcmV0")
+
+		// Perform the request plain with the AppTest.
+		resp, err := AppTest.Test(req, -1) // the -1 disables request latency
+		if err != nil {
+			log.Fatal("fail to get books test")
+		}
+
+		var getBooksResponse models.AllBooks
+		responseBodyBytes, _ := io.ReadAll(resp.Body)
+		_ = json.Unmarshal(responseBodyBytes, &getBooksResponse)
+		return resp.StatusCode, getBooksResponse.Count
+	}
+
+	status, count := countBooks("attr." + pagesName + "=320&attr." + formatName + "=paperback")
+	assert.Equal(t, 200, status)
+	assert.Equal(t, int64(1), count)
+
+	status, count = countBooks("attr." + formatName + "=hardcover")
+	assert.Equal(t, 200, status)
+	assert.Equal(t, int64(0), count)
+
+	status, _ = countBooks("attr." + pagesName + "=many")
+	assert.Equal(t, 400, status)
+
+	status, _ = countBooks("attr.unknown_" + suffix + "=1")
+	assert.Equal(t, 400, status)
+
+	// Books lose the value of a deleted attribute.
+	resp = sendTestRequest("DELETE", "/v1/attr/"+definitions[0].ID.String(), adminTokens.AccessToken, nil)
+	assert.Equal(t, 204, resp.StatusCode)
+	definitions = definitions[1:]
+
+	storedBook, err := database.BookDB().GetBookById(book.ID)
+	assert.NoError(t, err)
+	assert.NotContains(t, storedBook.BookAttrs.Extra, pagesName)
+	assert.Equal(t, "paperback", storedBook.BookAttrs.Extra[formatName])
+	assert.Equal(t, book.Version+1, storedBook.Version)
+}
+
+func TestBookBatch(t *testing.T) {
+	owner := createTestUser(repository.UserRoleName)
+	stranger := createTestUser(repository.UserRoleName)
+	ownBook := createTestBook(owner.ID)
+	strangerBook := createTestBook(stranger.ID)
+
+	bookIDs := []uuid.UUID{ownBook.ID, strangerBook.ID}
+	defer func() {
+		for _, id := range bookIDs {
+			if err := deleteTestBook(id); err != nil {
+				log.Fatal("Fail to delete book")
+			}
+		}
+		for _, user := range []*models.User{owner, stranger} {
+			if err := database.UserDB().DeleteUser(user.ID); err != nil {
+				log.Fatal("fail to delete user")
+			}
+		}
+	}()
+
+	ownerTokens, err := utils.GenerateNewTokens(owner.ID.String(), []string{"book:create", "book:update", "book:delete"})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	newBook := func(title string) map[string]interface{} {
+		return map[string]interface{}{
+			"title":      title,
+			"author":     "Batch Author",
+			"book_attrs": map[string]interface{}{"rating": 5},
+		}
+	}
+	sendBatch := func(atomic bool, operations []map[string]interface{}) models.BookBatchReport {
+		resp := sendTestRequest("POST", "/v1/books/batch", ownerTokens.AccessToken, map[string]interface{}{
+			"atomic":     atomic,
+			"operations": operations,
+		})
+		assert.Equal(t, 200, resp.StatusCode)
+
+		var report models.BookBatchReport
+		responseBodyBytes, _ := io.ReadAll(resp.Body)
+		_ = json.Unmarshal(responseBodyBytes, &report)
+		return report
+	}
+	statuses := func(report models.BookBatchReport) []int {
+		codes := []int{}
+		for _, result := range report.Results {
+			codes = append(codes, result.Status)
+		}
+		return codes
+	}
+
+	// Best effort applies every operation it can.
+	updatedBook := newBook("Batch Two")
+	updatedBook["version"] = 7
+	report := sendBatch(false, []map[string]interface{}{
+		{"op": "create", "book": newBook("Batch One")},
+		{"op": "update", "id": strangerBook.ID, "book": newBook("Not Mine")},
+		{"op": "delete", "id": ownBook.ID, "version": 99},
+		{"op": "update", "id": ownBook.ID, "version": 1, "book": updatedBook},
+	})
+	assert.Equal(t, []int{201, 403, 412, 200}, statuses(report))
+	assert.Equal(t, 2, report.Succeeded)
+	assert.Equal(t, 2, report.Failed)
+	assert.False(t, report.RolledBack)
+	if len(report.Results) == 4 && report.Results[0].ID != nil {
+		bookIDs = append(bookIDs, *report.Results[0].ID)
+	}
+	if len(report.Results) == 4 && assert.NotNil(t, report.Results[3].Book) {
+		// The updated book carries its new version for the next `version` or If-Match, not the one sent.
+		assert.Equal(t, 2, report.Results[3].Book.Version)
+	}
+
+	storedBook, err := database.BookDB().GetBookById(ownBook.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "Batch Two", storedBook.Title)
+
+	// Atomic batches are rolled back by any failure.
+	report = sendBatch(true, []map[string]interface{}{
+		{"op": "create", "book": newBook("Batch Three")},
+		{"op": "delete", "id": ownBook.ID},
+		{"op": "create", "book": newBook("")},
+		{"op": "delete", "id": strangerBook.ID},
+	})
+	assert.Equal(t, []int{424, 424, 400, 424}, statuses(report))
+	assert.Equal(t, 0, report.Succeeded)
+	assert.True(t, report.RolledBack)
+
+	_, err = database.BookDB().GetBookById(ownBook.ID)
+	assert.NoError(t, err)
+
+	var count int64
+	err = database.BookDB().Table("books").Where("user_id = ? AND title = ?", owner.ID, "Batch Three").Count(&count).Error
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), count)
+
+	// Operations of an atomic batch see the books created before them.
+	first, second := newBook("Batch Four"), newBook("Batch Five")
+	first["isbn"], second["isbn"] = "978-0-13-419044-0", "9780134190440"
+	report = sendBatch(true, []map[string]interface{}{
+		{"op": "create", "book": first},
+		{"op": "create", "book": second},
+	})
+	assert.Equal(t, []int{424, 409}, statuses(report))
+	assert.True(t, report.RolledBack)
+}
+
+func TestAuthorEntities(t *testing.T) {
+	owner := createTestUser(repository.UserRoleName)
+	moderator := createTestUser(repository.ModeratorRoleName)
+
+	ownerTokens, err := utils.GenerateNewTokens(owner.ID.String(), []string{"book:create", "book:update"})
+	if err != nil {
+		log.Fatal(err)
+	}
+	moderatorTokens, err := utils.GenerateNewTokens(moderator.ID.String(), []string{"book:taxonomy"})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Unique names to keep the authors apart from other tests.
+	suffix := utils.String(8)
+
+	resp := sendTestRequest("POST", "/v1/book", ownerTokens.AccessToken, map[string]interface{}{
+		"title":      "Credits Title",
+		"author":     "J. Doe " + suffix,
+		"book_attrs": map[string]interface{}{"rating": 5},
+	})
+	assert.Equal(t, 201, resp.StatusCode)
+
+	var book models.Book
+	responseBodyBytes, _ := io.ReadAll(resp.Body)
+	_ = json.Unmarshal(responseBodyBytes, &book)
+
+	authorIDs := []uuid.UUID{}
+	defer func() {
+		for _, id := range authorIDs {
+			_ = database.AuthorDB().DeleteAuthor(id)
+		}
+		if err := deleteTestBook(book.ID); err != nil {
+			log.Fatal("Fail to delete book")
+		}
+		for _, user := range []*models.User{owner, moderator} {
+			if err := database.UserDB().DeleteUser(user.ID); err != nil {
+				log.Fatal("fail to delete user")
+			}
+		}
+	}()
+
+	getBookAuthors := func() []models.BookAuthor {
+		// The book is still a draft, only its owner sees it.
+		req := httptest.NewRequest("GET", "/v1/book/"+book.ID.String()+"/authors", nil)
+		req.Header.Add("Authorization", "Bearer "+ownerTokens.AccessToken)
+
+		// Perform the request plain with the AppTest.
+		resp, err := AppTest.Test(req, -1) // the -1 disables request latency
+		if err != nil {
+			log.Fatal("fail to get book authors test")
+		}
+		assert.Equal(t, 200, resp.StatusCode)
+
+		var authors []models.BookAuthor
+		responseBodyBytes, _ := io.ReadAll(resp.Body)
+		_ = json.Unmarshal(responseBodyBytes, &authors)
+		return authors
+	}
+
+	// New books are credited to the author of their author name.
+	credits := getBookAuthors()
+	if assert.Len(t, credits, 1) {
+		assert.Equal(t, "J. Doe "+suffix, credits[0].Name)
+		assert.Equal(t, repository.AuthorRoleAuthor, credits[0].Role)
+		authorIDs = append(authorIDs, credits[0].ID)
+	}
+
+	createAuthor := func(name string) models.Author {
+		resp := sendTestRequest("POST", "/v1/author", ownerTokens.AccessToken, map[string]interface{}{"name": name})
+		assert.Equal(t, 201, resp.StatusCode)
+
+		var author models.Author
+		responseBodyBytes, _ := io.ReadAll(resp.Body)
+		_ = json.Unmarshal(responseBodyBytes, &author)
+		authorIDs = append(authorIDs, author.ID)
+		return author
+	}
+	john := createAuthor("John Doe " + suffix)
+	translator := createAuthor("Jan Translator " + suffix)
+
+	bookAuthorsRoute := "/v1/book/" + book.ID.String() + "/authors"
+	resp = sendTestRequest("PUT", bookAuthorsRoute, ownerTokens.AccessToken, map[string]interface{}{
+		"authors": []map[string]interface{}{{"author_id": uuid.New(), "role": "author"}},
+	})
+	assert.Equal(t, 400, resp.StatusCode)
+
+	resp = sendTestRequest("PUT", bookAuthorsRoute, ownerTokens.AccessToken, map[string]interface{}{
+		"authors": []map[string]interface{}{
+			{"author_id": john.ID, "role": "author"},
+			{"author_id": translator.ID, "role": "translator"},
+		},
+	})
+	assert.Equal(t, 200, resp.StatusCode)
+
+	// The author name follows the credits.
+	storedBook, err := database.BookDB().GetBookById(book.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, john.Name, storedBook.Author)
+	assert.Equal(t, book.Version+1, storedBook.Version)
+
+	// The credits follow the author name, other roles are kept.
+	bookRoute := "/v1/book/" + book.ID.String()
+	resp = sendTestRequest("PATCH", bookRoute, ownerTokens.AccessToken, map[string]interface{}{"author": "Jane Roe " + suffix})
+	assert.Equal(t, 200, resp.StatusCode)
+
+	credits = getBookAuthors()
+	if assert.Len(t, credits, 2) {
+		assert.Equal(t, "Jane Roe "+suffix, credits[0].Name)
+		assert.Equal(t, repository.AuthorRoleAuthor, credits[0].Role)
+		assert.Equal(t, translator.ID, credits[1].ID)
+		authorIDs = append(authorIDs, credits[0].ID)
+	}
+
+	resp = sendTestRequest("PATCH", bookRoute, ownerTokens.AccessToken, map[string]interface{}{"author": john.Name})
+	assert.Equal(t, 200, resp.StatusCode)
+
+	credits = getBookAuthors()
+	if assert.Len(t, credits, 2) {
+		assert.Equal(t, john.ID, credits[0].ID)
+		assert.Equal(t, translator.ID, credits[1].ID)
+	}
+
+	err = database.BookDB().Exec("UPDATE books SET book_status = ? WHERE id = ?", repository.BookStatusPublished, book.ID).Error
+	assert.NoError(t, err)
+
+	req := httptest.NewRequest("GET", "/v1/author/"+translator.ID.String()+"/books", nil)
+	req.Header.Add("Authorization", "Basic YWRtaW46c2VjcmV0")
+	resp, _ = AppTest.Test(req, -1)
+	assert.Equal(t, 200, resp.StatusCode)
+
+	var getBooksResponse models.AllBooks
+	responseBodyBytes, _ = io.ReadAll(resp.Body)
+	_ = json.Unmarshal(responseBodyBytes, &getBooksResponse)
+	assert.Equal(t, int64(1), getBooksResponse.Count)
+
+	// Only taxonomy managers edit shared author profiles.
+	resp = sendTestRequest("PUT", "/v1/author/"+john.ID.String(), ownerTokens.AccessToken, map[string]interface{}{"name": john.Name})
+	assert.Equal(t, 403, resp.StatusCode)
+
+	resp = sendTestRequest("PUT", "/v1/author/"+john.ID.String(), moderatorTokens.AccessToken, map[string]interface{}{
+		"name":    john.Name,
+		"website": "not a url",
+	})
+	assert.Equal(t, 400, resp.StatusCode)
+
+	resp = sendTestRequest("PUT", "/v1/author/"+john.ID.String(), moderatorTokens.AccessToken, map[string]interface{}{
+		"name":       john.Name,
+		"bio":        "Writes books",
+		"birth_year": 1970,
+	})
+	assert.Equal(t, 200, resp.StatusCode)
+
+	resp = sendTestRequest("DELETE", "/v1/author/"+john.ID.String(), moderatorTokens.AccessToken, nil)
+	assert.Equal(t, 204, resp.StatusCode)
+
+	credits = getBookAuthors()
+	if assert.Len(t, credits, 1) {
+		assert.Equal(t, translator.ID, credits[0].ID)
+	}
+}
+
+func TestReadingLists(t *testing.T) {
+	owner := createTestUser(repository.UserRoleName)
+	reader := createTestUser(repository.UserRoleName)
+
+	readerTokens, err := utils.GenerateNewTokens(reader.ID.String(), []string{})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	published := createTestBook(owner.ID)
+	other := createTestBook(owner.ID)
+	draft := createTestBook(owner.ID)
+	err = database.BookDB().Exec("UPDATE books SET book_status = ? WHERE id = ?", repository.BookStatusDraft, draft.ID).Error
+	assert.NoError(t, err)
+
+	defer func() {
+		for _, book := range []*models.Book{published, other, draft} {
+			if err := deleteTestBook(book.ID); err != nil {
+				log.Fatal("Fail to delete book")
+			}
+		}
+		for _, user := range []*models.User{owner, reader} {
+			if err := database.UserDB().DeleteUser(user.ID); err != nil {
+				log.Fatal("fail to delete user")
+			}
+		}
+	}()
+
+	// The built-in lists are there from the start.
+	resp := sendTestRequest("GET", "/v1/reading-lists", readerTokens.AccessToken, nil)
+	assert.Equal(t, 200, resp.StatusCode)
+
+	var lists []models.ReadingList
+	responseBodyBytes, _ := io.ReadAll(resp.Body)
+	_ = json.Unmarshal(responseBodyBytes, &lists)
+	if assert.Len(t, lists, 3) {
+		assert.Equal(t, repository.ReadingListWantToRead, lists[0].Kind)
+	}
+
+	resp = sendTestRequest("DELETE", "/v1/reading-list/"+lists[0].ID.String(), readerTokens.AccessToken, nil)
+	assert.Equal(t, 409, resp.StatusCode)
+
+	resp = sendTestRequest("POST", "/v1/reading-list", readerTokens.AccessToken, map[string]interface{}{"name": "Summer"})
+	assert.Equal(t, 201, resp.StatusCode)
+
+	var list models.ReadingList
+	responseBodyBytes, _ = io.ReadAll(resp.Body)
+	_ = json.Unmarshal(responseBodyBytes, &list)
+	assert.Nil(t, list.ShareToken)
+
+	listRoute := "/v1/reading-list/" + list.ID.String()
+	for _, book := range []*models.Book{published, other} {
+		resp = sendTestRequest("POST", listRoute+"/books", readerTokens.AccessToken, map[string]interface{}{"book_id": book.ID})
+		assert.Equal(t, 200, resp.StatusCode)
+	}
+	resp = sendTestRequest("POST", listRoute+"/books", readerTokens.AccessToken, map[string]interface{}{"book_id": published.ID})
+	assert.Equal(t, 409, resp.StatusCode)
+
+	// Drafts of other users can not be added.
+	resp = sendTestRequest("POST", listRoute+"/books", readerTokens.AccessToken, map[string]interface{}{"book_id": draft.ID})
+	assert.Equal(t, 403, resp.StatusCode)
+
+	resp = sendTestRequest("PUT", listRoute+"/order", readerTokens.AccessToken, map[string]interface{}{
+		"book_ids": []uuid.UUID{other.ID},
+	})
+	assert.Equal(t, 400, resp.StatusCode)
+
+	resp = sendTestRequest("PUT", listRoute+"/order", readerTokens.AccessToken, map[string]interface{}{
+		"book_ids": []uuid.UUID{other.ID, published.ID},
+	})
+	assert.Equal(t, 200, resp.StatusCode)
+
+	resp = sendTestRequest("PUT", "/v1/book/"+other.ID.String()+"/progress", readerTokens.AccessToken, map[string]interface{}{
+		"percent": 40,
+		"page":    120,
+	})
+	assert.Equal(t, 200, resp.StatusCode)
+
+	var withBooks models.ReadingListWithBooks
+	resp = sendTestRequest("GET", listRoute, readerTokens.AccessToken, nil)
+	assert.Equal(t, 200, resp.StatusCode)
+	responseBodyBytes, _ = io.ReadAll(resp.Body)
+	_ = json.Unmarshal(responseBodyBytes, &withBooks)
+	if assert.Len(t, withBooks.Books, 2) {
+		assert.Equal(t, other.ID, withBooks.Books[0].BookID)
+		if assert.NotNil(t, withBooks.Books[0].Percent) {
+			assert.Equal(t, 40, *withBooks.Books[0].Percent)
+		}
+	}
+
+	// Lists of other users stay hidden.
+	ownerTokens, err := utils.GenerateNewTokens(owner.ID.String(), []string{})
+	if err != nil {
+		log.Fatal(err)
+	}
+	resp = sendTestRequest("GET", listRoute, ownerTokens.AccessToken, nil)
+	assert.Equal(t, 404, resp.StatusCode)
+
+	// Sharing gives a link anyone can read, until the list is private again.
+	resp = sendTestRequest("PUT", listRoute, readerTokens.AccessToken, map[string]interface{}{"name": "Summer", "is_public": true})
+	assert.Equal(t, 200, resp.StatusCode)
+	responseBodyBytes, _ = io.ReadAll(resp.Body)
+	_ = json.Unmarshal(responseBodyBytes, &list)
+	if assert.NotNil(t, list.ShareToken) {
+		sharedRoute := "/v1/reading-lists/shared/" + *list.ShareToken
+
+		resp, _ = AppTest.Test(httptest.NewRequest("GET", sharedRoute, nil), -1)
+		assert.Equal(t, 200, resp.StatusCode)
+		responseBodyBytes, _ = io.ReadAll(resp.Body)
+		_ = json.Unmarshal(responseBodyBytes, &withBooks)
+		if assert.Len(t, withBooks.Books, 2) {
+			assert.Nil(t, withBooks.Books[0].Percent)
+		}
+
+		resp = sendTestRequest("PUT", listRoute, readerTokens.AccessToken, map[string]interface{}{"name": "Summer"})
+		assert.Equal(t, 200, resp.StatusCode)
+
+		resp, _ = AppTest.Test(httptest.NewRequest("GET", sharedRoute, nil), -1)
+		assert.Equal(t, 404, resp.StatusCode)
+	}
+
+	resp = sendTestRequest("DELETE", listRoute+"/book/"+published.ID.String(), readerTokens.AccessToken, nil)
+	assert.Equal(t, 204, resp.StatusCode)
+
+	resp = sendTestRequest("DELETE", listRoute, readerTokens.AccessToken, nil)
+	assert.Equal(t, 204, resp.StatusCode)
+}
+
+func TestBookLending(t *testing.T) {
+	librarian := createTestUser(repository.ModeratorRoleName)
+	first := createTestUser(repository.UserRoleName)
+	second := createTestUser(repository.UserRoleName)
+
+	librarianTokens, err := utils.GenerateNewTokens(librarian.ID.String(), []string{"book:lending"})
+	if err != nil {
+		log.Fatal(err)
+	}
+	firstTokens, err := utils.GenerateNewTokens(first.ID.String(), []string{})
+	if err != nil {
+		log.Fatal(err)
+	}
+	secondTokens, err := utils.GenerateNewTokens(second.ID.String(), []string{})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	book := createTestBook(librarian.ID)
+
+	defer func() {
+		if err := deleteTestBook(book.ID); err != nil {
+			log.Fatal("Fail to delete book")
+		}
+		for _, user := range []*models.User{librarian, first, second} {
+			if err := database.UserDB().DeleteUser(user.ID); err != nil {
+				log.Fatal("fail to delete user")
+			}
+		}
+	}()
+
+	bookRoute := "/v1/book/" + book.ID.String()
+
+	resp := sendTestRequest("POST", bookRoute+"/copies", firstTokens.AccessToken, map[string]interface{}{"notes": "shelf A"})
+	assert.Equal(t, 403, resp.StatusCode)
+
+	resp = sendTestRequest("POST", bookRoute+"/copies", librarianTokens.AccessToken, map[string]interface{}{"notes": "shelf A"})
+	assert.Equal(t, 201, resp.StatusCode)
+
+	checkout := func(accessToken string, status int) models.BookLoan {
+		resp := sendTestRequest("POST", bookRoute+"/loans", accessToken, nil)
+		assert.Equal(t, status, resp.StatusCode)
+
+		var loan models.BookLoan
+		responseBodyBytes, _ := io.ReadAll(resp.Body)
+		_ = json.Unmarshal(responseBodyBytes, &loan)
+		return loan
+	}
+
+	firstLoan := checkout(firstTokens.AccessToken, 201)
+	checkout(firstTokens.AccessToken, 409)
+	checkout(secondTokens.AccessToken, 409)
+
+	// With all copies out users queue for the book.
+	resp = sendTestRequest("POST", bookRoute+"/holds", secondTokens.AccessToken, nil)
+	assert.Equal(t, 201, resp.StatusCode)
+
+	var hold models.BookHold
+	responseBodyBytes, _ := io.ReadAll(resp.Body)
+	_ = json.Unmarshal(responseBodyBytes, &hold)
+	assert.Equal(t, int64(1), hold.Position)
+
+	resp = sendTestRequest("POST", bookRoute+"/holds", secondTokens.AccessToken, nil)
+	assert.Equal(t, 409, resp.StatusCode)
+
+	// Books others wait for are not renewed.
+	resp = sendTestRequest("POST", "/v1/loan/"+firstLoan.ID.String()+"/renew", firstTokens.AccessToken, nil)
+	assert.Equal(t, 409, resp.StatusCode)
+
+	resp = sendTestRequest("POST", "/v1/loan/"+firstLoan.ID.String()+"/return", secondTokens.AccessToken, nil)
+	assert.Equal(t, 403, resp.StatusCode)
+
+	resp = sendTestRequest("POST", "/v1/loan/"+firstLoan.ID.String()+"/return", firstTokens.AccessToken, nil)
+	assert.Equal(t, 200, resp.StatusCode)
+
+	// The returned copy is set aside for the hold.
+	req := httptest.NewRequest("GET", bookRoute+"/copies", nil)
+	req.Header.Add("Authorization", "Basic YWRtaW46c2VjcmV0")
+	resp, _ = AppTest.Test(req, -1)
+	assert.Equal(t, 200, resp.StatusCode)
+
+	var availability models.BookAvailability
+	responseBodyBytes, _ = io.ReadAll(resp.Body)
+	_ = json.Unmarshal(responseBodyBytes, &availability)
+	assert.Equal(t, 0, availability.Available)
+	if assert.Len(t, availability.Copies, 1) {
+		assert.Equal(t, repository.BookCopyOnHold, availability.Copies[0].Status)
+	}
+
+	checkout(firstTokens.AccessToken, 409)
+	secondLoan := checkout(secondTokens.AccessToken, 201)
+
+	// Loans past their due date are listed for librarians.
+	err = database.BookLoanDB().Exec("UPDATE book_loans SET due_at = ? WHERE id = ?", time.Now().Add(-time.Hour), secondLoan.ID).Error
+	assert.NoError(t, err)
+
+	resp = sendTestRequest("GET", "/v1/loans/overdue", secondTokens.AccessToken, nil)
+	assert.Equal(t, 403, resp.StatusCode)
+
+	resp = sendTestRequest("GET", "/v1/loans/overdue?limit=100", librarianTokens.AccessToken, nil)
+	assert.Equal(t, 200, resp.StatusCode)
+
+	var overdue models.AllBookLoans
+	responseBodyBytes, _ = io.ReadAll(resp.Body)
+	_ = json.Unmarshal(responseBodyBytes, &overdue)
+	found := false
+	for _, loan := range overdue.Loans {
+		found = found || loan.ID == secondLoan.ID
+	}
+	assert.True(t, found)
+
+	resp = sendTestRequest("POST", "/v1/loan/"+secondLoan.ID.String()+"/renew", secondTokens.AccessToken, nil)
+	assert.Equal(t, 409, resp.StatusCode)
+
+	resp = sendTestRequest("POST", "/v1/loan/"+secondLoan.ID.String()+"/return", librarianTokens.AccessToken, nil)
+	assert.Equal(t, 200, resp.StatusCode)
+}
@@ -1,13 +1,27 @@
 package routes
 
 import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/aryanicosa/go-fiber-rest-api/app/models"
+	"github.com/aryanicosa/go-fiber-rest-api/pkg/configs"
+	"github.com/aryanicosa/go-fiber-rest-api/pkg/middleware"
+	"github.com/aryanicosa/go-fiber-rest-api/pkg/repository"
+	"github.com/aryanicosa/go-fiber-rest-api/pkg/utils"
 	"github.com/aryanicosa/go-fiber-rest-api/platform/database"
 	"github.com/aryanicosa/go-fiber-rest-api/platform/migrations"
+	"github.com/aryanicosa/go-fiber-rest-api/platform/storage"
 	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
 	"github.com/joho/godotenv"
+	"io"
 	"log"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"testing"
+	"time"
 )
 
 var AppTest *fiber.App
@@ -19,7 +33,8 @@ func TestMain(m *testing.M) {
 	}
 
 	// Define Fiber AppTest.
-	AppTest = fiber.New()
+	AppTest = fiber.New(configs.FiberConfig())
+	AppTest.Use(middleware.BodyLimit())
 
 	// init connect to db
 	_, err := database.InitDBConnection()
@@ -27,6 +42,16 @@ func TestMain(m *testing.M) {
 		log.Fatal("fail to load database")
 	}
 
+	// init file storage
+	if _, err := storage.InitStorage(); err != nil {
+		log.Fatal(err)
+	}
+
+	// load the keys access tokens are signed with
+	if err := utils.InitSigningKeys(); err != nil {
+		log.Fatal(err)
+	}
+
 	// migration
 	migrationFileSource := os.Getenv("SQL_SOURCE_PATH")
 	err = migrations.Migrate(migrationFileSource)
@@ -38,6 +63,91 @@ func TestMain(m *testing.M) {
 	UsersRoutes(AppTest)
 	BooksRoutes(AppTest)
 	MiscRoutes(AppTest)
+	WellKnownRoutes(AppTest)
+	MediaRoute(AppTest)
+
+	// Keep a user managing roles through all tests, test users holding `role:manage` can only be deleted
+	// while another one is left.
+	manager := createTestUser(repository.AdminRoleName)
+	code := m.Run()
+	if err := database.UserDB().DB.Exec("DELETE FROM users WHERE id = ?", manager.ID).Error; err != nil {
+		log.Fatal(err)
+	}
+
+	os.Exit(code)
+}
+
+// createTestUser func for creating a user with given role for a test.
+func createTestUser(role string) *models.User {
+	user := &models.User{
+		ID:           uuid.New(),
+		CreatedAt:    time.Now(),
+		UpdatedAt:    time.Now(),
+		Email:        fmt.Sprintf("test%s@mail.com", utils.String(12)),
+		PasswordHash: utils.GeneratePassword("Password123"),
+		UserStatus:   1,
+		UserRoles:    []string{role},
+	}
+	if err := database.UserDB().CreateUser(user); err != nil {
+		log.Fatal("unable to create user")
+	}
+
+	return user
+}
+
+// createTestBook func for creating a book owned by given user for a test.
+func createTestBook(userID uuid.UUID) *models.Book {
+	book := &models.Book{
+		ID:         uuid.New(),
+		CreatedAt:  time.Now(),
+		UpdatedAt:  time.Now(),
+		UserID:     userID,
+		Title:      "Test Title",
+		Author:     "John Doe",
+		BookStatus: repository.BookStatusPublished,
+		BookAttrs: models.BookAttrs{
+			Picture:     "Picture",
+			Description: "Description",
+			Rating:      5,
+		},
+	}
+	if err := database.BookDB().CreateBook(book); err != nil {
+		log.Fatal("fail to create book")
+	}
+
+	return book
+}
+
+// deleteTestBook func for moving a test book to the trash, whatever version it reached.
+func deleteTestBook(id uuid.UUID) error {
+	db := database.BookDB()
+	book, err := db.GetBookById(id)
+	if err != nil {
+		// Already in the trash.
+		return nil
+	}
+	return db.DeleteBook(book.ID, book.Version)
+}
+
+// sendTestRequest func for performing a request with optional JSON body and bearer token.
+func sendTestRequest(method, route, accessToken string, body interface{}) *http.Response {
+	var reqBody io.Reader
+	if body != nil {
+		reqBodyStr, _ := json.Marshal(body)
+		reqBody = bytes.NewBuffer(reqBodyStr)
+	}
+
+	req := httptest.NewRequest(method, route, reqBody)
+	req.Header.Add("Content-Type", "application/json")
+	if accessToken != "" {
+		req.Header.Add("Authorization", "Bearer "+accessToken)
+	}
+
+	// Perform the request plain with the AppTest.
+	resp, err := AppTest.Test(req, -1) // the -1 disables request latency
+	if err != nil {
+		log.Fatal(err)
+	}
 
-	os.Exit(m.Run())
+	return resp
 }
@@ -0,0 +1,14 @@
+package routes
+
+import (
+	"github.com/aryanicosa/go-fiber-rest-api/platform/storage"
+	"github.com/gofiber/fiber/v2"
+)
+
+// MediaRoute func for serving uploaded files, when they are saved by the app itself.
+func MediaRoute(a *fiber.App) {
+	if local, ok := storage.Store().(*storage.LocalStorage); ok {
+		// Routes for GET method:
+		a.Static(storage.LocalStorageRoute, local.Root) // get an uploaded file
+	}
+}
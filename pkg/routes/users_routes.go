@@ -19,4 +19,22 @@ func UsersRoutes(a *fiber.App) {
 	route.Post("/user/sign/out", middleware.JWTProtected(), controllers.UserSignOut)   // de-authorization user
 	route.Post("/user/sign/renew", middleware.JWTProtected(), controllers.RenewTokens) // renew AccessToken & RefreshToken tokens
 
+	// Routes for sessions of users:
+	route.Get("/user/sessions", middleware.JWTProtected(), controllers.GetUserSessions)          // get own signed in devices
+	route.Delete("/user/sessions", middleware.JWTProtected(), controllers.DeleteUserSessions)    // sign out everywhere
+	route.Delete("/user/sessions/:id", middleware.JWTProtected(), controllers.DeleteUserSession) // sign out one device
+
+	// Routes for accounts of users:
+	route.Put("/user/password", middleware.JWTProtected(), controllers.ChangeUserPassword) // change own password, sign out everywhere
+	route.Put("/user/:id/status", middleware.JWTProtected(), controllers.UpdateUserStatus) // block or unblock user
+
+	// Routes for roles and permissions of users:
+	route.Get("/roles", middleware.JWTProtected(), controllers.GetRoles)                     // get list of all roles with permissions
+	route.Post("/role", middleware.JWTProtected(), controllers.CreateRole)                   // create a new role
+	route.Put("/role/:id", middleware.JWTProtected(), controllers.UpdateRole)                // update one role by ID
+	route.Delete("/role/:id", middleware.JWTProtected(), controllers.DeleteRole)             // delete one role by ID
+	route.Get("/permissions", middleware.JWTProtected(), controllers.GetPermissions)         // get list of all permissions
+	route.Post("/permission", middleware.JWTProtected(), controllers.CreatePermission)       // create a new permission
+	route.Delete("/permission/:id", middleware.JWTProtected(), controllers.DeletePermission) // delete one permission by ID
+	route.Put("/user/:id/roles", middleware.JWTProtected(), controllers.SetUserRoles)        // replace roles of a user
 }
@@ -2,16 +2,20 @@ package routes
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"github.com/aryanicosa/go-fiber-rest-api/app/models"
+	"github.com/aryanicosa/go-fiber-rest-api/app/queries"
 	"github.com/aryanicosa/go-fiber-rest-api/pkg/repository"
 	"github.com/aryanicosa/go-fiber-rest-api/pkg/utils"
+	"github.com/aryanicosa/go-fiber-rest-api/platform/cache"
 	"github.com/aryanicosa/go-fiber-rest-api/platform/database"
 	"github.com/google/uuid"
 	"io"
 	"log"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
@@ -75,8 +79,8 @@ func TestUserSignIn(t *testing.T) {
 		UpdatedAt:    time.Now(),
 		Email:        fmt.Sprintf("test%s@mail.com", suffix),
 		PasswordHash: utils.GeneratePassword("Password123"),
-		UserStatus:   0,
-		UserRole:     repository.UserRoleName,
+		UserStatus:   1,
+		UserRoles:    []string{repository.UserRoleName},
 	}
 	err := db.CreateUser(user)
 	if err != nil {
@@ -133,8 +137,8 @@ func TestUserRenewToken(t *testing.T) {
 		UpdatedAt:    time.Now(),
 		Email:        fmt.Sprintf("test%s@mail.com", suffix),
 		PasswordHash: utils.GeneratePassword("Password123"),
-		UserStatus:   0,
-		UserRole:     repository.UserRoleName,
+		UserStatus:   1,
+		UserRoles:    []string{repository.UserRoleName},
 	}
 	err := db.CreateUser(user)
 	if err != nil {
@@ -241,3 +245,361 @@ func TestUserSignOut(t *testing.T) {
 
 	assert.Equal(t, testSignOut.expectedCode, resp.StatusCode)
 }
+
+func TestUserRefreshTokenRotation(t *testing.T) {
+	db := database.UserDB()
+
+	suffix := utils.String(12)
+	user := &models.User{
+		ID:           uuid.New(),
+		CreatedAt:    time.Now(),
+		UpdatedAt:    time.Now(),
+		Email:        fmt.Sprintf("test%s@mail.com", suffix),
+		PasswordHash: utils.GeneratePassword("Password123"),
+		UserStatus:   1,
+		UserRoles:    []string{repository.UserRoleName},
+	}
+	err := db.CreateUser(user)
+	if err != nil {
+		log.Fatal("unable to create user")
+	}
+
+	defer func() {
+		err = db.DeleteUser(user.ID)
+		if err != nil {
+			fmt.Println("fail to delete user")
+		}
+	}()
+
+	renew := func(tokens utils.Tokens, expectedCode int) utils.Tokens {
+		resp := sendTestRequest("POST", "/v1/user/sign/renew", tokens.AccessToken, &models.Renew{RefreshToken: tokens.RefreshToken})
+		assert.Equal(t, expectedCode, resp.StatusCode)
+
+		var renewed utils.Tokens
+		responseBodyBytes, _ := io.ReadAll(resp.Body)
+		_ = json.Unmarshal(responseBodyBytes, &renewed)
+		return renewed
+	}
+
+	// Signing in on a second device keeps the first session.
+	laptop := signInTestUser(t, user.Email, "laptop")
+	phone := signInTestUser(t, user.Email, "phone")
+
+	rotated := renew(laptop, 200)
+	assert.NotEqual(t, laptop.RefreshToken, rotated.RefreshToken)
+	phone = renew(phone, 200)
+
+	// Reusing a rotated refresh token revokes its session, also the tokens issued since.
+	renew(laptop, 401)
+	renew(rotated, 401)
+
+	// A refresh token sent with the access token of another session is refused without being used up.
+	tablet := signInTestUser(t, user.Email, "tablet")
+	renew(utils.Tokens{AccessToken: tablet.AccessToken, RefreshToken: phone.RefreshToken}, 401)
+
+	renew(phone, 200)
+}
+
+func TestUserSessions(t *testing.T) {
+	db := database.UserDB()
+
+	user := createTestUser(repository.UserRoleName)
+	otherUser := createTestUser(repository.UserRoleName)
+	defer func() {
+		for _, u := range []*models.User{user, otherUser} {
+			if err := db.DeleteUser(u.ID); err != nil {
+				fmt.Println("fail to delete user")
+			}
+		}
+	}()
+
+	laptop := signInTestUser(t, user.Email, "laptop")
+	phone := signInTestUser(t, user.Email, "phone")
+
+	resp := sendTestRequest("GET", "/v1/user/sessions", laptop.AccessToken, nil)
+	assert.Equal(t, 200, resp.StatusCode)
+
+	var sessions []models.UserSession
+	responseBodyBytes, _ := io.ReadAll(resp.Body)
+	_ = json.Unmarshal(responseBodyBytes, &sessions)
+	var phoneSession models.UserSession
+	if assert.Len(t, sessions, 2) {
+		for _, session := range sessions {
+			assert.Equal(t, session.Device == "laptop", session.Current)
+			if session.Device == "phone" {
+				phoneSession = session
+			}
+		}
+	}
+
+	// Saving a session that ends sooner does not cut the lifetime of the others.
+	sessionCache, err := cache.SessionCache()
+	if err != nil {
+		log.Fatal(err)
+	}
+	userSessionsKey := "user_sessions:" + user.ID.String()
+	ttl, err := sessionCache.TTL(context.Background(), userSessionsKey).Result()
+	assert.NoError(t, err)
+
+	shortSession := &models.Session{ID: uuid.New(), UserID: user.ID, ExpiresAt: time.Now().Add(time.Minute)}
+	assert.NoError(t, sessionCache.SaveSession(shortSession, utils.String(32)))
+	shortTTL, err := sessionCache.TTL(context.Background(), userSessionsKey).Result()
+	assert.NoError(t, err)
+	assert.Greater(t, shortTTL, ttl-time.Minute)
+	assert.NoError(t, sessionCache.DeleteSession(shortSession))
+
+	// Sessions of other users are not found.
+	other := signInTestUser(t, otherUser.Email, "")
+	resp = sendTestRequest("DELETE", "/v1/user/sessions/"+phoneSession.ID.String(), other.AccessToken, nil)
+	assert.Equal(t, 404, resp.StatusCode)
+
+	resp = sendTestRequest("DELETE", "/v1/user/sessions/"+phoneSession.ID.String(), laptop.AccessToken, nil)
+	assert.Equal(t, 204, resp.StatusCode)
+
+	resp = sendTestRequest("POST", "/v1/user/sign/renew", phone.AccessToken, &models.Renew{RefreshToken: phone.RefreshToken})
+	assert.Equal(t, 401, resp.StatusCode)
+
+	// Signing out everywhere ends the current session too.
+	resp = sendTestRequest("DELETE", "/v1/user/sessions", laptop.AccessToken, nil)
+	assert.Equal(t, 204, resp.StatusCode)
+
+	resp = sendTestRequest("POST", "/v1/user/sign/renew", laptop.AccessToken, &models.Renew{RefreshToken: laptop.RefreshToken})
+	assert.Equal(t, 401, resp.StatusCode)
+}
+
+func TestAccessTokenRevocation(t *testing.T) {
+	db := database.UserDB()
+
+	user := createTestUser(repository.UserRoleName)
+	admin := createTestUser(repository.AdminRoleName)
+	defer func() {
+		for _, u := range []*models.User{user, admin} {
+			if err := db.DeleteUser(u.ID); err != nil {
+				fmt.Println("fail to delete user")
+			}
+		}
+	}()
+
+	// A signed out access token is denied before it expires.
+	laptop := signInTestUser(t, user.Email, "laptop")
+	phone := signInTestUser(t, user.Email, "phone")
+	resp := sendTestRequest("POST", "/v1/user/sign/out", laptop.AccessToken, nil)
+	assert.Equal(t, 204, resp.StatusCode)
+	resp = sendTestRequest("GET", "/v1/user/sessions", laptop.AccessToken, nil)
+	assert.Equal(t, 401, resp.StatusCode)
+	resp = sendTestRequest("GET", "/v1/user/sessions", phone.AccessToken, nil)
+	assert.Equal(t, 200, resp.StatusCode)
+
+	// Changing the password denies the tokens of every device.
+	resp = sendTestRequest("PUT", "/v1/user/password", phone.AccessToken, &models.ChangePassword{CurrentPassword: "wrong", NewPassword: "Password456"})
+	assert.Equal(t, 401, resp.StatusCode)
+	resp = sendTestRequest("PUT", "/v1/user/password", phone.AccessToken, &models.ChangePassword{CurrentPassword: "Password123", NewPassword: "Password123"})
+	assert.Equal(t, 204, resp.StatusCode)
+	resp = sendTestRequest("GET", "/v1/user/sessions", phone.AccessToken, nil)
+	assert.Equal(t, 401, resp.StatusCode)
+
+	// Tokens issued without a session are denied too, even in the second of the change.
+	sessionless, err := utils.GenerateNewTokens(user.ID.String(), []string{})
+	if err != nil {
+		log.Fatal(err)
+	}
+	resp = sendTestRequest("PUT", "/v1/user/password", sessionless.AccessToken, &models.ChangePassword{CurrentPassword: "Password123", NewPassword: "Password123"})
+	assert.Equal(t, 204, resp.StatusCode)
+	resp = sendTestRequest("GET", "/v1/user/sessions", sessionless.AccessToken, nil)
+	assert.Equal(t, 401, resp.StatusCode)
+
+	// Blocking a user denies its tokens and signing in again.
+	phone = signInTestUser(t, user.Email, "phone")
+	adminTokens := signInTestUser(t, admin.Email, "")
+	blocked := 0
+	resp = sendTestRequest("PUT", "/v1/user/"+user.ID.String()+"/status", phone.AccessToken, &models.UpdateUserStatus{UserStatus: &blocked})
+	assert.Equal(t, 403, resp.StatusCode)
+	resp = sendTestRequest("PUT", "/v1/user/"+user.ID.String()+"/status", adminTokens.AccessToken, &models.UpdateUserStatus{UserStatus: &blocked})
+	assert.Equal(t, 200, resp.StatusCode)
+	resp = sendTestRequest("GET", "/v1/user/sessions", phone.AccessToken, nil)
+	assert.Equal(t, 401, resp.StatusCode)
+
+	reqBodyStr, _ := json.Marshal(&models.SignIn{Email: user.Email, Password: "Password123"})
+	req := httptest.NewRequest("POST", "/v1/user/sign/in", bytes.NewBufferString(string(reqBodyStr)))
+	req.Header.Add("Content-Type", "application/json")
+	req.Header.Add("Authorization", "Basic YWRtaW46c2VjcmV0")
+	resp, err = AppTest.Test(req, -1) // the -1 disables request latency
+	if err != nil {
+		log.Fatal("fail to sign in user test")
+	}
+	assert.Equal(t, 403, resp.StatusCode)
+}
+
+func TestRolesAndPermissions(t *testing.T) {
+	db := database.UserDB()
+
+	user := createTestUser(repository.UserRoleName)
+	admin := createTestUser(repository.AdminRoleName)
+	defer func() {
+		for _, u := range []*models.User{user, admin} {
+			if err := db.DeleteUser(u.ID); err != nil {
+				fmt.Println("fail to delete user")
+			}
+		}
+	}()
+
+	adminTokens := signInTestUser(t, admin.Email, "")
+	userTokens := signInTestUser(t, user.Email, "")
+	blocked := 0
+
+	// Only holders of `role:manage` see the roles.
+	resp := sendTestRequest("GET", "/v1/roles", userTokens.AccessToken, nil)
+	assert.Equal(t, 403, resp.StatusCode)
+	resp = sendTestRequest("GET", "/v1/roles", adminTokens.AccessToken, nil)
+	assert.Equal(t, 200, resp.StatusCode)
+
+	// A permission only other services check.
+	suffix := strings.ToLower(utils.String(8))
+	resp = sendTestRequest("POST", "/v1/permission", adminTokens.AccessToken, &models.Permission{Name: "Report View"})
+	assert.Equal(t, 400, resp.StatusCode)
+	resp = sendTestRequest("POST", "/v1/permission", adminTokens.AccessToken, &models.Permission{Name: "report:view_" + suffix})
+	assert.Equal(t, 201, resp.StatusCode)
+	var permission models.Permission
+	responseBodyBytes, _ := io.ReadAll(resp.Body)
+	_ = json.Unmarshal(responseBodyBytes, &permission)
+	defer func() {
+		sendTestRequest("DELETE", "/v1/permission/"+permission.ID.String(), adminTokens.AccessToken, nil)
+	}()
+
+	resp = sendTestRequest("POST", "/v1/role", adminTokens.AccessToken, &models.SaveRole{Name: "reporter_" + suffix, Permissions: []string{"report:missing"}})
+	assert.Equal(t, 400, resp.StatusCode)
+	resp = sendTestRequest("POST", "/v1/role", adminTokens.AccessToken, &models.SaveRole{Name: "reporter_" + suffix, Permissions: []string{permission.Name}})
+	assert.Equal(t, 201, resp.StatusCode)
+	var role models.Role
+	responseBodyBytes, _ = io.ReadAll(resp.Body)
+	_ = json.Unmarshal(responseBodyBytes, &role)
+	defer func() { sendTestRequest("DELETE", "/v1/role/"+role.ID.String(), adminTokens.AccessToken, nil) }()
+
+	resp = sendTestRequest("POST", "/v1/role", adminTokens.AccessToken, &models.SaveRole{Name: repository.UserRoleName})
+	assert.Equal(t, 409, resp.StatusCode)
+
+	// A user with two roles gets the permissions of both on the next sign in.
+	resp = sendTestRequest("PUT", "/v1/user/"+user.ID.String()+"/roles", adminTokens.AccessToken, &models.UserRoles{Roles: []string{repository.UserRoleName, role.Name}})
+	assert.Equal(t, 200, resp.StatusCode)
+
+	token, err := utils.ParseToken(signInTestUser(t, user.Email, "").AccessToken)
+	if assert.NoError(t, err) {
+		claims, err := utils.ParseTokenMetadata(token)
+		assert.NoError(t, err)
+		assert.True(t, claims.Credentials[permission.Name])
+		assert.True(t, claims.Credentials[repository.BookCreateCredential])
+		assert.False(t, claims.Credentials[repository.RoleManageCredential])
+	}
+
+	// Permissions removed from a role are gone on the next sign in, tokens carrying them are denied.
+	resp = sendTestRequest("PUT", "/v1/role/"+role.ID.String(), adminTokens.AccessToken, &models.SaveRole{Name: role.Name})
+	assert.Equal(t, 200, resp.StatusCode)
+	resp = sendTestRequest("GET", "/v1/user/sessions", userTokens.AccessToken, nil)
+	assert.Equal(t, 401, resp.StatusCode)
+
+	token, err = utils.ParseToken(signInTestUser(t, user.Email, "").AccessToken)
+	if assert.NoError(t, err) {
+		claims, err := utils.ParseTokenMetadata(token)
+		assert.NoError(t, err)
+		assert.False(t, claims.Credentials[permission.Name])
+	}
+
+	// Permissions checked by the code can not be deleted.
+	resp = sendTestRequest("GET", "/v1/permissions", adminTokens.AccessToken, nil)
+	assert.Equal(t, 200, resp.StatusCode)
+	var permissions []models.Permission
+	responseBodyBytes, _ = io.ReadAll(resp.Body)
+	_ = json.Unmarshal(responseBodyBytes, &permissions)
+	for _, p := range permissions {
+		if p.Name == repository.RoleManageCredential {
+			resp = sendTestRequest("DELETE", "/v1/permission/"+p.ID.String(), adminTokens.AccessToken, nil)
+			assert.Equal(t, 409, resp.StatusCode)
+		}
+	}
+	resp = sendTestRequest("DELETE", "/v1/permission/"+uuid.New().String(), adminTokens.AccessToken, nil)
+	assert.Equal(t, 404, resp.StatusCode)
+
+	// One manager holds the only role with `role:manage`, every other role gives it up for a while.
+	manager := createTestUser(repository.UserRoleName)
+	defer func() {
+		if err := db.DeleteUser(manager.ID); err != nil {
+			fmt.Println("fail to delete user")
+		}
+	}()
+	resp = sendTestRequest("POST", "/v1/role", adminTokens.AccessToken, &models.SaveRole{
+		Name:        "manager_" + suffix,
+		Permissions: []string{repository.RoleManageCredential, repository.UserManageCredential},
+	})
+	assert.Equal(t, 201, resp.StatusCode)
+	var managerRole models.Role
+	responseBodyBytes, _ = io.ReadAll(resp.Body)
+	_ = json.Unmarshal(responseBodyBytes, &managerRole)
+	defer func() { sendTestRequest("DELETE", "/v1/role/"+managerRole.ID.String(), adminTokens.AccessToken, nil) }()
+	resp = sendTestRequest("PUT", "/v1/user/"+manager.ID.String()+"/roles", adminTokens.AccessToken, &models.UserRoles{Roles: []string{managerRole.Name}})
+	assert.Equal(t, 200, resp.StatusCode)
+	managerTokens := signInTestUser(t, manager.Email, "")
+
+	resp = sendTestRequest("GET", "/v1/roles", managerTokens.AccessToken, nil)
+	assert.Equal(t, 200, resp.StatusCode)
+	var roles []models.Role
+	responseBodyBytes, _ = io.ReadAll(resp.Body)
+	_ = json.Unmarshal(responseBodyBytes, &roles)
+	var strippedRoles []models.Role
+	for _, r := range roles {
+		if r.ID == managerRole.ID {
+			continue
+		}
+		permissions := []string{}
+		for _, name := range r.Permissions {
+			if name != repository.RoleManageCredential {
+				permissions = append(permissions, name)
+			}
+		}
+		if len(permissions) < len(r.Permissions) {
+			resp = sendTestRequest("PUT", "/v1/role/"+r.ID.String(), managerTokens.AccessToken, &models.SaveRole{Name: r.Name, Description: r.Description, Permissions: permissions})
+			assert.Equal(t, 200, resp.StatusCode)
+			strippedRoles = append(strippedRoles, r)
+		}
+	}
+
+	// The last role holding `role:manage` can not lose it.
+	resp = sendTestRequest("PUT", "/v1/role/"+managerRole.ID.String(), managerTokens.AccessToken, &models.SaveRole{Name: managerRole.Name, Permissions: []string{repository.UserManageCredential}})
+	assert.Equal(t, 409, resp.StatusCode)
+	resp = sendTestRequest("DELETE", "/v1/role/"+managerRole.ID.String(), managerTokens.AccessToken, nil)
+	assert.Equal(t, 409, resp.StatusCode)
+
+	// Nor can the last user holding it, by losing the role, being blocked or being deleted.
+	resp = sendTestRequest("PUT", "/v1/user/"+manager.ID.String()+"/roles", managerTokens.AccessToken, &models.UserRoles{Roles: []string{repository.UserRoleName}})
+	assert.Equal(t, 409, resp.StatusCode)
+	resp = sendTestRequest("PUT", "/v1/user/"+manager.ID.String()+"/status", managerTokens.AccessToken, &models.UpdateUserStatus{UserStatus: &blocked})
+	assert.Equal(t, 409, resp.StatusCode)
+	assert.ErrorIs(t, db.DeleteUser(manager.ID), queries.ErrLastRoleManager)
+
+	// Give the other roles `role:manage` back.
+	for _, r := range strippedRoles {
+		resp = sendTestRequest("PUT", "/v1/role/"+r.ID.String(), managerTokens.AccessToken, &models.SaveRole{Name: r.Name, Description: r.Description, Permissions: r.Permissions})
+		assert.Equal(t, 200, resp.StatusCode)
+	}
+	adminTokens = signInTestUser(t, admin.Email, "")
+}
+
+// signInTestUser func for signing in a test user with the test password on given device.
+func signInTestUser(t *testing.T, email, device string) utils.Tokens {
+	reqBodyStr, _ := json.Marshal(&models.SignIn{Email: email, Password: "Password123", Device: device})
+
+	req := httptest.NewRequest("POST", "/v1/user/sign/in", bytes.NewBufferString(string(reqBodyStr)))
+	req.Header.Add("Content-Type", "application/json")
+	req.Header.Add("Authorization", "Basic YWRtaW46c2VjcmV0")
+
+	// Perform the request plain with the AppTest.
+	resp, err := AppTest.Test(req, -1) // the -1 disables request latency
+	if err != nil {
+		log.Fatal("fail to sign in user test")
+	}
+	assert.Equal(t, 200, resp.StatusCode)
+
+	var tokens utils.Tokens
+	responseBodyBytes, _ := io.ReadAll(resp.Body)
+	_ = json.Unmarshal(responseBodyBytes, &tokens)
+	return tokens
+}
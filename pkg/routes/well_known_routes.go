@@ -0,0 +1,15 @@
+package routes
+
+import (
+	"github.com/aryanicosa/go-fiber-rest-api/app/controllers"
+	"github.com/gofiber/fiber/v2"
+)
+
+// WellKnownRoutes func for describe group of /.well-known routes, read by other services.
+func WellKnownRoutes(a *fiber.App) {
+	// Create routes group.
+	route := a.Group("/.well-known")
+
+	// Routes for GET method:
+	route.Get("/jwks.json", controllers.GetJWKS) // public keys to verify access tokens with
+}
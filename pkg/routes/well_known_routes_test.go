@@ -0,0 +1,43 @@
+package routes
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"github.com/aryanicosa/go-fiber-rest-api/pkg/utils"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"testing"
+)
+
+func TestJWKS(t *testing.T) {
+	tokens, err := utils.GenerateNewTokens(uuid.New().String(), []string{})
+	if err != nil {
+		panic(err)
+	}
+
+	resp := sendTestRequest("GET", "/.well-known/jwks.json", "", nil)
+	assert.Equal(t, 200, resp.StatusCode)
+
+	var set utils.JSONWebKeySet
+	responseBodyBytes, _ := io.ReadAll(resp.Body)
+	_ = json.Unmarshal(responseBodyBytes, &set)
+
+	// Another service verifies the token with the published key only.
+	token, err := jwt.Parse(tokens.AccessToken, func(token *jwt.Token) (interface{}, error) {
+		for _, key := range set.Keys {
+			if key.Kid == token.Header["kid"] && key.Kty == "OKP" {
+				public, err := base64.RawURLEncoding.DecodeString(key.X)
+				return ed25519.PublicKey(public), err
+			}
+		}
+		return nil, jwt.ErrTokenUnverifiable
+	})
+	if assert.NoError(t, err) {
+		assert.True(t, token.Valid)
+	}
+
+	assert.Equal(t, "public, max-age=300", resp.Header.Get("Cache-Control"))
+}
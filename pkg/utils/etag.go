@@ -0,0 +1,24 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+)
+
+// VersionETag func for building a strong entity tag from a row version.
+func VersionETag(version int) string {
+	return fmt.Sprintf(`"%d"`, version)
+}
+
+// MatchETag func for checking if an `If-Match` or `If-None-Match` header value
+// lists given entity tag. Weak tags are compared by their opaque value.
+func MatchETag(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,59 @@
+package utils
+
+import (
+	"errors"
+	"strings"
+)
+
+// NormalizeISBN func for checking an ISBN-10 or ISBN-13 and returning it as ISBN-13.
+// Hyphens and spaces are ignored, a trailing `x` of an ISBN-10 may be lower case.
+func NormalizeISBN(isbn string) (string, error) {
+	digits := strings.ToUpper(strings.NewReplacer("-", "", " ", "").Replace(isbn))
+
+	switch len(digits) {
+	case 10:
+		sum := 0
+		for i, r := range digits {
+			value := int(r - '0')
+			if r == 'X' && i == 9 {
+				value = 10
+			} else if r < '0' || r > '9' {
+				return "", errors.New("isbn must only contain digits")
+			}
+			sum += (10 - i) * value
+		}
+		if sum%11 != 0 {
+			return "", errors.New("isbn checksum is invalid")
+		}
+
+		// ISBN-10 become ISBN-13 under the 978 prefix, with a new check digit.
+		isbn13 := "978" + digits[:9]
+		return isbn13 + string(isbn13CheckDigit(isbn13)), nil
+	case 13:
+		for _, r := range digits {
+			if r < '0' || r > '9' {
+				return "", errors.New("isbn must only contain digits")
+			}
+		}
+		if isbn13CheckDigit(digits[:12]) != digits[12] {
+			return "", errors.New("isbn checksum is invalid")
+		}
+		return digits, nil
+	default:
+		return "", errors.New("isbn must have 10 or 13 digits")
+	}
+}
+
+// isbn13CheckDigit func for computing the check digit of the first 12 digits of an ISBN-13.
+func isbn13CheckDigit(digits string) byte {
+	sum := 0
+	for i := 0; i < 12; i++ {
+		weight := 1
+		if i%2 == 1 {
+			weight = 3
+		}
+		sum += weight * int(digits[i]-'0')
+	}
+
+	return byte('0' + (10-sum%10)%10)
+}
@@ -0,0 +1,370 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// MergePatchContentType is the media type of a JSON Merge Patch document (RFC 7396).
+	MergePatchContentType = "application/merge-patch+json"
+
+	// JSONPatchContentType is the media type of a JSON Patch document (RFC 6902).
+	JSONPatchContentType = "application/json-patch+json"
+)
+
+// JSONPatchOperation struct to describe one operation of a JSON Patch document.
+type JSONPatchOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	From  string          `json:"from,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// MergePatch func for applying a JSON Merge Patch document (RFC 7396) to a JSON document.
+func MergePatch(doc, patch []byte) ([]byte, error) {
+	var target, patchValue interface{}
+
+	if err := json.Unmarshal(doc, &target); err != nil {
+		return nil, fmt.Errorf("document is not valid JSON: %w", err)
+	}
+	if err := json.Unmarshal(patch, &patchValue); err != nil {
+		return nil, fmt.Errorf("merge patch is not valid JSON: %w", err)
+	}
+
+	return json.Marshal(mergeValue(target, patchValue))
+}
+
+func mergeValue(target, patch interface{}) interface{} {
+	patchObject, ok := patch.(map[string]interface{})
+	if !ok {
+		// Anything but an object replaces the target as a whole.
+		return patch
+	}
+
+	targetObject, ok := target.(map[string]interface{})
+	if !ok {
+		targetObject = map[string]interface{}{}
+	}
+
+	for key, value := range patchObject {
+		if value == nil {
+			delete(targetObject, key)
+			continue
+		}
+		targetObject[key] = mergeValue(targetObject[key], value)
+	}
+
+	return targetObject
+}
+
+// ApplyJSONPatch func for applying a JSON Patch document (RFC 6902) to a JSON document.
+// Operations are applied in order and the whole patch fails if any of them fails.
+func ApplyJSONPatch(doc, patch []byte) ([]byte, error) {
+	var target interface{}
+	var operations []JSONPatchOperation
+
+	if err := json.Unmarshal(doc, &target); err != nil {
+		return nil, fmt.Errorf("document is not valid JSON: %w", err)
+	}
+	if err := json.Unmarshal(patch, &operations); err != nil {
+		return nil, fmt.Errorf("json patch must be an array of operations: %w", err)
+	}
+
+	for i, operation := range operations {
+		var err error
+		target, err = applyJSONPatchOperation(target, &operation)
+		if err != nil {
+			return nil, fmt.Errorf("json patch operation %d (%s %s): %w", i, operation.Op, operation.Path, err)
+		}
+	}
+
+	return json.Marshal(target)
+}
+
+func applyJSONPatchOperation(doc interface{}, operation *JSONPatchOperation) (interface{}, error) {
+	path, err := parseJSONPointer(operation.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch operation.Op {
+	case "add", "replace", "test":
+		if operation.Value == nil {
+			return nil, fmt.Errorf("value is required")
+		}
+		var value interface{}
+		if err := json.Unmarshal(operation.Value, &value); err != nil {
+			return nil, err
+		}
+
+		switch operation.Op {
+		case "add":
+			return jsonPointerAdd(doc, path, value)
+		case "replace":
+			if len(path) == 0 {
+				return value, nil
+			}
+			if _, err := jsonPointerGet(doc, path); err != nil {
+				return nil, err
+			}
+			if doc, err = jsonPointerRemove(doc, path); err != nil {
+				return nil, err
+			}
+			return jsonPointerAdd(doc, path, value)
+		default:
+			current, err := jsonPointerGet(doc, path)
+			if err != nil {
+				return nil, err
+			}
+			if !reflect.DeepEqual(current, value) {
+				return nil, fmt.Errorf("test failed")
+			}
+			return doc, nil
+		}
+	case "remove":
+		return jsonPointerRemove(doc, path)
+	case "move", "copy":
+		from, err := parseJSONPointer(operation.From)
+		if err != nil {
+			return nil, err
+		}
+		value, err := jsonPointerGet(doc, from)
+		if err != nil {
+			return nil, err
+		}
+
+		if operation.Op == "move" {
+			if strings.HasPrefix(operation.Path+"/", operation.From+"/") && operation.Path != operation.From {
+				return nil, fmt.Errorf("can not move a value into itself")
+			}
+			if doc, err = jsonPointerRemove(doc, from); err != nil {
+				return nil, err
+			}
+		} else {
+			// Copy a detached value so later operations do not touch the source.
+			b, _ := json.Marshal(value)
+			_ = json.Unmarshal(b, &value)
+		}
+		return jsonPointerAdd(doc, path, value)
+	default:
+		return nil, fmt.Errorf("operation '%v' is not supported", operation.Op)
+	}
+}
+
+// DiffJSON func for building JSON Patch operations (RFC 6902) turning one JSON document into another.
+// Objects are compared member by member, arrays and scalars are replaced as a whole.
+func DiffJSON(from, to []byte) ([]JSONPatchOperation, error) {
+	var source, target interface{}
+
+	if err := json.Unmarshal(from, &source); err != nil {
+		return nil, fmt.Errorf("source document is not valid JSON: %w", err)
+	}
+	if err := json.Unmarshal(to, &target); err != nil {
+		return nil, fmt.Errorf("target document is not valid JSON: %w", err)
+	}
+
+	operations := []JSONPatchOperation{}
+	if err := diffValue("", source, target, &operations); err != nil {
+		return nil, err
+	}
+
+	return operations, nil
+}
+
+func diffValue(path string, source, target interface{}, operations *[]JSONPatchOperation) error {
+	if reflect.DeepEqual(source, target) {
+		return nil
+	}
+
+	sourceObject, sourceIsObject := source.(map[string]interface{})
+	targetObject, targetIsObject := target.(map[string]interface{})
+	if !sourceIsObject || !targetIsObject {
+		value, err := json.Marshal(target)
+		if err != nil {
+			return err
+		}
+		*operations = append(*operations, JSONPatchOperation{Op: "replace", Path: path, Value: value})
+		return nil
+	}
+
+	// Walk members in a stable order so equal documents give equal patches.
+	keys := make([]string, 0, len(sourceObject)+len(targetObject))
+	for key := range sourceObject {
+		keys = append(keys, key)
+	}
+	for key := range targetObject {
+		if _, ok := sourceObject[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		memberPath := path + "/" + strings.NewReplacer("~", "~0", "/", "~1").Replace(key)
+		sourceValue, inSource := sourceObject[key]
+		targetValue, inTarget := targetObject[key]
+
+		switch {
+		case !inTarget:
+			*operations = append(*operations, JSONPatchOperation{Op: "remove", Path: memberPath})
+		case !inSource:
+			value, err := json.Marshal(targetValue)
+			if err != nil {
+				return err
+			}
+			*operations = append(*operations, JSONPatchOperation{Op: "add", Path: memberPath, Value: value})
+		default:
+			if err := diffValue(memberPath, sourceValue, targetValue, operations); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// parseJSONPointer func for splitting a JSON Pointer (RFC 6901) into reference tokens.
+func parseJSONPointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return []string{}, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("path '%v' must start with '/'", pointer)
+	}
+
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.NewReplacer("~1", "/", "~0", "~").Replace(token)
+	}
+
+	return tokens, nil
+}
+
+func jsonPointerGet(doc interface{}, path []string) (interface{}, error) {
+	current := doc
+	for _, token := range path {
+		switch node := current.(type) {
+		case map[string]interface{}:
+			value, ok := node[token]
+			if !ok {
+				return nil, fmt.Errorf("member '%v' does not exist", token)
+			}
+			current = value
+		case []interface{}:
+			index, err := jsonPointerIndex(token, len(node)-1)
+			if err != nil {
+				return nil, err
+			}
+			current = node[index]
+		default:
+			return nil, fmt.Errorf("can not reach '%v' in a scalar value", token)
+		}
+	}
+
+	return current, nil
+}
+
+func jsonPointerAdd(doc interface{}, path []string, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+
+	parent, err := jsonPointerGet(doc, path[:len(path)-1])
+	if err != nil {
+		return nil, err
+	}
+	token := path[len(path)-1]
+
+	switch node := parent.(type) {
+	case map[string]interface{}:
+		node[token] = value
+		return doc, nil
+	case []interface{}:
+		index := len(node)
+		if token != "-" {
+			if index, err = jsonPointerIndex(token, len(node)); err != nil {
+				return nil, err
+			}
+		}
+		node = append(node, nil)
+		copy(node[index+1:], node[index:])
+		node[index] = value
+		return jsonPointerSet(doc, path[:len(path)-1], node)
+	default:
+		return nil, fmt.Errorf("can not add '%v' to a scalar value", token)
+	}
+}
+
+func jsonPointerRemove(doc interface{}, path []string) (interface{}, error) {
+	if len(path) == 0 {
+		return nil, fmt.Errorf("can not remove the whole document")
+	}
+
+	parent, err := jsonPointerGet(doc, path[:len(path)-1])
+	if err != nil {
+		return nil, err
+	}
+	token := path[len(path)-1]
+
+	switch node := parent.(type) {
+	case map[string]interface{}:
+		if _, ok := node[token]; !ok {
+			return nil, fmt.Errorf("member '%v' does not exist", token)
+		}
+		delete(node, token)
+		return doc, nil
+	case []interface{}:
+		index, err := jsonPointerIndex(token, len(node)-1)
+		if err != nil {
+			return nil, err
+		}
+		node = append(node[:index], node[index+1:]...)
+		return jsonPointerSet(doc, path[:len(path)-1], node)
+	default:
+		return nil, fmt.Errorf("can not remove '%v' from a scalar value", token)
+	}
+}
+
+// jsonPointerSet func for replacing the value at path, used when a resized array has to be put back.
+func jsonPointerSet(doc interface{}, path []string, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+
+	parent, err := jsonPointerGet(doc, path[:len(path)-1])
+	if err != nil {
+		return nil, err
+	}
+	token := path[len(path)-1]
+
+	switch node := parent.(type) {
+	case map[string]interface{}:
+		node[token] = value
+	case []interface{}:
+		index, err := jsonPointerIndex(token, len(node)-1)
+		if err != nil {
+			return nil, err
+		}
+		node[index] = value
+	}
+
+	return doc, nil
+}
+
+func jsonPointerIndex(token string, max int) (int, error) {
+	if token == "" || (len(token) > 1 && token[0] == '0') {
+		return 0, fmt.Errorf("array index '%v' is not valid", token)
+	}
+
+	index, err := strconv.Atoi(token)
+	if err != nil || index < 0 || index > max {
+		return 0, fmt.Errorf("array index '%v' is out of bounds", token)
+	}
+
+	return index, nil
+}
@@ -3,8 +3,10 @@ package utils
 import (
 	"crypto/sha256"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/uuid"
 	"os"
 	"strconv"
 	"strings"
@@ -19,8 +21,13 @@ type Tokens struct {
 
 // GenerateNewTokens func for generate a new AccessToken & RefreshToken tokens.
 func GenerateNewTokens(id string, credentials []string) (*Tokens, error) {
+	return GenerateNewSessionTokens(id, "", credentials)
+}
+
+// GenerateNewSessionTokens func for generate a new AccessToken & RefreshToken tokens of a sign in session.
+func GenerateNewSessionTokens(id, sessionID string, credentials []string) (*Tokens, error) {
 	// Generate JWT AccessToken token.
-	accessToken, err := generateNewAccessToken(id, credentials)
+	accessToken, err := generateNewAccessToken(id, sessionID, credentials)
 	if err != nil {
 		// Return token generation error.
 		return nil, err
@@ -39,8 +46,11 @@ func GenerateNewTokens(id string, credentials []string) (*Tokens, error) {
 	}, nil
 }
 
-func generateNewAccessToken(id string, credentials []string) (string, error) {
-	secret := os.Getenv("JWT_SECRET_KEY")
+func generateNewAccessToken(id, sessionID string, credentials []string) (string, error) {
+	key := currentSigningKey
+	if key == nil {
+		return "", errors.New("signing keys are not loaded")
+	}
 
 	// set expires in minutes
 	minutesCount, err := strconv.Atoi(os.Getenv("JWT_SECRET_KEY_EXPIRE_MINUTES_COUNT"))
@@ -48,29 +58,46 @@ func generateNewAccessToken(id string, credentials []string) (string, error) {
 		return "", err
 	}
 	if minutesCount <= 0 {
-		return "", err
+		return "", errors.New("JWT_SECRET_KEY_EXPIRE_MINUTES_COUNT must be positive")
 	}
 
 	// Create a new claims.
 	claims := jwt.MapClaims{}
 
+	// Set registered claims, the token ID lets the token be revoked before it expires:
+	now := time.Now()
+	claims["jti"] = uuid.NewString()
+	claims["sub"] = id
+	claims["iat"] = now.Unix()
+	claims["nbf"] = now.Unix()
+	claims["exp"] = now.Add(time.Minute * time.Duration(minutesCount)).Unix()
+	if issuer := os.Getenv("JWT_ISSUER"); issuer != "" {
+		claims["iss"] = issuer
+	}
+	if audience := os.Getenv("JWT_AUDIENCE"); audience != "" {
+		claims["aud"] = audience
+	}
+
 	// Set public claims:
-	claims["id"] = id
-	claims["expires"] = time.Now().Add(time.Minute * time.Duration(minutesCount)).Unix()
-	claims["book:create"] = false
-	claims["book:update"] = false
-	claims["book:delete"] = false
-
-	// Set private token credentials:
-	for _, credential := range credentials {
-		claims[credential] = true
+	if sessionID != "" {
+		claims["sid"] = sessionID
+	}
+
+	// Set private issue time in milliseconds, to tell tokens from revocations of the same second:
+	claims["iat_ms"] = now.UnixMilli()
+
+	// Set private token credentials, the permissions of all roles of the user:
+	if credentials == nil {
+		credentials = []string{}
 	}
+	claims["permissions"] = credentials
 
-	// Create a new JWT access token with claims.
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	// Create a new JWT access token with claims, the kid tells verifiers which public key to use.
+	token := jwt.NewWithClaims(key.method, claims)
+	token.Header["kid"] = key.id
 
 	// Generate token.
-	t, err := token.SignedString([]byte(secret))
+	t, err := token.SignedString(key.private)
 	if err != nil {
 		// Return error, it JWT token generation failed.
 		return "", err
@@ -83,8 +110,8 @@ func generateNewRefreshToken() (string, error) {
 	// Create a new SHA256 hash.
 	hash := sha256.New()
 
-	// Create a new now date and time string with salt.
-	refresh := os.Getenv("JWT_REFRESH_KEY") + time.Now().String()
+	// Create a new random string with salt, refresh tokens must not be guessable.
+	refresh := os.Getenv("JWT_REFRESH_KEY") + String(32) + time.Now().String()
 
 	// See: https://pkg.go.dev/io#Writer.Write
 	_, err := hash.Write([]byte(refresh))
@@ -93,11 +120,8 @@ func generateNewRefreshToken() (string, error) {
 		return "", err
 	}
 
-	// Set expires hours count for refresh key from .env file.
-	hoursCount, _ := strconv.Atoi(os.Getenv("JWT_REFRESH_KEY_EXPIRE_HOURS_COUNT"))
-
 	// Set expiration time.
-	expireTime := fmt.Sprint(time.Now().Add(time.Hour * time.Duration(hoursCount)).Unix())
+	expireTime := fmt.Sprint(time.Now().Add(RefreshTokenLifetime()).Unix())
 
 	// Create a new refresh token (sha256 string with salt + expire time).
 	t := hex.EncodeToString(hash.Sum(nil)) + "." + expireTime
@@ -105,7 +129,23 @@ func generateNewRefreshToken() (string, error) {
 	return t, nil
 }
 
+// AccessTokenLifetime func for how long access tokens last, from .env file.
+func AccessTokenLifetime() time.Duration {
+	minutesCount, _ := strconv.Atoi(os.Getenv("JWT_SECRET_KEY_EXPIRE_MINUTES_COUNT"))
+	return time.Minute * time.Duration(minutesCount)
+}
+
+// RefreshTokenLifetime func for how long refresh tokens and their sessions last, from .env file.
+func RefreshTokenLifetime() time.Duration {
+	hoursCount, _ := strconv.Atoi(os.Getenv("JWT_REFRESH_KEY_EXPIRE_HOURS_COUNT"))
+	return time.Hour * time.Duration(hoursCount)
+}
+
 // ParseRefreshToken func for parse second argument from refresh token.
 func ParseRefreshToken(refreshToken string) (int64, error) {
-	return strconv.ParseInt(strings.Split(refreshToken, ".")[1], 0, 64)
+	parts := strings.Split(refreshToken, ".")
+	if len(parts) != 2 {
+		return 0, fmt.Errorf("malformed refresh token")
+	}
+	return strconv.ParseInt(parts[1], 0, 64)
 }
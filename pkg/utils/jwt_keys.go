@@ -0,0 +1,210 @@
+package utils
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// signingKey struct to describe a key access tokens are signed or verified with.
+type signingKey struct {
+	id      string
+	method  jwt.SigningMethod
+	private crypto.Signer // nil for retired keys, kept only to verify tokens signed earlier
+	public  crypto.PublicKey
+}
+
+// JSONWebKey struct to describe a public key in a JWK Set (RFC 7517).
+type JSONWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`   // RSA modulus
+	E   string `json:"e,omitempty"`   // RSA exponent
+	Crv string `json:"crv,omitempty"` // Ed25519 curve
+	X   string `json:"x,omitempty"`   // Ed25519 public key
+}
+
+// JSONWebKeySet struct to describe the public keys access tokens can be verified with.
+type JSONWebKeySet struct {
+	Keys []JSONWebKey `json:"keys"`
+}
+
+var (
+	signingKeys       = map[string]*signingKey{}
+	currentSigningKey *signingKey
+)
+
+// InitSigningKeys func for loading the keys of JWT_SIGNING_KEYS_PATH, new tokens are signed with JWT_SIGNING_KEY_ID.
+//
+// Every PEM file of the folder holds one key, its name without extension is the key ID (kid). Private RSA keys
+// sign with RS256, private Ed25519 keys with EdDSA, public keys only verify tokens signed before a rotation.
+// The folder is required, only with APP_ENV=development a throwaway Ed25519 key is made without it. Tokens
+// signed with that key do not outlive a restart and are not accepted by other instances.
+func InitSigningKeys() error {
+	keys := map[string]*signingKey{}
+
+	path := os.Getenv("JWT_SIGNING_KEYS_PATH")
+	if path == "" {
+		if os.Getenv("APP_ENV") != "development" {
+			return errors.New("JWT_SIGNING_KEYS_PATH is required, unless APP_ENV is development")
+		}
+		_, private, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return err
+		}
+		key, err := newSigningKey("dev-"+String(8), private)
+		if err != nil {
+			return err
+		}
+		keys[key.id] = key
+		log.Println("JWT_SIGNING_KEYS_PATH is not set, signing access tokens with a throwaway key")
+	} else {
+		files, err := filepath.Glob(filepath.Join(path, "*.pem"))
+		if err != nil {
+			return err
+		}
+		for _, file := range files {
+			data, err := os.ReadFile(file)
+			if err != nil {
+				return err
+			}
+			key, err := parseSigningKey(strings.TrimSuffix(filepath.Base(file), ".pem"), data)
+			if err != nil {
+				return fmt.Errorf("signing key '%v': %w", file, err)
+			}
+			keys[key.id] = key
+		}
+	}
+
+	// Pick the key new tokens are signed with, it may be left out if there is only one to choose.
+	var current *signingKey
+	if id := os.Getenv("JWT_SIGNING_KEY_ID"); id != "" {
+		current = keys[id]
+		if current == nil {
+			return fmt.Errorf("signing key '%v' is not found", id)
+		}
+	} else {
+		for _, key := range keys {
+			if key.private == nil {
+				continue
+			}
+			if current != nil {
+				return errors.New("JWT_SIGNING_KEY_ID is required with more than one private signing key")
+			}
+			current = key
+		}
+	}
+	if current == nil || current.private == nil {
+		return errors.New("no private key to sign access tokens with")
+	}
+
+	signingKeys, currentSigningKey = keys, current
+
+	return nil
+}
+
+// parseSigningKey func for reading a PEM encoded private or public key.
+func parseSigningKey(id string, data []byte) (*signingKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM data found")
+	}
+
+	var key interface{}
+	var err error
+	switch block.Type {
+	case "PRIVATE KEY":
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		key, err = x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		key, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("PEM type '%v' is not supported", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return newSigningKey(id, key)
+}
+
+// newSigningKey func for picking the signing method of a parsed key.
+func newSigningKey(id string, key interface{}) (*signingKey, error) {
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		if k.N.BitLen() < 2048 {
+			return nil, errors.New("RSA keys need at least 2048 bits")
+		}
+		return &signingKey{id: id, method: jwt.SigningMethodRS256, private: k, public: &k.PublicKey}, nil
+	case *rsa.PublicKey:
+		if k.N.BitLen() < 2048 {
+			return nil, errors.New("RSA keys need at least 2048 bits")
+		}
+		return &signingKey{id: id, method: jwt.SigningMethodRS256, public: k}, nil
+	case ed25519.PrivateKey:
+		return &signingKey{id: id, method: jwt.SigningMethodEdDSA, private: k, public: k.Public()}, nil
+	case ed25519.PublicKey:
+		return &signingKey{id: id, method: jwt.SigningMethodEdDSA, public: k}, nil
+	default:
+		return nil, fmt.Errorf("key type %T is not supported, use RSA or Ed25519", key)
+	}
+}
+
+// SigningKeySet func for the public keys of all loaded signing keys, also the retired ones.
+func SigningKeySet() JSONWebKeySet {
+	set := JSONWebKeySet{Keys: []JSONWebKey{}}
+	for _, key := range signingKeys {
+		jwk := JSONWebKey{Kid: key.id, Use: "sig", Alg: key.method.Alg()}
+		switch public := key.public.(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(public)
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+
+	// Keep the order stable, caches of other services compare the whole set.
+	sort.Slice(set.Keys, func(i, j int) bool { return set.Keys[i].Kid < set.Keys[j].Kid })
+
+	return set
+}
+
+// jwtKeyFunc func for picking the public key a token was signed with, by its kid header.
+func jwtKeyFunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	key, ok := signingKeys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unexpected jwt key id=%v", token.Header["kid"])
+	}
+
+	// The algorithm has to be the one of the key, or a public key could pass as a HMAC secret.
+	if token.Method.Alg() != key.method.Alg() {
+		return nil, fmt.Errorf("unexpected jwt signing method=%v", token.Header["alg"])
+	}
+
+	return key.public, nil
+}
@@ -0,0 +1,110 @@
+package utils
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSigningKeyRotation(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("JWT_SIGNING_KEYS_PATH", dir)
+	t.Setenv("JWT_SECRET_KEY_EXPIRE_MINUTES_COUNT", "15")
+	t.Setenv("JWT_ISSUER", "go-fiber-rest-api")
+	t.Setenv("JWT_AUDIENCE", "go-fiber-rest-api")
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	rsaDER, err := x509.MarshalPKCS8PrivateKey(rsaKey)
+	assert.NoError(t, err)
+	writeTestKey(t, dir, "2024-01", "PRIVATE KEY", rsaDER)
+
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+	edDER, err := x509.MarshalPKCS8PrivateKey(edKey)
+	assert.NoError(t, err)
+	writeTestKey(t, dir, "2024-02", "PRIVATE KEY", edDER)
+
+	// With two private keys the one to sign with has to be named.
+	assert.Error(t, InitSigningKeys())
+	t.Setenv("JWT_SIGNING_KEY_ID", "2024-01")
+	assert.NoError(t, InitSigningKeys())
+
+	userID := uuid.New()
+	oldTokens, err := GenerateNewTokens(userID.String(), []string{})
+	assert.NoError(t, err)
+	oldToken, err := ParseToken(oldTokens.AccessToken)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "RS256", oldToken.Header["alg"])
+		assert.Equal(t, "2024-01", oldToken.Header["kid"])
+		claims, err := ParseTokenMetadata(oldToken)
+		assert.NoError(t, err)
+		assert.Equal(t, userID, claims.UserID)
+	}
+
+	// Rotate to the Ed25519 key, the RSA key is kept only to verify.
+	rsaPublicDER, err := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	assert.NoError(t, err)
+	writeTestKey(t, dir, "2024-01", "PUBLIC KEY", rsaPublicDER)
+	t.Setenv("JWT_SIGNING_KEY_ID", "2024-02")
+	assert.NoError(t, InitSigningKeys())
+
+	newTokens, err := GenerateNewTokens(userID.String(), []string{})
+	assert.NoError(t, err)
+	newToken, err := ParseToken(newTokens.AccessToken)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "EdDSA", newToken.Header["alg"])
+		assert.Equal(t, "2024-02", newToken.Header["kid"])
+	}
+	_, err = ParseToken(oldTokens.AccessToken)
+	assert.NoError(t, err)
+
+	set := SigningKeySet()
+	if assert.Len(t, set.Keys, 2) {
+		assert.Equal(t, JSONWebKey{Kty: "RSA", Kid: "2024-01", Use: "sig", Alg: "RS256", N: set.Keys[0].N, E: "AQAB"}, set.Keys[0])
+		assert.Equal(t, "OKP", set.Keys[1].Kty)
+		assert.Equal(t, "Ed25519", set.Keys[1].Crv)
+	}
+
+	// Public keys can not sign.
+	t.Setenv("JWT_SIGNING_KEY_ID", "2024-01")
+	assert.Error(t, InitSigningKeys())
+
+	// Tokens of another audience, or with a public key passed off as HMAC secret, are rejected.
+	t.Setenv("JWT_AUDIENCE", "other-service")
+	_, err = ParseToken(newTokens.AccessToken)
+	assert.Error(t, err)
+
+	forged := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"sub": userID.String()})
+	forged.Header["kid"] = "2024-01"
+	forgedString, err := forged.SignedString(rsaPublicDER)
+	assert.NoError(t, err)
+	_, err = ParseToken(forgedString)
+	assert.Error(t, err)
+}
+
+func TestSigningKeysRequired(t *testing.T) {
+	t.Setenv("JWT_SIGNING_KEYS_PATH", "")
+	t.Setenv("JWT_SIGNING_KEY_ID", "")
+	t.Setenv("APP_ENV", "")
+	assert.Error(t, InitSigningKeys())
+
+	// Only development may sign with a throwaway key.
+	t.Setenv("APP_ENV", "development")
+	assert.NoError(t, InitSigningKeys())
+	assert.Len(t, SigningKeySet().Keys, 1)
+}
+
+func writeTestKey(t *testing.T, dir, id, pemType string, der []byte) {
+	data := pem.EncodeToMemory(&pem.Block{Type: pemType, Bytes: der})
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, id+".pem"), data, 0o600))
+}
@@ -1,9 +1,11 @@
 package utils
 
 import (
+	"errors"
 	"github.com/google/uuid"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/golang-jwt/jwt/v4"
@@ -11,8 +13,11 @@ import (
 
 // TokenMetadata struct to describe metadata in JWT.
 type TokenMetadata struct {
+	TokenID     string
 	UserID      uuid.UUID
+	SessionID   uuid.UUID // uuid.Nil for tokens not issued by signing in
 	Credentials map[string]bool
+	IssuedAt    int64 // in milliseconds
 	Expires     int64
 }
 
@@ -23,33 +28,58 @@ func ExtractTokenMetadata(c *fiber.Ctx) (*TokenMetadata, error) {
 		return nil, err
 	}
 
+	return ParseTokenMetadata(token)
+}
+
+// ParseTokenMetadata func to read metadata from the claims of a verified JWT.
+func ParseTokenMetadata(token *jwt.Token) (*TokenMetadata, error) {
 	// Setting and checking token and credentials.
 	claims, ok := token.Claims.(jwt.MapClaims)
 	if ok && token.Valid {
 		// User ID.
-		userID, err := uuid.Parse(claims["id"].(string))
+		sub, _ := claims["sub"].(string)
+		userID, err := uuid.Parse(sub)
 		if err != nil {
 			return nil, err
 		}
 
-		// Expires time.
-		expires := int64(claims["expires"].(float64))
+		// Session ID, tokens without one are not tied to a session.
+		sessionID := uuid.Nil
+		if sid, ok := claims["sid"].(string); ok {
+			if sessionID, err = uuid.Parse(sid); err != nil {
+				return nil, err
+			}
+		}
 
-		// User credentials.
-		credentials := map[string]bool{
-			"book:create": claims["book:create"].(bool),
-			"book:update": claims["book:update"].(bool),
-			"book:delete": claims["book:delete"].(bool),
+		// Token ID, issue and expires time.
+		tokenID, _ := claims["jti"].(string)
+		issuedAt, _ := claims["iat"].(float64)
+		issuedAtMs := int64(issuedAt) * 1000
+		if ms, ok := claims["iat_ms"].(float64); ok {
+			issuedAtMs = int64(ms)
+		}
+		expires, _ := claims["exp"].(float64)
+
+		// User credentials, whatever permissions the token holds.
+		credentials := map[string]bool{}
+		permissions, _ := claims["permissions"].([]interface{})
+		for _, permission := range permissions {
+			if name, ok := permission.(string); ok {
+				credentials[name] = true
+			}
 		}
 
 		return &TokenMetadata{
+			TokenID:     tokenID,
 			UserID:      userID,
+			SessionID:   sessionID,
 			Credentials: credentials,
-			Expires:     expires,
+			IssuedAt:    issuedAtMs,
+			Expires:     int64(expires),
 		}, nil
 	}
 
-	return nil, err
+	return nil, errors.New("invalid token claims")
 }
 
 func extractToken(c *fiber.Ctx) string {
@@ -65,16 +95,30 @@ func extractToken(c *fiber.Ctx) string {
 }
 
 func verifyToken(c *fiber.Ctx) (*jwt.Token, error) {
-	tokenString := extractToken(c)
+	return ParseToken(extractToken(c))
+}
 
-	token, err := jwt.Parse(tokenString, jwtKeyFunc)
+// ParseToken func to verify the signature and registered claims of a JWT access token.
+func ParseToken(tokenString string) (*jwt.Token, error) {
+	token, err := jwt.Parse(tokenString, jwtKeyFunc, jwt.WithValidMethods([]string{
+		jwt.SigningMethodRS256.Alg(),
+		jwt.SigningMethodEdDSA.Alg(),
+	}))
 	if err != nil {
 		return nil, err
 	}
 
-	return token, nil
-}
+	// Parse checks exp, nbf and iat when they are set, the rest is up to us.
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !claims.VerifyExpiresAt(time.Now().Unix(), true) {
+		return nil, errors.New("token has no expiry or is expired")
+	}
+	if issuer := os.Getenv("JWT_ISSUER"); issuer != "" && !claims.VerifyIssuer(issuer, true) {
+		return nil, errors.New("token issuer is not accepted")
+	}
+	if audience := os.Getenv("JWT_AUDIENCE"); audience != "" && !claims.VerifyAudience(audience, true) {
+		return nil, errors.New("token audience is not accepted")
+	}
 
-func jwtKeyFunc(token *jwt.Token) (interface{}, error) {
-	return []byte(os.Getenv("JWT_SECRET_KEY")), nil
+	return token, nil
 }
@@ -0,0 +1,130 @@
+package utils
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+
+	"github.com/aryanicosa/go-fiber-rest-api/app/models"
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	// DefaultPageLimit is the page size used when `limit` is not given.
+	DefaultPageLimit = 20
+
+	// MaxPageLimit is the biggest page size a client can ask for.
+	MaxPageLimit = 100
+)
+
+// ParsePagination func for reading `page`, `limit` and `cursor` query params.
+func ParsePagination(c *fiber.Ctx) (*models.Pagination, error) {
+	pagination := &models.Pagination{
+		Page:  1,
+		Limit: DefaultPageLimit,
+	}
+
+	if page := c.Query("page"); page != "" {
+		p, err := strconv.Atoi(page)
+		if err != nil || p < 1 {
+			return nil, fmt.Errorf("page must be a positive number")
+		}
+		pagination.Page = p
+	}
+
+	if limit := c.Query("limit"); limit != "" {
+		l, err := strconv.Atoi(limit)
+		if err != nil || l < 1 || l > MaxPageLimit {
+			return nil, fmt.Errorf("limit must be a number between 1 and %d", MaxPageLimit)
+		}
+		pagination.Limit = l
+	}
+
+	// An empty `cursor=` param starts keyset paging from the first row.
+	if c.Context().QueryArgs().Has("cursor") {
+		pagination.UseCursor = true
+
+		if cursor := c.Query("cursor"); cursor != "" {
+			decoded, err := DecodeCursor(cursor)
+			if err != nil {
+				return nil, err
+			}
+			pagination.Cursor = decoded
+		}
+	}
+
+	return pagination, nil
+}
+
+// EncodeCursor func for turning a keyset position into an opaque string.
+func EncodeCursor(cursor *models.Cursor) string {
+	b, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// DecodeCursor func for reading a keyset position from an opaque string.
+func DecodeCursor(s string) (*models.Cursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("cursor is malformed")
+	}
+
+	cursor := &models.Cursor{}
+	if err := json.Unmarshal(b, cursor); err != nil {
+		return nil, fmt.Errorf("cursor is malformed")
+	}
+
+	return cursor, nil
+}
+
+// BuildPageLink func for building a link to the current route with replaced query params.
+// An empty value removes the param from the link.
+func BuildPageLink(c *fiber.Ctx, params map[string]string) string {
+	query, _ := url.ParseQuery(string(c.Context().QueryArgs().QueryString()))
+
+	for key, value := range params {
+		if value == "" {
+			query.Del(key)
+			continue
+		}
+		query.Set(key, value)
+	}
+
+	return c.BaseURL() + c.Path() + "?" + query.Encode()
+}
+
+// BuildPageLinks func for building next/prev links of a list page.
+// The first and last cursors point at the edges of the returned page and are
+// only used in keyset mode, hasMore tells if rows exist past the page edge.
+func BuildPageLinks(c *fiber.Ctx, p *models.Pagination, total int64, hasMore bool, first, last *models.Cursor) models.PageLinks {
+	links := models.PageLinks{}
+
+	if !p.UseCursor {
+		if int64(p.Page*p.Limit) < total {
+			links.Next = BuildPageLink(c, map[string]string{"page": strconv.Itoa(p.Page + 1)})
+		}
+		if p.Page > 1 {
+			links.Prev = BuildPageLink(c, map[string]string{"page": strconv.Itoa(p.Page - 1)})
+		}
+		return links
+	}
+
+	backward := p.Cursor != nil && p.Cursor.Backward
+	hasNext := backward || hasMore
+	hasPrev := (backward && hasMore) || (!backward && p.Cursor != nil)
+
+	if hasNext && last != nil {
+		next := *last
+		next.Backward = false
+		links.Next = BuildPageLink(c, map[string]string{"page": "", "cursor": EncodeCursor(&next)})
+	}
+	if hasPrev && first != nil {
+		prev := *first
+		prev.Backward = true
+		links.Prev = BuildPageLink(c, map[string]string{"page": "", "cursor": EncodeCursor(&prev)})
+	}
+
+	return links
+}
@@ -19,6 +19,12 @@ func NewValidator() *validator.Validate {
 		return false
 	})
 
+	// Custom validation for ISBN-10 and ISBN-13 fields, checksum included.
+	_ = validate.RegisterValidation("isbn", func(fl validator.FieldLevel) bool {
+		_, err := NormalizeISBN(fl.Field().String())
+		return err == nil
+	})
+
 	return validate
 }
 
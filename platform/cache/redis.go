@@ -1,14 +1,29 @@
 package cache
 
 import (
+	"github.com/aryanicosa/go-fiber-rest-api/app/queries"
 	"github.com/aryanicosa/go-fiber-rest-api/pkg/utils"
 	"github.com/go-redis/redis/v8"
 	"os"
 	"strconv"
+	"sync"
+)
+
+var (
+	// client is shared by all callers, it keeps a pool of connections to Redis.
+	client      *redis.Client
+	clientMutex sync.Mutex
 )
 
 // RedisConnection func for connect to Redis server.
 func RedisConnection() (*redis.Client, error) {
+	clientMutex.Lock()
+	defer clientMutex.Unlock()
+
+	if client != nil {
+		return client, nil
+	}
+
 	// Define Redis database number.
 	dbNumber, _ := strconv.Atoi(os.Getenv("REDIS_DB_NUMBER"))
 
@@ -25,5 +40,24 @@ func RedisConnection() (*redis.Client, error) {
 		DB:       dbNumber,
 	}
 
-	return redis.NewClient(options), nil
+	client = redis.NewClient(options)
+	return client, nil
+}
+
+// SessionCache used for init sessions redis query
+func SessionCache() (*queries.SessionQueries, error) {
+	client, err := RedisConnection()
+	if err != nil {
+		return nil, err
+	}
+	return &queries.SessionQueries{Client: client}, nil
+}
+
+// TokenDenylistCache used for init access token denylist redis query
+func TokenDenylistCache() (*queries.TokenDenylistQueries, error) {
+	client, err := RedisConnection()
+	if err != nil {
+		return nil, err
+	}
+	return &queries.TokenDenylistQueries{Client: client}, nil
 }
@@ -16,8 +16,19 @@ var (
 
 // Queries struct for collect all app queries.
 type Queries struct {
-	*queries.UserQueries // load queries from User model
-	*queries.BookQueries // load queries from Book model
+	*queries.UserQueries             // load queries from User model
+	*queries.BookQueries             // load queries from Book model
+	*queries.BookCollaboratorQueries // load queries from BookCollaborator model
+	*queries.BookAuditQueries        // load queries from BookAuditLog model
+	*queries.BookRevisionQueries     // load queries from BookRevision model
+	*queries.TagQueries              // load queries from Tag model
+	*queries.CategoryQueries         // load queries from Category model
+	*queries.ReviewQueries           // load queries from Review model
+	*queries.BookAttrQueries         // load queries from BookAttrDefinition model
+	*queries.AuthorQueries           // load queries from Author model
+	*queries.ReadingListQueries      // load queries from ReadingList model
+	*queries.BookLoanQueries         // load queries from BookCopy, BookLoan and BookHold models
+	*queries.RoleQueries             // load queries from Role and Permission models
 }
 
 // InitDBConnection func for connection to PostgreSQL database.
@@ -35,8 +46,19 @@ func InitDBConnection() (*Queries, error) {
 	}
 
 	return &Queries{
-		UserQueries: &queries.UserQueries{DB: db},
-		BookQueries: &queries.BookQueries{DB: db},
+		UserQueries:             &queries.UserQueries{DB: db},
+		BookQueries:             &queries.BookQueries{DB: db},
+		BookCollaboratorQueries: &queries.BookCollaboratorQueries{DB: db},
+		BookAuditQueries:        &queries.BookAuditQueries{DB: db},
+		BookRevisionQueries:     &queries.BookRevisionQueries{DB: db},
+		TagQueries:              &queries.TagQueries{DB: db},
+		CategoryQueries:         &queries.CategoryQueries{DB: db},
+		ReviewQueries:           &queries.ReviewQueries{DB: db},
+		BookAttrQueries:         &queries.BookAttrQueries{DB: db},
+		AuthorQueries:           &queries.AuthorQueries{DB: db},
+		ReadingListQueries:      &queries.ReadingListQueries{DB: db},
+		BookLoanQueries:         &queries.BookLoanQueries{DB: db},
+		RoleQueries:             &queries.RoleQueries{DB: db},
 	}, nil
 }
 
@@ -61,3 +83,63 @@ func UserDB() *queries.UserQueries {
 func BookDB() *queries.BookQueries {
 	return &queries.BookQueries{DB: db}
 }
+
+// BookCollaboratorDB used for init book collaborators db query
+func BookCollaboratorDB() *queries.BookCollaboratorQueries {
+	return &queries.BookCollaboratorQueries{DB: db}
+}
+
+// BookAuditDB used for init book audit logs db query
+func BookAuditDB() *queries.BookAuditQueries {
+	return &queries.BookAuditQueries{DB: db}
+}
+
+// BookRevisionDB used for init book revisions db query
+func BookRevisionDB() *queries.BookRevisionQueries {
+	return &queries.BookRevisionQueries{DB: db}
+}
+
+// TagDB used for init tags db query
+func TagDB() *queries.TagQueries {
+	return &queries.TagQueries{DB: db}
+}
+
+// CategoryDB used for init categories db query
+func CategoryDB() *queries.CategoryQueries {
+	return &queries.CategoryQueries{DB: db}
+}
+
+// ReviewDB used for init reviews db query
+func ReviewDB() *queries.ReviewQueries {
+	return &queries.ReviewQueries{DB: db}
+}
+
+// BookAttrDB used for init book attribute definitions db query
+func BookAttrDB() *queries.BookAttrQueries {
+	return &queries.BookAttrQueries{DB: db}
+}
+
+// AuthorDB used for init authors db query
+func AuthorDB() *queries.AuthorQueries {
+	return &queries.AuthorQueries{DB: db}
+}
+
+// ReadingListDB used for init reading lists db query
+func ReadingListDB() *queries.ReadingListQueries {
+	return &queries.ReadingListQueries{DB: db}
+}
+
+// BookLoanDB used for init book lending db query
+func BookLoanDB() *queries.BookLoanQueries {
+	return &queries.BookLoanQueries{DB: db}
+}
+
+// RoleDB used for init role and permission db query
+func RoleDB() *queries.RoleQueries {
+	return &queries.RoleQueries{DB: db}
+}
+
+// Transaction used for running queries in one transaction, rolled back if fn returns an error
+func Transaction(fn func(tx *gorm.DB) error) error {
+	return db.Transaction(fn)
+}
@@ -0,0 +1,95 @@
+package storage
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// LocalStorageRoute is the path local files are served under.
+	LocalStorageRoute = "/media"
+
+	// defaultLocalStoragePath is the folder local files are saved to when STORAGE_LOCAL_PATH is not set.
+	defaultLocalStoragePath = "./media"
+)
+
+// LocalStorage struct for saving files to a folder served by the app itself.
+type LocalStorage struct {
+	Root      string
+	PublicURL string
+}
+
+// NewLocalStorage func for creating a storage saving files under root.
+// Files are served from publicURL, or from LocalStorageRoute of the app when it is empty.
+func NewLocalStorage(root, publicURL string) *LocalStorage {
+	if root == "" {
+		root = defaultLocalStoragePath
+	}
+	if publicURL == "" {
+		publicURL = LocalStorageRoute
+	}
+
+	return &LocalStorage{Root: root, PublicURL: strings.TrimSuffix(publicURL, "/")}
+}
+
+// Put method for writing a file, first to a temporary name so readers never see it half written.
+func (s *LocalStorage) Put(key string, body []byte, contentType string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(body); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+// Delete method for removing a file.
+func (s *LocalStorage) Delete(key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	return nil
+}
+
+// URL method for getting the address of a file.
+func (s *LocalStorage) URL(key string) string {
+	return s.PublicURL + "/" + escapePath(key)
+}
+
+// path method for turning a key into a file path, refusing keys that leave the root folder.
+func (s *LocalStorage) path(key string) (string, error) {
+	clean := filepath.Clean("/" + key)
+	if key == "" || clean != "/"+key {
+		return "", errors.New("storage key '" + key + "' is not valid")
+	}
+
+	return filepath.Join(s.Root, filepath.FromSlash(clean)), nil
+}
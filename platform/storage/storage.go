@@ -0,0 +1,51 @@
+package storage
+
+import (
+	"fmt"
+	"os"
+)
+
+// Storage interface for saving uploaded files and serving them by URL.
+type Storage interface {
+	// Put saves body under given key, replacing any file with the same key.
+	Put(key string, body []byte, contentType string) error
+
+	// Delete removes the file saved under given key, missing files are not an error.
+	Delete(key string) error
+
+	// URL returns the address the file saved under given key is served from.
+	URL(key string) string
+}
+
+var store Storage
+
+// InitStorage func for setting up the storage driver chosen by STORAGE_DRIVER.
+func InitStorage() (Storage, error) {
+	switch driver := os.Getenv("STORAGE_DRIVER"); driver {
+	case "", "local":
+		store = NewLocalStorage(os.Getenv("STORAGE_LOCAL_PATH"), os.Getenv("STORAGE_PUBLIC_URL"))
+	case "s3":
+		s3, err := NewS3Storage(&S3Config{
+			Endpoint:  os.Getenv("STORAGE_S3_ENDPOINT"),
+			Region:    os.Getenv("STORAGE_S3_REGION"),
+			Bucket:    os.Getenv("STORAGE_S3_BUCKET"),
+			AccessKey: os.Getenv("STORAGE_S3_ACCESS_KEY"),
+			SecretKey: os.Getenv("STORAGE_S3_SECRET_KEY"),
+			PathStyle: os.Getenv("STORAGE_S3_PATH_STYLE") == "true",
+			PublicURL: os.Getenv("STORAGE_PUBLIC_URL"),
+		})
+		if err != nil {
+			return nil, err
+		}
+		store = s3
+	default:
+		return nil, fmt.Errorf("storage driver '%v' is not supported", driver)
+	}
+
+	return store, nil
+}
+
+// Store func for getting the storage set up by InitStorage.
+func Store() Storage {
+	return store
+}
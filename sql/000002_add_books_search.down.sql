@@ -0,0 +1,5 @@
+-- Delete indexes
+DROP INDEX IF EXISTS books_search_vector;
+
+-- Delete columns
+ALTER TABLE books DROP COLUMN IF EXISTS search_vector;
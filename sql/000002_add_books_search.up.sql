@@ -0,0 +1,9 @@
+-- Add full-text search column over title, author and description
+ALTER TABLE books ADD COLUMN search_vector TSVECTOR GENERATED ALWAYS AS (
+  setweight(to_tsvector('english', coalesce(title, '')), 'A') ||
+  setweight(to_tsvector('english', coalesce(author, '')), 'B') ||
+  setweight(to_tsvector('english', coalesce(book_attrs->>'description', '')), 'C')
+) STORED;
+
+-- Add indexes
+CREATE INDEX books_search_vector ON books USING GIN (search_vector);
@@ -0,0 +1,2 @@
+-- Delete indexes
+DROP INDEX IF EXISTS books_author_lower;
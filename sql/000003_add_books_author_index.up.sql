@@ -0,0 +1,2 @@
+-- Add indexes
+CREATE INDEX books_author_lower ON books (lower(author));
@@ -0,0 +1,12 @@
+-- Create book collaborators table
+CREATE TABLE book_collaborators (
+                     book_id UUID NOT NULL REFERENCES books (id) ON DELETE CASCADE,
+                     user_id UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+                     created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW (),
+                     updated_at TIMESTAMP NULL,
+                     collaborator_role VARCHAR (25) NOT NULL,
+                     PRIMARY KEY (book_id, user_id)
+);
+
+-- Add indexes
+CREATE INDEX book_collaborators_user ON book_collaborators (user_id);
@@ -0,0 +1,14 @@
+-- Create book audit logs table, kept without foreign keys so entries outlive the book and users
+CREATE TABLE book_audit_logs (
+                     id UUID DEFAULT uuid_generate_v4 () PRIMARY KEY,
+                     created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW (),
+                     book_id UUID NOT NULL,
+                     owner_id UUID NOT NULL,
+                     actor_id UUID NOT NULL,
+                     action VARCHAR (25) NOT NULL,
+                     credential VARCHAR (50) NOT NULL,
+                     reason TEXT NOT NULL
+);
+
+-- Add indexes
+CREATE INDEX book_audit_logs_book ON book_audit_logs (book_id, created_at);
@@ -0,0 +1,2 @@
+-- Delete columns
+ALTER TABLE books DROP COLUMN IF EXISTS version;
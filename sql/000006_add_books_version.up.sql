@@ -0,0 +1,2 @@
+-- Add version column bumped on every update, used for optimistic concurrency
+ALTER TABLE books ADD COLUMN version INT NOT NULL DEFAULT 1;
@@ -0,0 +1,15 @@
+-- Delete indexes
+DROP INDEX IF EXISTS books_deleted_at;
+
+-- Delete triggers
+DROP TRIGGER IF EXISTS users_trash_books ON users;
+DROP FUNCTION IF EXISTS trash_user_books ();
+
+-- Wipe trashed and orphaned books, restore cascading delete from users
+DELETE FROM books WHERE deleted_at IS NOT NULL OR user_id IS NULL;
+ALTER TABLE books DROP CONSTRAINT IF EXISTS books_user_id_fkey;
+ALTER TABLE books ADD CONSTRAINT books_user_id_fkey FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE;
+ALTER TABLE books ALTER COLUMN user_id SET NOT NULL;
+
+-- Delete columns
+ALTER TABLE books DROP COLUMN IF EXISTS deleted_at;
@@ -0,0 +1,19 @@
+-- Add soft delete column, trashed books are purged after a retention period
+ALTER TABLE books ADD COLUMN deleted_at TIMESTAMP WITH TIME ZONE NULL;
+
+-- Keep books of deleted users in the trash instead of wiping them
+ALTER TABLE books ALTER COLUMN user_id DROP NOT NULL;
+ALTER TABLE books DROP CONSTRAINT IF EXISTS books_user_id_fkey;
+ALTER TABLE books ADD CONSTRAINT books_user_id_fkey FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE SET NULL;
+
+CREATE FUNCTION trash_user_books() RETURNS TRIGGER AS $$
+BEGIN
+  UPDATE books SET deleted_at = NOW () WHERE user_id = OLD.id AND deleted_at IS NULL;
+  RETURN OLD;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER users_trash_books BEFORE DELETE ON users FOR EACH ROW EXECUTE PROCEDURE trash_user_books ();
+
+-- Add indexes
+CREATE INDEX books_deleted_at ON books (deleted_at) WHERE deleted_at IS NOT NULL;
@@ -0,0 +1,11 @@
+-- Create book revisions table, one full snapshot of the row per change
+CREATE TABLE book_revisions (
+                     id UUID DEFAULT uuid_generate_v4 () PRIMARY KEY,
+                     created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW (),
+                     book_id UUID NOT NULL REFERENCES books (id) ON DELETE CASCADE,
+                     revision INT NOT NULL,
+                     user_id UUID NULL REFERENCES users (id) ON DELETE SET NULL,
+                     action VARCHAR (25) NOT NULL,
+                     snapshot JSONB NOT NULL,
+                     UNIQUE (book_id, revision)
+);
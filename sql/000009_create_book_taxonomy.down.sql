@@ -0,0 +1,5 @@
+-- Delete tables
+DROP TABLE IF EXISTS book_categories;
+DROP TABLE IF EXISTS book_tags;
+DROP TABLE IF EXISTS categories;
+DROP TABLE IF EXISTS tags;
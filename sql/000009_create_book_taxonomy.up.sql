@@ -0,0 +1,35 @@
+-- Create tags table, flat labels of books
+CREATE TABLE tags (
+                     id UUID DEFAULT uuid_generate_v4 () PRIMARY KEY,
+                     created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW (),
+                     name VARCHAR (50) NOT NULL,
+                     slug VARCHAR (50) NOT NULL UNIQUE
+);
+
+-- Create categories table, nested through parent_id
+CREATE TABLE categories (
+                     id UUID DEFAULT uuid_generate_v4 () PRIMARY KEY,
+                     created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW (),
+                     updated_at TIMESTAMP NULL,
+                     parent_id UUID NULL REFERENCES categories (id) ON DELETE RESTRICT,
+                     name VARCHAR (100) NOT NULL,
+                     slug VARCHAR (100) NOT NULL UNIQUE
+);
+
+-- Create many-to-many joins of books
+CREATE TABLE book_tags (
+                     book_id UUID NOT NULL REFERENCES books (id) ON DELETE CASCADE,
+                     tag_id UUID NOT NULL REFERENCES tags (id) ON DELETE CASCADE,
+                     PRIMARY KEY (book_id, tag_id)
+);
+
+CREATE TABLE book_categories (
+                     book_id UUID NOT NULL REFERENCES books (id) ON DELETE CASCADE,
+                     category_id UUID NOT NULL REFERENCES categories (id) ON DELETE CASCADE,
+                     PRIMARY KEY (book_id, category_id)
+);
+
+-- Add indexes
+CREATE INDEX categories_parent ON categories (parent_id);
+CREATE INDEX book_tags_tag ON book_tags (tag_id);
+CREATE INDEX book_categories_category ON book_categories (category_id);
@@ -0,0 +1,10 @@
+-- Delete triggers
+DROP TRIGGER IF EXISTS reviews_refresh_book_rating ON reviews;
+DROP FUNCTION IF EXISTS refresh_book_rating ();
+
+-- Delete columns
+ALTER TABLE books DROP COLUMN IF EXISTS rating_count;
+ALTER TABLE books DROP COLUMN IF EXISTS rating_average;
+
+-- Delete tables
+DROP TABLE IF EXISTS reviews;
@@ -0,0 +1,38 @@
+-- Create reviews table, one rating and review per user and book
+CREATE TABLE reviews (
+                     id UUID DEFAULT uuid_generate_v4 () PRIMARY KEY,
+                     created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW (),
+                     updated_at TIMESTAMP NULL,
+                     book_id UUID NOT NULL REFERENCES books (id) ON DELETE CASCADE,
+                     user_id UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+                     rating INT NOT NULL CHECK (rating BETWEEN 0 AND 10),
+                     body TEXT NOT NULL DEFAULT '',
+                     UNIQUE (book_id, user_id)
+);
+
+-- Add rating aggregate columns, kept up to date by a trigger on reviews
+ALTER TABLE books ADD COLUMN rating_average NUMERIC (4, 2) NOT NULL DEFAULT 0;
+ALTER TABLE books ADD COLUMN rating_count INT NOT NULL DEFAULT 0;
+
+CREATE FUNCTION refresh_book_rating() RETURNS TRIGGER AS $$
+DECLARE
+  changed_book_id UUID;
+BEGIN
+  IF TG_OP = 'DELETE' THEN
+    changed_book_id := OLD.book_id;
+  ELSE
+    changed_book_id := NEW.book_id;
+  END IF;
+
+  UPDATE books SET
+    rating_average = coalesce((SELECT avg(rating) FROM reviews WHERE book_id = changed_book_id), 0),
+    rating_count = (SELECT count(*) FROM reviews WHERE book_id = changed_book_id)
+  WHERE id = changed_book_id;
+  RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER reviews_refresh_book_rating AFTER INSERT OR UPDATE OF rating OR DELETE ON reviews FOR EACH ROW EXECUTE PROCEDURE refresh_book_rating ();
+
+-- Add indexes
+CREATE INDEX reviews_book_created ON reviews (book_id, created_at DESC);
@@ -0,0 +1,15 @@
+-- Delete indexes
+DROP INDEX IF EXISTS books_book_status;
+DROP INDEX IF EXISTS active_books;
+
+-- Turn named states back into numbers, only published books stay active
+UPDATE book_revisions SET snapshot = jsonb_set(snapshot, '{book_status}',
+  CASE snapshot->>'book_status' WHEN 'published' THEN '1'::jsonb ELSE '0'::jsonb END)
+  WHERE jsonb_typeof(snapshot->'book_status') = 'string';
+
+ALTER TABLE books DROP CONSTRAINT IF EXISTS books_book_status_check;
+ALTER TABLE books ALTER COLUMN book_status DROP DEFAULT;
+ALTER TABLE books ALTER COLUMN book_status TYPE INT
+  USING CASE book_status WHEN 'published' THEN 1 ELSE 0 END;
+
+CREATE INDEX active_books ON books (title) WHERE book_status = 1;
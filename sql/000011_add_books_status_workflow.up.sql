@@ -0,0 +1,17 @@
+-- Replace numeric book status with named workflow states, 1 was active and anything else a draft
+DROP INDEX IF EXISTS active_books;
+
+ALTER TABLE books ALTER COLUMN book_status TYPE VARCHAR (25)
+  USING CASE book_status WHEN 1 THEN 'published' ELSE 'draft' END;
+ALTER TABLE books ALTER COLUMN book_status SET DEFAULT 'draft';
+ALTER TABLE books ADD CONSTRAINT books_book_status_check
+  CHECK (book_status IN ('draft', 'review', 'published', 'archived'));
+
+-- Keep revision snapshots readable as books
+UPDATE book_revisions SET snapshot = jsonb_set(snapshot, '{book_status}',
+  CASE snapshot->>'book_status' WHEN '1' THEN '"published"'::jsonb ELSE '"draft"'::jsonb END)
+  WHERE jsonb_typeof(snapshot->'book_status') = 'number';
+
+-- Add indexes
+CREATE INDEX active_books ON books (title) WHERE book_status = 'published';
+CREATE INDEX books_book_status ON books (book_status, created_at);
@@ -0,0 +1,5 @@
+-- Add ISBN column, stored as ISBN-13 and unique across all books, trashed ones included
+ALTER TABLE books ADD COLUMN isbn VARCHAR (13) NULL;
+
+-- Add indexes
+CREATE UNIQUE INDEX books_isbn ON books (isbn);
@@ -0,0 +1,5 @@
+-- Delete indexes
+DROP INDEX IF EXISTS books_book_attrs;
+
+-- Delete tables
+DROP TABLE IF EXISTS book_attr_definitions;
@@ -0,0 +1,12 @@
+-- Create book_attr_definitions table, extra attributes of books registered by admins
+CREATE TABLE book_attr_definitions (
+                     id UUID DEFAULT uuid_generate_v4 () PRIMARY KEY,
+                     created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW (),
+                     name VARCHAR (50) NOT NULL UNIQUE,
+                     type VARCHAR (25) NOT NULL CHECK (type IN ('string', 'number', 'integer', 'boolean', 'enum')),
+                     required BOOLEAN NOT NULL DEFAULT FALSE,
+                     enum_values JSONB NOT NULL DEFAULT '[]'
+);
+
+-- Add indexes, for filtering books by attribute values with @>
+CREATE INDEX books_book_attrs ON books USING GIN (book_attrs jsonb_path_ops);
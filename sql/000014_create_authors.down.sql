@@ -0,0 +1,3 @@
+-- Delete tables, names of authors are kept on books
+DROP TABLE IF EXISTS book_authors;
+DROP TABLE IF EXISTS authors;
@@ -0,0 +1,31 @@
+-- Create authors table, people credited on books
+CREATE TABLE authors (
+                     id UUID DEFAULT uuid_generate_v4 () PRIMARY KEY,
+                     created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW (),
+                     updated_at TIMESTAMP NULL,
+                     name VARCHAR (255) NOT NULL,
+                     bio TEXT NOT NULL DEFAULT '',
+                     website VARCHAR (255) NOT NULL DEFAULT '',
+                     birth_year INT NULL
+);
+
+-- Create book_authors table, credits of books in order
+CREATE TABLE book_authors (
+                     book_id UUID NOT NULL REFERENCES books (id) ON DELETE CASCADE,
+                     author_id UUID NOT NULL REFERENCES authors (id) ON DELETE CASCADE,
+                     role VARCHAR (25) NOT NULL DEFAULT 'author' CHECK (role IN ('author', 'editor', 'translator')),
+                     position INT NOT NULL DEFAULT 1,
+                     PRIMARY KEY (book_id, author_id, role)
+);
+
+-- Back-fill one author per author name of existing books, ignoring case
+INSERT INTO authors (created_at, updated_at, name)
+SELECT min(created_at), min(created_at), min(author) FROM books GROUP BY lower(author);
+
+INSERT INTO book_authors (book_id, author_id, role, position)
+SELECT books.id, authors.id, 'author', 1
+FROM books JOIN authors ON lower(authors.name) = lower(books.author);
+
+-- Add indexes
+CREATE INDEX authors_name_lower ON authors (lower(name));
+CREATE INDEX book_authors_author ON book_authors (author_id);
@@ -0,0 +1,4 @@
+-- Delete tables
+DROP TABLE IF EXISTS reading_progress;
+DROP TABLE IF EXISTS reading_list_books;
+DROP TABLE IF EXISTS reading_lists;
@@ -0,0 +1,34 @@
+-- Create reading_lists table, built-in and custom lists of books kept by users
+CREATE TABLE reading_lists (
+                     id UUID DEFAULT uuid_generate_v4 () PRIMARY KEY,
+                     created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW (),
+                     updated_at TIMESTAMP NULL,
+                     user_id UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+                     name VARCHAR (100) NOT NULL,
+                     kind VARCHAR (25) NOT NULL DEFAULT 'custom' CHECK (kind IN ('want_to_read', 'reading', 'finished', 'custom')),
+                     is_public BOOLEAN NOT NULL DEFAULT FALSE,
+                     share_token VARCHAR (64) NULL UNIQUE
+);
+
+-- Create reading_list_books table, books of lists in order
+CREATE TABLE reading_list_books (
+                     list_id UUID NOT NULL REFERENCES reading_lists (id) ON DELETE CASCADE,
+                     book_id UUID NOT NULL REFERENCES books (id) ON DELETE CASCADE,
+                     position INT NOT NULL,
+                     added_at TIMESTAMP WITH TIME ZONE DEFAULT NOW (),
+                     PRIMARY KEY (list_id, book_id)
+);
+
+-- Create reading_progress table, how far users got in books
+CREATE TABLE reading_progress (
+                     user_id UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+                     book_id UUID NOT NULL REFERENCES books (id) ON DELETE CASCADE,
+                     updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW (),
+                     percent INT NOT NULL CHECK (percent BETWEEN 0 AND 100),
+                     page INT NULL CHECK (page >= 0),
+                     PRIMARY KEY (user_id, book_id)
+);
+
+-- Add indexes, each user has one list of every built-in kind
+CREATE UNIQUE INDEX reading_lists_user_kind ON reading_lists (user_id, kind) WHERE kind <> 'custom';
+CREATE INDEX reading_list_books_book ON reading_list_books (book_id);
@@ -0,0 +1,4 @@
+-- Delete tables
+DROP TABLE IF EXISTS book_holds;
+DROP TABLE IF EXISTS book_loans;
+DROP TABLE IF EXISTS book_copies;
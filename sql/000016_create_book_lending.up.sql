@@ -0,0 +1,39 @@
+-- Create book_copies table, physical copies of books the library lends
+CREATE TABLE book_copies (
+                     id UUID DEFAULT uuid_generate_v4 () PRIMARY KEY,
+                     created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW (),
+                     book_id UUID NOT NULL REFERENCES books (id) ON DELETE CASCADE,
+                     barcode VARCHAR (64) NULL UNIQUE,
+                     notes VARCHAR (255) NOT NULL DEFAULT ''
+);
+
+-- Create book_loans table, copies checked out by users, kept after they are returned
+CREATE TABLE book_loans (
+                     id UUID DEFAULT uuid_generate_v4 () PRIMARY KEY,
+                     copy_id UUID NOT NULL REFERENCES book_copies (id) ON DELETE CASCADE,
+                     book_id UUID NOT NULL REFERENCES books (id) ON DELETE CASCADE,
+                     user_id UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+                     loaned_at TIMESTAMP WITH TIME ZONE DEFAULT NOW (),
+                     due_at TIMESTAMP WITH TIME ZONE NOT NULL,
+                     returned_at TIMESTAMP WITH TIME ZONE NULL,
+                     renewals INT NOT NULL DEFAULT 0
+);
+
+-- Create book_holds table, users queueing for a book while all copies are out
+-- A returned copy is set aside for the oldest hold, which is then ready to be checked out
+CREATE TABLE book_holds (
+                     id UUID DEFAULT uuid_generate_v4 () PRIMARY KEY,
+                     created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW (),
+                     book_id UUID NOT NULL REFERENCES books (id) ON DELETE CASCADE,
+                     user_id UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+                     copy_id UUID NULL UNIQUE REFERENCES book_copies (id) ON DELETE SET NULL,
+                     ready_at TIMESTAMP WITH TIME ZONE NULL
+);
+
+-- Add indexes, a copy is out to one user at a time and a user borrows or holds a book once
+CREATE UNIQUE INDEX book_loans_copy_active ON book_loans (copy_id) WHERE returned_at IS NULL;
+CREATE UNIQUE INDEX book_loans_book_user_active ON book_loans (book_id, user_id) WHERE returned_at IS NULL;
+CREATE INDEX book_loans_user ON book_loans (user_id);
+CREATE INDEX book_loans_due ON book_loans (due_at) WHERE returned_at IS NULL;
+CREATE INDEX book_copies_book ON book_copies (book_id);
+CREATE UNIQUE INDEX book_holds_book_user ON book_holds (book_id, user_id);
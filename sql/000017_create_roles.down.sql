@@ -0,0 +1,21 @@
+-- Keep one role per user, the one with the most permissions
+ALTER TABLE users ADD COLUMN user_role VARCHAR (25) NOT NULL DEFAULT 'user';
+
+UPDATE users SET user_role = top.name
+FROM (
+    SELECT DISTINCT ON (user_roles.user_id) user_roles.user_id, roles.name
+    FROM user_roles
+    JOIN roles ON roles.id = user_roles.role_id
+    LEFT JOIN role_permissions ON role_permissions.role_id = roles.id
+    GROUP BY user_roles.user_id, roles.id, roles.name
+    ORDER BY user_roles.user_id, count(role_permissions.permission_id) DESC
+) AS top
+WHERE top.user_id = users.id;
+
+ALTER TABLE users ALTER COLUMN user_role DROP DEFAULT;
+
+-- Delete tables
+DROP TABLE IF EXISTS user_roles;
+DROP TABLE IF EXISTS role_permissions;
+DROP TABLE IF EXISTS permissions;
+DROP TABLE IF EXISTS roles;
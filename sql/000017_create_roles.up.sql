@@ -0,0 +1,64 @@
+-- Create roles table, users get the permissions of all their roles
+CREATE TABLE roles (
+                     id UUID DEFAULT uuid_generate_v4 () PRIMARY KEY,
+                     created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW (),
+                     updated_at TIMESTAMP NULL,
+                     name VARCHAR (25) NOT NULL UNIQUE,
+                     description VARCHAR (255) NOT NULL DEFAULT ''
+);
+
+-- Create permissions table, carried by name in access tokens
+CREATE TABLE permissions (
+                     id UUID DEFAULT uuid_generate_v4 () PRIMARY KEY,
+                     created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW (),
+                     name VARCHAR (50) NOT NULL UNIQUE,
+                     description VARCHAR (255) NOT NULL DEFAULT ''
+);
+
+-- Create many-to-many joins of roles
+CREATE TABLE role_permissions (
+                     role_id UUID NOT NULL REFERENCES roles (id) ON DELETE CASCADE,
+                     permission_id UUID NOT NULL REFERENCES permissions (id) ON DELETE CASCADE,
+                     PRIMARY KEY (role_id, permission_id)
+);
+
+CREATE TABLE user_roles (
+                     user_id UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+                     role_id UUID NOT NULL REFERENCES roles (id) ON DELETE CASCADE,
+                     PRIMARY KEY (user_id, role_id)
+);
+
+-- Seed the roles and permissions built in so far
+INSERT INTO roles (name, description) VALUES
+    ('admin', 'All access, also on books and accounts of other users'),
+    ('moderator', 'Book creation and update, also on books of other users, tags and categories, publishing and lending'),
+    ('user', 'Book creation only');
+
+INSERT INTO permissions (name, description) VALUES
+    ('book:create', 'Create a new book'),
+    ('book:update', 'Update own books'),
+    ('book:delete', 'Delete own books'),
+    ('book:update:any', 'Update books of other users, audited'),
+    ('book:delete:any', 'Delete books of other users, audited'),
+    ('book:taxonomy', 'Manage tags and categories of books'),
+    ('book:publish', 'Publish and unpublish books of any user'),
+    ('book:attrs', 'Register extra attributes of books'),
+    ('book:lending', 'Manage copies of books and loans of any user'),
+    ('user:manage', 'Block and unblock users'),
+    ('role:manage', 'Manage roles and permissions, and the roles of users');
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT roles.id, permissions.id FROM roles CROSS JOIN permissions
+WHERE roles.name = 'admin'
+   OR (roles.name = 'moderator' AND permissions.name IN ('book:create', 'book:update', 'book:update:any', 'book:taxonomy', 'book:publish', 'book:lending'))
+   OR (roles.name = 'user' AND permissions.name = 'book:create');
+
+-- Back-fill the role of every user, users can have more than one from now on
+INSERT INTO user_roles (user_id, role_id)
+SELECT users.id, roles.id FROM users JOIN roles ON roles.name = users.user_role;
+
+ALTER TABLE users DROP COLUMN user_role;
+
+-- Add indexes
+CREATE INDEX role_permissions_permission ON role_permissions (permission_id);
+CREATE INDEX user_roles_role ON user_roles (role_id);
@@ -0,0 +1,18 @@
+-- Count reviews without locking the book
+CREATE OR REPLACE FUNCTION refresh_book_rating() RETURNS TRIGGER AS $$
+DECLARE
+  changed_book_id UUID;
+BEGIN
+  IF TG_OP = 'DELETE' THEN
+    changed_book_id := OLD.book_id;
+  ELSE
+    changed_book_id := NEW.book_id;
+  END IF;
+
+  UPDATE books SET
+    rating_average = coalesce((SELECT avg(rating) FROM reviews WHERE book_id = changed_book_id), 0),
+    rating_count = (SELECT count(*) FROM reviews WHERE book_id = changed_book_id)
+  WHERE id = changed_book_id;
+  RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;